@@ -1,14 +1,12 @@
 package main
 
 import (
-	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/config"
-	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/app"
-	"go.uber.org/zap"
+	"os"
+
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/cli"
 )
 
-// main точка старта приложения
+// main точка старта приложения. Без аргументов запускается сервер, список команд: main --help.
 func main() {
-	logger := zap.Must(zap.NewProduction())
-	config.Read("./config/config.yaml", logger)
-	app.Run(logger)
+	os.Exit(cli.Run(os.Args[1:]))
 }
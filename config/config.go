@@ -34,6 +34,27 @@ func readEnvAndSetDefault(logger *zap.Logger) {
 	}
 
 	viper.SetDefault("postgres.sslmode", "disable")
+	viper.SetDefault("postgres.pool.max_conns", 10)
+	viper.SetDefault("postgres.pool.min_conns", 2)
+	viper.SetDefault("postgres.pool.max_conn_lifetime", time.Hour)
+	viper.SetDefault("postgres.pool.max_conn_idle_time", 30*time.Minute)
+	viper.SetDefault("postgres.pool.health_check_period", time.Minute)
+	viper.SetDefault("postgres.pool.connect_timeout", 5*time.Second)
+	viper.SetDefault("postgres.tx.max_retries", 3)
+	viper.SetDefault("postgres.tx.retry_backoff", 10*time.Millisecond)
+	viper.SetDefault("postgres.migrations.on_start", true)
+	viper.SetDefault("postgres.migrations.timeout", 5*time.Minute)
+
+	// MONGO
+	viper.SetDefault("mongo.host", "mongo")
+	viper.SetDefault("mongo.port", 27017)
+	viper.SetDefault("mongo.database", "health")
+	viper.SetDefault("mongo.timeout", 10*time.Second)
+
+	// MEMCACHED
+	viper.SetDefault("memcached.host", "memcached")
+	viper.SetDefault("memcached.port", 11211)
+	viper.SetDefault("memcached.user_profile_ttl", 5*time.Minute)
 
 	// SERVER
 	if address := os.Getenv("PS_SERVER_ADDRESS"); address != "" {
@@ -90,6 +111,85 @@ func readEnvAndSetDefault(logger *zap.Logger) {
 		viper.SetDefault("server.shutdown_duration", 10*time.Second)
 	}
 
+	// заголовок X-Real-IP можно использовать только за доверенным прокси (nginx), иначе клиент подделает адрес
+	viper.SetDefault("server.trust_x_real_ip", false)
+	// при остановке /readyz отвечает 503 за drain_delay до закрытия соединений, чтобы балансировщик успел
+	// исключить экземпляр
+	viper.SetDefault("server.drain_delay", 5*time.Second)
+
+	// METRICS
+	// метрики Prometheus отдаются на отдельном адресе, пустой адрес отключает их
+	viper.SetDefault("metrics.address", ":9090")
+
+	// HEALTH
+	viper.SetDefault("health.timeout", 2*time.Second)
+
+	// AUTH
+	viper.SetDefault("auth.access_token_ttl", 15*time.Minute)
+	viper.SetDefault("auth.refresh_token_ttl", 14*24*time.Hour)
+	viper.SetDefault("auth.mfa_challenge_ttl", 5*time.Minute)
+	viper.SetDefault("auth.totp_issuer", "Healthcheck")
+	viper.SetDefault("auth.argon2.memory", 64*1024)
+	viper.SetDefault("auth.argon2.time", 3)
+	viper.SetDefault("auth.argon2.threads", 2)
+	viper.SetDefault("auth.argon2.key_length", 32)
+	viper.SetDefault("auth.argon2.salt_length", 16)
+	viper.SetDefault("auth.password_policy.min_strength", 2)
+	viper.SetDefault("auth.password_policy.breached_dir", "")
+	viper.SetDefault("auth.lockout.free_attempts", 3)
+	viper.SetDefault("auth.lockout.account_threshold", 10)
+	viper.SetDefault("auth.lockout.ip_threshold", 50)
+	viper.SetDefault("auth.lockout.backoff_base", time.Second)
+	viper.SetDefault("auth.lockout.backoff_max", time.Minute)
+	viper.SetDefault("auth.lockout.duration", 15*time.Minute)
+	viper.SetDefault("auth.lockout.window", time.Hour)
+	viper.SetDefault("auth.api_keys.max_per_user", 20)
+	viper.SetDefault("oidc.flow_ttl", 10*time.Minute)
+	viper.SetDefault("oidc.registration_ttl", 30*time.Minute)
+	// привязывать внешнюю учетную запись к пользователю с той же подтвержденной почтой без входа в его учетную запись
+	viper.SetDefault("oidc.link_by_email", false)
+
+	// CORS
+	viper.SetDefault("cors.allowed_origins", []string{"http://localhost:3000"})
+	viper.SetDefault("cors.allowed_methods", []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"})
+	viper.SetDefault("cors.allowed_headers", []string{"Content-Type", "Authorization", "X-CSRF-Token"})
+	viper.SetDefault("cors.exposed_headers", []string{"X-CSRF-Token", "Retry-After"})
+	viper.SetDefault("cors.allow_credentials", true)
+	viper.SetDefault("cors.max_age", 10*time.Minute)
+
+	// COOKIE
+	// по умолчанию cookie сессии недоступны js, передаются только по HTTPS и не отправляются со сторонних сайтов
+	viper.SetDefault("cookie.http_only", true)
+	viper.SetDefault("cookie.secure", true)
+	viper.SetDefault("cookie.same_site", "strict")
+
+	// ADMIN
+	viper.SetDefault("admin.page_size", 20)
+	viper.SetDefault("admin.max_page_size", 100)
+
+	// TRACING
+	// none — спаны не экспортируются, но trace_id все равно пишется в журнал запросов
+	viper.SetDefault("tracing.exporter", "none")
+	viper.SetDefault("tracing.service_name", "healthcheck")
+	viper.SetDefault("tracing.sample_ratio", 1.0)
+	viper.SetDefault("tracing.otlp.endpoint", "http://localhost:4318/v1/traces")
+	viper.SetDefault("tracing.otlp.headers", map[string]string{})
+	viper.SetDefault("tracing.otlp.timeout", 10*time.Second)
+	viper.SetDefault("tracing.file.path", "traces.jsonl")
+
+	// STORAGE
+	// memory — режим разработки: данные хранятся в памяти процесса, базы данных не нужны
+	viper.SetDefault("storage", "postgres")
+
+	// MEASUREMENTS
+	viper.SetDefault("measurements.storage", "postgres")
+	viper.SetDefault("measurements.max_batch", 500)
+	viper.SetDefault("measurements.page_size", 500)
+	viper.SetDefault("measurements.max_page_size", 5000)
+	viper.SetDefault("measurements.mongo.collection", "measurements")
+	viper.SetDefault("measurements.mongo.granularity", "minutes")
+	viper.SetDefault("measurements.mongo.retention", time.Duration(0))
+
 	viper.SetDefault("secret_key", uuid.NewV4().String())
 }
 
@@ -0,0 +1,234 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// Validate проверяет прочитанную конфигурацию и возвращает все найденные ошибки. viper молча превращает
+// некорректные значения в нулевые, поэтому значения разбираются заново из исходного вида.
+func Validate() error {
+	v := &validator{}
+
+	// SERVER
+	v.nonEmpty("server.address")
+	for _, key := range []string{"server.write_timeout", "server.read_timeout", "server.idle_timeout", "server.shutdown_duration"} {
+		v.positiveDuration(key)
+	}
+	v.duration("server.drain_delay")
+	v.positiveDuration("health.timeout")
+	// метрики не должны попасть на адрес API, иначе их увидят клиенты сервиса
+	if addr := viper.GetString("metrics.address"); addr != "" && addr == viper.GetString("server.address") {
+		v.add("metrics.address", errors.New("must differ from server.address"))
+	}
+
+	// POSTGRES
+	v.port("postgres.port")
+	for _, key := range []string{"postgres.user", "postgres.connectionHost", "postgres.database_name"} {
+		v.nonEmpty(key)
+	}
+	maxConns := v.intInRange("postgres.pool.max_conns", 1, 1<<15)
+	minConns := v.intInRange("postgres.pool.min_conns", 0, 1<<15)
+	if minConns > maxConns {
+		v.add("postgres.pool.min_conns", fmt.Errorf("must not exceed postgres.pool.max_conns (%d)", maxConns))
+	}
+	for _, key := range []string{"postgres.pool.max_conn_lifetime", "postgres.pool.max_conn_idle_time",
+		"postgres.pool.health_check_period", "postgres.pool.connect_timeout", "postgres.migrations.timeout"} {
+		v.positiveDuration(key)
+	}
+	v.intInRange("postgres.tx.max_retries", 0, 100)
+	v.duration("postgres.tx.retry_backoff")
+
+	// MEMCACHED
+	v.port("memcached.port")
+	// Memcached считает срок больше 30 дней моментом времени unix, а не длительностью
+	if ttl := v.positiveDuration("memcached.user_profile_ttl"); ttl > 30*24*time.Hour {
+		v.add("memcached.user_profile_ttl", errors.New("must not exceed 720h"))
+	}
+
+	// AUTH
+	accessTTL := v.positiveDuration("auth.access_token_ttl")
+	refreshTTL := v.positiveDuration("auth.refresh_token_ttl")
+	if accessTTL > 0 && refreshTTL > 0 && accessTTL >= refreshTTL {
+		v.add("auth.access_token_ttl", errors.New("must be shorter than auth.refresh_token_ttl"))
+	}
+	v.positiveDuration("auth.mfa_challenge_ttl")
+	for _, key := range []string{"auth.argon2.memory", "auth.argon2.time", "auth.argon2.threads"} {
+		v.intInRange(key, 1, 1<<31-1)
+	}
+	v.intInRange("auth.argon2.key_length", 16, 1024)
+	v.intInRange("auth.argon2.salt_length", 8, 1024)
+	v.intInRange("auth.password_policy.min_strength", 0, 4)
+	v.intInRange("auth.lockout.free_attempts", 0, 1<<20)
+	v.intInRange("auth.lockout.account_threshold", 1, 1<<20)
+	v.intInRange("auth.lockout.ip_threshold", 1, 1<<20)
+	backoffBase := v.positiveDuration("auth.lockout.backoff_base")
+	backoffMax := v.positiveDuration("auth.lockout.backoff_max")
+	if backoffBase > backoffMax {
+		v.add("auth.lockout.backoff_base", errors.New("must not exceed auth.lockout.backoff_max"))
+	}
+	v.positiveDuration("auth.lockout.duration")
+	v.positiveDuration("auth.lockout.window")
+	v.intInRange("auth.api_keys.max_per_user", 1, 1<<20)
+	v.positiveDuration("oidc.flow_ttl")
+	v.positiveDuration("oidc.registration_ttl")
+
+	// CORS
+	for _, origin := range viper.GetStringSlice("cors.allowed_origins") {
+		if _, err := path.Match(origin, ""); err != nil {
+			v.add("cors.allowed_origins", fmt.Errorf("invalid pattern %q: %w", origin, err))
+		}
+	}
+	if viper.GetBool("cors.allow_credentials") {
+		for _, origin := range viper.GetStringSlice("cors.allowed_origins") {
+			if origin == "*" {
+				v.add("cors.allowed_origins", errors.New(`"*" allows any site to send credentialed requests`))
+			}
+		}
+	}
+	v.duration("cors.max_age")
+
+	// COOKIE
+	switch sameSite := strings.ToLower(viper.GetString("cookie.same_site")); sameSite {
+	case "strict", "lax":
+	case "none":
+		if !viper.GetBool("cookie.secure") {
+			v.add("cookie.same_site", errors.New("none requires cookie.secure: true"))
+		}
+	default:
+		v.add("cookie.same_site", fmt.Errorf("unknown value %q, allowed: strict, lax, none", sameSite))
+	}
+
+	// ADMIN
+	pageSize := v.intInRange("admin.page_size", 1, 1<<20)
+	maxPageSize := v.intInRange("admin.max_page_size", 1, 1<<20)
+	if pageSize > maxPageSize {
+		v.add("admin.page_size", errors.New("must not exceed admin.max_page_size"))
+	}
+
+	// TRACING
+	switch exporter := viper.GetString("tracing.exporter"); exporter {
+	case mc.TracingExporterNone, mc.TracingExporterStdout:
+	case mc.TracingExporterOTLP:
+		if endpoint, err := url.Parse(viper.GetString("tracing.otlp.endpoint")); err != nil || endpoint.Host == "" ||
+			(endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+			v.add("tracing.otlp.endpoint", errors.New("must be an absolute http or https URL"))
+		}
+		v.positiveDuration("tracing.otlp.timeout")
+	case mc.TracingExporterFile:
+		v.nonEmpty("tracing.file.path")
+	default:
+		v.add("tracing.exporter", fmt.Errorf("unknown value %q, allowed: %s, %s, %s, %s", exporter,
+			mc.TracingExporterNone, mc.TracingExporterOTLP, mc.TracingExporterStdout, mc.TracingExporterFile))
+	}
+	v.nonEmpty("tracing.service_name")
+	if ratio, err := cast.ToFloat64E(viper.Get("tracing.sample_ratio")); err != nil {
+		v.add("tracing.sample_ratio", err)
+	} else if ratio < 0 || ratio > 1 {
+		v.add("tracing.sample_ratio", fmt.Errorf("must be in range [0, 1], got %v", ratio))
+	}
+
+	// STORAGE
+	storage := viper.GetString("storage")
+	switch storage {
+	case mc.StoragePostgres, mc.StorageMemory:
+	default:
+		v.add("storage", fmt.Errorf("unknown value %q, allowed: %s, %s", storage, mc.StoragePostgres, mc.StorageMemory))
+	}
+
+	// MEASUREMENTS
+	v.intInRange("measurements.max_batch", 1, 1<<20)
+	measurePageSize := v.intInRange("measurements.page_size", 1, 1<<20)
+	measureMaxPageSize := v.intInRange("measurements.max_page_size", 1, 1<<20)
+	if measurePageSize > measureMaxPageSize {
+		v.add("measurements.page_size", errors.New("must not exceed measurements.max_page_size"))
+	}
+	switch measureStorage := viper.GetString("measurements.storage"); measureStorage {
+	case mc.MeasureStoragePostgres:
+	case mc.MeasureStorageMongo:
+		if storage == mc.StorageMemory {
+			break
+		}
+		v.port("mongo.port")
+		for _, key := range []string{"mongo.host", "mongo.database", "measurements.mongo.collection"} {
+			v.nonEmpty(key)
+		}
+		v.positiveDuration("mongo.timeout")
+		switch granularity := viper.GetString("measurements.mongo.granularity"); granularity {
+		case "seconds", "minutes", "hours":
+		default:
+			v.add("measurements.mongo.granularity", fmt.Errorf("unknown value %q, allowed: seconds, minutes, hours", granularity))
+		}
+		v.duration("measurements.mongo.retention")
+	default:
+		v.add("measurements.storage", fmt.Errorf("unknown value %q, allowed: %s, %s", measureStorage,
+			mc.MeasureStoragePostgres, mc.MeasureStorageMongo))
+	}
+
+	if len(viper.GetString("secret_key")) < 16 {
+		v.add("secret_key", errors.New("must be at least 16 characters long"))
+	}
+	return errors.Join(v.errs...)
+}
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) add(key string, err error) {
+	v.errs = append(v.errs, fmt.Errorf("%s: %w", key, err))
+}
+
+func (v *validator) nonEmpty(key string) {
+	if strings.TrimSpace(viper.GetString(key)) == "" {
+		v.add(key, errors.New("must not be empty"))
+	}
+}
+
+func (v *validator) duration(key string) time.Duration {
+	d, err := cast.ToDurationE(viper.Get(key))
+	if err != nil {
+		v.add(key, err)
+		return 0
+	}
+	if d < 0 {
+		v.add(key, errors.New("must not be negative"))
+	}
+	return d
+}
+
+func (v *validator) positiveDuration(key string) time.Duration {
+	d, err := cast.ToDurationE(viper.Get(key))
+	if err != nil {
+		v.add(key, err)
+		return 0
+	}
+	if d <= 0 {
+		v.add(key, errors.New("must be positive"))
+	}
+	return d
+}
+
+func (v *validator) intInRange(key string, min, max int) int {
+	n, err := cast.ToIntE(viper.Get(key))
+	if err != nil {
+		v.add(key, err)
+		return 0
+	}
+	if n < min || n > max {
+		v.add(key, fmt.Errorf("must be in range [%d, %d], got %d", min, max, n))
+	}
+	return n
+}
+
+func (v *validator) port(key string) {
+	v.intInRange(key, 1, 65535)
+}
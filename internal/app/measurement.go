@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	rMeasurement "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/measurement"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	mongodb "github.com/cantylv/hackathon-bmstu-2024-healthcheck/services/mongo"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.uber.org/zap"
+)
+
+// InitMeasurementStorage возвращает хранилище измерений, выбранное параметром measurements.storage, клиента
+// MongoDB (nil, если измерения хранятся в PostgreSQL) и функцию, закрывающую его соединения. Хранилище MongoDB
+// при первом запуске создает коллекцию временных рядов.
+func InitMeasurementStorage(postgresClient *pgxpool.Pool, logger *zap.Logger) (rMeasurement.Repo, *mongo.Client, func()) {
+	if viper.GetString("measurements.storage") != mc.MeasureStorageMongo {
+		return rMeasurement.NewRepoLayer(postgresClient), nil, func() {}
+	}
+	mongoClient := mongodb.Init(logger)
+	closeMongo := func() {
+		err := mongoClient.Disconnect(context.Background())
+		if err != nil {
+			logger.Error(fmt.Sprintf("error while closing connection with mongo: %v", err))
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), viper.GetDuration("mongo.timeout"))
+	defer cancel()
+	coll, err := mongodb.EnsureTimeSeries(ctx, mongoClient)
+	if err != nil {
+		closeMongo()
+		logger.Fatal(fmt.Sprintf("error while preparing measurements collection: %v", err))
+	}
+	logger.Info("measurements are stored in MongoDB time series collection " + coll.Name())
+	return rMeasurement.NewRepoMongo(coll), mongoClient, closeMongo
+}
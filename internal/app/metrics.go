@@ -0,0 +1,38 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/metrics"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// InitMetricsServer запускает сервер, который отдает метрики Prometheus по /metrics на metrics.address.
+// Адрес отделен от адреса API, чтобы метрики не были доступны клиентам сервиса: порт метрик открывается
+// только для Prometheus. Пустой metrics.address отключает сервер, тогда возвращается nil.
+func InitMetricsServer(logger *zap.Logger) *http.Server {
+	addr := viper.GetString("metrics.address")
+	if addr == "" {
+		logger.Info("metrics server is disabled")
+		return nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+	srv := &http.Server{
+		Handler:      mux,
+		Addr:         addr,
+		WriteTimeout: viper.GetDuration("server.write_timeout"),
+		ReadTimeout:  viper.GetDuration("server.read_timeout"),
+		IdleTimeout:  viper.GetDuration("server.idle_timeout"),
+	}
+	go func() {
+		logger.Info(fmt.Sprintf("metrics server has started at the address %s", addr))
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error(fmt.Sprintf("metrics server has stopped: %v", err))
+		}
+	}()
+	return srv
+}
@@ -6,34 +6,80 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
+	"time"
 
+	gomemcache "github.com/bradfitz/gomemcache/memcache"
+	dHealth "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/health"
 	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/route"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/password"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/services/memcache"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/services/oidc"
 	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/services/postgres"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/services/postgres/migrations"
 	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
 // Run движок нашего сервера, здесь инициализируется доступ к БД, обработчики запросов.
 func Run(logger *zap.Logger) {
-	// init psql
-	postgresClient := postgres.Init(logger)
-	// mongoClient := mongodb.Init(logger)
-	defer func() {
-		err := postgresClient.Close(context.Background())
-		if err != nil {
-			logger.Error(fmt.Sprintf("error while closing connection with psql: %v", err))
+	// init tracing before storage, so queries on start are traced too
+	shutdownTracing := InitTracing(logger)
+	// dependencies checked by /readyz
+	var checks []dHealth.Check
+	// init storage: postgres or process memory for development
+	var repos *repo.Repos
+	if viper.GetString("storage") == mc.StorageMemory {
+		logger.Warn("storage is memory: data is kept in process memory and will be lost on shutdown")
+		repos = repo.NewMemory()
+	} else {
+		// init psql
+		postgresClient := postgres.Init(logger)
+		defer postgresClient.Close()
+		// migrate schema
+		if viper.GetBool("postgres.migrations.on_start") {
+			migrate(postgresClient, logger)
 		}
-		// err = mongoClient.Disconnect(context.Background())
-		// if err != nil {
-		// 	logger.Error(fmt.Sprintf("error while closing connection with mongo: %v", err))
-		// }
-	}()
+		repos = repo.NewPostgres(postgresClient)
+		checks = append(checks, dHealth.Check{Name: "postgres", Ping: postgresClient.Ping})
+		// init measurements storage: postgres or mongo time series
+		repoMeasurement, mongoClient, closeMeasurement := InitMeasurementStorage(postgresClient, logger)
+		defer closeMeasurement()
+		repos.Measurement = repoMeasurement
+		if mongoClient != nil {
+			checks = append(checks, dHealth.Check{Name: "mongo", Ping: func(ctx context.Context) error {
+				return mongoClient.Ping(ctx, nil)
+			}})
+		}
+	}
+	// init memcached. It keeps sign-in attempt counters shared by all instances, so once configured it is
+	// required: its unavailability is reported by /readyz instead of switching to per-instance counters
+	var memcacheClient *gomemcache.Client
+	switch {
+	case viper.GetString("storage") == mc.StorageMemory:
+		logger.Info("storage is memory, memcached is not used")
+	case !memcache.Enabled():
+		logger.Warn("memcached is disabled: sign-in attempt counters are kept per instance, profiles are not cached")
+	default:
+		memcacheClient = memcache.Init(logger)
+		checks = append(checks, dHealth.Check{Name: "memcached", Ping: func(ctx context.Context) error {
+			return memcache.Ping(ctx, memcacheClient)
+		}})
+	}
+	oidcProviders := oidc.Init(logger)
+	// check password policy
+	if err := password.CheckBreachedDir(); err != nil {
+		logger.Warn(fmt.Sprintf("breached passwords corpus is unavailable, check is disabled: %v", err))
+	}
 	// define handlers
 	r := mux.NewRouter()
 	// run server
-	// handler := route.InitHTTPHandlers(r, postgresClient, mongoClient, logger)
-	handler := route.InitHTTPHandlers(r, postgresClient, logger)
+	healthHandlerManager := dHealth.NewHealthHandlerManager(checks, viper.GetDuration("health.timeout"), logger)
+	handler := route.InitHTTPHandlers(r, repos, memcacheClient, oidcProviders, healthHandlerManager, logger)
 	srv := &http.Server{
 		Handler:      handler,
 		Addr:         viper.GetString("server.address"),
@@ -42,6 +88,8 @@ func Run(logger *zap.Logger) {
 		IdleTimeout:  viper.GetDuration("server.idle_timeout"),
 	}
 
+	metricsSrv := InitMetricsServer(logger)
+
 	go func() {
 		logger.Info(fmt.Sprintf("server has started at the address %s", viper.GetString("server.address")))
 		if err := srv.ListenAndServe(); err != nil {
@@ -51,15 +99,44 @@ func Run(logger *zap.Logger) {
 
 	// graceful shutdown
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	<-c
 
+	// /readyz fails first, so load balancers stop sending new requests before connections are closed
+	healthHandlerManager.Drain()
+	if delay := viper.GetDuration("server.drain_delay"); delay > 0 {
+		logger.Info(fmt.Sprintf("server is draining, shutdown in %s", delay))
+		time.Sleep(delay)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), viper.GetDuration("server.shutdown_duration"))
 	defer cancel()
 	err := srv.Shutdown(ctx)
+	if metricsSrv != nil {
+		if err := metricsSrv.Shutdown(ctx); err != nil {
+			logger.Warn(fmt.Sprintf("metrics server has shut down with an error: %v", err))
+		}
+	}
+	shutdownTracing(ctx)
 	if err != nil {
 		logger.Error(fmt.Sprintf("server has shut down with an error: %v", err))
 		os.Exit(1)
 	}
 	logger.Info("server has shut down")
 }
+
+// migrate применяет миграции схемы базы данных. Сервис со старой схемой работать не сможет, поэтому
+// ошибка миграции останавливает запуск.
+func migrate(postgresClient *pgxpool.Pool, logger *zap.Logger) {
+	migrator, err := migrations.New(postgresClient, logger)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("error while loading migrations: %v", err))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), viper.GetDuration("postgres.migrations.timeout"))
+	defer cancel()
+	applied, err := migrator.Up(ctx, false)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("error while migrating postgresql schema: %v", err))
+	}
+	logger.Info(fmt.Sprintf("postgresql schema is up to date, %d migrations applied", len(applied)))
+}
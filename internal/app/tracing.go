@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.uber.org/zap"
+)
+
+// InitTracing настраивает OpenTelemetry: заголовок W3C traceparent принимается и передается дальше, спаны
+// отправляются экспортеру, выбранному параметром tracing.exporter. Возвращает функцию, которая отправляет
+// накопленные спаны при остановке. С exporter: none спаны не экспортируются, но trace_id все равно есть
+// у каждого запроса и пишется в журнал.
+func InitTracing(logger *zap.Logger) func(ctx context.Context) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn(fmt.Sprintf("tracing error: %v", err))
+	}))
+
+	exporter, closeExporter, err := newSpanExporter()
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("error while creating tracing exporter: %v", err))
+	}
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName(viper.GetString("tracing.service_name")))),
+		// решение о выборке зависит только от trace_id, поэтому одинаково во всех сервисах трассировки
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(viper.GetFloat64("tracing.sample_ratio")))),
+	}
+	if exporter != nil {
+		// спаны отправляются пачками в фоне, чтобы экспорт не задерживал запросы
+		options = append(options, sdktrace.WithBatcher(exporter))
+		logger.Info(fmt.Sprintf("tracing is enabled, spans are exported to %s", viper.GetString("tracing.exporter")))
+	}
+	provider := sdktrace.NewTracerProvider(options...)
+	otel.SetTracerProvider(provider)
+	return func(ctx context.Context) {
+		if err := provider.Shutdown(ctx); err != nil {
+			logger.Error(fmt.Sprintf("error while flushing spans: %v", err))
+		}
+		if err := closeExporter(); err != nil {
+			logger.Error(fmt.Sprintf("error while closing tracing file: %v", err))
+		}
+	}
+}
+
+// newSpanExporter возвращает экспортер, выбранный параметром tracing.exporter, и функцию, которая освобождает
+// его файл. Для exporter: none экспортер nil.
+func newSpanExporter() (sdktrace.SpanExporter, func() error, error) {
+	noop := func() error { return nil }
+	switch viper.GetString("tracing.exporter") {
+	case mc.TracingExporterOTLP:
+		exporter, err := otlptracehttp.New(context.Background(),
+			otlptracehttp.WithEndpointURL(viper.GetString("tracing.otlp.endpoint")),
+			otlptracehttp.WithHeaders(viper.GetStringMapString("tracing.otlp.headers")),
+			otlptracehttp.WithTimeout(viper.GetDuration("tracing.otlp.timeout")),
+		)
+		return exporter, noop, err
+	case mc.TracingExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		return exporter, noop, err
+	case mc.TracingExporterFile:
+		file, err := os.OpenFile(viper.GetString("tracing.file.path"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, noop, err
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, noop, err
+		}
+		return exporter, file.Close, nil
+	default:
+		return nil, noop, nil
+	}
+}
@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/config"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/app"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// коды завершения: ошибка выполнения команды и неверные аргументы
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// errUsage ошибка в аргументах команды, описание уже выведено вместе со справкой.
+var errUsage = errors.New("invalid usage")
+
+const usage = `Использование: healthcheck [--config путь] [--log-level уровень] <команда> [аргументы]
+
+Команды:
+  serve [--storage postgres|memory]     запустить HTTP-сервер (по умолчанию)
+  migrate up [--dry-run]                применить миграции схемы базы данных
+  migrate down [--steps N] [--dry-run]  откатить последние миграции
+  migrate status                        показать примененные и непримененные миграции
+  user create                           создать пользователя
+  user delete --username U              удалить пользователя
+  user reset-password --username U      потребовать сменить пароль при следующем входе
+  user set-role --username U --role R   назначить роль: user, coach или admin
+  seed --users N                        создать демонстрационных пользователей
+  config validate                       проверить конфигурацию
+
+Справка по аргументам команды: healthcheck <команда> --help
+`
+
+// env общие для всех команд параметры: логгер и потоки вывода. Конфигурация к моменту вызова команды
+// уже прочитана в viper.
+type env struct {
+	logger *zap.Logger
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// command выполняет команду с оставшимися после ее имени аргументами.
+type command func(e *env, args []string) error
+
+var commands = map[string]command{
+	"serve":   serve,
+	"migrate": migrate,
+	"user":    user,
+	"seed":    seed,
+	"config":  configCommand,
+}
+
+// Run разбирает глобальные флаги, читает конфигурацию и выполняет команду. Возвращает код завершения.
+// Без команды запускается сервер, чтобы образ и старые скрипты запуска работали без изменений.
+func Run(args []string) int {
+	fs := flag.NewFlagSet("healthcheck", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage, "\nГлобальные флаги:\n")
+		fs.PrintDefaults()
+	}
+	configPath := fs.String("config", "./config/config.yaml", "путь к файлу конфигурации")
+	logLevel := fs.String("log-level", "info", "уровень логирования: debug, info, warn, error")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	logger, err := newLogger(*logLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --log-level: %v\n", err)
+		return exitUsage
+	}
+	defer func() {
+		_ = logger.Sync()
+	}()
+
+	name, cmdArgs := "serve", fs.Args()
+	if len(cmdArgs) > 0 {
+		name, cmdArgs = cmdArgs[0], cmdArgs[1:]
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		fs.Usage()
+		return exitUsage
+	}
+
+	config.Read(*configPath, logger)
+	err = cmd(&env{logger: logger, stdout: os.Stdout, stderr: os.Stderr}, cmdArgs)
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	default:
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitError
+	}
+}
+
+// newLogger создает логгер в формате production с указанным уровнем.
+func newLogger(level string) (*zap.Logger, error) {
+	lvl, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	cfg := zap.NewProductionConfig()
+	cfg.Level = zap.NewAtomicLevelAt(lvl)
+	return cfg.Build()
+}
+
+// newFlagSet возвращает набор флагов команды. Ошибки разбора выводятся вместе со справкой по команде.
+func newFlagSet(e *env, name, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "Использование: healthcheck %s\n", synopsis)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags разбирает флаги команды и проверяет, что лишних аргументов нет.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "unexpected arguments: %v", fs.Args())
+	}
+	return nil
+}
+
+// usageError выводит описание ошибки в аргументах и справку по команде.
+func usageError(fs *flag.FlagSet, format string, a ...any) error {
+	fmt.Fprintf(fs.Output(), format+"\n", a...)
+	fs.Usage()
+	return errUsage
+}
+
+// subcommand выбирает подкоманду группы команд (migrate up, user create).
+func subcommand(e *env, group string, subcommands map[string]command, args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprintf(e.stderr, "Использование: healthcheck %s <команда>, команды: ", group)
+		fmt.Fprintln(e.stderr, strings.Join(slices.Sorted(maps.Keys(subcommands)), ", "))
+		if len(args) == 0 {
+			return errUsage
+		}
+		return flag.ErrHelp
+	}
+	cmd, ok := subcommands[args[0]]
+	if !ok {
+		fmt.Fprintf(e.stderr, "unknown command %q for %s\n", args[0], group)
+		return errUsage
+	}
+	return cmd(e, args[1:])
+}
+
+// serve проверяет конфигурацию и запускает HTTP-сервер.
+func serve(e *env, args []string) error {
+	fs := newFlagSet(e, "serve", "serve [--storage postgres|memory]")
+	storage := fs.String("storage", "", "хранилище данных, заменяет параметр storage: postgres или memory (без баз данных)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	switch *storage {
+	case "":
+	case mc.StoragePostgres, mc.StorageMemory:
+		viper.Set("storage", *storage)
+	default:
+		return usageError(fs, "--storage must be %s or %s", mc.StoragePostgres, mc.StorageMemory)
+	}
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("configuration is invalid:\n%w", err)
+	}
+	app.Run(e.logger)
+	return nil
+}
+
+// configCommand команды работы с конфигурацией.
+func configCommand(e *env, args []string) error {
+	return subcommand(e, "config", map[string]command{
+		"validate": configValidate,
+	}, args)
+}
+
+// configValidate проверяет конфигурацию и выводит все найденные ошибки.
+func configValidate(e *env, args []string) error {
+	fs := newFlagSet(e, "config validate", "config validate")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("configuration is invalid:\n%w", err)
+	}
+	fmt.Fprintln(e.stdout, "configuration is valid")
+	return nil
+}
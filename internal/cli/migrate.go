@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/services/postgres"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/services/postgres/migrations"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// migrate команды управления схемой базы данных.
+func migrate(e *env, args []string) error {
+	return subcommand(e, "migrate", map[string]command{
+		"up":     migrateUp,
+		"down":   migrateDown,
+		"status": migrateStatus,
+	}, args)
+}
+
+// migrateUp применяет непримененные миграции.
+func migrateUp(e *env, args []string) error {
+	fs := newFlagSet(e, "migrate up", "migrate up [--dry-run]")
+	dryRun := fs.Bool("dry-run", false, "только показать миграции, которые будут выполнены")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	return withMigrator(e, func(ctx context.Context, migrator *migrations.Migrator) error {
+		applied, err := migrator.Up(ctx, *dryRun)
+		if err != nil {
+			return fmt.Errorf("error while applying migrations: %w", err)
+		}
+		e.logger.Info(fmt.Sprintf("%d migrations applied", len(applied)), zap.Bool("dry_run", *dryRun))
+		return nil
+	})
+}
+
+// migrateDown откатывает последние примененные миграции.
+func migrateDown(e *env, args []string) error {
+	fs := newFlagSet(e, "migrate down", "migrate down [--steps N] [--dry-run]")
+	steps := fs.Int("steps", 1, "число откатываемых миграций")
+	dryRun := fs.Bool("dry-run", false, "только показать миграции, которые будут откачены")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *steps <= 0 {
+		return usageError(fs, "--steps must be positive")
+	}
+	return withMigrator(e, func(ctx context.Context, migrator *migrations.Migrator) error {
+		reverted, err := migrator.Down(ctx, *steps, *dryRun)
+		if err != nil {
+			return fmt.Errorf("error while reverting migrations: %w", err)
+		}
+		e.logger.Info(fmt.Sprintf("%d migrations reverted", len(reverted)), zap.Bool("dry_run", *dryRun))
+		return nil
+	})
+}
+
+// migrateStatus выводит список миграций и отметку о применении.
+func migrateStatus(e *env, args []string) error {
+	fs := newFlagSet(e, "migrate status", "migrate status")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	return withMigrator(e, func(ctx context.Context, migrator *migrations.Migrator) error {
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("error while reading migrations status: %w", err)
+		}
+		for _, s := range statuses {
+			mark := "pending"
+			if s.Applied {
+				mark = "applied"
+			}
+			fmt.Fprintf(e.stdout, "%04d_%s\t%s\n", s.Version, s.Name, mark)
+		}
+		return nil
+	})
+}
+
+// withMigrator подключается к базе и выполняет fn с ограничением postgres.migrations.timeout.
+func withMigrator(e *env, fn func(ctx context.Context, migrator *migrations.Migrator) error) error {
+	postgresClient := postgres.Init(e.logger)
+	defer postgresClient.Close()
+
+	migrator, err := migrations.New(postgresClient, e.logger)
+	if err != nil {
+		return fmt.Errorf("error while loading migrations: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), viper.GetDuration("postgres.migrations.timeout"))
+	defer cancel()
+	return fn(ctx, migrator)
+}
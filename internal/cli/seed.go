@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
+	rUser "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/services/postgres"
+)
+
+// seedMaxUsers ограничение на число демонстрационных пользователей за один запуск.
+const seedMaxUsers = 10000
+
+var (
+	seedFirstNames = []string{"Алексей", "Мария", "Иван", "Анна", "Дмитрий", "Елена", "Сергей", "Ольга",
+		"Никита", "Дарья", "Alex", "Kate"}
+	seedActivities = []string{"NFA", "LA", "MA", "HA", "EA"}
+	seedSexes      = []string{"M", "F"}
+)
+
+// seed создает демонстрационных пользователей со случайными профилями и общим паролем. Пароль хэшируется
+// один раз, иначе создание сотен пользователей заняло бы минуты.
+func seed(e *env, args []string) error {
+	fs := newFlagSet(e, "seed", "seed --users N [--password P]")
+	users := fs.Int("users", 0, "число создаваемых пользователей")
+	password := fs.String("password", "", "пароль всех пользователей, по умолчанию генерируется и выводится")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *users <= 0 || *users > seedMaxUsers {
+		return usageError(fs, "--users must be in range [1, %d]", seedMaxUsers)
+	}
+	if *password == "" {
+		pwd, err := newTemporaryPassword()
+		if err != nil {
+			return err
+		}
+		*password = pwd
+	} else if err := dto.ValidateNewPassword(*password); err != nil {
+		return usageError(fs, "%v", err)
+	}
+	hashedPassword, err := f.GetHashedPassword(*password)
+	if err != nil {
+		return err
+	}
+
+	postgresClient := postgres.Init(e.logger)
+	defer postgresClient.Close()
+	repoUser := rUser.NewRepoLayer(postgresClient)
+	txManager := transaction.NewManager(postgresClient)
+
+	// все пользователи создаются в одной транзакции: прерванный запуск не оставит часть данных
+	var created []string
+	err = txManager.Do(context.Background(), transaction.ReadCommitted, func(ctx context.Context) error {
+		created = created[:0]
+		taken := make(map[string]struct{}, *users)
+		for range *users {
+			u, err := createSeedUser(ctx, repoUser, hashedPassword, taken)
+			if err != nil {
+				return err
+			}
+			created = append(created, u.Username)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error while seeding users: %w", err)
+	}
+	for _, username := range created {
+		fmt.Fprintln(e.stdout, username)
+	}
+	fmt.Fprintf(e.stdout, "%d demo users created, password: %s\n", len(created), *password)
+	return nil
+}
+
+// createSeedUser создает пользователя со случайным профилем. Ошибка уникальности прервала бы всю
+// транзакцию, поэтому свободный никнейм подбирается заранее.
+func createSeedUser(ctx context.Context, repoUser rUser.Repo, hashedPassword string, taken map[string]struct{}) (*ent.User, error) {
+	username, err := freeSeedUsername(ctx, repoUser, taken)
+	if err != nil {
+		return nil, err
+	}
+	data := &dto.CreateData{
+		Username:         username,
+		FirstName:        seedFirstNames[rand.IntN(len(seedFirstNames))],
+		Weight:           float32(450+rand.IntN(700)) / 10,
+		Height:           150 + rand.IntN(50),
+		Age:              18 + rand.IntN(50),
+		Sex:              seedSexes[rand.IntN(len(seedSexes))],
+		PhysicalActivity: seedActivities[rand.IntN(len(seedActivities))],
+	}
+	return repoUser.Create(ctx, &ent.User{
+		Username:         data.Username,
+		FirstName:        data.FirstName,
+		Weight:           data.Weight,
+		Height:           data.Height,
+		Age:              data.Age,
+		Sex:              data.Sex,
+		PhysicalActivity: data.PhysicalActivity,
+		DayCalories:      float32(f.GetDayCalories(data)),
+		Password:         hashedPassword,
+		Role:             mc.RoleUser,
+	})
+}
+
+// freeSeedUsername подбирает никнейм demo_xxxxxx, который еще не занят.
+func freeSeedUsername(ctx context.Context, repoUser rUser.Repo, taken map[string]struct{}) (string, error) {
+	for range 10 {
+		username := fmt.Sprintf("demo_%06x", rand.IntN(1<<24))
+		if _, ok := taken[username]; ok {
+			continue
+		}
+		_, err := repoUser.GetByUsername(ctx, username)
+		if errors.Is(err, sql.ErrNoRows) {
+			taken[username] = struct{}{}
+			return username, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", me.ErrUserAlreadyExist
+}
@@ -0,0 +1,214 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/app"
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	rAttempt "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/attempt"
+	rAudit "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/audit"
+	rToken "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/token"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
+	rUser "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	ucAdmin "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/admin"
+	ucLockout "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/lockout"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/services/memcache"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/services/postgres"
+	"github.com/spf13/viper"
+)
+
+// cliUserAgent отмечает в журнале действия, выполненные из командной строки.
+const cliUserAgent = "healthcheck-cli"
+
+// user команды управления пользователями. Выполняются от имени администратора и записываются в журнал
+// действий так же, как действия через API администратора.
+func user(e *env, args []string) error {
+	return subcommand(e, "user", map[string]command{
+		"create":         userCreate,
+		"delete":         userDelete,
+		"reset-password": userResetPassword,
+		"set-role":       userSetRole,
+	}, args)
+}
+
+// userCreate создает пользователя с указанной ролью.
+func userCreate(e *env, args []string) error {
+	fs := newFlagSet(e, "user create", "user create --username U --first-name N --password P "+
+		"--weight W --height H --age A --sex M|F [--activity NFA|LA|MA|HA|EA] [--role user|coach|admin]")
+	var data dto.CreateData
+	fs.StringVar(&data.Username, "username", "", "никнейм")
+	fs.StringVar(&data.FirstName, "first-name", "", "имя")
+	fs.StringVar(&data.Password, "password", "", "пароль, должен соответствовать политике паролей")
+	weight := fs.Float64("weight", 0, "масса тела, кг")
+	fs.IntVar(&data.Height, "height", 0, "рост, см")
+	fs.IntVar(&data.Age, "age", 0, "возраст")
+	fs.StringVar(&data.Sex, "sex", "", "пол: M или F")
+	fs.StringVar(&data.PhysicalActivity, "activity", "MA", "уровень физической активности")
+	role := fs.String("role", mc.RoleUser, "роль: user, coach или admin")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	data.Weight = float32(*weight)
+	if err := data.Validate(); err != nil {
+		return usageError(fs, "%v", err)
+	}
+
+	return withAdmin(e, func(ctx context.Context, uc ucAdmin.Usecase) error {
+		u, err := uc.CreateUser(ctx, &data, *role, newCliEvent(mc.AuditAdminUserCreate, data.Username))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(e.stdout, "user %s created with role %s\n", u.Username, u.Role)
+		return nil
+	})
+}
+
+// userDelete удаляет пользователя.
+func userDelete(e *env, args []string) error {
+	fs := newFlagSet(e, "user delete", "user delete --username U")
+	username := fs.String("username", "", "никнейм")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return usageError(fs, "--username is required")
+	}
+
+	return withAdmin(e, func(ctx context.Context, uc ucAdmin.Usecase) error {
+		err := uc.DeleteUser(ctx, *username, newCliEvent(mc.AuditAdminUserDelete, *username))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(e.stdout, "user %s deleted\n", *username)
+		return nil
+	})
+}
+
+// userResetPassword требует от пользователя сменить пароль при следующем входе и завершает его сессии.
+// С --password или --generate пароль дополнительно заменяется временным: так можно вернуть доступ
+// пользователю, который забыл пароль.
+func userResetPassword(e *env, args []string) error {
+	fs := newFlagSet(e, "user reset-password", "user reset-password --username U [--password P | --generate]")
+	username := fs.String("username", "", "никнейм")
+	password := fs.String("password", "", "временный пароль")
+	generate := fs.Bool("generate", false, "сгенерировать временный пароль и вывести его")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return usageError(fs, "--username is required")
+	}
+	if *password != "" && *generate {
+		return usageError(fs, "--password and --generate are mutually exclusive")
+	}
+	if *generate {
+		pwd, err := newTemporaryPassword(*username)
+		if err != nil {
+			return err
+		}
+		*password = pwd
+	} else if *password != "" {
+		if err := dto.ValidateNewPassword(*password, *username); err != nil {
+			return usageError(fs, "%v", err)
+		}
+	}
+
+	return withAdmin(e, func(ctx context.Context, uc ucAdmin.Usecase) error {
+		event := newCliEvent(mc.AuditAdminPasswordReset, *username)
+		if *password == "" {
+			err := uc.ForcePasswordReset(ctx, *username, event)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(e.stdout, "user %s must change password at next sign in\n", *username)
+			return nil
+		}
+		err := uc.SetTemporaryPassword(ctx, *username, *password, event)
+		if err != nil {
+			return err
+		}
+		if *generate {
+			fmt.Fprintf(e.stdout, "temporary password for %s: %s\n", *username, *password)
+		}
+		fmt.Fprintf(e.stdout, "user %s must change the temporary password at next sign in\n", *username)
+		return nil
+	})
+}
+
+// userSetRole назначает пользователю роль.
+func userSetRole(e *env, args []string) error {
+	fs := newFlagSet(e, "user set-role", "user set-role --username U --role user|coach|admin")
+	username := fs.String("username", "", "никнейм")
+	role := fs.String("role", "", "роль: user, coach или admin")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *username == "" || *role == "" {
+		return usageError(fs, "--username and --role are required")
+	}
+
+	return withAdmin(e, func(ctx context.Context, uc ucAdmin.Usecase) error {
+		err := uc.SetRole(ctx, *username, *role, newCliEvent(mc.AuditAdminUserRole, *username))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(e.stdout, "user %s now has role %s\n", *username, *role)
+		return nil
+	})
+}
+
+// withAdmin подключается к базе и выполняет fn с usecase администратора. Блокировки входа хранятся
+// в Memcached и из командной строки не меняются, поэтому счетчики попыток здесь локальные.
+func withAdmin(e *env, fn func(ctx context.Context, uc ucAdmin.Usecase) error) error {
+	postgresClient := postgres.Init(e.logger)
+	defer postgresClient.Close()
+
+	// изменения пользователя должны сбросить его профиль в кэше сервиса
+	var repoUser rUser.Repo = rUser.NewRepoLayer(postgresClient)
+	if memcacheClient := memcache.Init(e.logger); memcacheClient != nil {
+		repoUser = rUser.NewRepoCache(repoUser, memcacheClient, viper.GetDuration("memcached.user_profile_ttl"))
+	}
+	// измерения в MongoDB удаляются вместе с пользователем
+	repoMeasurement, _, closeMeasurement := app.InitMeasurementStorage(postgresClient, e.logger)
+	defer closeMeasurement()
+	uc := ucAdmin.NewUsecaseLayer(
+		repoUser,
+		rToken.NewRepoLayer(postgresClient),
+		rAudit.NewRepoLayer(postgresClient),
+		repoMeasurement,
+		ucLockout.NewUsecaseLayer(rAttempt.NewRepoMemory(), ucLockout.NewNotifier(e.logger)),
+		transaction.NewManager(postgresClient),
+		e.logger,
+	)
+	return fn(context.Background(), uc)
+}
+
+// newCliEvent запись журнала о действии из командной строки. Администратор, запустивший команду,
+// сервису неизвестен, поэтому actor не заполняется.
+func newCliEvent(action, subject string) *ent.AuditEvent {
+	return &ent.AuditEvent{
+		Action:    action,
+		Subject:   subject,
+		UserAgent: cliUserAgent,
+		Details:   map[string]any{"source": "cli"},
+	}
+}
+
+// newTemporaryPassword генерирует случайный пароль, который проходит политику паролей.
+func newTemporaryPassword(personalData ...string) (string, error) {
+	for range 100 {
+		pwd, err := f.NewRandomString(12)
+		if err != nil {
+			return "", err
+		}
+		if dto.ValidateNewPassword(pwd, personalData...) == nil {
+			return pwd, nil
+		}
+	}
+	return "", errors.New("failed to generate a password that satisfies the password policy")
+}
@@ -0,0 +1,162 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	ucAdmin "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/admin"
+	ucAudit "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/audit"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// errStatus коды ответа на ошибки бизнес-логики, остальные ошибки считаются внутренними.
+var errStatus = map[error]int{
+	me.ErrAdminSelf:      http.StatusBadRequest,
+	me.ErrUserOwnsGroups: http.StatusBadRequest,
+	me.ErrUserNotExist:   http.StatusNotFound,
+}
+
+type AdminHandlerManager struct {
+	ucAdmin   ucAdmin.Usecase
+	ucAudit   ucAudit.Usecase
+	dbStat    func() *pgxpool.Stat
+	cacheStat func() ent.CacheStats
+	logger    *zap.Logger
+}
+
+// NewAdminHandlerManager возвращает менеджер хендлеров, отвечающих за управление пользователями администратором
+func NewAdminHandlerManager(ucAdmin ucAdmin.Usecase, ucAudit ucAudit.Usecase, dbStat func() *pgxpool.Stat,
+	cacheStat func() ent.CacheStats, logger *zap.Logger) *AdminHandlerManager {
+	return &AdminHandlerManager{
+		ucAdmin:   ucAdmin,
+		ucAudit:   ucAudit,
+		dbStat:    dbStat,
+		cacheStat: cacheStat,
+		logger:    logger,
+	}
+}
+
+// ListUsers ищет пользователей. Параметры запроса: q, activity, bmi, created_from, created_to, page, per_page.
+func (h *AdminHandlerManager) ListUsers(w http.ResponseWriter, r *http.Request) {
+	filter, page, err := getUserFilter(r.URL.Query())
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+		return
+	}
+	users, total, err := h.ucAdmin.ListUsers(r.Context(), filter, f.NewAuditEvent(r, mc.AuditAdminUsersList, ""))
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, getUserPage(users, total, page, filter.Limit), http.StatusOK)
+}
+
+// GetUser возвращает профиль пользователя.
+func (h *AdminHandlerManager) GetUser(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+	u, err := h.ucAdmin.GetUser(r.Context(), username, f.NewAuditEvent(r, mc.AuditAdminUserView, username))
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, getAdminUser(u), http.StatusOK)
+}
+
+// Lock блокирует учетную запись.
+func (h *AdminHandlerManager) Lock(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+	err := h.ucAdmin.Lock(r.Context(), username, f.NewAuditEvent(r, mc.AuditAdminUserLock, username))
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, dto.ResponseDetail{Detail: "Учетная запись заблокирована"}, http.StatusOK)
+}
+
+// Unlock разблокирует учетную запись.
+func (h *AdminHandlerManager) Unlock(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+	err := h.ucAdmin.Unlock(r.Context(), username, f.NewAuditEvent(r, mc.AuditAdminUserUnlock, username))
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, dto.ResponseDetail{Detail: "Учетная запись разблокирована"}, http.StatusOK)
+}
+
+// ForcePasswordReset требует от пользователя сменить пароль при следующем входе.
+func (h *AdminHandlerManager) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+	err := h.ucAdmin.ForcePasswordReset(r.Context(), username, f.NewAuditEvent(r, mc.AuditAdminPasswordReset, username))
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, dto.ResponseDetail{Detail: "Пользователь должен будет сменить пароль при следующем входе"}, http.StatusOK)
+}
+
+// DeleteUser удаляет пользователя.
+func (h *AdminHandlerManager) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+	err := h.ucAdmin.DeleteUser(r.Context(), username, f.NewAuditEvent(r, mc.AuditAdminUserDelete, username))
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, dto.ResponseDetail{Detail: "Пользователь удален"}, http.StatusOK)
+}
+
+// ListAudit ищет записи журнала действий. Параметры запроса: actor, subject, action (точное действие или
+// префикс, например auth), from, to, page, per_page.
+func (h *AdminHandlerManager) ListAudit(w http.ResponseWriter, r *http.Request) {
+	filter, page, err := getAuditFilter(r.URL.Query())
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+		return
+	}
+	events, total, err := h.ucAudit.List(r.Context(), filter)
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, getAuditPage(events, total, page, filter.Limit), http.StatusOK)
+}
+
+// DBStats возвращает состояние пула соединений с базой. В режиме storage: memory базы нет.
+func (h *AdminHandlerManager) DBStats(w http.ResponseWriter, r *http.Request) {
+	if h.dbStat == nil {
+		f.Response(w, dto.PoolStats{}, http.StatusOK)
+		return
+	}
+	f.Response(w, getPoolStats(h.dbStat()), http.StatusOK)
+}
+
+// CacheStats возвращает счетчики кэша профилей пользователей. Без Memcached кэш выключен.
+func (h *AdminHandlerManager) CacheStats(w http.ResponseWriter, r *http.Request) {
+	if h.cacheStat == nil {
+		f.Response(w, dto.CacheStats{}, http.StatusOK)
+		return
+	}
+	f.Response(w, getCacheStats(h.cacheStat()), http.StatusOK)
+}
+
+func (h *AdminHandlerManager) responseError(w http.ResponseWriter, r *http.Request, err error) {
+	for knownErr, status := range errStatus {
+		if errors.Is(err, knownErr) {
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: knownErr.Error()}, status)
+			return
+		}
+	}
+	h.logger.Error(err.Error(), f.LogFields(r)...)
+	f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+}
@@ -0,0 +1,154 @@
+package admin
+
+import (
+	"net/url"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// getUserFilter собирает фильтр из параметров запроса и возвращает его вместе с номером страницы.
+// created_to в формате даты включает весь указанный день.
+func getUserFilter(query url.Values) (*ent.UserFilter, int, error) {
+	filter := &ent.UserFilter{Query: query.Get("q")}
+	if activity := query.Get("activity"); activity != "" {
+		if _, ok := mc.AllowedActivities[activity]; !ok {
+			return nil, 0, dto.ErrInvalidActivity
+		}
+		filter.Activity = activity
+	}
+	if category := query.Get("bmi"); category != "" {
+		bmiRange, ok := mc.BMICategories[category]
+		if !ok {
+			return nil, 0, dto.ErrInvalidBMICategory
+		}
+		filter.BMIMin, filter.BMIMax = &bmiRange.Min, &bmiRange.Max
+	}
+	var err error
+	filter.CreatedFrom, err = parseDate(query.Get("created_from"), false)
+	if err != nil {
+		return nil, 0, err
+	}
+	filter.CreatedTo, err = parseDate(query.Get("created_to"), true)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, perPage, err := f.GetPagination(query)
+	if err != nil {
+		return nil, 0, err
+	}
+	filter.Limit = perPage
+	filter.Offset = (page - 1) * perPage
+	return filter, page, nil
+}
+
+func parseDate(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, dto.ErrInvalidDate
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+func getAdminUser(user *ent.User) dto.AdminUser {
+	user.BMI.Calculate(user.Weight, user.Height)
+	return dto.AdminUser{
+		UserWithoutPassword: dto.UserWithoutPassword{
+			ID:               user.ID,
+			Email:            user.Email,
+			FirstName:        user.FirstName,
+			Username:         user.Username,
+			Weight:           user.Weight,
+			Height:           user.Height,
+			Age:              user.Age,
+			Sex:              user.Sex,
+			DayCalories:      user.DayCalories,
+			PhysicalActivity: user.PhysicalActivity,
+			Role:             user.Role,
+			BMI:              dto.BMIType{Value: user.BMI.Value, Comment: user.BMI.Comment},
+		},
+		LockedAt:              user.LockedAt,
+		PasswordResetRequired: user.PasswordResetRequired,
+		CreatedAt:             user.CreatedAt,
+	}
+}
+
+func getUserPage(users []*ent.User, total, page, perPage int) dto.UserPage {
+	result := make([]dto.AdminUser, 0, len(users))
+	for _, u := range users {
+		result = append(result, getAdminUser(u))
+	}
+	return dto.UserPage{Users: result, Total: total, Page: page, PerPage: perPage}
+}
+
+// getAuditFilter собирает фильтр журнала из параметров запроса и возвращает его вместе с номером страницы.
+func getAuditFilter(query url.Values) (*ent.AuditFilter, int, error) {
+	filter := &ent.AuditFilter{Actor: query.Get("actor"), Action: query.Get("action")}
+	if subject := query.Get("subject"); subject != "" {
+		filter.Subjects = []string{subject}
+	}
+	var err error
+	filter.From, err = parseDate(query.Get("from"), false)
+	if err != nil {
+		return nil, 0, err
+	}
+	filter.To, err = parseDate(query.Get("to"), true)
+	if err != nil {
+		return nil, 0, err
+	}
+	page, perPage, err := f.GetPagination(query)
+	if err != nil {
+		return nil, 0, err
+	}
+	filter.Limit = perPage
+	filter.Offset = (page - 1) * perPage
+	return filter, page, nil
+}
+
+func getAuditPage(events []*ent.AuditEvent, total, page, perPage int) dto.AuditPage {
+	return dto.AuditPage{Events: f.GetAuditEvents(events), Total: total, Page: page, PerPage: perPage}
+}
+
+func getPoolStats(stat *pgxpool.Stat) dto.PoolStats {
+	return dto.PoolStats{
+		MaxConns:                stat.MaxConns(),
+		TotalConns:              stat.TotalConns(),
+		AcquiredConns:           stat.AcquiredConns(),
+		IdleConns:               stat.IdleConns(),
+		ConstructingConns:       stat.ConstructingConns(),
+		AcquireCount:            stat.AcquireCount(),
+		AcquireDurationMs:       stat.AcquireDuration().Milliseconds(),
+		EmptyAcquireCount:       stat.EmptyAcquireCount(),
+		CanceledAcquireCount:    stat.CanceledAcquireCount(),
+		NewConnsCount:           stat.NewConnsCount(),
+		MaxLifetimeDestroyCount: stat.MaxLifetimeDestroyCount(),
+		MaxIdleDestroyCount:     stat.MaxIdleDestroyCount(),
+	}
+}
+
+func getCacheStats(stats ent.CacheStats) dto.CacheStats {
+	result := dto.CacheStats{
+		Enabled: true,
+		Hits:    stats.Hits,
+		Misses:  stats.Misses,
+		Errors:  stats.Errors,
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		result.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return result
+}
@@ -0,0 +1,194 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	ucAgent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/agent"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// errStatus коды ответа на ошибки бизнес-логики, остальные ошибки считаются внутренними.
+var errStatus = map[error]int{
+	me.ErrAgentAlreadyExist: http.StatusBadRequest,
+	me.ErrForbidden:         http.StatusForbidden,
+	me.ErrAgentNotExist:     http.StatusNotFound,
+	me.ErrGroupNotExist:     http.StatusNotFound,
+	me.ErrUserNotExist:      http.StatusNotFound,
+	me.ErrPrivilegeNotExist: http.StatusNotFound,
+}
+
+type AgentHandlerManager struct {
+	ucAgent ucAgent.Usecase
+	logger  *zap.Logger
+}
+
+// NewAgentHandlerManager возвращает менеджер хендлеров, отвечающих за агентов и доступ к ним
+func NewAgentHandlerManager(ucAgent ucAgent.Usecase, logger *zap.Logger) *AgentHandlerManager {
+	return &AgentHandlerManager{
+		ucAgent: ucAgent,
+		logger:  logger,
+	}
+}
+
+// Create добавляет агента.
+func (h *AgentHandlerManager) Create(w http.ResponseWriter, r *http.Request) {
+	var agentForm dto.AgentCreate
+	if !h.readForm(w, r, &agentForm) {
+		return
+	}
+	a, err := h.ucAgent.Create(r.Context(), agentForm.Name)
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, dto.Agent{Name: a.Name, CreatedAt: a.CreatedAt}, http.StatusCreated)
+}
+
+// List возвращает всех агентов.
+func (h *AgentHandlerManager) List(w http.ResponseWriter, r *http.Request) {
+	agents, err := h.ucAgent.List(r.Context())
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	result := make([]dto.Agent, 0, len(agents))
+	for _, a := range agents {
+		result = append(result, dto.Agent{Name: a.Name, CreatedAt: a.CreatedAt})
+	}
+	f.Response(w, result, http.StatusOK)
+}
+
+// Delete удаляет агента.
+func (h *AgentHandlerManager) Delete(w http.ResponseWriter, r *http.Request) {
+	err := h.ucAgent.Delete(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, dto.ResponseDetail{Detail: "Агент удален"}, http.StatusOK)
+}
+
+// GrantUser выдает пользователю прямой доступ к агенту.
+func (h *AgentHandlerManager) GrantUser(w http.ResponseWriter, r *http.Request) {
+	var privilegeForm dto.PrivilegeUser
+	if !h.readForm(w, r, &privilegeForm) {
+		return
+	}
+	err := h.ucAgent.GrantUser(r.Context(), mux.Vars(r)["name"], privilegeForm.Username)
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, dto.ResponseDetail{Detail: "Доступ к агенту выдан"}, http.StatusOK)
+}
+
+// RevokeUser отзывает прямой доступ пользователя к агенту.
+func (h *AgentHandlerManager) RevokeUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	err := h.ucAgent.RevokeUser(r.Context(), vars["name"], vars["username"])
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, dto.ResponseDetail{Detail: "Доступ к агенту отозван"}, http.StatusOK)
+}
+
+// GrantGroup выдает группе доступ к агенту.
+func (h *AgentHandlerManager) GrantGroup(w http.ResponseWriter, r *http.Request) {
+	var privilegeForm dto.PrivilegeGroup
+	if !h.readForm(w, r, &privilegeForm) {
+		return
+	}
+	err := h.ucAgent.GrantGroup(r.Context(), mux.Vars(r)["name"], privilegeForm.Group)
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, dto.ResponseDetail{Detail: "Доступ к агенту выдан"}, http.StatusOK)
+}
+
+// RevokeGroup отзывает доступ группы к агенту.
+func (h *AgentHandlerManager) RevokeGroup(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	err := h.ucAgent.RevokeGroup(r.Context(), vars["name"], vars["group"])
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, dto.ResponseDetail{Detail: "Доступ к агенту отозван"}, http.StatusOK)
+}
+
+// Check проверяет доступ пользователя к агенту: GET /check?user=&agent=. Без параметра user проверяется
+// текущий пользователь, проверять других пользователей может только root (право access:check).
+func (h *AgentHandlerManager) Check(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	username := query.Get("user")
+	if username == "" {
+		username = f.GetUsernameCtx(r)
+	}
+	if username != f.GetUsernameCtx(r) && !f.HasPermission(r, mc.PermAccessCheck) {
+		h.responseError(w, r, me.ErrForbidden)
+		return
+	}
+	agentName := query.Get("agent")
+	if err := dto.ValidateAgentName(agentName); err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	access, err := h.ucAgent.Check(r.Context(), username, agentName)
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, dto.Access{
+		User:    access.Username,
+		Agent:   access.Agent,
+		Access:  len(access.Sources) != 0,
+		Sources: access.Sources,
+	}, http.StatusOK)
+}
+
+// readForm читает тело запроса в form и проверяет его. Если данные неверные, отвечает клиенту и возвращает false.
+func (h *AgentHandlerManager) readForm(w http.ResponseWriter, r *http.Request, form interface{ Validate() error }) bool {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
+		return false
+	}
+	err = json.Unmarshal(body, form)
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
+		return false
+	}
+	err = form.Validate()
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func (h *AgentHandlerManager) responseError(w http.ResponseWriter, r *http.Request, err error) {
+	for knownErr, status := range errStatus {
+		if errors.Is(err, knownErr) {
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: knownErr.Error()}, status)
+			return
+		}
+	}
+	h.logger.Error(err.Error(), f.LogFields(r)...)
+	f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+}
@@ -0,0 +1,125 @@
+package apikey
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	ucApiKey "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/apikey"
+	ucAudit "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/audit"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type ApiKeyHandlerManager struct {
+	ucApiKey ucApiKey.Usecase
+	ucAudit  ucAudit.Usecase
+	logger   *zap.Logger
+}
+
+// NewApiKeyHandlerManager возвращает менеджер хендлеров, отвечающих за персональные API-ключи
+func NewApiKeyHandlerManager(ucApiKey ucApiKey.Usecase, ucAudit ucAudit.Usecase, logger *zap.Logger) *ApiKeyHandlerManager {
+	return &ApiKeyHandlerManager{
+		ucApiKey: ucApiKey,
+		ucAudit:  ucAudit,
+		logger:   logger,
+	}
+}
+
+// Create выпускает новый API-ключ. Ключ показывается только в ответе на этот запрос.
+func (h *ApiKeyHandlerManager) Create(w http.ResponseWriter, r *http.Request) {
+	username := f.GetUsernameCtx(r)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
+		return
+	}
+	var keyForm dto.ApiKeyCreate
+	err = json.Unmarshal(body, &keyForm)
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
+		return
+	}
+	err = keyForm.Validate()
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	k, rawKey, err := h.ucApiKey.Create(r.Context(), username, keyForm.Name, keyForm.Scopes)
+	if err != nil {
+		if errors.Is(err, me.ErrApiKeyLimit) {
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+			return
+		}
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+		return
+	}
+	h.audit(r, mc.AuditApiKeyCreate, map[string]any{"id": k.ID, "name": k.Name, "scopes": k.Scopes})
+	f.Response(w, dto.ApiKeyCreated{ApiKey: getApiKey(k), Key: rawKey}, http.StatusCreated)
+}
+
+// List возвращает действующие API-ключи пользователя.
+func (h *ApiKeyHandlerManager) List(w http.ResponseWriter, r *http.Request) {
+	username := f.GetUsernameCtx(r)
+
+	keys, err := h.ucApiKey.List(r.Context(), username)
+	if err != nil {
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+		return
+	}
+	result := make([]dto.ApiKey, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, getApiKey(k))
+	}
+	f.Response(w, result, http.StatusOK)
+}
+
+// Revoke отзывает API-ключ пользователя.
+func (h *ApiKeyHandlerManager) Revoke(w http.ResponseWriter, r *http.Request) {
+	username := f.GetUsernameCtx(r)
+	keyID := mux.Vars(r)["id"]
+	if !govalidator.IsUUID(keyID) {
+		h.logger.Info(me.ErrApiKeyNotFound.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrApiKeyNotFound.Error()}, http.StatusNotFound)
+		return
+	}
+
+	err := h.ucApiKey.Revoke(r.Context(), keyID, username)
+	if err != nil {
+		if errors.Is(err, me.ErrApiKeyNotFound) {
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusNotFound)
+			return
+		}
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+		return
+	}
+	h.audit(r, mc.AuditApiKeyRevoke, map[string]any{"id": keyID})
+	f.Response(w, dto.ResponseDetail{Detail: "API-ключ отозван"}, http.StatusOK)
+}
+
+// audit записывает действие пользователя над своей учетной записью. Недоступность журнала не должна
+// мешать запросу, поэтому ошибка только пишется в лог.
+func (h *ApiKeyHandlerManager) audit(r *http.Request, action string, details map[string]any) {
+	e := f.NewAuditEvent(r, action, f.GetUsernameCtx(r))
+	e.Details = details
+	err := h.ucAudit.Record(r.Context(), e)
+	if err != nil {
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+	}
+}
@@ -4,44 +4,60 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"math"
 	"net/http"
+	"strconv"
 
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
 	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	ucAudit "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/audit"
 	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/auth"
+	ucLockout "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/lockout"
+	ucMfa "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/mfa"
+	ucOidc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/oidc"
+	ucToken "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/token"
 	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/metrics"
 	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
 	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
 	"go.uber.org/zap"
 )
 
 type AuthHandlerManager struct {
-	ucAuth auth.Usecase
-	logger *zap.Logger
+	ucAuth    auth.Usecase
+	ucToken   ucToken.Usecase
+	ucMfa     ucMfa.Usecase
+	ucLockout ucLockout.Usecase
+	ucOidc    ucOidc.Usecase
+	ucAudit   ucAudit.Usecase
+	logger    *zap.Logger
 }
 
 // NewUserHandlerManager возвращает менеджер хендлеров, отвечающих за создание/удаление пользователя из системы
-func NewAuthHandlerManager(ucAuth auth.Usecase, logger *zap.Logger) *AuthHandlerManager {
+func NewAuthHandlerManager(ucAuth auth.Usecase, ucToken ucToken.Usecase, ucMfa ucMfa.Usecase, ucLockout ucLockout.Usecase,
+	ucOidc ucOidc.Usecase, ucAudit ucAudit.Usecase, logger *zap.Logger) *AuthHandlerManager {
 	return &AuthHandlerManager{
-		ucAuth: ucAuth,
-		logger: logger,
+		ucAuth:    ucAuth,
+		ucToken:   ucToken,
+		ucMfa:     ucMfa,
+		ucLockout: ucLockout,
+		ucOidc:    ucOidc,
+		ucAudit:   ucAudit,
+		logger:    logger,
 	}
 }
 
 func (h *AuthHandlerManager) SignUp(w http.ResponseWriter, r *http.Request) {
-	requestID, err := f.GetCtxRequestID(r)
-	if err != nil {
-		h.logger.Error(err.Error(), zap.String(mc.RequestID, requestID))
-	}
 	username := f.GetUsernameCtx(r)
 	if username != "" {
-		h.logger.Info(me.ErrAlreadyRegistered.Error(), zap.String(mc.RequestID, requestID))
+		h.logger.Info(me.ErrAlreadyRegistered.Error(), f.LogFields(r)...)
 		f.Response(w, dto.ResponseError{Error: me.ErrAlreadyRegistered.Error()}, http.StatusUnauthorized)
 		return
 	}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		h.logger.Info(err.Error(), zap.String(mc.RequestID, requestID))
+		h.logger.Info(err.Error(), f.LogFields(r)...)
 		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
 		return
 	}
@@ -49,13 +65,13 @@ func (h *AuthHandlerManager) SignUp(w http.ResponseWriter, r *http.Request) {
 	var signForm dto.CreateData
 	err = json.Unmarshal(body, &signForm)
 	if err != nil {
-		h.logger.Info(err.Error(), zap.String(mc.RequestID, requestID))
+		h.logger.Info(err.Error(), f.LogFields(r)...)
 		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
 		return
 	}
 	err = signForm.Validate()
 	if err != nil {
-		h.logger.Info(err.Error(), zap.String(mc.RequestID, requestID))
+		h.logger.Info(err.Error(), f.LogFields(r)...)
 		f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
 		return
 	}
@@ -63,90 +79,336 @@ func (h *AuthHandlerManager) SignUp(w http.ResponseWriter, r *http.Request) {
 	u, err := h.ucAuth.SignUp(r.Context(), &signForm)
 	if err != nil {
 		if errors.Is(err, me.ErrUserAlreadyExist) {
-			h.logger.Info(err.Error(), zap.String(mc.RequestID, requestID))
+			h.logger.Info(err.Error(), f.LogFields(r)...)
 			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
 			return
 		}
-		h.logger.Error(err.Error(), zap.String(mc.RequestID, requestID))
+		h.logger.Error(err.Error(), f.LogFields(r)...)
 		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
 		return
 	}
+	h.audit(r, u.Username, mc.AuditSignUp, nil)
+	metrics.SignUps.WithLabelValues(metrics.MethodPassword).Inc()
 
-	w, err = f.SetCookieAndHeaders(w, u.Username)
-	if err != nil {
-		h.logger.Error(err.Error(), zap.String(mc.RequestID, requestID))
-		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
-		return
-	}
-
-	f.Response(w, getUserWithoutPassword(u), http.StatusOK)
+	h.responseWithTokens(w, r, u, false)
 }
 
 func (h *AuthHandlerManager) SignIn(w http.ResponseWriter, r *http.Request) {
-	requestID, err := f.GetCtxRequestID(r)
-	if err != nil {
-		h.logger.Error(err.Error(), zap.String(mc.RequestID, requestID))
-	}
 	username := f.GetUsernameCtx(r)
 	if username != "" {
-		h.logger.Info(me.ErrAlreadyAuthenticated.Error(), zap.String(mc.RequestID, requestID))
+		h.logger.Info(me.ErrAlreadyAuthenticated.Error(), f.LogFields(r)...)
 		f.Response(w, dto.ResponseError{Error: me.ErrAlreadyAuthenticated.Error()}, http.StatusUnauthorized)
 		return
 	}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		h.logger.Info(err.Error(), zap.String(mc.RequestID, requestID))
+		h.logger.Info(err.Error(), f.LogFields(r)...)
 		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
 		return
 	}
 	var signForm dto.AuthData
 	err = json.Unmarshal(body, &signForm)
 	if err != nil {
-		h.logger.Info(err.Error(), zap.String(mc.RequestID, requestID))
+		h.logger.Info(err.Error(), f.LogFields(r)...)
 		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
 		return
 	}
 	err = signForm.Validate()
 	if err != nil {
-		h.logger.Info(err.Error(), zap.String(mc.RequestID, requestID))
+		h.logger.Info(err.Error(), f.LogFields(r)...)
 		f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
 		return
 	}
 
-	u, err := h.ucAuth.SignIn(r.Context(), &signForm)
+	// счетчик неудачных попыток ведется по никнейму, чтобы вход по почте и по никнейму не давал двух счетчиков
+	clientIP := f.GetClientIP(r)
+	accountLogin, err := h.ucAuth.ResolveLogin(r.Context(), signForm.Login)
+	if err != nil {
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+		return
+	}
+	if !h.checkAttempts(w, r, accountLogin, clientIP) {
+		return
+	}
+
+	result, err := h.ucAuth.SignIn(r.Context(), &signForm)
 	if err != nil {
 		if errors.Is(err, me.ErrIncorrectPwdOrLogin) {
-			h.logger.Info(err.Error(), zap.String(mc.RequestID, requestID))
+			h.registerFailure(r, accountLogin, clientIP)
+			h.auditSignInFailure(r, signForm.Login, "password")
+			h.logger.Info(err.Error(), f.LogFields(r)...)
 			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
 			return
 		}
-		h.logger.Error(err.Error(), zap.String(mc.RequestID, requestID))
+		if errors.Is(err, me.ErrAccountLocked) || errors.Is(err, me.ErrPasswordResetNeeded) {
+			h.auditSignInFailure(r, signForm.Login, signInFailureReason(err))
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, me.ErrPasswordNotChanged) || errors.Is(err, me.ErrPasswordResetNotNeeded) || dto.IsPasswordPolicyError(err) {
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+			return
+		}
+		h.logger.Error(err.Error(), f.LogFields(r)...)
 		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
 		return
 	}
+	// счетчик попыток сбрасывается, только когда выданы токены: при включенном втором факторе верный пароль
+	// еще не завершает вход, иначе повторная отправка пароля сбрасывала бы счетчик перебора кода
+	h.finishSignIn(w, r, result, signForm.TokensInBody, metrics.MethodPassword)
+}
+
+// SignInSecondFactor второй шаг авторизации: обменивает challenge_token и код на пару токенов.
+func (h *AuthHandlerManager) SignInSecondFactor(w http.ResponseWriter, r *http.Request) {
+	username := f.GetUsernameCtx(r)
+	if username != "" {
+		h.logger.Info(me.ErrAlreadyAuthenticated.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrAlreadyAuthenticated.Error()}, http.StatusUnauthorized)
+		return
+	}
 
-	w, err = f.SetCookieAndHeaders(w, u.Username)
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		h.logger.Error(err.Error(), zap.String(mc.RequestID, requestID))
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
+		return
+	}
+	var mfaForm dto.MfaSignIn
+	err = json.Unmarshal(body, &mfaForm)
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
+		return
+	}
+	err = mfaForm.Validate()
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	challengeUsername, err := h.ucMfa.ParseChallenge(mfaForm.ChallengeToken)
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusUnauthorized)
+		return
+	}
+	// перебор кода второго фактора ограничивается так же, как перебор пароля
+	clientIP := f.GetClientIP(r)
+	if !h.checkAttempts(w, r, challengeUsername, clientIP) {
+		return
+	}
+
+	result, err := h.ucMfa.VerifyChallenge(r.Context(), mfaForm.ChallengeToken, mfaForm.Code)
+	if err != nil {
+		if errors.Is(err, me.ErrInvalidMfaChallenge) || errors.Is(err, me.ErrIncorrectPwdOrLogin) || errors.Is(err, me.ErrMfaNotEnabled) {
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, me.ErrInvalidMfaCode) {
+			h.registerFailure(r, challengeUsername, clientIP)
+			h.auditSignInFailure(r, challengeUsername, "mfa_code")
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+			return
+		}
+		h.logger.Error(err.Error(), f.LogFields(r)...)
 		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
 		return
 	}
-	f.Response(w, getUserWithoutPassword(u), http.StatusOK)
+	if !h.applyPasswordReset(w, r, result) {
+		return
+	}
+	metrics.Logins.WithLabelValues(metrics.MethodMfa).Inc()
+
+	h.responseWithTokens(w, r, result.User, mfaForm.TokensInBody)
 }
 
 func (h *AuthHandlerManager) SignOut(w http.ResponseWriter, r *http.Request) {
-	requestID, err := f.GetCtxRequestID(r)
+	refreshToken, err := f.GetRefreshToken(r)
 	if err != nil {
-		h.logger.Error(err.Error(), zap.String(mc.RequestID, requestID))
+		// клиенты без cookie передают refresh-токен в теле запроса
+		refreshToken = getRefreshTokenFromBody(r)
 	}
-	username := f.GetUsernameCtx(r)
-	if username == "" {
-		h.logger.Info(me.ErrNotAuthenticated.Error(), zap.String(mc.RequestID, requestID))
-		f.Response(w, dto.ResponseError{Error: me.ErrNotAuthenticated.Error()}, http.StatusUnauthorized)
-		return
+	username := f.GetSessionUsernameCtx(r)
+	if refreshToken != "" {
+		// access-токен к этому моменту мог истечь, поэтому владелец сессии определяется по refresh-токену
+		if owner, err := h.ucToken.Owner(r.Context(), refreshToken); err == nil {
+			username = owner
+		}
+		err = h.ucToken.Revoke(r.Context(), refreshToken)
+		if err != nil {
+			h.logger.Error(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if username != "" {
+		h.audit(r, username, mc.AuditSignOut, nil)
 	}
 
 	f.FlashCookie(w, r)
 	f.Response(w, dto.ResponseDetail{Detail: "Вы успешно завершили сессию"}, http.StatusOK)
 }
+
+// getRefreshTokenFromBody возвращает refresh-токен из тела запроса или пустую строку, если его там нет.
+func getRefreshTokenFromBody(r *http.Request) string {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return ""
+	}
+	var refreshData dto.RefreshData
+	err = json.Unmarshal(body, &refreshData)
+	if err != nil {
+		return ""
+	}
+	return refreshData.RefreshToken
+}
+
+// checkAttempts проверяет, не заблокирован ли вход для учетной записи или IP-адреса. Если заблокирован,
+// отвечает 429 с заголовком Retry-After и возвращает false.
+func (h *AuthHandlerManager) checkAttempts(w http.ResponseWriter, r *http.Request, login, clientIP string) bool {
+	retryAfter, err := h.ucLockout.Check(r.Context(), login, clientIP)
+	if err != nil {
+		if errors.Is(err, me.ErrTooManyAttempts) {
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			w.Header().Set(mc.RetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusTooManyRequests)
+			return false
+		}
+		// недоступность хранилища счетчиков не должна мешать пользователям входить
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+	}
+	return true
+}
+
+func (h *AuthHandlerManager) registerFailure(r *http.Request, login, clientIP string) {
+	err := h.ucLockout.Fail(r.Context(), login, clientIP)
+	if err != nil {
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+	}
+}
+
+func (h *AuthHandlerManager) resetAttempts(r *http.Request, login string) {
+	err := h.ucLockout.Reset(r.Context(), login)
+	if err != nil {
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+	}
+}
+
+// finishSignIn завершает первый шаг авторизации: при включенном втором факторе токены выдаются только
+// после ввода кода, поэтому клиент получает challenge_token. Новый пароль, если его потребовал сменить
+// администратор, тоже сохраняется только после ввода кода. method — способ первого шага для метрик входа.
+func (h *AuthHandlerManager) finishSignIn(w http.ResponseWriter, r *http.Request, result *ent.SignInResult, tokensInBody bool, method string) {
+	u := result.User
+	mfaEnabled, err := h.ucMfa.IsEnabled(r.Context(), u.Username)
+	if err != nil {
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+		return
+	}
+	if mfaEnabled {
+		challengeToken, expiresAt, err := h.ucMfa.NewChallenge(u.Username, result.NewPasswordHash)
+		if err != nil {
+			h.logger.Error(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+			return
+		}
+		f.Response(w, dto.MfaChallenge{MfaRequired: true, ChallengeToken: challengeToken, ExpiresAt: expiresAt}, http.StatusOK)
+		return
+	}
+	if !h.applyPasswordReset(w, r, result) {
+		return
+	}
+	metrics.Logins.WithLabelValues(method).Inc()
+	h.responseWithTokens(w, r, u, tokensInBody)
+}
+
+// applyPasswordReset сохраняет новый пароль, если администратор потребовал его сменить. Если сохранить не
+// удалось, отвечает 500 и возвращает false.
+func (h *AuthHandlerManager) applyPasswordReset(w http.ResponseWriter, r *http.Request, result *ent.SignInResult) bool {
+	if result.NewPasswordHash == "" {
+		return true
+	}
+	err := h.ucAuth.ResetPassword(r.Context(), result.User, result.NewPasswordHash)
+	if err != nil {
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+		return false
+	}
+	h.audit(r, result.User.Username, mc.AuditPasswordChange, nil)
+	return true
+}
+
+// responseWithTokens выдает пользователю пару токенов: в cookie или, по просьбе клиента, в теле ответа.
+// Вход на этом завершен, поэтому здесь же сбрасывается счетчик неудачных попыток.
+func (h *AuthHandlerManager) responseWithTokens(w http.ResponseWriter, r *http.Request, u *ent.User, tokensInBody bool) {
+	tokens, err := h.ucToken.Issue(r.Context(), u.Username)
+	if err != nil {
+		if errors.Is(err, me.ErrAccountLocked) {
+			h.auditSignInFailure(r, u.Username, signInFailureReason(err))
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusForbidden)
+			return
+		}
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+		return
+	}
+	h.resetAttempts(r, u.Username)
+	h.audit(r, u.Username, mc.AuditSignInSuccess, nil)
+	if tokensInBody {
+		f.Response(w, dto.UserWithTokens{User: getUserWithoutPassword(u), Tokens: f.GetTokenPair(tokens)}, http.StatusOK)
+		return
+	}
+	w, err = f.SetCookieAndHeaders(w, tokens)
+	if err != nil {
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+		return
+	}
+	f.Response(w, getUserWithoutPassword(u), http.StatusOK)
+}
+
+// audit записывает в журнал действие пользователя над своей учетной записью. До авторизации пользователь
+// еще не известен из контекста запроса, поэтому он передается явно. Недоступность журнала не должна мешать
+// входу, поэтому ошибка только пишется в лог.
+func (h *AuthHandlerManager) audit(r *http.Request, username, action string, details map[string]any) {
+	e := f.NewAuditEvent(r, action, username)
+	e.Actor = username
+	e.Details = details
+	err := h.ucAudit.Record(r.Context(), e)
+	if err != nil {
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+	}
+}
+
+// auditSignInFailure записывает неудачный вход. login — то, что ввел клиент (никнейм или почта), поэтому
+// актор не указывается.
+func (h *AuthHandlerManager) auditSignInFailure(r *http.Request, login, reason string) {
+	metrics.LoginFailures.WithLabelValues(reason).Inc()
+	e := f.NewAuditEvent(r, mc.AuditSignInFailure, login)
+	e.Actor = ""
+	e.Details = map[string]any{"reason": reason}
+	err := h.ucAudit.Record(r.Context(), e)
+	if err != nil {
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+	}
+}
+
+func signInFailureReason(err error) string {
+	switch {
+	case errors.Is(err, me.ErrAccountLocked):
+		return "locked"
+	case errors.Is(err, me.ErrPasswordResetNeeded):
+		return "password_reset_required"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/attempt"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/audit"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/memory"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/mfa"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/token"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	ucAudit "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/audit"
+	ucAuth "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/auth"
+	ucLockout "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/lockout"
+	ucMfa "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/mfa"
+	ucToken "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/token"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+type fixture struct {
+	h         *AuthHandlerManager
+	repoUser  user.Repo
+	repoToken token.Repo
+	repoAudit audit.Repo
+	ucToken   *ucToken.UsecaseLayer
+	ucMfa     *ucMfa.UsecaseLayer
+}
+
+// newFixture создает обработчики на хранилище в памяти и пользователя ivan с паролем Passw0rd1.
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("secret_key", "secret")
+	viper.Set("auth.access_token_ttl", 15*time.Minute)
+	viper.Set("auth.refresh_token_ttl", 24*time.Hour)
+	viper.Set("auth.mfa_challenge_ttl", 5*time.Minute)
+	viper.Set("auth.password_policy.min_strength", 2)
+	viper.Set("auth.lockout.free_attempts", 3)
+	viper.Set("auth.lockout.account_threshold", 10)
+	viper.Set("auth.lockout.ip_threshold", 50)
+	viper.Set("auth.lockout.window", time.Hour)
+	viper.Set("auth.argon2.memory", 1024)
+	viper.Set("auth.argon2.time", 1)
+	viper.Set("auth.argon2.threads", 1)
+	viper.Set("auth.argon2.key_length", 32)
+	viper.Set("auth.argon2.salt_length", 16)
+
+	store := memory.NewStore()
+	repoUser := user.NewRepoMemory(store)
+	password, err := f.GetHashedPassword("Passw0rd1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repoUser.Create(context.Background(), &ent.User{
+		Username: "ivan", FirstName: "Ivan", Weight: 80, Height: 180, Age: 30, Sex: "M",
+		PhysicalActivity: "MA", Password: password,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repoToken := token.NewRepoMemory(store)
+	repoAudit := audit.NewRepoMemory(store)
+	usecaseToken := ucToken.NewUsecaseLayer(repoToken, repoUser)
+	usecaseMfa := ucMfa.NewUsecaseLayer(mfa.NewRepoMemory(store), repoUser)
+	h := NewAuthHandlerManager(ucAuth.NewUsecaseLayer(repoUser), usecaseToken, usecaseMfa,
+		ucLockout.NewUsecaseLayer(attempt.NewRepoMemory(), nil), nil, ucAudit.NewUsecaseLayer(repoAudit, repoUser), zap.NewNop())
+	return &fixture{h: h, repoUser: repoUser, repoToken: repoToken, repoAudit: repoAudit, ucToken: usecaseToken, ucMfa: usecaseMfa}
+}
+
+func (fx *fixture) auditEvents(t *testing.T, action string) []*ent.AuditEvent {
+	t.Helper()
+	events, _, err := fx.repoAudit.List(context.Background(), &ent.AuditFilter{Action: action, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+// passwordIs сообщает, подходит ли пароль pwd к учетной записи ivan.
+func (fx *fixture) passwordIs(t *testing.T, pwd string) bool {
+	t.Helper()
+	uDB, err := fx.repoUser.GetByUsername(context.Background(), "ivan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, _, err := f.VerifyPassword(pwd, uDB.Password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ok
+}
+
+func post(handler http.HandlerFunc, path string, body any) *httptest.ResponseRecorder {
+	raw, _ := json.Marshal(body)
+	r := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(raw))
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+// Выход с истекшим access-токеном (запрос анонимный) все равно отзывает семейство и удаляет cookie.
+func TestSignOutWithExpiredAccessToken(t *testing.T) {
+	fx := newFixture(t)
+	ctx := context.Background()
+	pair, err := fx.ucToken.Issue(ctx, "ivan")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/signout", nil)
+	r.AddCookie(&http.Cookie{Name: mc.RefreshToken, Value: pair.RefreshToken})
+	w := httptest.NewRecorder()
+	fx.h.SignOut(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("SignOut() status = %d, want %d", w.Code, http.StatusOK)
+	}
+	tDB, err := fx.repoToken.GetByHash(ctx, f.HashToken(pair.RefreshToken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tDB.RevokedAt == nil {
+		t.Error("SignOut() kept the refresh token active")
+	}
+	flashed := false
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == mc.RefreshToken && cookie.MaxAge < 0 {
+			flashed = true
+		}
+	}
+	if !flashed {
+		t.Error("SignOut() did not clear the refresh token cookie")
+	}
+	if events := fx.auditEvents(t, mc.AuditSignOut); len(events) != 1 || events[0].Actor != "ivan" {
+		t.Errorf("audit events = %+v, want one sign-out by ivan", events)
+	}
+}
+
+// Новый пароль, который потребовал установить администратор, сохраняется только после ввода второго фактора.
+func TestSignInPasswordResetWaitsForSecondFactor(t *testing.T) {
+	fx := newFixture(t)
+	ctx := context.Background()
+	enrollment, err := fx.ucMfa.Enroll(ctx, "ivan")
+	if err != nil {
+		t.Fatalf("Enroll() error = %v", err)
+	}
+	step := f.GetTOTPStep(time.Now())
+	confirmCode, err := f.GetTOTPCode(enrollment.Secret, step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fx.ucMfa.Confirm(ctx, "ivan", confirmCode); err != nil {
+		t.Fatalf("Confirm() error = %v", err)
+	}
+	if err := fx.repoUser.SetPasswordResetRequired(ctx, "ivan"); err != nil {
+		t.Fatal(err)
+	}
+	const newPassword = "kX9#vQ2!mZ7$"
+
+	w := post(fx.h.SignIn, "/api/v1/signin", dto.AuthData{Login: "ivan", Password: "Passw0rd1", NewPassword: newPassword})
+	if w.Code != http.StatusOK {
+		t.Fatalf("SignIn() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var challenge dto.MfaChallenge
+	if err := json.Unmarshal(w.Body.Bytes(), &challenge); err != nil || !challenge.MfaRequired {
+		t.Fatalf("SignIn() = %s, want an mfa challenge", w.Body)
+	}
+	if !fx.passwordIs(t, "Passw0rd1") {
+		t.Fatal("password changed before the second factor")
+	}
+
+	w = post(fx.h.SignInSecondFactor, "/api/v1/signin/2fa", dto.MfaSignIn{ChallengeToken: challenge.ChallengeToken, Code: "ABCDE-FGHJK"})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("SignInSecondFactor() with a wrong code status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if !fx.passwordIs(t, "Passw0rd1") || len(fx.auditEvents(t, mc.AuditPasswordChange)) != 0 {
+		t.Fatal("password changed after a wrong second factor")
+	}
+
+	code, err := f.GetTOTPCode(enrollment.Secret, step+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = post(fx.h.SignInSecondFactor, "/api/v1/signin/2fa", dto.MfaSignIn{ChallengeToken: challenge.ChallengeToken, Code: code})
+	if w.Code != http.StatusOK {
+		t.Fatalf("SignInSecondFactor() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if !fx.passwordIs(t, newPassword) {
+		t.Error("new password is not saved after the second factor")
+	}
+	uDB, err := fx.repoUser.GetByUsername(ctx, "ivan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uDB.PasswordResetRequired {
+		t.Error("password reset is still required after the second factor")
+	}
+	if events := fx.auditEvents(t, mc.AuditPasswordChange); len(events) != 1 {
+		t.Errorf("password change audit events = %+v, want one", events)
+	}
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/metrics"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/gorilla/mux"
+)
+
+// OIDCLogin перенаправляет пользователя на страницу входа внешнего провайдера.
+func (h *AuthHandlerManager) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	authURL, flow, expiresAt, err := h.ucOidc.Begin(r.Context(), mux.Vars(r)["provider"])
+	if err != nil {
+		if errors.Is(err, me.ErrUnknownProvider) {
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusNotFound)
+			return
+		}
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+		return
+	}
+	f.SetOIDCFlowCookie(w, flow, expiresAt)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback обрабатывает возврат от провайдера. Если внешняя учетная запись уже привязана (или была
+// привязана сейчас), пользователь авторизуется. Иначе возвращается registration_token для заполнения профиля.
+func (h *AuthHandlerManager) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		h.logger.Info("oidc provider returned error: "+providerErr, f.LogFields(r)...)
+		f.FlashOIDCFlowCookie(w)
+		f.Response(w, dto.ResponseError{Error: me.ErrOIDCLoginFailed.Error()}, http.StatusUnauthorized)
+		return
+	}
+	flow, err := f.GetOIDCFlowCookie(r)
+	if err != nil {
+		h.logger.Info(me.ErrInvalidOIDCState.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInvalidOIDCState.Error()}, http.StatusBadRequest)
+		return
+	}
+	// состояние одноразовое
+	f.FlashOIDCFlowCookie(w)
+
+	// привязать провайдера к учетной записи можно только из сессии, API-ключа для этого недостаточно
+	currentUsername := f.GetSessionUsernameCtx(r)
+	result, err := h.ucOidc.Callback(r.Context(), mux.Vars(r)["provider"], query.Get("code"), query.Get("state"),
+		flow, currentUsername)
+	if err != nil {
+		switch {
+		case errors.Is(err, me.ErrUnknownProvider):
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: me.ErrUnknownProvider.Error()}, http.StatusNotFound)
+		case errors.Is(err, me.ErrInvalidOIDCState) || errors.Is(err, me.ErrIdentityAlreadyLinked):
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+		case errors.Is(err, me.ErrOIDCEmailTaken):
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusConflict)
+		case errors.Is(err, me.ErrOIDCLoginFailed) || errors.Is(err, me.ErrUserNotExist):
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: me.ErrOIDCLoginFailed.Error()}, http.StatusUnauthorized)
+		default:
+			h.logger.Error(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if result.User == nil {
+		f.Response(w, dto.OIDCProfileRequired{
+			ProfileRequired:   true,
+			RegistrationToken: result.RegistrationToken,
+			Email:             result.Identity.Email,
+			FirstName:         result.Identity.FirstName,
+		}, http.StatusOK)
+		return
+	}
+	// пользователь уже авторизован и просто привязал провайдера
+	if currentUsername != "" {
+		f.Response(w, getUserWithoutPassword(result.User), http.StatusOK)
+		return
+	}
+	h.finishSignIn(w, r, &ent.SignInResult{User: result.User}, false, metrics.MethodOIDC)
+}
+
+// OIDCComplete завершает регистрацию пользователя, впервые вошедшего через внешнего провайдера.
+func (h *AuthHandlerManager) OIDCComplete(w http.ResponseWriter, r *http.Request) {
+	username := f.GetUsernameCtx(r)
+	if username != "" {
+		h.logger.Info(me.ErrAlreadyRegistered.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrAlreadyRegistered.Error()}, http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
+		return
+	}
+	var profileForm dto.CompleteProfileData
+	err = json.Unmarshal(body, &profileForm)
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
+		return
+	}
+	err = profileForm.Validate()
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	u, err := h.ucOidc.CompleteProfile(r.Context(), profileForm.RegistrationToken, &profileForm.CreateData)
+	if err != nil {
+		if errors.Is(err, me.ErrInvalidRegistrationToken) || errors.Is(err, me.ErrIdentityAlreadyLinked) ||
+			errors.Is(err, me.ErrUserAlreadyExist) {
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+			return
+		}
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+		return
+	}
+	h.audit(r, u.Username, mc.AuditSignUp, map[string]any{"oidc": true})
+	metrics.SignUps.WithLabelValues(metrics.MethodOIDC).Inc()
+	h.responseWithTokens(w, r, u, profileForm.TokensInBody)
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// oidcSpy запоминает, от чьего имени вызван Callback.
+type oidcSpy struct {
+	currentUsername string
+}
+
+func (s *oidcSpy) Begin(context.Context, string) (string, string, time.Time, error) {
+	return "", "", time.Time{}, nil
+}
+
+func (s *oidcSpy) Callback(_ context.Context, _, _, _, _, currentUsername string) (*ent.OIDCResult, error) {
+	s.currentUsername = currentUsername
+	return &ent.OIDCResult{RegistrationToken: "registration", Identity: &ent.ExternalIdentity{}}, nil
+}
+
+func (s *oidcSpy) CompleteProfile(context.Context, string, *dto.CreateData) (*ent.User, error) {
+	return nil, nil
+}
+
+func TestOIDCCallbackLinksOnlyFromSession(t *testing.T) {
+	tests := []struct {
+		name         string
+		apiKey       bool
+		wantUsername string
+	}{
+		{name: "session", wantUsername: "ivan"},
+		{name: "api key", apiKey: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spy := &oidcSpy{}
+			h := NewAuthHandlerManager(nil, nil, nil, nil, spy, nil, zap.NewNop())
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/oidc/google/callback?code=code&state=state", nil)
+			r.AddCookie(&http.Cookie{Name: mc.OIDCFlow, Value: "flow"})
+			ctx := context.WithValue(r.Context(), "username", "ivan")
+			if tt.apiKey {
+				ctx = context.WithValue(ctx, "api_key_scopes", []string{mc.ScopeReadAll})
+			}
+			r = mux.SetURLVars(r.WithContext(ctx), map[string]string{"provider": "google"})
+			w := httptest.NewRecorder()
+
+			h.OIDCCallback(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("OIDCCallback() status = %d, want %d", w.Code, http.StatusOK)
+			}
+			if spy.currentUsername != tt.wantUsername {
+				t.Errorf("Callback() currentUsername = %q, want %q", spy.currentUsername, tt.wantUsername)
+			}
+		})
+	}
+}
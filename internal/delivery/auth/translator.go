@@ -17,5 +17,6 @@ func getUserWithoutPassword(user *ent.User) *dto.UserWithoutPassword {
 		Sex:              user.Sex,
 		DayCalories:      user.DayCalories,
 		PhysicalActivity: user.PhysicalActivity,
+		Role:             user.Role,
 	}
 }
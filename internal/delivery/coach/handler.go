@@ -0,0 +1,249 @@
+package coach
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	ucCoach "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/coach"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// errStatus коды ответа на ошибки бизнес-логики, остальные ошибки считаются внутренними.
+var errStatus = map[error]int{
+	me.ErrNotCoach:          http.StatusBadRequest,
+	me.ErrCoachSelf:         http.StatusBadRequest,
+	me.ErrCoachLinkExist:    http.StatusBadRequest,
+	me.ErrCoachNoConsent:    http.StatusForbidden,
+	me.ErrCoachLinkNotExist: http.StatusNotFound,
+	me.ErrUserNotExist:      http.StatusNotFound,
+}
+
+type CoachHandlerManager struct {
+	ucCoach ucCoach.Usecase
+	logger  *zap.Logger
+}
+
+// NewCoachHandlerManager возвращает менеджер хендлеров, отвечающих за связи тренеров и клиентов
+func NewCoachHandlerManager(ucCoach ucCoach.Usecase, logger *zap.Logger) *CoachHandlerManager {
+	return &CoachHandlerManager{
+		ucCoach: ucCoach,
+		logger:  logger,
+	}
+}
+
+// Invite отправляет приглашение тренеру.
+func (h *CoachHandlerManager) Invite(w http.ResponseWriter, r *http.Request) {
+	var inviteForm dto.CoachInvite
+	if !h.readForm(w, r, &inviteForm) {
+		return
+	}
+	l, err := h.ucCoach.Invite(r.Context(), f.GetUsernameCtx(r), inviteForm.Coach, inviteForm.Scopes)
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, getCoachLink(l), http.StatusCreated)
+}
+
+// ListCoaches возвращает тренеров пользователя и отправленные им приглашения.
+func (h *CoachHandlerManager) ListCoaches(w http.ResponseWriter, r *http.Request) {
+	links, err := h.ucCoach.ListCoaches(r.Context(), f.GetUsernameCtx(r))
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, getCoachLinks(links), http.StatusOK)
+}
+
+// UpdateScopes меняет данные, которые пользователь открыл тренеру.
+func (h *CoachHandlerManager) UpdateScopes(w http.ResponseWriter, r *http.Request) {
+	var scopesForm dto.CoachScopes
+	if !h.readForm(w, r, &scopesForm) {
+		return
+	}
+	l, err := h.ucCoach.UpdateScopes(r.Context(), f.GetUsernameCtx(r), mux.Vars(r)["coach"], scopesForm.Scopes)
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, getCoachLink(l), http.StatusOK)
+}
+
+// RevokeCoach разрывает связь с тренером или отзывает приглашение.
+func (h *CoachHandlerManager) RevokeCoach(w http.ResponseWriter, r *http.Request) {
+	err := h.ucCoach.Revoke(r.Context(), f.GetUsernameCtx(r), mux.Vars(r)["coach"])
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, dto.ResponseDetail{Detail: "Связь с тренером разорвана"}, http.StatusOK)
+}
+
+// ListComments возвращает комментарии тренера пользователю.
+func (h *CoachHandlerManager) ListComments(w http.ResponseWriter, r *http.Request) {
+	comments, err := h.ucCoach.ListComments(r.Context(), f.GetUsernameCtx(r), mux.Vars(r)["coach"])
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, getCoachComments(comments), http.StatusOK)
+}
+
+// ListInvitations возвращает приглашения, которые тренер еще не принял.
+func (h *CoachHandlerManager) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	links, err := h.ucCoach.ListInvitations(r.Context(), f.GetUsernameCtx(r))
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, getCoachLinks(links), http.StatusOK)
+}
+
+// AcceptInvitation принимает приглашение клиента.
+func (h *CoachHandlerManager) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	l, err := h.ucCoach.Accept(r.Context(), f.GetUsernameCtx(r), mux.Vars(r)["client"])
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, getCoachLink(l), http.StatusOK)
+}
+
+// DeclineInvitation отклоняет приглашение клиента.
+func (h *CoachHandlerManager) DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	err := h.ucCoach.Revoke(r.Context(), mux.Vars(r)["client"], f.GetUsernameCtx(r))
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, dto.ResponseDetail{Detail: "Приглашение отклонено"}, http.StatusOK)
+}
+
+// ListClients возвращает клиентов тренера.
+func (h *CoachHandlerManager) ListClients(w http.ResponseWriter, r *http.Request) {
+	links, err := h.ucCoach.ListClients(r.Context(), f.GetUsernameCtx(r))
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, getCoachLinks(links), http.StatusOK)
+}
+
+// GetClientProfile возвращает профиль клиента.
+func (h *CoachHandlerManager) GetClientProfile(w http.ResponseWriter, r *http.Request) {
+	u, err := h.ucCoach.GetClientProfile(r.Context(), f.GetUsernameCtx(r), mux.Vars(r)["username"])
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	u.BMI.Calculate(u.Weight, u.Height)
+	f.Response(w, getClientProfile(u), http.StatusOK)
+}
+
+// GetClientWeight возвращает историю массы тела клиента: его измерения вида weight.
+func (h *CoachHandlerManager) GetClientWeight(w http.ResponseWriter, r *http.Request) {
+	client := mux.Vars(r)["username"]
+	measurements, err := h.ucCoach.GetClientMeasurements(r.Context(), f.GetUsernameCtx(r), client, mc.MeasureWeight)
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, getWeightHistory(client, measurements), http.StatusOK)
+}
+
+// GetClientMeasurements возвращает последние измерения клиента вида из параметра kind.
+func (h *CoachHandlerManager) GetClientMeasurements(w http.ResponseWriter, r *http.Request) {
+	kind := r.URL.Query().Get("kind")
+	err := dto.ValidateMeasureKind(kind)
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+		return
+	}
+	measurements, err := h.ucCoach.GetClientMeasurements(r.Context(), f.GetUsernameCtx(r), mux.Vars(r)["username"], kind)
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, getMeasurementList(measurements), http.StatusOK)
+}
+
+// RevokeClient разрывает связь с клиентом.
+func (h *CoachHandlerManager) RevokeClient(w http.ResponseWriter, r *http.Request) {
+	err := h.ucCoach.Revoke(r.Context(), mux.Vars(r)["username"], f.GetUsernameCtx(r))
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, dto.ResponseDetail{Detail: "Связь с клиентом разорвана"}, http.StatusOK)
+}
+
+// AddComment оставляет комментарий клиенту.
+func (h *CoachHandlerManager) AddComment(w http.ResponseWriter, r *http.Request) {
+	var commentForm dto.CoachCommentCreate
+	if !h.readForm(w, r, &commentForm) {
+		return
+	}
+	c, err := h.ucCoach.AddComment(r.Context(), f.GetUsernameCtx(r), mux.Vars(r)["username"], commentForm.Text)
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, getCoachComment(c), http.StatusCreated)
+}
+
+// SetCalorieTarget задает клиенту норму калорий.
+func (h *CoachHandlerManager) SetCalorieTarget(w http.ResponseWriter, r *http.Request) {
+	var targetForm dto.CalorieTarget
+	if !h.readForm(w, r, &targetForm) {
+		return
+	}
+	l, err := h.ucCoach.SetCalorieTarget(r.Context(), f.GetUsernameCtx(r), mux.Vars(r)["username"], targetForm.DayCalories)
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, getCoachLink(l), http.StatusOK)
+}
+
+// readForm читает тело запроса в form и проверяет его. Если данные неверные, отвечает клиенту и возвращает false.
+func (h *CoachHandlerManager) readForm(w http.ResponseWriter, r *http.Request, form interface{ Validate() error }) bool {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
+		return false
+	}
+	err = json.Unmarshal(body, form)
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
+		return false
+	}
+	err = form.Validate()
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func (h *CoachHandlerManager) responseError(w http.ResponseWriter, r *http.Request, err error) {
+	for knownErr, status := range errStatus {
+		if errors.Is(err, knownErr) {
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: knownErr.Error()}, status)
+			return
+		}
+	}
+	h.logger.Error(err.Error(), f.LogFields(r)...)
+	f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+}
@@ -0,0 +1,77 @@
+package coach
+
+import (
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+)
+
+func getCoachLink(l *ent.CoachLink) dto.CoachLink {
+	return dto.CoachLink{
+		Client:        l.ClientUsername,
+		Coach:         l.CoachUsername,
+		Status:        l.Status,
+		Scopes:        l.Scopes,
+		CalorieTarget: l.CalorieTarget,
+		CreatedAt:     l.CreatedAt,
+		UpdatedAt:     l.UpdatedAt,
+	}
+}
+
+func getCoachLinks(links []*ent.CoachLink) []dto.CoachLink {
+	result := make([]dto.CoachLink, 0, len(links))
+	for _, l := range links {
+		result = append(result, getCoachLink(l))
+	}
+	return result
+}
+
+func getCoachComment(c *ent.CoachComment) dto.CoachComment {
+	return dto.CoachComment{
+		ID:        c.ID,
+		Coach:     c.CoachUsername,
+		Text:      c.Text,
+		CreatedAt: c.CreatedAt,
+	}
+}
+
+func getCoachComments(comments []*ent.CoachComment) []dto.CoachComment {
+	result := make([]dto.CoachComment, 0, len(comments))
+	for _, c := range comments {
+		result = append(result, getCoachComment(c))
+	}
+	return result
+}
+
+func getWeightHistory(username string, measurements []*ent.Measurement) dto.WeightHistory {
+	result := make([]dto.WeightRecord, 0, len(measurements))
+	for _, m := range measurements {
+		result = append(result, dto.WeightRecord{Weight: m.Value, RecordedAt: m.RecordedAt})
+	}
+	return dto.WeightHistory{Username: username, Records: result}
+}
+
+func getMeasurementList(measurements []*ent.Measurement) dto.MeasurementList {
+	list := dto.MeasurementList{Measurements: make([]dto.Measurement, 0, len(measurements))}
+	for _, m := range measurements {
+		list.Measurements = append(list.Measurements, dto.Measurement{
+			Kind:       m.Kind,
+			Value:      m.Value,
+			RecordedAt: m.RecordedAt,
+		})
+	}
+	return list
+}
+
+func getClientProfile(user *ent.User) dto.ClientProfile {
+	return dto.ClientProfile{
+		FirstName:        user.FirstName,
+		Username:         user.Username,
+		Weight:           user.Weight,
+		Height:           user.Height,
+		Age:              user.Age,
+		Sex:              user.Sex,
+		DayCalories:      user.DayCalories,
+		PhysicalActivity: user.PhysicalActivity,
+		BMI:              dto.BMIType{Value: user.BMI.Value, Comment: user.BMI.Comment},
+	}
+}
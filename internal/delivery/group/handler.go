@@ -0,0 +1,209 @@
+package group
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	ucGroup "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/group"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// errStatus коды ответа на ошибки бизнес-логики, остальные ошибки считаются внутренними.
+var errStatus = map[error]int{
+	me.ErrGroupAlreadyExist: http.StatusBadRequest,
+	me.ErrBidAlreadyExist:   http.StatusBadRequest,
+	me.ErrAlreadyMember:     http.StatusBadRequest,
+	me.ErrCannotRemoveOwner: http.StatusBadRequest,
+	me.ErrNotGroupOwner:     http.StatusForbidden,
+	me.ErrGroupNotExist:     http.StatusNotFound,
+	me.ErrBidNotExist:       http.StatusNotFound,
+	me.ErrUserNotExist:      http.StatusNotFound,
+	me.ErrNotMember:         http.StatusNotFound,
+}
+
+type GroupHandlerManager struct {
+	ucGroup ucGroup.Usecase
+	logger  *zap.Logger
+}
+
+// NewGroupHandlerManager возвращает менеджер хендлеров, отвечающих за группы и заявки на их создание
+func NewGroupHandlerManager(ucGroup ucGroup.Usecase, logger *zap.Logger) *GroupHandlerManager {
+	return &GroupHandlerManager{
+		ucGroup: ucGroup,
+		logger:  logger,
+	}
+}
+
+// CreateBid создает заявку на создание группы.
+func (h *GroupHandlerManager) CreateBid(w http.ResponseWriter, r *http.Request) {
+	var bidForm dto.BidCreate
+	if !h.readForm(w, r, &bidForm) {
+		return
+	}
+	b, err := h.ucGroup.CreateBid(r.Context(), f.GetUsernameCtx(r), bidForm.GroupName)
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, getBid(b), http.StatusCreated)
+}
+
+// ListBids возвращает заявки пользователя.
+func (h *GroupHandlerManager) ListBids(w http.ResponseWriter, r *http.Request) {
+	bids, err := h.ucGroup.ListBids(r.Context(), f.GetUsernameCtx(r))
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, getBids(bids), http.StatusOK)
+}
+
+// ListPendingBids возвращает нерассмотренные заявки всех пользователей.
+func (h *GroupHandlerManager) ListPendingBids(w http.ResponseWriter, r *http.Request) {
+	bids, err := h.ucGroup.ListPendingBids(r.Context())
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, getBids(bids), http.StatusOK)
+}
+
+// ApproveBid одобряет заявку и создает группу.
+func (h *GroupHandlerManager) ApproveBid(w http.ResponseWriter, r *http.Request) {
+	bidID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.responseError(w, r, me.ErrBidNotExist)
+		return
+	}
+	g, err := h.ucGroup.ApproveBid(r.Context(), bidID)
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, getGroup(g), http.StatusCreated)
+}
+
+// RejectBid отклоняет заявку.
+func (h *GroupHandlerManager) RejectBid(w http.ResponseWriter, r *http.Request) {
+	bidID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		h.responseError(w, r, me.ErrBidNotExist)
+		return
+	}
+	b, err := h.ucGroup.RejectBid(r.Context(), bidID)
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, getBid(b), http.StatusOK)
+}
+
+// ListGroups возвращает группы, в которых состоит пользователь.
+func (h *GroupHandlerManager) ListGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.ucGroup.ListGroups(r.Context(), f.GetUsernameCtx(r))
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	result := make([]dto.Group, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, getGroup(g))
+	}
+	f.Response(w, result, http.StatusOK)
+}
+
+// DeleteGroup удаляет группу.
+func (h *GroupHandlerManager) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	err := h.ucGroup.DeleteGroup(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, dto.ResponseDetail{Detail: "Группа удалена"}, http.StatusOK)
+}
+
+// ListMembers возвращает участников группы.
+func (h *GroupHandlerManager) ListMembers(w http.ResponseWriter, r *http.Request) {
+	groupName := mux.Vars(r)["name"]
+	members, err := h.ucGroup.ListMembers(r.Context(), getActor(r), groupName)
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, dto.GroupMembers{Group: groupName, Members: members}, http.StatusOK)
+}
+
+// AddMember добавляет пользователя в группу.
+func (h *GroupHandlerManager) AddMember(w http.ResponseWriter, r *http.Request) {
+	var memberForm dto.MemberAdd
+	if !h.readForm(w, r, &memberForm) {
+		return
+	}
+	err := h.ucGroup.AddMember(r.Context(), getActor(r), mux.Vars(r)["name"], memberForm.Username)
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, dto.ResponseDetail{Detail: "Пользователь добавлен в группу"}, http.StatusOK)
+}
+
+// RemoveMember исключает пользователя из группы.
+func (h *GroupHandlerManager) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	err := h.ucGroup.RemoveMember(r.Context(), getActor(r), vars["name"], vars["username"])
+	if err != nil {
+		h.responseError(w, r, err)
+		return
+	}
+	f.Response(w, dto.ResponseDetail{Detail: "Пользователь исключен из группы"}, http.StatusOK)
+}
+
+func getActor(r *http.Request) ucGroup.Actor {
+	return ucGroup.Actor{
+		Username:  f.GetUsernameCtx(r),
+		CanManage: f.HasPermission(r, mc.PermGroupsManage),
+	}
+}
+
+// readForm читает тело запроса в form и проверяет его. Если данные неверные, отвечает клиенту и возвращает false.
+func (h *GroupHandlerManager) readForm(w http.ResponseWriter, r *http.Request, form interface{ Validate() error }) bool {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
+		return false
+	}
+	err = json.Unmarshal(body, form)
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
+		return false
+	}
+	err = form.Validate()
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func (h *GroupHandlerManager) responseError(w http.ResponseWriter, r *http.Request, err error) {
+	for knownErr, status := range errStatus {
+		if errors.Is(err, knownErr) {
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: knownErr.Error()}, status)
+			return
+		}
+	}
+	h.logger.Error(err.Error(), f.LogFields(r)...)
+	f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+}
@@ -0,0 +1,33 @@
+package group
+
+import (
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+)
+
+func getBid(b *ent.Bid) dto.Bid {
+	return dto.Bid{
+		ID:        b.ID,
+		GroupName: b.GroupName,
+		Username:  b.Username,
+		Status:    b.Status,
+		CreatedAt: b.CreatedAt,
+		UpdatedAt: b.UpdatedAt,
+	}
+}
+
+func getBids(bids []*ent.Bid) []dto.Bid {
+	result := make([]dto.Bid, 0, len(bids))
+	for _, b := range bids {
+		result = append(result, getBid(b))
+	}
+	return result
+}
+
+func getGroup(g *ent.Group) dto.Group {
+	return dto.Group{
+		Name:      g.Name,
+		Owner:     g.OwnerUsername,
+		CreatedAt: g.CreatedAt,
+	}
+}
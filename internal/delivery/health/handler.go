@@ -0,0 +1,93 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"go.uber.org/zap"
+)
+
+// Check проверка зависимости сервиса. Ping должен вернуть ошибку, если зависимость недоступна или не ответила
+// до отмены ctx.
+type Check struct {
+	Name string
+	Ping func(ctx context.Context) error
+}
+
+type HealthHandlerManager struct {
+	checks   []Check
+	timeout  time.Duration
+	draining atomic.Bool
+	logger   *zap.Logger
+}
+
+// NewHealthHandlerManager возвращает менеджер хендлеров проверки состояния сервиса. Каждая зависимость
+// проверяется не дольше timeout.
+func NewHealthHandlerManager(checks []Check, timeout time.Duration, logger *zap.Logger) *HealthHandlerManager {
+	return &HealthHandlerManager{
+		checks:  checks,
+		timeout: timeout,
+		logger:  logger,
+	}
+}
+
+// Drain переводит сервис в режим остановки: /readyz начинает отвечать 503, чтобы балансировщик перестал
+// направлять запросы до того, как сервер закроет соединения.
+func (h *HealthHandlerManager) Drain() {
+	h.draining.Store(true)
+}
+
+// Live отвечает, что процесс жив. Зависимости не проверяются: их недоступность не лечится перезапуском.
+func (h *HealthHandlerManager) Live(w http.ResponseWriter, r *http.Request) {
+	f.Response(w, dto.Readiness{Status: mc.HealthOK}, http.StatusOK)
+}
+
+// Ready проверяет зависимости параллельно и возвращает результат по каждой. Если хотя бы одна недоступна или
+// сервис останавливается, отвечает 503.
+func (h *HealthHandlerManager) Ready(w http.ResponseWriter, r *http.Request) {
+	if h.draining.Load() {
+		f.Response(w, dto.Readiness{Status: mc.HealthDraining}, http.StatusServiceUnavailable)
+		return
+	}
+
+	statuses := make([]dto.DependencyStatus, len(h.checks))
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = h.ping(r.Context(), check)
+		}()
+	}
+	wg.Wait()
+
+	resp := dto.Readiness{Status: mc.HealthOK, Checks: make(map[string]dto.DependencyStatus, len(h.checks))}
+	code := http.StatusOK
+	for i, check := range h.checks {
+		resp.Checks[check.Name] = statuses[i]
+		if statuses[i].Status != mc.HealthOK {
+			resp.Status, code = mc.HealthFail, http.StatusServiceUnavailable
+		}
+	}
+	f.Response(w, resp, code)
+}
+
+func (h *HealthHandlerManager) ping(ctx context.Context, check Check) dto.DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	start := time.Now()
+	err := check.Ping(ctx)
+	status := dto.DependencyStatus{Status: mc.HealthOK, LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		h.logger.Warn(fmt.Sprintf("%s is not ready: %v", check.Name, err))
+		status.Status, status.Error = mc.HealthFail, err.Error()
+	}
+	return status
+}
@@ -0,0 +1,145 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"go.uber.org/zap"
+)
+
+// timeout время проверки одной зависимости в тестах.
+const timeout = 50 * time.Millisecond
+
+func okCheck(name string) Check {
+	return Check{Name: name, Ping: func(context.Context) error { return nil }}
+}
+
+func failCheck(name string) Check {
+	return Check{Name: name, Ping: func(context.Context) error { return errors.New("connection refused") }}
+}
+
+// hangCheck зависимость, которая не отвечает, пока проверку не отменят.
+func hangCheck(name string) Check {
+	return Check{Name: name, Ping: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+}
+
+func serve(t *testing.T, handler http.HandlerFunc) (int, dto.Readiness) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var resp dto.Readiness
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	return w.Code, resp
+}
+
+func TestReady(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     []Check
+		wantStatus int
+		wantBody   string
+		// wantChecks статус каждой зависимости, wantErrors — подстрока ошибки зависимости
+		wantChecks map[string]string
+		wantErrors map[string]string
+	}{
+		{
+			name:       "no dependencies",
+			wantStatus: http.StatusOK, wantBody: mc.HealthOK,
+			wantChecks: map[string]string{},
+		},
+		{
+			name:       "all dependencies are ready",
+			checks:     []Check{okCheck("postgres"), okCheck("memcached")},
+			wantStatus: http.StatusOK, wantBody: mc.HealthOK,
+			wantChecks: map[string]string{"postgres": mc.HealthOK, "memcached": mc.HealthOK},
+		},
+		{
+			name:       "one dependency fails",
+			checks:     []Check{okCheck("postgres"), failCheck("memcached")},
+			wantStatus: http.StatusServiceUnavailable, wantBody: mc.HealthFail,
+			wantChecks: map[string]string{"postgres": mc.HealthOK, "memcached": mc.HealthFail},
+			wantErrors: map[string]string{"memcached": "connection refused"},
+		},
+		{
+			name:       "dependency does not respond in time",
+			checks:     []Check{hangCheck("mongo"), okCheck("postgres")},
+			wantStatus: http.StatusServiceUnavailable, wantBody: mc.HealthFail,
+			wantChecks: map[string]string{"mongo": mc.HealthFail, "postgres": mc.HealthOK},
+			wantErrors: map[string]string{"mongo": context.DeadlineExceeded.Error()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealthHandlerManager(tt.checks, timeout, zap.NewNop())
+
+			code, resp := serve(t, h.Ready)
+			if code != tt.wantStatus || resp.Status != tt.wantBody {
+				t.Fatalf("Ready() = %d %q, want %d %q", code, resp.Status, tt.wantStatus, tt.wantBody)
+			}
+			if len(resp.Checks) != len(tt.wantChecks) {
+				t.Errorf("Ready() checks = %+v, want %v", resp.Checks, tt.wantChecks)
+			}
+			for name, want := range tt.wantChecks {
+				got, ok := resp.Checks[name]
+				if !ok || got.Status != want {
+					t.Errorf("Ready() check %s = %+v, want status %q", name, got, want)
+				}
+				wantErr := tt.wantErrors[name]
+				if (wantErr == "" && got.Error != "") || !strings.Contains(got.Error, wantErr) {
+					t.Errorf("Ready() check %s error = %q, want %q", name, got.Error, wantErr)
+				}
+			}
+		})
+	}
+}
+
+// Зависимости проверяются параллельно, поэтому зависшие зависимости не складывают время ответа.
+func TestReadyChecksInParallel(t *testing.T) {
+	checks := []Check{hangCheck("postgres"), hangCheck("mongo"), hangCheck("memcached")}
+	h := NewHealthHandlerManager(checks, timeout, zap.NewNop())
+
+	start := time.Now()
+	code, _ := serve(t, h.Ready)
+	if elapsed, limit := time.Since(start), time.Duration(len(checks))*timeout; elapsed >= limit {
+		t.Errorf("Ready() took %v, want less than %v", elapsed, limit)
+	}
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("Ready() status = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestReadyAfterDrain(t *testing.T) {
+	var pings atomic.Int32
+	check := Check{Name: "postgres", Ping: func(context.Context) error {
+		pings.Add(1)
+		return nil
+	}}
+	h := NewHealthHandlerManager([]Check{check}, timeout, zap.NewNop())
+	h.Drain()
+
+	code, resp := serve(t, h.Ready)
+	if code != http.StatusServiceUnavailable || resp.Status != mc.HealthDraining {
+		t.Fatalf("Ready() = %d %q, want %d %q", code, resp.Status, http.StatusServiceUnavailable, mc.HealthDraining)
+	}
+	if pings.Load() != 0 {
+		t.Errorf("dependencies checked %d times while draining, want 0", pings.Load())
+	}
+	// процесс жив, пока сервер завершает запросы
+	if code, resp = serve(t, h.Live); code != http.StatusOK || resp.Status != mc.HealthOK {
+		t.Errorf("Live() = %d %q, want %d %q", code, resp.Status, http.StatusOK, mc.HealthOK)
+	}
+}
@@ -0,0 +1,91 @@
+package measurement
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	ucMeasurement "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/measurement"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/metrics"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"go.uber.org/zap"
+)
+
+type MeasurementHandlerManager struct {
+	ucMeasurement ucMeasurement.Usecase
+	logger        *zap.Logger
+}
+
+// NewMeasurementHandlerManager возвращает менеджер хендлеров, отвечающих за измерения пользователя (масса тела,
+// шаги, пульс).
+func NewMeasurementHandlerManager(ucMeasurement ucMeasurement.Usecase, logger *zap.Logger) *MeasurementHandlerManager {
+	return &MeasurementHandlerManager{
+		ucMeasurement: ucMeasurement,
+		logger:        logger,
+	}
+}
+
+// Add сохраняет пачку измерений пользователя. Пачка сохраняется целиком: если хотя бы одно измерение
+// не прошло проверку, не сохраняется ни одно.
+func (h *MeasurementHandlerManager) Add(w http.ResponseWriter, r *http.Request) {
+	username := f.GetUsernameCtx(r)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	var batch dto.MeasurementBatch
+	err = json.Unmarshal(body, &batch)
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
+		return
+	}
+	err = batch.Validate()
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	measurements := newMeasurements(username, &batch)
+	err = h.ucMeasurement.Add(r.Context(), measurements)
+	if err != nil {
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+		return
+	}
+	for _, m := range measurements {
+		metrics.Measurements.WithLabelValues(m.Kind).Inc()
+		if m.Kind == mc.MeasureWeight {
+			metrics.WeighIns.WithLabelValues(metrics.SourceMeasurement).Inc()
+		}
+	}
+	f.Response(w, dto.ResponseDetail{Detail: "Измерения успешно сохранены"}, http.StatusCreated)
+}
+
+// List возвращает измерения пользователя, начиная с последних. Параметры запроса: kind, from, to, limit.
+func (h *MeasurementHandlerManager) List(w http.ResponseWriter, r *http.Request) {
+	username := f.GetUsernameCtx(r)
+
+	filter, err := getMeasurementFilter(username, r.URL.Query())
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	measurements, err := h.ucMeasurement.List(r.Context(), filter)
+	if err != nil {
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+		return
+	}
+	f.Response(w, getMeasurementList(measurements), http.StatusOK)
+}
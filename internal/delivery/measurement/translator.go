@@ -0,0 +1,90 @@
+package measurement
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	"github.com/spf13/viper"
+)
+
+// newMeasurements переводит пачку измерений в сущности. Измерение без времени считается сделанным в момент
+// получения запроса.
+func newMeasurements(username string, batch *dto.MeasurementBatch) []*ent.Measurement {
+	now := time.Now().UTC()
+	measurements := make([]*ent.Measurement, 0, len(batch.Measurements))
+	for _, m := range batch.Measurements {
+		recordedAt := m.RecordedAt.UTC()
+		if m.RecordedAt.IsZero() {
+			recordedAt = now
+		}
+		measurements = append(measurements, &ent.Measurement{
+			Username:   username,
+			Kind:       m.Kind,
+			Value:      m.Value,
+			RecordedAt: recordedAt,
+		})
+	}
+	return measurements
+}
+
+// getMeasurementFilter собирает фильтр из параметров запроса. to в формате даты включает весь указанный день.
+// Число измерений по умолчанию и максимальное берутся из measurements.page_size и measurements.max_page_size.
+func getMeasurementFilter(username string, query url.Values) (*ent.MeasurementFilter, error) {
+	filter := &ent.MeasurementFilter{Username: username, Limit: viper.GetInt("measurements.page_size")}
+	if kind := query.Get("kind"); kind != "" {
+		if err := dto.ValidateMeasureKind(kind); err != nil {
+			return nil, err
+		}
+		filter.Kind = kind
+	}
+	var err error
+	filter.From, err = parseDate(query.Get("from"), false)
+	if err != nil {
+		return nil, err
+	}
+	filter.To, err = parseDate(query.Get("to"), true)
+	if err != nil {
+		return nil, err
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return nil, dto.ErrInvalidMeasureLimit
+		}
+		filter.Limit = n
+	}
+	filter.Limit = min(filter.Limit, viper.GetInt("measurements.max_page_size"))
+	return filter, nil
+}
+
+func parseDate(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, dto.ErrInvalidDate
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+func getMeasurementList(measurements []*ent.Measurement) *dto.MeasurementList {
+	list := &dto.MeasurementList{Measurements: make([]dto.Measurement, 0, len(measurements))}
+	for _, m := range measurements {
+		list.Measurements = append(list.Measurements, dto.Measurement{
+			Kind:       m.Kind,
+			Value:      m.Value,
+			RecordedAt: m.RecordedAt,
+		})
+	}
+	return list
+}
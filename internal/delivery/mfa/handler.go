@@ -0,0 +1,139 @@
+package mfa
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	ucAudit "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/audit"
+	ucMfa "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/mfa"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"go.uber.org/zap"
+)
+
+type MfaHandlerManager struct {
+	ucMfa   ucMfa.Usecase
+	ucAudit ucAudit.Usecase
+	logger  *zap.Logger
+}
+
+// NewMfaHandlerManager возвращает менеджер хендлеров, отвечающих за настройку двухфакторной аутентификации
+func NewMfaHandlerManager(ucMfa ucMfa.Usecase, ucAudit ucAudit.Usecase, logger *zap.Logger) *MfaHandlerManager {
+	return &MfaHandlerManager{
+		ucMfa:   ucMfa,
+		ucAudit: ucAudit,
+		logger:  logger,
+	}
+}
+
+// Enroll создает секрет TOTP и возвращает его вместе со ссылкой otpauth://.
+func (h *MfaHandlerManager) Enroll(w http.ResponseWriter, r *http.Request) {
+	username := f.GetUsernameCtx(r)
+
+	enrollment, err := h.ucMfa.Enroll(r.Context(), username)
+	if err != nil {
+		if errors.Is(err, me.ErrMfaAlreadyEnabled) {
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+			return
+		}
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+		return
+	}
+	f.Response(w, dto.TOTPEnrollment{Secret: enrollment.Secret, OtpauthURI: enrollment.OtpauthURI}, http.StatusOK)
+}
+
+// Confirm подтверждает регистрацию второго фактора кодом и возвращает коды восстановления.
+func (h *MfaHandlerManager) Confirm(w http.ResponseWriter, r *http.Request) {
+	username := f.GetUsernameCtx(r)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
+		return
+	}
+	var code dto.MfaCode
+	err = json.Unmarshal(body, &code)
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
+		return
+	}
+	err = code.Validate()
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.ucMfa.Confirm(r.Context(), username, code.Code)
+	if err != nil {
+		if errors.Is(err, me.ErrMfaAlreadyEnabled) || errors.Is(err, me.ErrMfaNotEnabled) || errors.Is(err, me.ErrInvalidMfaCode) {
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+			return
+		}
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+		return
+	}
+	h.audit(r, mc.AuditMfaEnable, nil)
+	f.Response(w, dto.RecoveryCodes{RecoveryCodes: codes}, http.StatusOK)
+}
+
+// Disable отключает второй фактор, требует пароль и код.
+func (h *MfaHandlerManager) Disable(w http.ResponseWriter, r *http.Request) {
+	username := f.GetUsernameCtx(r)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
+		return
+	}
+	var disableForm dto.MfaDisable
+	err = json.Unmarshal(body, &disableForm)
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
+		return
+	}
+	err = disableForm.Validate()
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	err = h.ucMfa.Disable(r.Context(), username, disableForm.Password, disableForm.Code)
+	if err != nil {
+		if errors.Is(err, me.ErrIncorrectPwdOrLogin) || errors.Is(err, me.ErrMfaNotEnabled) ||
+			errors.Is(err, me.ErrInvalidMfaCode) || errors.Is(err, me.ErrUserNotExist) {
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+			return
+		}
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+		return
+	}
+	h.audit(r, mc.AuditMfaDisable, nil)
+	f.Response(w, dto.ResponseDetail{Detail: "Двухфакторная аутентификация отключена"}, http.StatusOK)
+}
+
+// audit записывает действие пользователя над своей учетной записью. Недоступность журнала не должна
+// мешать запросу, поэтому ошибка только пишется в лог.
+func (h *MfaHandlerManager) audit(r *http.Request, action string, details map[string]any) {
+	e := f.NewAuditEvent(r, action, f.GetUsernameCtx(r))
+	e.Details = details
+	err := h.ucAudit.Record(r.Context(), e)
+	if err != nil {
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+	}
+}
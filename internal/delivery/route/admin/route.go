@@ -0,0 +1,31 @@
+package admin
+
+import (
+	dAdmin "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/admin"
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/middlewares"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo"
+	ucAdmin "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/admin"
+	ucAudit "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/audit"
+	ucLockout "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/lockout"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// InitHandlers инициализирует обработчики запросов администратора для управления пользователями.
+func InitHandlers(r *mux.Router, repos *repo.Repos, cacheStat func() ent.CacheStats, usecaseLockout ucLockout.Usecase,
+	usecaseAudit ucAudit.Usecase, logger *zap.Logger) {
+	ucAdmin := ucAdmin.NewUsecaseLayer(repos.User, repos.Token, repos.Audit, repos.Measurement, usecaseLockout, repos.TxManager, logger)
+	adminHandlerManager := dAdmin.NewAdminHandlerManager(ucAdmin, usecaseAudit, repos.DBStat, cacheStat, logger)
+	// ручки доступны только администратору, каждое действие записывается в журнал
+	r.Handle("/admin/users", middlewares.Authorize(adminHandlerManager.ListUsers, logger, mc.PermUsersManage)).Methods("GET")                                     // поиск пользователей
+	r.Handle("/admin/users/{username}", middlewares.Authorize(adminHandlerManager.GetUser, logger, mc.PermUsersManage)).Methods("GET")                            // профиль пользователя
+	r.Handle("/admin/users/{username}", middlewares.Authorize(adminHandlerManager.DeleteUser, logger, mc.PermUsersManage)).Methods("DELETE")                      // удаление пользователя
+	r.Handle("/admin/users/{username}/lock", middlewares.Authorize(adminHandlerManager.Lock, logger, mc.PermUsersManage)).Methods("POST")                         // блокировка
+	r.Handle("/admin/users/{username}/unlock", middlewares.Authorize(adminHandlerManager.Unlock, logger, mc.PermUsersManage)).Methods("POST")                     // разблокировка
+	r.Handle("/admin/users/{username}/password-reset", middlewares.Authorize(adminHandlerManager.ForcePasswordReset, logger, mc.PermUsersManage)).Methods("POST") // принудительная смена пароля
+	r.Handle("/admin/audit", middlewares.Authorize(adminHandlerManager.ListAudit, logger, mc.PermUsersManage)).Methods("GET")                                     // журнал действий
+	r.Handle("/admin/db/stats", middlewares.Authorize(adminHandlerManager.DBStats, logger, mc.PermUsersManage)).Methods("GET")                                    // состояние пула соединений с базой
+	r.Handle("/admin/cache/stats", middlewares.Authorize(adminHandlerManager.CacheStats, logger, mc.PermUsersManage)).Methods("GET")                              // попадания и промахи кэша профилей
+}
@@ -0,0 +1,27 @@
+package agent
+
+import (
+	dAgent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/agent"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/middlewares"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo"
+	ucAgent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/agent"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// InitHandlers инициализирует обработчики запросов для работы с агентами и проверки доступа к ним.
+func InitHandlers(r *mux.Router, repos *repo.Repos, logger *zap.Logger) {
+	ucAgent := ucAgent.NewUsecaseLayer(repos.Agent, repos.Group, repos.User)
+	agentHandlerManager := dAgent.NewAgentHandlerManager(ucAgent, logger)
+	// ручки, отвечающие за агентов, доступны только root
+	r.Handle("/agents", middlewares.Authorize(agentHandlerManager.Create, logger, mc.PermAgentsManage)).Methods("POST")          // добавление агента
+	r.Handle("/agents", middlewares.Authorize(agentHandlerManager.List, logger, mc.PermAgentsManage)).Methods("GET")             // список агентов
+	r.Handle("/agents/{name}", middlewares.Authorize(agentHandlerManager.Delete, logger, mc.PermAgentsManage)).Methods("DELETE") // удаление агента
+	// ручки, отвечающие за доступ к агентам
+	r.Handle("/agents/{name}/users", middlewares.Authorize(agentHandlerManager.GrantUser, logger, mc.PermAgentsManage)).Methods("POST")               // доступ пользователю
+	r.Handle("/agents/{name}/users/{username}", middlewares.Authorize(agentHandlerManager.RevokeUser, logger, mc.PermAgentsManage)).Methods("DELETE") // отзыв доступа пользователя
+	r.Handle("/agents/{name}/groups", middlewares.Authorize(agentHandlerManager.GrantGroup, logger, mc.PermAgentsManage)).Methods("POST")             // доступ группе
+	r.Handle("/agents/{name}/groups/{group}", middlewares.Authorize(agentHandlerManager.RevokeGroup, logger, mc.PermAgentsManage)).Methods("DELETE")  // отзыв доступа группы
+	r.Handle("/check", middlewares.Authorize(agentHandlerManager.Check, logger, mc.PermAccessRead)).Methods("GET")                                    // проверка доступа
+}
@@ -0,0 +1,20 @@
+package apikey
+
+import (
+	dApiKey "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/apikey"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/middlewares"
+	ucApiKey "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/apikey"
+	ucAudit "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/audit"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// InitHandlers инициализирует обработчики запросов для управления персональными API-ключами.
+func InitHandlers(r *mux.Router, ucApiKey ucApiKey.Usecase, usecaseAudit ucAudit.Usecase, logger *zap.Logger) {
+	apiKeyHandlerManager := dApiKey.NewApiKeyHandlerManager(ucApiKey, usecaseAudit, logger)
+	// ручки, отвечающие за API-ключи; сами API-ключи к ним доступа не дают
+	r.Handle("/api-keys", middlewares.Authorize(apiKeyHandlerManager.Create, logger, mc.PermSecurityManage)).Methods("POST")        // выпуск ключа
+	r.Handle("/api-keys", middlewares.Authorize(apiKeyHandlerManager.List, logger, mc.PermSecurityManage)).Methods("GET")           // список ключей
+	r.Handle("/api-keys/{id}", middlewares.Authorize(apiKeyHandlerManager.Revoke, logger, mc.PermSecurityManage)).Methods("DELETE") // отзыв ключа
+}
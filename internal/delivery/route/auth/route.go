@@ -2,20 +2,37 @@ package auth
 
 import (
 	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/auth"
-	rUser "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo"
+	ucAudit "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/audit"
 	ucAuth "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/auth"
+	ucLockout "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/lockout"
+	ucMfa "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/mfa"
+	ucOidc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/oidc"
+	ucToken "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/token"
+	sOidc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/services/oidc"
 	"github.com/gorilla/mux"
-	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 )
 
 // InitHandlers инициализирует обработчики запросов для работы с пользователями (получение, удаление, создание).
-func InitHandlers(r *mux.Router, postgresClient *pgx.Conn, logger *zap.Logger) {
-	repoUser := rUser.NewRepoLayer(postgresClient)
-	usecaseAuth := ucAuth.NewUsecaseLayer(repoUser)
-	authHandlerManager := auth.NewAuthHandlerManager(usecaseAuth, logger)
+func InitHandlers(r *mux.Router, repos *repo.Repos, usecaseLockout ucLockout.Usecase, usecaseAudit ucAudit.Usecase,
+	oidcProviders map[string]*sOidc.Provider, logger *zap.Logger) {
+	usecaseAuth := ucAuth.NewUsecaseLayer(repos.User)
+	usecaseToken := ucToken.NewUsecaseLayer(repos.Token, repos.User)
+	usecaseMfa := ucMfa.NewUsecaseLayer(repos.Mfa, repos.User)
+	providers := make(map[string]ucOidc.Provider, len(oidcProviders))
+	for name, provider := range oidcProviders {
+		providers[name] = provider
+	}
+	usecaseOidc := ucOidc.NewUsecaseLayer(repos.User, repos.Identity, repos.TxManager, providers)
+	authHandlerManager := auth.NewAuthHandlerManager(usecaseAuth, usecaseToken, usecaseMfa, usecaseLockout, usecaseOidc, usecaseAudit, logger)
 	// ручки, отвечающие за сессию пользователя
-	r.HandleFunc("/signup", authHandlerManager.SignUp).Methods("POST")   // регистрация
-	r.HandleFunc("/signin", authHandlerManager.SignIn).Methods("POST")   // авторизация
-	r.HandleFunc("/signout", authHandlerManager.SignOut).Methods("POST") // деавторизация
+	r.HandleFunc("/signup", authHandlerManager.SignUp).Methods("POST")                 // регистрация
+	r.HandleFunc("/signin", authHandlerManager.SignIn).Methods("POST")                 // авторизация
+	r.HandleFunc("/signin/2fa", authHandlerManager.SignInSecondFactor).Methods("POST") // второй шаг авторизации
+	r.HandleFunc("/signout", authHandlerManager.SignOut).Methods("POST")               // деавторизация, в том числе с истекшим access-токеном
+	// вход через внешних провайдеров OpenID Connect
+	r.HandleFunc("/oidc/complete", authHandlerManager.OIDCComplete).Methods("POST")           // заполнение профиля
+	r.HandleFunc("/oidc/{provider}/login", authHandlerManager.OIDCLogin).Methods("GET")       // перенаправление к провайдеру
+	r.HandleFunc("/oidc/{provider}/callback", authHandlerManager.OIDCCallback).Methods("GET") // возврат от провайдера
 }
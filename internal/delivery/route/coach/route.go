@@ -0,0 +1,34 @@
+package coach
+
+import (
+	dCoach "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/coach"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/middlewares"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo"
+	ucCoach "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/coach"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// InitHandlers инициализирует обработчики запросов для работы со связями тренеров и клиентов.
+func InitHandlers(r *mux.Router, repos *repo.Repos, logger *zap.Logger) {
+	ucCoach := ucCoach.NewUsecaseLayer(repos.Coach, repos.User, repos.Measurement)
+	coachHandlerManager := dCoach.NewCoachHandlerManager(ucCoach, logger)
+	// ручки клиента, отвечающие за его тренеров
+	r.Handle("/coaches/invite", middlewares.Authorize(coachHandlerManager.Invite, logger, mc.PermCoachingUse)).Methods("POST")                // приглашение тренера
+	r.Handle("/coaches", middlewares.Authorize(coachHandlerManager.ListCoaches, logger, mc.PermCoachingUse)).Methods("GET")                   // свои тренеры
+	r.Handle("/coaches/{coach}/scopes", middlewares.Authorize(coachHandlerManager.UpdateScopes, logger, mc.PermCoachingUse)).Methods("PUT")   // данные, открытые тренеру
+	r.Handle("/coaches/{coach}", middlewares.Authorize(coachHandlerManager.RevokeCoach, logger, mc.PermCoachingUse)).Methods("DELETE")        // разрыв связи с тренером
+	r.Handle("/coaches/{coach}/comments", middlewares.Authorize(coachHandlerManager.ListComments, logger, mc.PermCoachingUse)).Methods("GET") // комментарии тренера
+	// ручки тренера, отвечающие за его клиентов
+	r.Handle("/coach/invitations", middlewares.Authorize(coachHandlerManager.ListInvitations, logger, mc.PermClientsRead)).Methods("GET")                           // приглашения клиентов
+	r.Handle("/coach/invitations/{client}/accept", middlewares.Authorize(coachHandlerManager.AcceptInvitation, logger, mc.PermClientsWrite)).Methods("POST")        // принятие приглашения
+	r.Handle("/coach/invitations/{client}/decline", middlewares.Authorize(coachHandlerManager.DeclineInvitation, logger, mc.PermClientsWrite)).Methods("POST")      // отклонение приглашения
+	r.Handle("/coach/clients", middlewares.Authorize(coachHandlerManager.ListClients, logger, mc.PermClientsRead)).Methods("GET")                                   // свои клиенты
+	r.Handle("/coach/clients/{username}", middlewares.Authorize(coachHandlerManager.RevokeClient, logger, mc.PermClientsWrite)).Methods("DELETE")                   // разрыв связи с клиентом
+	r.Handle("/coach/clients/{username}/profile", middlewares.Authorize(coachHandlerManager.GetClientProfile, logger, mc.PermClientsRead)).Methods("GET")           // профиль клиента
+	r.Handle("/coach/clients/{username}/weight", middlewares.Authorize(coachHandlerManager.GetClientWeight, logger, mc.PermClientsRead)).Methods("GET")             // история массы тела клиента
+	r.Handle("/coach/clients/{username}/measurements", middlewares.Authorize(coachHandlerManager.GetClientMeasurements, logger, mc.PermClientsRead)).Methods("GET") // измерения клиента
+	r.Handle("/coach/clients/{username}/comments", middlewares.Authorize(coachHandlerManager.AddComment, logger, mc.PermClientsWrite)).Methods("POST")              // комментарий клиенту
+	r.Handle("/coach/clients/{username}/calorie-target", middlewares.Authorize(coachHandlerManager.SetCalorieTarget, logger, mc.PermClientsWrite)).Methods("PUT")   // норма калорий клиента
+}
@@ -0,0 +1,29 @@
+package group
+
+import (
+	dGroup "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/group"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/middlewares"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo"
+	ucGroup "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/group"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// InitHandlers инициализирует обработчики запросов для работы с группами и заявками на их создание.
+func InitHandlers(r *mux.Router, repos *repo.Repos, logger *zap.Logger) {
+	ucGroup := ucGroup.NewUsecaseLayer(repos.Group)
+	groupHandlerManager := dGroup.NewGroupHandlerManager(ucGroup, logger)
+	// ручки, отвечающие за заявки на создание групп
+	r.Handle("/bids", middlewares.Authorize(groupHandlerManager.CreateBid, logger, mc.PermGroupsUse)).Methods("POST")                         // подача заявки
+	r.Handle("/bids", middlewares.Authorize(groupHandlerManager.ListBids, logger, mc.PermGroupsUse)).Methods("GET")                           // свои заявки
+	r.Handle("/bids/pending", middlewares.Authorize(groupHandlerManager.ListPendingBids, logger, mc.PermGroupsManage)).Methods("GET")         // нерассмотренные заявки
+	r.Handle("/bids/{id:[0-9]+}/approve", middlewares.Authorize(groupHandlerManager.ApproveBid, logger, mc.PermGroupsManage)).Methods("POST") // одобрение заявки
+	r.Handle("/bids/{id:[0-9]+}/reject", middlewares.Authorize(groupHandlerManager.RejectBid, logger, mc.PermGroupsManage)).Methods("POST")   // отклонение заявки
+	// ручки, отвечающие за группы и их участников
+	r.Handle("/groups", middlewares.Authorize(groupHandlerManager.ListGroups, logger, mc.PermGroupsUse)).Methods("GET")                                // свои группы
+	r.Handle("/groups/{name}", middlewares.Authorize(groupHandlerManager.DeleteGroup, logger, mc.PermGroupsManage)).Methods("DELETE")                  // удаление группы
+	r.Handle("/groups/{name}/members", middlewares.Authorize(groupHandlerManager.ListMembers, logger, mc.PermGroupsUse)).Methods("GET")                // участники группы
+	r.Handle("/groups/{name}/members", middlewares.Authorize(groupHandlerManager.AddMember, logger, mc.PermGroupsUse)).Methods("POST")                 // добавление участника
+	r.Handle("/groups/{name}/members/{username}", middlewares.Authorize(groupHandlerManager.RemoveMember, logger, mc.PermGroupsUse)).Methods("DELETE") // исключение участника
+}
@@ -0,0 +1,13 @@
+package health
+
+import (
+	dHealth "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/health"
+	"github.com/gorilla/mux"
+)
+
+// InitHandlers инициализирует ручки проверки состояния сервиса. Они регистрируются вне /api/v1 и цепочки
+// middlewares: балансировщику и оркестратору не нужны авторизация и журнал запросов.
+func InitHandlers(r *mux.Router, healthHandlerManager *dHealth.HealthHandlerManager) {
+	r.HandleFunc("/healthz", healthHandlerManager.Live).Methods("GET") // процесс жив
+	r.HandleFunc("/readyz", healthHandlerManager.Ready).Methods("GET") // сервис готов принимать запросы
+}
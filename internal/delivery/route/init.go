@@ -3,18 +3,75 @@ package route
 import (
 	"net/http"
 
+	"github.com/bradfitz/gomemcache/memcache"
+	dHealth "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/health"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/route/admin"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/route/agent"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/route/apikey"
 	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/route/auth"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/route/coach"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/route/group"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/route/health"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/route/measurement"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/route/mfa"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/route/token"
 	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/route/user"
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
 	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/middlewares"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo"
+	rAttempt "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/attempt"
+	rUser "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	ucApiKey "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/apikey"
+	ucAudit "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/audit"
+	ucLockout "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/lockout"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/metrics"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/services/oidc"
 	"github.com/gorilla/mux"
-	"github.com/jackc/pgx/v5"
+	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
 // InitHTTPHandlers инициализирует обработчики запросов, а также добавляет цепочку middlewares в обработку запроса.
-func InitHTTPHandlers(r *mux.Router, postgresClient *pgx.Conn, logger *zap.Logger) http.Handler {
-	s := r.PathPrefix("/api/v1").Subrouter()
-	user.InitHandlers(s, postgresClient, logger)
-	auth.InitHandlers(s, postgresClient, logger)
-	return middlewares.Init(s, logger)
+// Репозитории передаются вместе с хранилищем, выбранным параметром storage; repos.User здесь оборачивается кэшем.
+// Ручки проверки состояния регистрируются в r напрямую, без цепочки middlewares. Метрики пула соединений и кэша
+// добавляются в metrics.Default, который отдает отдельный сервер метрик.
+func InitHTTPHandlers(r *mux.Router, repos *repo.Repos, memcacheClient *memcache.Client,
+	oidcProviders map[string]*oidc.Provider, healthHandlerManager *dHealth.HealthHandlerManager, logger *zap.Logger) http.Handler {
+	health.InitHandlers(r, healthHandlerManager)
+	s := mux.NewRouter().PathPrefix("/api/v1").Subrouter()
+	// счетчики попыток входа храним в Memcached, чтобы они были общими для всех экземпляров сервиса. Без
+	// Memcached (storage: memory или пустой memcached.host) счетчики у каждого экземпляра свои.
+	// Usecase общий для входа и API администратора, который снимает блокировку входа
+	var repoAttempt rAttempt.Repo = rAttempt.NewRepoMemory()
+	if memcacheClient != nil {
+		repoAttempt = rAttempt.NewRepoLayer(memcacheClient)
+	}
+	usecaseLockout := ucLockout.NewUsecaseLayer(repoAttempt, ucLockout.NewNotifier(logger))
+	// журнал действий пополняется из ручек разных пакетов, поэтому usecase тоже общий
+	// профили пользователей кэшируются в Memcached. Repo общий: любое изменение пользователя должно
+	// сбрасывать кэш, через какую бы ручку оно ни пришло
+	var cacheStat func() ent.CacheStats
+	if memcacheClient != nil {
+		repoCache := rUser.NewRepoCache(repos.User, memcacheClient, viper.GetDuration("memcached.user_profile_ttl"))
+		repos.User, cacheStat = repoCache, repoCache.Stats
+		metrics.RegisterCacheStats(metrics.Default, cacheStat)
+	}
+	if repos.DBStat != nil {
+		metrics.RegisterDBStats(metrics.Default, repos.DBStat)
+	}
+	usecaseAudit := ucAudit.NewUsecaseLayer(repos.Audit, repos.User)
+	user.InitHandlers(s, repos, usecaseAudit, logger)
+	measurement.InitHandlers(s, repos, logger)
+	auth.InitHandlers(s, repos, usecaseLockout, usecaseAudit, oidcProviders, logger)
+	token.InitHandlers(s, repos, usecaseAudit, logger)
+	mfa.InitHandlers(s, repos, usecaseAudit, logger)
+	group.InitHandlers(s, repos, logger)
+	agent.InitHandlers(s, repos, logger)
+	coach.InitHandlers(s, repos, logger)
+	admin.InitHandlers(s, repos, cacheStat, usecaseLockout, usecaseAudit, logger)
+	// API-ключи проверяются в middleware, поэтому usecase общий для ручек и middleware
+	usecaseApiKey := ucApiKey.NewUsecaseLayer(repos.ApiKey)
+	apikey.InitHandlers(s, usecaseApiKey, usecaseAudit, logger)
+	r.PathPrefix("/api/v1").Handler(middlewares.Init(s, usecaseApiKey, logger))
+	return r
 }
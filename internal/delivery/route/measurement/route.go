@@ -0,0 +1,19 @@
+package measurement
+
+import (
+	dMeasurement "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/measurement"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/middlewares"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo"
+	ucMeasurement "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/measurement"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// InitHandlers инициализирует обработчики запросов для работы с измерениями пользователя.
+func InitHandlers(r *mux.Router, repos *repo.Repos, logger *zap.Logger) {
+	ucMeasurement := ucMeasurement.NewUsecaseLayer(repos.Measurement)
+	measurementHandlerManager := dMeasurement.NewMeasurementHandlerManager(ucMeasurement, logger)
+	r.Handle("/users/measurements", middlewares.Authorize(measurementHandlerManager.Add, logger, mc.PermMeasureWrite)).Methods("POST") // сохранение пачки измерений
+	r.Handle("/users/measurements", middlewares.Authorize(measurementHandlerManager.List, logger, mc.PermMeasureRead)).Methods("GET")  // чтение измерений
+}
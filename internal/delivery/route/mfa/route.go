@@ -0,0 +1,22 @@
+package mfa
+
+import (
+	dMfa "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/mfa"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/middlewares"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo"
+	ucAudit "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/audit"
+	ucMfa "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/mfa"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// InitHandlers инициализирует обработчики запросов для настройки двухфакторной аутентификации.
+func InitHandlers(r *mux.Router, repos *repo.Repos, usecaseAudit ucAudit.Usecase, logger *zap.Logger) {
+	ucMfa := ucMfa.NewUsecaseLayer(repos.Mfa, repos.User)
+	mfaHandlerManager := dMfa.NewMfaHandlerManager(ucMfa, usecaseAudit, logger)
+	// ручки, отвечающие за второй фактор
+	r.Handle("/2fa/enroll", middlewares.Authorize(mfaHandlerManager.Enroll, logger, mc.PermSecurityManage)).Methods("POST")   // получение секрета TOTP
+	r.Handle("/2fa/confirm", middlewares.Authorize(mfaHandlerManager.Confirm, logger, mc.PermSecurityManage)).Methods("POST") // включение второго фактора
+	r.Handle("/2fa/disable", middlewares.Authorize(mfaHandlerManager.Disable, logger, mc.PermSecurityManage)).Methods("POST") // отключение второго фактора
+}
@@ -0,0 +1,18 @@
+package token
+
+import (
+	dToken "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/token"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo"
+	ucAudit "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/audit"
+	ucToken "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/token"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// InitHandlers инициализирует обработчики запросов для работы с токенами пользователя.
+func InitHandlers(r *mux.Router, repos *repo.Repos, usecaseAudit ucAudit.Usecase, logger *zap.Logger) {
+	ucToken := ucToken.NewUsecaseLayer(repos.Token, repos.User)
+	tokenHandlerManager := dToken.NewTokenHandlerManager(ucToken, usecaseAudit, logger)
+	// ручки, отвечающие за обновление сессии
+	r.HandleFunc("/token/refresh", tokenHandlerManager.Refresh).Methods("POST") // ротация refresh-токена
+}
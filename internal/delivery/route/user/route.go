@@ -2,20 +2,22 @@ package user
 
 import (
 	dUser "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/delivery/user"
-	rUser "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/middlewares"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo"
+	ucAudit "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/audit"
 	ucUser "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/user"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
 	"github.com/gorilla/mux"
-	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 )
 
 // InitHandlers инициализирует обработчики запросов для работы с пользователями (получение, удаление, создание).
-func InitHandlers(r *mux.Router, postgresClient *pgx.Conn, logger *zap.Logger) {
-	repoUser := rUser.NewRepoLayer(postgresClient)
-	ucUser := ucUser.NewUsecaseLayer(repoUser)
-	userHandlerManager := dUser.NewUserHandlerManager(ucUser, logger)
+func InitHandlers(r *mux.Router, repos *repo.Repos, usecaseAudit ucAudit.Usecase, logger *zap.Logger) {
+	ucUser := ucUser.NewUsecaseLayer(repos.User, repos.Coach, repos.Measurement, repos.TxManager, logger)
+	userHandlerManager := dUser.NewUserHandlerManager(ucUser, usecaseAudit, logger)
 	// ручки, отвечающие за получение и удаление пользователя
-	r.HandleFunc("/users", userHandlerManager.Read).Methods("GET")                // чтение данных пользователя
-	r.HandleFunc("/users", userHandlerManager.Delete).Methods("DELETE")           // удаление пользователя
-	r.HandleFunc("/users/weight", userHandlerManager.UpdateWeight).Methods("PUT") // обновление массы тела
+	r.Handle("/users", middlewares.Authorize(userHandlerManager.Read, logger, mc.PermProfileRead)).Methods("GET")                                  // чтение данных пользователя
+	r.Handle("/users", middlewares.Authorize(userHandlerManager.Delete, logger, mc.PermProfileWrite)).Methods("DELETE")                            // удаление пользователя
+	r.Handle("/users/weight", middlewares.Authorize(userHandlerManager.UpdateWeight, logger, mc.PermWeightWrite)).Methods("PUT")                   // обновление массы тела
+	r.Handle("/users/security-activity", middlewares.Authorize(userHandlerManager.SecurityActivity, logger, mc.PermSecurityManage)).Methods("GET") // журнал действий с учетной записью
 }
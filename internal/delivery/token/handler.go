@@ -0,0 +1,113 @@
+package token
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	ucAudit "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/audit"
+	ucToken "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/token"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"go.uber.org/zap"
+)
+
+type TokenHandlerManager struct {
+	ucToken ucToken.Usecase
+	ucAudit ucAudit.Usecase
+	logger  *zap.Logger
+}
+
+// NewTokenHandlerManager возвращает менеджер хендлеров, отвечающих за обновление токенов пользователя
+func NewTokenHandlerManager(ucToken ucToken.Usecase, ucAudit ucAudit.Usecase, logger *zap.Logger) *TokenHandlerManager {
+	return &TokenHandlerManager{
+		ucToken: ucToken,
+		ucAudit: ucAudit,
+		logger:  logger,
+	}
+}
+
+// Refresh обменивает refresh-токен из cookie на новую пару токенов.
+func (h *TokenHandlerManager) Refresh(w http.ResponseWriter, r *http.Request) {
+	// cookie имеет приоритет, клиенты без cookie передают refresh-токен в теле запроса и получают
+	// новую пару токенов также в теле ответа
+	tokensInBody := false
+	refreshToken, err := f.GetRefreshToken(r)
+	if err != nil {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
+			return
+		}
+		var refreshData dto.RefreshData
+		if len(body) != 0 {
+			err = json.Unmarshal(body, &refreshData)
+			if err != nil {
+				h.logger.Info(err.Error(), f.LogFields(r)...)
+				f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
+				return
+			}
+		}
+		if refreshData.RefreshToken == "" {
+			h.logger.Info(me.ErrInvalidRefreshToken.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: me.ErrInvalidRefreshToken.Error()}, http.StatusUnauthorized)
+			return
+		}
+		refreshToken = refreshData.RefreshToken
+		tokensInBody = true
+	}
+
+	tokens, err := h.ucToken.Refresh(r.Context(), refreshToken)
+	if err != nil {
+		if errors.Is(err, me.ErrRefreshTokenReused) {
+			h.auditTokenReuse(r, refreshToken)
+		}
+		if errors.Is(err, me.ErrInvalidRefreshToken) || errors.Is(err, me.ErrRefreshTokenReused) {
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.FlashCookie(w, r)
+			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, me.ErrAccountLocked) {
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.FlashCookie(w, r)
+			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusForbidden)
+			return
+		}
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+		return
+	}
+
+	if tokensInBody {
+		f.Response(w, f.GetTokenPair(tokens), http.StatusOK)
+		return
+	}
+	w, err = f.SetCookieAndHeaders(w, tokens)
+	if err != nil {
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+		return
+	}
+	f.Response(w, dto.ResponseDetail{Detail: "Токены успешно обновлены"}, http.StatusOK)
+}
+
+// auditTokenReuse записывает в журнал повторное использование refresh-токена: скорее всего, токен украден,
+// и все сессии этого входа завершены. Недоступность журнала не должна мешать запросу.
+func (h *TokenHandlerManager) auditTokenReuse(r *http.Request, refreshToken string) {
+	username, err := h.ucToken.Owner(r.Context(), refreshToken)
+	if err != nil {
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+		return
+	}
+	e := f.NewAuditEvent(r, mc.AuditTokenReuse, username)
+	e.Actor = ""
+	err = h.ucAudit.Record(r.Context(), e)
+	if err != nil {
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+	}
+}
@@ -7,40 +7,35 @@ import (
 	"net/http"
 
 	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	ucAudit "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/audit"
 	ucUser "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/user"
 	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/metrics"
 	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
 	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
 	"go.uber.org/zap"
 )
 
 type UserHandlerManager struct {
-	ucUser ucUser.Usecase
-	logger *zap.Logger
+	ucUser  ucUser.Usecase
+	ucAudit ucAudit.Usecase
+	logger  *zap.Logger
 }
 
 // NewUserHandlerManager возвращает менеджер хендлеров, отвечающих за создание/удаление пользователя из системы
-func NewUserHandlerManager(ucUser ucUser.Usecase, logger *zap.Logger) *UserHandlerManager {
+func NewUserHandlerManager(ucUser ucUser.Usecase, ucAudit ucAudit.Usecase, logger *zap.Logger) *UserHandlerManager {
 	return &UserHandlerManager{
-		ucUser: ucUser,
-		logger: logger,
+		ucUser:  ucUser,
+		ucAudit: ucAudit,
+		logger:  logger,
 	}
 }
 
 // Read метод чтения данных пользователя, в случае успеха возвращает пользователю его данные.
 func (h *UserHandlerManager) Read(w http.ResponseWriter, r *http.Request) {
-	requestID, err := f.GetCtxRequestID(r)
-	if err != nil {
-		h.logger.Error(err.Error(), zap.String(mc.RequestID, requestID))
-	}
 	username := f.GetUsernameCtx(r)
-	if username == "" {
-		h.logger.Info(me.ErrNotAuthenticated.Error(), zap.String(mc.RequestID, requestID))
-		f.Response(w, dto.ResponseError{Error: me.ErrNotAuthenticated.Error()}, http.StatusUnauthorized)
-		return
-	}
 	if err := dto.ValidateUsername(username); err != nil {
-		h.logger.Info(err.Error(), zap.String(mc.RequestID, requestID))
+		h.logger.Info(err.Error(), f.LogFields(r)...)
 		f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
 		return
 	}
@@ -48,11 +43,11 @@ func (h *UserHandlerManager) Read(w http.ResponseWriter, r *http.Request) {
 	u.BMI.Calculate(u.Weight, u.Height)
 	if err != nil {
 		if errors.Is(err, me.ErrUserNotExist) {
-			h.logger.Info(err.Error(), zap.String(mc.RequestID, requestID))
+			h.logger.Info(err.Error(), f.LogFields(r)...)
 			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
 			return
 		}
-		h.logger.Error(err.Error(), zap.String(mc.RequestID, requestID))
+		h.logger.Error(err.Error(), f.LogFields(r)...)
 		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
 		return
 	}
@@ -61,49 +56,32 @@ func (h *UserHandlerManager) Read(w http.ResponseWriter, r *http.Request) {
 
 // Delete метод удаление пользователя, в случае успеха возвращает сообщение о том, что пользователь был удален.
 func (h *UserHandlerManager) Delete(w http.ResponseWriter, r *http.Request) {
-	requestID, err := f.GetCtxRequestID(r)
-	if err != nil {
-		h.logger.Error(err.Error(), zap.String(mc.RequestID, requestID))
-	}
 	username := f.GetUsernameCtx(r)
-	if username == "" {
-		h.logger.Info(me.ErrNotAuthenticated.Error(), zap.String(mc.RequestID, requestID))
-		f.Response(w, dto.ResponseError{Error: me.ErrNotAuthenticated.Error()}, http.StatusUnauthorized)
-		return
-	}
-	err = h.ucUser.Delete(r.Context(), username)
+	err := h.ucUser.Delete(r.Context(), username)
 
 	if err != nil {
-		if errors.Is(err, me.ErrUserNotExist) {
-			h.logger.Info(err.Error(), zap.String(mc.RequestID, requestID))
+		if errors.Is(err, me.ErrUserNotExist) || errors.Is(err, me.ErrUserOwnsGroups) {
+			h.logger.Info(err.Error(), f.LogFields(r)...)
 			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
 			return
 		}
-		h.logger.Error(err.Error(), zap.String(mc.RequestID, requestID))
+		h.logger.Error(err.Error(), f.LogFields(r)...)
 		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
 		return
 	}
 
+	h.audit(r, mc.AuditUserDelete, nil)
 	f.FlashCookie(w, r)
 	f.Response(w, dto.ResponseDetail{Detail: "Вы успешно удалили себя из приложения 'Healthcheck'"}, http.StatusOK)
 }
 
 // UpdateWeight изменяет вес пользовател, считает новое значение дневного рациона.
 func (h *UserHandlerManager) UpdateWeight(w http.ResponseWriter, r *http.Request) {
-	requestID, err := f.GetCtxRequestID(r)
-	if err != nil {
-		h.logger.Error(err.Error(), zap.String(mc.RequestID, requestID))
-	}
 	username := f.GetUsernameCtx(r)
-	if username == "" {
-		h.logger.Info(me.ErrNotAuthenticated.Error(), zap.String(mc.RequestID, requestID))
-		f.Response(w, dto.ResponseError{Error: me.ErrNotAuthenticated.Error()}, http.StatusUnauthorized)
-		return
-	}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		h.logger.Info(err.Error(), zap.String(mc.RequestID, requestID))
+		h.logger.Info(err.Error(), f.LogFields(r)...)
 		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
 		return
 	}
@@ -111,13 +89,13 @@ func (h *UserHandlerManager) UpdateWeight(w http.ResponseWriter, r *http.Request
 	var weight dto.Weight
 	err = json.Unmarshal(body, &weight)
 	if err != nil {
-		h.logger.Info(err.Error(), zap.String(mc.RequestID, requestID))
+		h.logger.Info(err.Error(), f.LogFields(r)...)
 		f.Response(w, dto.ResponseError{Error: me.ErrInvalidData.Error()}, http.StatusBadRequest)
 		return
 	}
 	err = weight.Validate()
 	if err != nil {
-		h.logger.Info(err.Error(), zap.String(mc.RequestID, requestID))
+		h.logger.Info(err.Error(), f.LogFields(r)...)
 		f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
 		return
 	}
@@ -125,13 +103,50 @@ func (h *UserHandlerManager) UpdateWeight(w http.ResponseWriter, r *http.Request
 	u, err := h.ucUser.UpdateWeight(r.Context(), weight.Value, username)
 	if err != nil {
 		if errors.Is(err, me.ErrUserNotExist) {
-			h.logger.Info(err.Error(), zap.String(mc.RequestID, requestID))
+			h.logger.Info(err.Error(), f.LogFields(r)...)
 			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
 			return
 		}
-		h.logger.Error(err.Error(), zap.String(mc.RequestID, requestID))
+		h.logger.Error(err.Error(), f.LogFields(r)...)
 		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
 		return
 	}
+	metrics.WeighIns.WithLabelValues(metrics.SourceProfile).Inc()
 	f.Response(w, getUserWithoutPassword(u), http.StatusOK)
 }
+
+// SecurityActivity возвращает журнал действий с учетной записью пользователя: входы, в том числе неудачные,
+// смену пароля, настройку второго фактора и API-ключей, действия администратора. Параметры запроса: page, per_page.
+func (h *UserHandlerManager) SecurityActivity(w http.ResponseWriter, r *http.Request) {
+	username := f.GetUsernameCtx(r)
+
+	page, perPage, err := f.GetPagination(r.URL.Query())
+	if err != nil {
+		h.logger.Info(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+		return
+	}
+	events, total, err := h.ucAudit.ListSecurityActivity(r.Context(), username, perPage, (page-1)*perPage)
+	if err != nil {
+		if errors.Is(err, me.ErrUserNotExist) {
+			h.logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusBadRequest)
+			return
+		}
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+		f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+		return
+	}
+	f.Response(w, dto.AuditPage{Events: f.GetAuditEvents(events), Total: total, Page: page, PerPage: perPage}, http.StatusOK)
+}
+
+// audit записывает действие пользователя над своей учетной записью. Недоступность журнала не должна
+// мешать запросу, поэтому ошибка только пишется в лог.
+func (h *UserHandlerManager) audit(r *http.Request, action string, details map[string]any) {
+	e := f.NewAuditEvent(r, action, f.GetUsernameCtx(r))
+	e.Details = details
+	err := h.ucAudit.Record(r.Context(), e)
+	if err != nil {
+		h.logger.Error(err.Error(), f.LogFields(r)...)
+	}
+}
@@ -17,6 +17,7 @@ func getUserWithoutPassword(user *ent.User) *dto.UserWithoutPassword {
 		Sex:              user.Sex,
 		DayCalories:      user.DayCalories,
 		PhysicalActivity: user.PhysicalActivity,
+		Role:             user.Role,
 		BMI:              dto.BMIType{Value: user.BMI.Value, Comment: user.BMI.Comment},
 	}
 }
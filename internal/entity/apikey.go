@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+// ApiKey персональный API-ключ пользователя для скриптов и устройств. Сам ключ в базе не хранится, только его
+// хэш и несколько первых символов.
+type ApiKey struct {
+	ID         string
+	Username   string
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     []string
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+	// OwnerRole роль владельца ключа, заполняется только при проверке ключа.
+	OwnerRole string
+}
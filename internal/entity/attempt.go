@@ -0,0 +1,18 @@
+package entity
+
+import "time"
+
+// LoginAttempts счетчик неудачных попыток входа для учетной записи или IP-адреса.
+type LoginAttempts struct {
+	Failures     int       `json:"failures"`
+	BlockedUntil time.Time `json:"blocked_until"`
+	Locked       bool      `json:"locked"`
+}
+
+// AccountLockEvent событие блокировки учетной записи, передается в хук уведомлений.
+type AccountLockEvent struct {
+	Login       string    `json:"login"`
+	IP          string    `json:"ip"`
+	Failures    int       `json:"failures"`
+	LockedUntil time.Time `json:"locked_until"`
+}
@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+// AuditEvent запись журнала действий. Actor — кто совершил действие, Subject — над чьей учетной записью.
+type AuditEvent struct {
+	ID        int64
+	Actor     string
+	Action    string
+	Subject   string
+	IP        string
+	UserAgent string
+	RequestID string
+	Details   map[string]any
+	CreatedAt time.Time
+}
+
+// AuditFilter условия поиска по журналу. Пустые поля не ограничивают выборку, Subjects — любой из
+// перечисленных (никнейм и почта пользователя), время ищется в полуинтервале [From, To).
+type AuditFilter struct {
+	Actor    string
+	Subjects []string
+	Action   string
+	From     *time.Time
+	To       *time.Time
+	Limit    int
+	Offset   int
+}
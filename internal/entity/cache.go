@@ -0,0 +1,9 @@
+package entity
+
+// CacheStats счетчики кэша с момента запуска. Errors — неудачные обращения к кэшу, запрос при этом
+// выполняется через базу.
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+	Errors uint64
+}
@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// CoachLink связь тренера и клиента. Scopes перечисляет данные, которые клиент открыл тренеру,
+// CalorieTarget — норма калорий, заданная тренером вместо рассчитанной.
+type CoachLink struct {
+	ID             string
+	ClientUsername string
+	CoachUsername  string
+	Status         string
+	Scopes         []string
+	CalorieTarget  *float32
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// CoachComment комментарий тренера для клиента.
+type CoachComment struct {
+	ID            int
+	CoachUsername string
+	Text          string
+	CreatedAt     time.Time
+}
@@ -0,0 +1,53 @@
+package dto
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidBMICategory = errors.New("Указана несуществующая категория индекса массы тела")
+	ErrInvalidDate        = errors.New("Дата должна быть в формате 2006-01-02 или RFC 3339")
+	ErrInvalidPage        = errors.New("Номер и размер страницы должны быть положительными числами")
+)
+
+// OUTPUT DATAFLOW
+// AdminUser профиль пользователя для администратора.
+type AdminUser struct {
+	UserWithoutPassword
+	LockedAt              *time.Time `json:"locked_at"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	CreatedAt             time.Time  `json:"created_at"`
+}
+
+// PoolStats состояние пула соединений с PostgreSQL.
+type PoolStats struct {
+	MaxConns                int32 `json:"max_conns"`
+	TotalConns              int32 `json:"total_conns"`
+	AcquiredConns           int32 `json:"acquired_conns"`
+	IdleConns               int32 `json:"idle_conns"`
+	ConstructingConns       int32 `json:"constructing_conns"`
+	AcquireCount            int64 `json:"acquire_count"`
+	AcquireDurationMs       int64 `json:"acquire_duration_ms"`
+	EmptyAcquireCount       int64 `json:"empty_acquire_count"`
+	CanceledAcquireCount    int64 `json:"canceled_acquire_count"`
+	NewConnsCount           int64 `json:"new_conns_count"`
+	MaxLifetimeDestroyCount int64 `json:"max_lifetime_destroy_count"`
+	MaxIdleDestroyCount     int64 `json:"max_idle_destroy_count"`
+}
+
+// CacheStats счетчики кэша профилей пользователей с момента запуска.
+type CacheStats struct {
+	Enabled  bool    `json:"enabled"`
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	Errors   uint64  `json:"errors"`
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+type UserPage struct {
+	Users   []AdminUser `json:"users"`
+	Total   int         `json:"total"`
+	Page    int         `json:"page"`
+	PerPage int         `json:"per_page"`
+}
@@ -0,0 +1,58 @@
+package dto
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+)
+
+var (
+	ErrApiKeyNameEmpty   = errors.New("Укажите название API-ключа")
+	ErrApiKeyNameTooLong = errors.New("Длина названия API-ключа должна быть не больше 64 символов")
+	ErrApiKeyScopesEmpty = errors.New("Укажите хотя бы одно право API-ключа")
+	ErrInvalidScope      = errors.New("Указано несуществующее право API-ключа")
+)
+
+// INPUT DATAFLOW
+type ApiKeyCreate struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+func (k *ApiKeyCreate) Validate() error {
+	k.Name = strings.TrimSpace(k.Name)
+	if k.Name == "" {
+		return ErrApiKeyNameEmpty
+	}
+	if utf8.RuneCountInString(k.Name) > 64 {
+		return ErrApiKeyNameTooLong
+	}
+	if len(k.Scopes) == 0 {
+		return ErrApiKeyScopesEmpty
+	}
+	for _, scope := range k.Scopes {
+		if _, ok := myconstants.AllowedApiKeyScopes[scope]; !ok {
+			return ErrInvalidScope
+		}
+	}
+	return nil
+}
+
+// OUTPUT DATAFLOW
+type ApiKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ApiKeyCreated возвращается один раз при создании ключа, повторно получить ключ нельзя.
+type ApiKeyCreated struct {
+	ApiKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,23 @@
+package dto
+
+import "time"
+
+// OUTPUT DATAFLOW
+type AuditEvent struct {
+	ID        int64          `json:"id"`
+	Actor     string         `json:"actor"`
+	Action    string         `json:"action"`
+	Subject   string         `json:"subject"`
+	IP        string         `json:"ip"`
+	UserAgent string         `json:"user_agent"`
+	RequestID string         `json:"request_id"`
+	Details   map[string]any `json:"details,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type AuditPage struct {
+	Events  []AuditEvent `json:"events"`
+	Total   int          `json:"total"`
+	Page    int          `json:"page"`
+	PerPage int          `json:"per_page"`
+}
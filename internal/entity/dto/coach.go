@@ -0,0 +1,127 @@
+package dto
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+)
+
+var (
+	ErrCoachScopesEmpty      = errors.New("Укажите хотя бы одни данные, которые будут доступны тренеру")
+	ErrInvalidCoachScope     = errors.New("Указан несуществующий вид данных для тренера, доступны profile, weight, diary и vitals")
+	ErrCommentEmpty          = errors.New("Комментарий не может быть пустым")
+	ErrCommentTooLong        = errors.New("Длина комментария должна быть не больше 1000 символов")
+	ErrInvalidCalorieTarget  = errors.New("Норма калорий должна быть положительной")
+	ErrCalorieTargetTooLarge = errors.New("Норма калорий должна быть не больше 10000 ккал")
+)
+
+func validateCoachScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return ErrCoachScopesEmpty
+	}
+	for _, scope := range scopes {
+		if _, ok := myconstants.AllowedCoachScopes[scope]; !ok {
+			return ErrInvalidCoachScope
+		}
+	}
+	return nil
+}
+
+// INPUT DATAFLOW
+type CoachInvite struct {
+	Coach  string   `json:"coach"`
+	Scopes []string `json:"scopes"`
+}
+
+func (c *CoachInvite) Validate() error {
+	err := ValidateUsername(c.Coach)
+	if err != nil {
+		return err
+	}
+	return validateCoachScopes(c.Scopes)
+}
+
+type CoachScopes struct {
+	Scopes []string `json:"scopes"`
+}
+
+func (c *CoachScopes) Validate() error {
+	return validateCoachScopes(c.Scopes)
+}
+
+type CoachCommentCreate struct {
+	Text string `json:"text"`
+}
+
+func (c *CoachCommentCreate) Validate() error {
+	c.Text = strings.TrimSpace(c.Text)
+	if c.Text == "" {
+		return ErrCommentEmpty
+	}
+	if utf8.RuneCountInString(c.Text) > 1000 {
+		return ErrCommentTooLong
+	}
+	return nil
+}
+
+// CalorieTarget норма калорий, заданная тренером. null отменяет норму тренера.
+type CalorieTarget struct {
+	DayCalories *float32 `json:"day_calories"`
+}
+
+func (c *CalorieTarget) Validate() error {
+	if c.DayCalories == nil {
+		return nil
+	}
+	if *c.DayCalories <= 0 {
+		return ErrInvalidCalorieTarget
+	}
+	if *c.DayCalories > 10000 {
+		return ErrCalorieTargetTooLarge
+	}
+	return nil
+}
+
+// OUTPUT DATAFLOW
+type CoachLink struct {
+	Client        string    `json:"client"`
+	Coach         string    `json:"coach"`
+	Status        string    `json:"status"`
+	Scopes        []string  `json:"scopes"`
+	CalorieTarget *float32  `json:"calorie_target"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type CoachComment struct {
+	ID        int       `json:"id"`
+	Coach     string    `json:"coach"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ClientProfile профиль клиента для тренера. Почта и роль клиента тренеру не показываются.
+type ClientProfile struct {
+	Username         string  `json:"username"`
+	FirstName        string  `json:"first_name"`
+	Weight           float32 `json:"weight"`
+	Height           int     `json:"height"`
+	Age              int     `json:"age"`
+	Sex              string  `json:"sex"`
+	DayCalories      float32 `json:"day_calories"`
+	PhysicalActivity string  `json:"physical_activity"`
+	BMI              BMIType `json:"bmi"`
+}
+
+type WeightRecord struct {
+	Weight     float64   `json:"weight"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+type WeightHistory struct {
+	Username string         `json:"username"`
+	Records  []WeightRecord `json:"records"`
+}
@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCoachScopesValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		scopes  []string
+		wantErr error
+	}{
+		{name: "served scopes", scopes: []string{"profile", "weight", "diary", "vitals"}},
+		{name: "empty", wantErr: ErrCoachScopesEmpty},
+		{name: "unknown scope", scopes: []string{"passport"}, wantErr: ErrInvalidCoachScope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := CoachScopes{Scopes: tt.scopes}
+			if err := form.Validate(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate(%v) = %v, want %v", tt.scopes, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package dto
+
+import (
+	"errors"
+	"regexp"
+	"time"
+)
+
+var (
+	ErrInvalidGroupName = errors.New("Название группы должно быть длиной от 2 до 30 символов и состоять из латинских букв, цифр, '_' или '-'")
+	ErrInvalidAgentName = errors.New("Название агента должно быть длиной от 2 до 50 символов и состоять из латинских букв, цифр, '_' или '-'")
+)
+
+var (
+	groupNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]{2,30}$`)
+	agentNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]{2,50}$`)
+)
+
+// ValidateGroupName проверяет название группы.
+func ValidateGroupName(name string) error {
+	if !groupNameRegexp.MatchString(name) {
+		return ErrInvalidGroupName
+	}
+	return nil
+}
+
+// ValidateAgentName проверяет название агента.
+func ValidateAgentName(name string) error {
+	if !agentNameRegexp.MatchString(name) {
+		return ErrInvalidAgentName
+	}
+	return nil
+}
+
+// INPUT DATAFLOW
+type BidCreate struct {
+	GroupName string `json:"group_name"`
+}
+
+func (b *BidCreate) Validate() error {
+	return ValidateGroupName(b.GroupName)
+}
+
+type MemberAdd struct {
+	Username string `json:"username"`
+}
+
+func (m *MemberAdd) Validate() error {
+	return ValidateUsername(m.Username)
+}
+
+type AgentCreate struct {
+	Name string `json:"name"`
+}
+
+func (a *AgentCreate) Validate() error {
+	return ValidateAgentName(a.Name)
+}
+
+type PrivilegeUser struct {
+	Username string `json:"username"`
+}
+
+func (p *PrivilegeUser) Validate() error {
+	return ValidateUsername(p.Username)
+}
+
+type PrivilegeGroup struct {
+	Group string `json:"group"`
+}
+
+func (p *PrivilegeGroup) Validate() error {
+	return ValidateGroupName(p.Group)
+}
+
+// OUTPUT DATAFLOW
+type Bid struct {
+	ID        int       `json:"id"`
+	GroupName string    `json:"group_name"`
+	Username  string    `json:"username"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Group struct {
+	Name      string    `json:"name"`
+	Owner     string    `json:"owner"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type GroupMembers struct {
+	Group   string   `json:"group"`
+	Members []string `json:"members"`
+}
+
+type Agent struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Access ответ на GET /check: есть ли у пользователя доступ к агенту и откуда он получен.
+type Access struct {
+	User    string   `json:"user"`
+	Agent   string   `json:"agent"`
+	Access  bool     `json:"access"`
+	Sources []string `json:"sources"`
+}
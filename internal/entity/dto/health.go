@@ -0,0 +1,14 @@
+package dto
+
+// Readiness ответ /readyz: общий статус и результат проверки каждой зависимости.
+type Readiness struct {
+	Status string                      `json:"status"`
+	Checks map[string]DependencyStatus `json:"checks,omitempty"`
+}
+
+// DependencyStatus результат проверки зависимости.
+type DependencyStatus struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
@@ -1,9 +1,38 @@
 package dto
 
+import "time"
+
 type JwtTokenHeader struct {
 	Exp string `json:"exp"`
+	// Typ пустой у access-токенов, у служебных токенов (например, вызов второго фактора) заполнен.
+	Typ string `json:"typ,omitempty"`
 }
 
 type JwtTokenPayload struct {
 	Username string `json:"username"`
+	// Role пустая у токенов, выданных до появления ролей, такие токены имеют права обычного пользователя.
+	Role string `json:"role,omitempty"`
+	// PasswordReset зашифрованный хэш нового пароля в токене вызова второго фактора, если администратор
+	// потребовал сменить пароль. Пароль меняется только после ввода кода.
+	PasswordReset string `json:"password_reset,omitempty"`
+}
+
+// INPUT DATAFLOW
+type RefreshData struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// OUTPUT DATAFLOW
+// TokenPair отдается клиентам, которые не используют cookie (мобильное приложение, скрипты).
+type TokenPair struct {
+	TokenType        string    `json:"token_type"`
+	AccessToken      string    `json:"access_token"`
+	AccessExpiresAt  time.Time `json:"access_expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+type UserWithTokens struct {
+	User   *UserWithoutPassword `json:"user"`
+	Tokens *TokenPair           `json:"tokens"`
 }
@@ -0,0 +1,72 @@
+package dto
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrInvalidMeasureKind  = errors.New("Указан несуществующий вид измерения, доступны weight, steps, heart_rate и calories")
+	ErrMeasurementsEmpty   = errors.New("Передайте хотя бы одно измерение")
+	ErrMeasurementsTooMany = errors.New("Слишком много измерений в одном запросе")
+	ErrMeasurementInFuture = errors.New("Время измерения не может быть в будущем")
+	ErrInvalidMeasureLimit = errors.New("Параметр limit должен быть положительным числом")
+)
+
+const (
+	errMeasureValueTemplate = "Значение измерения %s должно быть в пределах от %g до %g"
+	// measurementClockSkew допустимое расхождение часов устройства и сервера
+	measurementClockSkew = 5 * time.Minute
+)
+
+// INPUT DATAFLOW
+// Measurement измерение, без recorded_at временем измерения считается время получения.
+type Measurement struct {
+	Kind       string    `json:"kind"`
+	Value      float64   `json:"value"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// MeasurementBatch пачка измерений, устройства отправляют накопленные измерения разом.
+type MeasurementBatch struct {
+	Measurements []Measurement `json:"measurements"`
+}
+
+func (h *MeasurementBatch) Validate() error {
+	if len(h.Measurements) == 0 {
+		return ErrMeasurementsEmpty
+	}
+	if len(h.Measurements) > viper.GetInt("measurements.max_batch") {
+		return ErrMeasurementsTooMany
+	}
+	now := time.Now()
+	for _, m := range h.Measurements {
+		if err := ValidateMeasureKind(m.Kind); err != nil {
+			return err
+		}
+		r := myconstants.AllowedMeasures[m.Kind]
+		if m.Value < r.Min || m.Value > r.Max {
+			return fmt.Errorf(errMeasureValueTemplate, m.Kind, r.Min, r.Max)
+		}
+		if m.RecordedAt.After(now.Add(measurementClockSkew)) {
+			return ErrMeasurementInFuture
+		}
+	}
+	return nil
+}
+
+func ValidateMeasureKind(kind string) error {
+	if _, ok := myconstants.AllowedMeasures[kind]; !ok {
+		return ErrInvalidMeasureKind
+	}
+	return nil
+}
+
+// OUTPUT DATAFLOW
+type MeasurementList struct {
+	Measurements []Measurement `json:"measurements"`
+}
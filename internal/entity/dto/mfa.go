@@ -0,0 +1,72 @@
+package dto
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrEmptyMfaCode     = errors.New("Введите код из приложения-аутентификатора или код восстановления")
+	ErrEmptyChallenge   = errors.New("Не передан challenge_token")
+	ErrEmptyMfaPassword = errors.New("Для отключения двухфакторной аутентификации введите пароль")
+)
+
+// INPUT DATAFLOW
+type MfaCode struct {
+	Code string `json:"code"`
+}
+
+func (m *MfaCode) Validate() error {
+	if m.Code == "" {
+		return ErrEmptyMfaCode
+	}
+	return nil
+}
+
+type MfaDisable struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+func (m *MfaDisable) Validate() error {
+	if m.Password == "" {
+		return ErrEmptyMfaPassword
+	}
+	if m.Code == "" {
+		return ErrEmptyMfaCode
+	}
+	return nil
+}
+
+type MfaSignIn struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	// TokensInBody если true, то токены возвращаются в теле ответа, а не в cookie.
+	TokensInBody bool `json:"tokens_in_body"`
+}
+
+func (m *MfaSignIn) Validate() error {
+	if m.ChallengeToken == "" {
+		return ErrEmptyChallenge
+	}
+	if m.Code == "" {
+		return ErrEmptyMfaCode
+	}
+	return nil
+}
+
+// OUTPUT DATAFLOW
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MfaChallenge struct {
+	MfaRequired    bool      `json:"mfa_required"`
+	ChallengeToken string    `json:"challenge_token"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
@@ -0,0 +1,32 @@
+package dto
+
+import "errors"
+
+var (
+	ErrEmptyRegistrationToken = errors.New("Не передан registration_token")
+)
+
+// INPUT DATAFLOW
+// CompleteProfileData данные профиля пользователя, который впервые вошел через внешнего провайдера.
+// Пароль не нужен, поле password игнорируется.
+type CompleteProfileData struct {
+	RegistrationToken string `json:"registration_token"`
+	// TokensInBody если true, то токены возвращаются в теле ответа, а не в cookie.
+	TokensInBody bool `json:"tokens_in_body"`
+	CreateData
+}
+
+func (h *CompleteProfileData) Validate() error {
+	if h.RegistrationToken == "" {
+		return ErrEmptyRegistrationToken
+	}
+	return h.CreateData.validateProfile()
+}
+
+// OUTPUT DATAFLOW
+type OIDCProfileRequired struct {
+	ProfileRequired   bool   `json:"profile_required"`
+	RegistrationToken string `json:"registration_token"`
+	Email             string `json:"email"`
+	FirstName         string `json:"first_name"`
+}
@@ -3,10 +3,13 @@ package dto
 import (
 	"errors"
 	"regexp"
+	"strings"
 	"unicode/utf8"
 
 	"github.com/asaskevich/govalidator"
 	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/password"
+	"github.com/spf13/viper"
 )
 
 var (
@@ -28,6 +31,10 @@ var (
 	ErrInvalidPasswordText = errors.New("Пароль должен содержать как минимум одну цифру и одну заглавную букву")
 	ErrPasswordTooLong     = errors.New("Длина пароля должна быть не больше 30 символов")
 	ErrPasswordTooShort    = errors.New("Длина пароля должна быть не меньше 8 символов")
+
+	ErrPasswordBreached     = errors.New("Этот пароль встречается в утечках данных, придумайте другой")
+	ErrPasswordPersonalData = errors.New("Пароль не должен содержать никнейм, почту или имя")
+	ErrPasswordTooWeak      = errors.New("Пароль слишком простой: избегайте распространенных слов, последовательностей, повторов и дат")
 )
 
 var (
@@ -54,6 +61,15 @@ type CreateData struct {
 }
 
 func (h *CreateData) Validate() error {
+	err := h.validateProfile()
+	if err != nil {
+		return err
+	}
+	return ValidateNewPassword(h.Password, PersonalData(h.Username, "", h.FirstName)...)
+}
+
+// validateProfile проверяет все данные профиля, кроме пароля.
+func (h *CreateData) validateProfile() error {
 	// first_name
 	err := ValidateUsername(h.Username)
 	if err != nil {
@@ -84,7 +100,7 @@ func (h *CreateData) Validate() error {
 	if _, ok := myconstants.AllowedHumanSex[h.Sex]; !ok {
 		return ErrInvalidSex
 	}
-	return isPasswordValid(h.Password)
+	return nil
 }
 
 func ValidateUsername(username string) error {
@@ -101,6 +117,28 @@ func ValidateUsername(username string) error {
 	return nil
 }
 
+// ValidateNewPassword проверяет формат нового пароля и его соответствие политике паролей. personalData —
+// никнейм, имя и другие данные пользователя, которые не должны встречаться в пароле.
+func ValidateNewPassword(pwd string, personalData ...string) error {
+	err := isPasswordValid(pwd)
+	if err != nil {
+		return err
+	}
+	return isPasswordStrong(pwd, personalData...)
+}
+
+// PersonalData данные пользователя, которые не должны встречаться в пароле: никнейм, имя почтового ящика
+// (часть почты до @) и имя.
+func PersonalData(username, email, firstName string) []string {
+	mailbox, _, _ := strings.Cut(email, "@")
+	return []string{username, mailbox, firstName}
+}
+
+// IsPasswordPolicyError сообщает, что новый пароль отклонен политикой паролей.
+func IsPasswordPolicyError(err error) bool {
+	return errors.Is(err, ErrPasswordPersonalData) || errors.Is(err, ErrPasswordTooWeak) || errors.Is(err, ErrPasswordBreached)
+}
+
 func isPasswordValid(pwd string) error {
 	pwdLen := utf8.RuneCountInString(pwd)
 	if pwdLen > 30 {
@@ -128,9 +166,34 @@ func isPasswordValid(pwd string) error {
 	return nil
 }
 
+// isPasswordStrong проверяет пароль по политике паролей. Применяется только к новым паролям, при входе
+// достаточно проверки формата, иначе пользователи со старыми паролями не смогли бы войти.
+func isPasswordStrong(pwd string, personalData ...string) error {
+	pwdLower := strings.ToLower(pwd)
+	for _, data := range personalData {
+		data = strings.ToLower(strings.TrimSpace(data))
+		if utf8.RuneCountInString(data) >= 3 && strings.Contains(pwdLower, data) {
+			return ErrPasswordPersonalData
+		}
+	}
+	if password.EstimateStrength(pwd, personalData...) < viper.GetInt("auth.password_policy.min_strength") {
+		return ErrPasswordTooWeak
+	}
+	// недоступная база утекших паролей не должна блокировать регистрацию, ее наличие проверяется при старте
+	breached, err := password.IsBreached(pwd)
+	if err == nil && breached {
+		return ErrPasswordBreached
+	}
+	return nil
+}
+
 type AuthData struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`
+	// TokensInBody если true, то токены возвращаются в теле ответа, а не в cookie.
+	TokensInBody bool `json:"tokens_in_body"`
+	// NewPassword новый пароль, если администратор потребовал сменить пароль при входе.
+	NewPassword string `json:"new_password"`
 }
 
 func (h *AuthData) Validate() error {
@@ -145,7 +208,15 @@ func (h *AuthData) Validate() error {
 		return ErrInvalidLogin
 	}
 
-	return isPasswordValid(h.Password)
+	err := isPasswordValid(h.Password)
+	if err != nil {
+		return err
+	}
+	if h.NewPassword == "" {
+		return nil
+	}
+	// политика паролей проверяется при смене пароля, когда известны все данные пользователя, а не только логин
+	return isPasswordValid(h.NewPassword)
 }
 
 type Weight struct {
@@ -171,6 +242,7 @@ type UserWithoutPassword struct {
 	Sex              string  `json:"sex"`
 	DayCalories      float32 `json:"day_calories"`
 	PhysicalActivity string  `json:"physical_activity"`
+	Role             string  `json:"role"`
 	BMI              BMIType `json:"bmi"`
 }
 
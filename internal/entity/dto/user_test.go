@@ -0,0 +1,28 @@
+package dto
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateNewPasswordPersonalData(t *testing.T) {
+	personalData := PersonalData("frontdev", "ivan.petrov@bmstu.ru", "Svetlana")
+	tests := []struct {
+		name     string
+		password string
+		wantErr  error
+	}{
+		{name: "contains username", password: "Xq7Frontdev!", wantErr: ErrPasswordPersonalData},
+		{name: "contains mailbox", password: "Ivan.Petrov7Q", wantErr: ErrPasswordPersonalData},
+		{name: "contains first name", password: "svetlana9Kz!", wantErr: ErrPasswordPersonalData},
+		{name: "unrelated password", password: "Kq7#vXr2pLm9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateNewPassword(tt.password, personalData...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateNewPassword(%q) = %v, want %v", tt.password, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package entity
+
+import "time"
+
+// Group группа пользователей. Права группы на агентов наследуются всеми ее участниками.
+type Group struct {
+	ID            int
+	Name          string
+	OwnerID       string
+	OwnerUsername string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Bid заявка пользователя на создание группы. После одобрения заявки пользователь становится
+// ответственным за группу.
+type Bid struct {
+	ID        int
+	GroupName string
+	UserID    string
+	Username  string
+	Status    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Agent сервис, доступ к которому выдается пользователям и группам.
+type Agent struct {
+	ID        int
+	Name      string
+	CreatedAt time.Time
+}
+
+// Access результат проверки доступа пользователя к агенту. Sources перечисляет, откуда получен доступ:
+// root, прямое право (direct) или право группы (group:<название>).
+type Access struct {
+	Username string
+	Agent    string
+	Sources  []string
+}
@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+// Measurement измерение пользователя: масса тела с умных весов, шаги, пульс. Вид измерения — Kind.
+type Measurement struct {
+	Username   string
+	Kind       string
+	Value      float64
+	RecordedAt time.Time
+}
+
+// MeasurementFilter условия выборки измерений пользователя. Пустой Kind не ограничивает вид, время ищется
+// в полуинтервале [From, To), выборка начинается с последних измерений.
+type MeasurementFilter struct {
+	Username string
+	Kind     string
+	From     *time.Time
+	To       *time.Time
+	Limit    int
+}
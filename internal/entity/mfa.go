@@ -0,0 +1,18 @@
+package entity
+
+import "time"
+
+// TOTP настройки второго фактора пользователя. Секрет хранится в зашифрованном виде.
+type TOTP struct {
+	Username        string
+	SecretEncrypted string
+	Enabled         bool
+	LastUsedStep    int64
+	CreatedAt       time.Time
+}
+
+// TOTPEnrollment данные, которые нужно передать в приложение-аутентификатор.
+type TOTPEnrollment struct {
+	Secret     string
+	OtpauthURI string
+}
@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+// OIDCFlow состояние входа через внешнего провайдера между перенаправлением на провайдера и возвратом
+// от него. Хранится у клиента в зашифрованной cookie.
+type OIDCFlow struct {
+	Provider     string    `json:"provider"`
+	State        string    `json:"state"`
+	Nonce        string    `json:"nonce"`
+	CodeVerifier string    `json:"code_verifier"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// OIDCRegistration данные внешней учетной записи, для которой нужно завершить регистрацию (заполнить
+// профиль). Передается клиенту в зашифрованном виде как registration_token.
+type OIDCRegistration struct {
+	Identity  ExternalIdentity `json:"identity"`
+	ExpiresAt time.Time        `json:"expires_at"`
+}
+
+// OIDCResult итог возврата от провайдера: либо пользователь найден (User), либо нужно заполнить профиль.
+type OIDCResult struct {
+	User              *User
+	Linked            bool
+	RegistrationToken string
+	Identity          *ExternalIdentity
+}
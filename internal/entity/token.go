@@ -0,0 +1,23 @@
+package entity
+
+import "time"
+
+// RefreshToken запись о выданном refresh-токене. Сам токен в базе не хранится, только его хэш.
+type RefreshToken struct {
+	ID         string
+	FamilyID   string
+	Username   string
+	TokenHash  string
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+	ReplacedBy *string
+	CreatedAt  time.Time
+}
+
+// TokenPair пара токенов, которая выдается пользователю при авторизации и при обновлении сессии.
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
@@ -1,5 +1,7 @@
 package entity
 
+import "time"
+
 type User struct {
 	ID               string
 	Email            string
@@ -12,7 +14,32 @@ type User struct {
 	PhysicalActivity string
 	DayCalories      float32
 	Password         string
+	Role             string
 	BMI              BMIType
+	// LockedAt время блокировки учетной записи администратором, nil — учетная запись не заблокирована.
+	LockedAt              *time.Time
+	PasswordResetRequired bool
+	CreatedAt             time.Time
+}
+
+// SignInResult итог проверки пароля. NewPasswordHash заполнен, если администратор потребовал сменить пароль:
+// новый пароль сохраняется только после завершения входа, при включенном втором факторе — после ввода кода.
+type SignInResult struct {
+	User            *User
+	NewPasswordHash string
+}
+
+// UserFilter условия поиска пользователей администратором. Пустые поля не ограничивают выборку,
+// индекс массы тела ищется в полуинтервале [BMIMin, BMIMax).
+type UserFilter struct {
+	Query       string
+	Activity    string
+	BMIMin      *float32
+	BMIMax      *float32
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Limit       int
+	Offset      int
 }
 
 type BMIType struct {
@@ -39,3 +66,13 @@ func (b *BMIType) Calculate(weight float32, height int) {
 		b.Comment = "Ожирение третьей степени"
 	}
 }
+
+// ExternalIdentity учетная запись пользователя у внешнего провайдера (OpenID Connect).
+type ExternalIdentity struct {
+	Provider      string `json:"provider"`
+	Subject       string `json:"subject"`
+	Username      string `json:"username,omitempty"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	FirstName     string `json:"first_name"`
+}
@@ -10,6 +10,7 @@ import (
 	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
 	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/recorder"
 	"github.com/satori/uuid"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -21,6 +22,8 @@ type AccessLogStart struct {
 	Method         string
 	StartTimeHuman string
 	RequestId      string
+	TraceId        string
+	SpanId         string
 	Logger         *zap.Logger
 }
 
@@ -30,13 +33,24 @@ type AccessLogEnd struct {
 	ResponseStatus int
 	EndTimeHuman   string
 	RequestId      string
+	TraceId        string
+	SpanId         string
 	Logger         *zap.Logger
 }
 
-// Access middleware, который регистрирует начало и конец обработки запроса.
+// Access middleware, который регистрирует начало и конец обработки запроса. Идентификатор запроса — trace_id
+// спана запроса: он продолжает трассировку из входящего traceparent или начинает новую, поэтому строки журнала
+// всех сервисов, через которые прошел запрос, находятся по одному значению. Без спана идентификатор создается
+// заново.
 func Access(h http.Handler, logger *zap.Logger) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		requestId := uuid.NewV4().String()
+		var requestId, traceId, spanId string
+		if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+			traceId, spanId = sc.TraceID().String(), sc.SpanID().String()
+			requestId = traceId
+		} else {
+			requestId = uuid.NewV4().String()
+		}
 		ctx := context.WithValue(r.Context(), mc.AccessKey(mc.RequestID), requestId)
 		r = r.WithContext(ctx)
 
@@ -51,6 +65,8 @@ func Access(h http.Handler, logger *zap.Logger) http.Handler {
 			Method:         r.Method,
 			StartTimeHuman: f.FormatTime(timeNow),
 			RequestId:      requestId,
+			TraceId:        traceId,
+			SpanId:         spanId,
 			Logger:         logger,
 		}
 		LogInitRequest(startLog)
@@ -63,6 +79,8 @@ func Access(h http.Handler, logger *zap.Logger) http.Handler {
 			ResponseStatus: rec.StatusCode,
 			EndTimeHuman:   f.FormatTime(timeEnd),
 			RequestId:      requestId,
+			TraceId:        traceId,
+			SpanId:         spanId,
 			Logger:         logger,
 		}
 		LogEndRequest(endLog)
@@ -80,6 +98,8 @@ func LogInitRequest(data AccessLogStart) {
 		zap.String("method", data.Method),
 		zap.String("start-time-human", data.StartTimeHuman),
 		zap.String(mc.RequestID, data.RequestId),
+		zap.String(mc.TraceID, data.TraceId),
+		zap.String(mc.SpanID, data.SpanId),
 	)
 }
 
@@ -91,5 +111,7 @@ func LogEndRequest(data AccessLogEnd) {
 		zap.Int("response-status", data.ResponseStatus),
 		zap.String("end-time-human", data.EndTimeHuman),
 		zap.String(mc.RequestID, data.RequestId),
+		zap.String(mc.TraceID, data.TraceId),
+		zap.String(mc.SpanID, data.SpanId),
 	)
 }
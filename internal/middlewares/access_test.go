@@ -0,0 +1,73 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/satori/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAccessRequestID(t *testing.T) {
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	})
+	tests := []struct {
+		name string
+		// sc спан запроса, нулевой — запрос вне трассировки
+		sc          trace.SpanContext
+		wantTraceID string
+		wantSpanID  string
+	}{
+		{name: "request id is trace id", sc: sc, wantTraceID: sc.TraceID().String(), wantSpanID: sc.SpanID().String()},
+		{name: "request without span"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.InfoLevel)
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				zap.New(core).Info("handler", f.LogFields(r)...)
+			})
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+			r = r.WithContext(trace.ContextWithSpanContext(r.Context(), tt.sc))
+
+			Access(next, zap.New(core)).ServeHTTP(httptest.NewRecorder(), r)
+			entries := logs.All()
+			if len(entries) != 3 {
+				t.Fatalf("logged %d entries, want init, handler and end", len(entries))
+			}
+			requestID := fieldValue(entries[0], mc.RequestID)
+			switch {
+			case tt.wantTraceID != "" && requestID != tt.wantTraceID:
+				t.Errorf("request_id = %q, want trace id %q", requestID, tt.wantTraceID)
+			case tt.wantTraceID == "" && uuid.FromStringOrNil(requestID) == uuid.Nil:
+				t.Errorf("request_id = %q, want generated uuid", requestID)
+			}
+			for _, entry := range entries {
+				for key, want := range map[string]string{mc.RequestID: requestID, mc.TraceID: tt.wantTraceID, mc.SpanID: tt.wantSpanID} {
+					if got := fieldValue(entry, key); got != want {
+						t.Errorf("%q entry %s = %q, want %q", entry.Message, key, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+// fieldValue возвращает значение строкового поля записи журнала.
+func fieldValue(entry observer.LoggedEntry, key string) string {
+	for _, field := range entry.Context {
+		if field.Key == key && field.Type == zapcore.StringType {
+			return field.String
+		}
+	}
+	return ""
+}
@@ -0,0 +1,12 @@
+package middlewares
+
+import (
+	"strings"
+
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+)
+
+// isApiKey отличает API-ключ от jwt-токена по префиксу.
+func isApiKey(token string) bool {
+	return strings.HasPrefix(token, mc.ApiKeyPrefix)
+}
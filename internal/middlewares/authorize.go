@@ -0,0 +1,45 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"go.uber.org/zap"
+)
+
+// Authorize
+// Оборачивает обработчик маршрута: пропускает только авторизованных пользователей, у роли которых есть все
+// перечисленные права. Если запрос выполнен по API-ключу, то права должны быть еще и у ключа. Маршрут без
+// прав доступен любому авторизованному пользователю, но не по API-ключу.
+func Authorize(h http.HandlerFunc, logger *zap.Logger, permissions ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if f.GetUsernameCtx(r) == "" {
+			logger.Info(me.ErrNotAuthenticated.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: me.ErrNotAuthenticated.Error()}, http.StatusUnauthorized)
+			return
+		}
+
+		for _, permission := range permissions {
+			if f.HasPermission(r, permission) {
+				continue
+			}
+			err := me.ErrForbidden
+			if f.RoleHasPermission(f.GetRoleCtx(r), permission) {
+				// право есть у владельца, но не у API-ключа
+				err = me.ErrApiKeyScope
+			}
+			logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusForbidden)
+			return
+		}
+		// маршруты без прав (управление сессией) по API-ключу недоступны
+		if len(permissions) == 0 && f.GetApiKeyScopesCtx(r) != nil {
+			logger.Info(me.ErrApiKeyScope.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: me.ErrApiKeyScope.Error()}, http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -3,19 +3,104 @@ package middlewares
 
 import (
 	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/spf13/viper"
 )
 
+// corsPolicy политика CORS из конфигурации (cors.*).
+type corsPolicy struct {
+	origins          []string
+	methods          string
+	headers          string
+	exposedHeaders   string
+	allowCredentials bool
+	maxAge           string
+}
+
+// newCorsPolicy читает политику. При allow_credentials "*" отбрасывается: иначе любой сайт смог бы
+// отправлять запросы с cookie пользователя (config validate сообщает о такой настройке).
+func newCorsPolicy() *corsPolicy {
+	origins := viper.GetStringSlice("cors.allowed_origins")
+	allowCredentials := viper.GetBool("cors.allow_credentials")
+	if allowCredentials {
+		origins = slices.DeleteFunc(slices.Clone(origins), func(origin string) bool { return origin == "*" })
+	}
+	return &corsPolicy{
+		origins:          origins,
+		methods:          strings.Join(viper.GetStringSlice("cors.allowed_methods"), ", "),
+		headers:          strings.Join(viper.GetStringSlice("cors.allowed_headers"), ", "),
+		exposedHeaders:   strings.Join(viper.GetStringSlice("cors.exposed_headers"), ", "),
+		allowCredentials: allowCredentials,
+		maxAge:           strconv.Itoa(int(viper.GetDuration("cors.max_age").Seconds())),
+	}
+}
+
+// originAllowed проверяет Origin по списку разрешенных. Элемент списка — точное значение
+// (https://app.example.com) или шаблон с * (https://*.example.com), "*" разрешает любой Origin, если
+// allow_credentials выключен.
+func (p *corsPolicy) originAllowed(origin string) bool {
+	for _, allowed := range p.origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		if strings.Contains(allowed, "*") {
+			if ok, err := path.Match(strings.ToLower(allowed), strings.ToLower(origin)); err == nil && ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // CORS (Cross-Origin Resource Sharing). Настраивает политику доступа различных веб-услуг к нашему серверу.
-func Cors(h http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Нужен для Postman | в реальной жизни для версии продукта мы должны устанавливать доменные имена вместо "*".
-		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
-		w.Header().Set("Access-Control-Allow-Methods", "POST, DELETE, GET, OPTIONS, HEAD")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Access-Control-Allow-Headers")
+// Origin возвращается в ответе, только если он разрешен. Preflight-запрос обрабатывается здесь, только если
+// существует маршрут с запрошенным методом, остальные запросы OPTIONS передаются дальше.
+func Cors(h http.Handler, r *mux.Router) http.Handler {
+	policy := newCorsPolicy()
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// ответ зависит от Origin, поэтому кэши должны его учитывать
+		w.Header().Add("Vary", "Origin")
+		origin := req.Header.Get("Origin")
+		if origin == "" || !policy.originAllowed(origin) {
+			h.ServeHTTP(w, req)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		if policy.allowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		requestMethod := req.Header.Get("Access-Control-Request-Method")
+		if req.Method != http.MethodOptions || requestMethod == "" {
+			if policy.exposedHeaders != "" {
+				w.Header().Set("Access-Control-Expose-Headers", policy.exposedHeaders)
+			}
+			h.ServeHTTP(w, req)
+			return
+		}
 		// Preflight-request обработка.
-		if r.Method == http.MethodOptions {
+		if !routeExists(r, req, requestMethod) {
+			h.ServeHTTP(w, req)
 			return
 		}
-		h.ServeHTTP(w, r)
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		w.Header().Set("Access-Control-Allow-Methods", policy.methods)
+		w.Header().Set("Access-Control-Allow-Headers", policy.headers)
+		w.Header().Set("Access-Control-Max-Age", policy.maxAge)
+		w.WriteHeader(http.StatusNoContent)
 	})
 }
+
+// routeExists сообщает, есть ли маршрут для пути запроса и метода, о котором спрашивает preflight.
+func routeExists(r *mux.Router, req *http.Request, method string) bool {
+	probe := req.Clone(req.Context())
+	probe.Method = method
+	var match mux.RouteMatch
+	return r.Match(probe, &match) && match.MatchErr == nil
+}
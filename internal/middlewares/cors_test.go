@@ -0,0 +1,233 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/spf13/viper"
+)
+
+func newCorsHandler(t *testing.T, origins []string, allowCredentials bool) http.Handler {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("cors.allowed_origins", origins)
+	viper.Set("cors.allowed_methods", []string{"GET", "POST", "PUT", "DELETE"})
+	viper.Set("cors.allowed_headers", []string{"Content-Type", "Authorization", "X-CSRF-Token"})
+	viper.Set("cors.exposed_headers", []string{"X-CSRF-Token", "Retry-After"})
+	viper.Set("cors.allow_credentials", allowCredentials)
+	viper.Set("cors.max_age", 10*time.Minute)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/users/weight", func(w http.ResponseWriter, _ *http.Request) {}).Methods("PUT")
+	r.HandleFunc("/api/v1/users", func(w http.ResponseWriter, _ *http.Request) {}).Methods("GET")
+	// запросы, которые CORS передает дальше, отвечают 418, чтобы их можно было отличить от preflight
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusTeapot) })
+	return Cors(next, r)
+}
+
+func TestCors(t *testing.T) {
+	const (
+		appOrigin  = "https://app.example.com"
+		evilOrigin = "https://evil.com"
+	)
+	tests := []struct {
+		name             string
+		origins          []string
+		allowCredentials bool
+		method           string
+		path             string
+		headers          map[string]string
+		wantStatus       int
+		// wantOrigin значение Access-Control-Allow-Origin, пустое — заголовка нет
+		wantOrigin      string
+		wantCredentials bool
+		wantPreflight   bool
+	}{
+		{
+			name:    "allowed origin",
+			origins: []string{appOrigin}, allowCredentials: true,
+			method: http.MethodGet, path: "/api/v1/users",
+			headers:    map[string]string{"Origin": appOrigin},
+			wantStatus: http.StatusTeapot, wantOrigin: appOrigin, wantCredentials: true,
+		},
+		{
+			name:    "origin is compared case-insensitively",
+			origins: []string{appOrigin}, allowCredentials: true,
+			method: http.MethodGet, path: "/api/v1/users",
+			headers:    map[string]string{"Origin": "https://APP.example.com"},
+			wantStatus: http.StatusTeapot, wantOrigin: "https://APP.example.com", wantCredentials: true,
+		},
+		{
+			name:    "denied origin",
+			origins: []string{appOrigin}, allowCredentials: true,
+			method: http.MethodGet, path: "/api/v1/users",
+			headers:    map[string]string{"Origin": evilOrigin},
+			wantStatus: http.StatusTeapot,
+		},
+		{
+			name:    "request without origin",
+			origins: []string{appOrigin}, allowCredentials: true,
+			method: http.MethodGet, path: "/api/v1/users",
+			wantStatus: http.StatusTeapot,
+		},
+		{
+			name:    "pattern matches subdomain",
+			origins: []string{"https://*.example.com"}, allowCredentials: true,
+			method: http.MethodGet, path: "/api/v1/users",
+			headers:    map[string]string{"Origin": appOrigin},
+			wantStatus: http.StatusTeapot, wantOrigin: appOrigin, wantCredentials: true,
+		},
+		{
+			name:    "pattern does not match suffix of another domain",
+			origins: []string{"https://*.example.com"}, allowCredentials: true,
+			method: http.MethodGet, path: "/api/v1/users",
+			headers:    map[string]string{"Origin": "https://app.example.com.evil.com"},
+			wantStatus: http.StatusTeapot,
+		},
+		{
+			name:    "pattern does not match another scheme",
+			origins: []string{"https://*.example.com"}, allowCredentials: true,
+			method: http.MethodGet, path: "/api/v1/users",
+			headers:    map[string]string{"Origin": "http://app.example.com"},
+			wantStatus: http.StatusTeapot,
+		},
+		{
+			name:    "wildcard echoes origin without credentials",
+			origins: []string{"*"},
+			method:  http.MethodGet, path: "/api/v1/users",
+			headers:    map[string]string{"Origin": evilOrigin},
+			wantStatus: http.StatusTeapot, wantOrigin: evilOrigin,
+		},
+		{
+			name:    "wildcard is refused with credentials",
+			origins: []string{"*"}, allowCredentials: true,
+			method: http.MethodGet, path: "/api/v1/users",
+			headers:    map[string]string{"Origin": evilOrigin},
+			wantStatus: http.StatusTeapot,
+		},
+		{
+			name:    "explicit origin next to refused wildcard",
+			origins: []string{"*", appOrigin}, allowCredentials: true,
+			method: http.MethodGet, path: "/api/v1/users",
+			headers:    map[string]string{"Origin": appOrigin},
+			wantStatus: http.StatusTeapot, wantOrigin: appOrigin, wantCredentials: true,
+		},
+		{
+			name:    "preflight",
+			origins: []string{appOrigin}, allowCredentials: true,
+			method: http.MethodOptions, path: "/api/v1/users/weight",
+			headers: map[string]string{
+				"Origin":                         appOrigin,
+				"Access-Control-Request-Method":  http.MethodPut,
+				"Access-Control-Request-Headers": "content-type, x-csrf-token",
+			},
+			wantStatus: http.StatusNoContent, wantOrigin: appOrigin, wantCredentials: true, wantPreflight: true,
+		},
+		{
+			name:    "preflight with wildcard origin",
+			origins: []string{"*"},
+			method:  http.MethodOptions, path: "/api/v1/users/weight",
+			headers: map[string]string{
+				"Origin":                        appOrigin,
+				"Access-Control-Request-Method": http.MethodPut,
+			},
+			wantStatus: http.StatusNoContent, wantOrigin: appOrigin, wantPreflight: true,
+		},
+		{
+			name:    "preflight from denied origin",
+			origins: []string{appOrigin}, allowCredentials: true,
+			method: http.MethodOptions, path: "/api/v1/users/weight",
+			headers: map[string]string{
+				"Origin":                        evilOrigin,
+				"Access-Control-Request-Method": http.MethodPut,
+			},
+			wantStatus: http.StatusTeapot,
+		},
+		{
+			name:    "preflight for method without route",
+			origins: []string{appOrigin}, allowCredentials: true,
+			method: http.MethodOptions, path: "/api/v1/users/weight",
+			headers: map[string]string{
+				"Origin":                        appOrigin,
+				"Access-Control-Request-Method": http.MethodDelete,
+			},
+			wantStatus: http.StatusTeapot, wantOrigin: appOrigin, wantCredentials: true,
+		},
+		{
+			name:    "preflight for unknown path",
+			origins: []string{appOrigin}, allowCredentials: true,
+			method: http.MethodOptions, path: "/api/v1/unknown",
+			headers: map[string]string{
+				"Origin":                        appOrigin,
+				"Access-Control-Request-Method": http.MethodGet,
+			},
+			wantStatus: http.StatusTeapot, wantOrigin: appOrigin, wantCredentials: true,
+		},
+		{
+			name:    "options without request method is not preflight",
+			origins: []string{appOrigin}, allowCredentials: true,
+			method: http.MethodOptions, path: "/api/v1/users/weight",
+			headers:    map[string]string{"Origin": appOrigin},
+			wantStatus: http.StatusTeapot, wantOrigin: appOrigin, wantCredentials: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newCorsHandler(t, tt.origins, tt.allowCredentials)
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			header := rec.Header()
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := header.Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := header.Get("Access-Control-Allow-Credentials") == "true"; got != tt.wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want credentials %v", header.Get("Access-Control-Allow-Credentials"), tt.wantCredentials)
+			}
+			if !slices.Contains(header.Values("Vary"), "Origin") {
+				t.Errorf("Vary = %v, want Origin", header.Values("Vary"))
+			}
+
+			if !tt.wantPreflight {
+				if got := header.Get("Access-Control-Allow-Methods"); got != "" {
+					t.Errorf("Access-Control-Allow-Methods = %q on a non-preflight response", got)
+				}
+				// заголовки ответа открываются только обычным запросам с разрешенным Origin
+				wantExposed := ""
+				if _, preflight := tt.headers["Access-Control-Request-Method"]; tt.wantOrigin != "" && !preflight {
+					wantExposed = "X-CSRF-Token, Retry-After"
+				}
+				if got := header.Get("Access-Control-Expose-Headers"); got != wantExposed {
+					t.Errorf("Access-Control-Expose-Headers = %q, want %q", got, wantExposed)
+				}
+				return
+			}
+			for key, want := range map[string]string{
+				"Access-Control-Allow-Methods": "GET, POST, PUT, DELETE",
+				"Access-Control-Allow-Headers": "Content-Type, Authorization, X-CSRF-Token",
+				"Access-Control-Max-Age":       "600",
+			} {
+				if got := header.Get(key); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+			for _, want := range []string{"Access-Control-Request-Method", "Access-Control-Request-Headers"} {
+				if !slices.Contains(header.Values("Vary"), want) {
+					t.Errorf("Vary = %v, want %s", header.Values("Vary"), want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"go.uber.org/zap"
+)
+
+// Csrf защищает от подделки межсайтовых запросов по схеме double submit cookie. Изменяющий запрос с cookie
+// сессии должен содержать заголовок X-CSRF-Token, совпадающий с cookie csrf-token: сторонний сайт может
+// заставить браузер отправить cookie, но не может их прочитать. Запросы с заголовком Authorization (jwt-токен
+// или API-ключ) браузер сам не отправляет, поэтому они не проверяются.
+func Csrf(h http.Handler, logger *zap.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isSafeMethod(r.Method) || r.Header.Get(mc.Authorization) != "" || !f.HasSessionCookie(r) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if _, ok := mc.CsrfExemptPaths[r.URL.Path]; ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		cookieToken, err := f.GetCsrfCookie(r)
+		headerToken := r.Header.Get(mc.XCsrfToken)
+		if err != nil || cookieToken == "" || subtle.ConstantTimeCompare([]byte(cookieToken), []byte(headerToken)) != 1 {
+			logger.Info(me.ErrInvalidCsrfToken.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: me.ErrInvalidCsrfToken.Error()}, http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// isSafeMethod сообщает, что метод по RFC 9110 не изменяет состояние сервера.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
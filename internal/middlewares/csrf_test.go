@@ -0,0 +1,73 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"go.uber.org/zap"
+)
+
+func TestCsrf(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		sessionCookie string // cookie сессии: jwt-token или refresh-token
+		csrfCookie    string
+		csrfHeader    string
+		authorization string
+		wantStatus    int
+	}{
+		{name: "cookie session without header", method: http.MethodPost, path: "/api/v1/users/weight",
+			sessionCookie: mc.JwtToken, csrfCookie: "token", wantStatus: http.StatusForbidden},
+		{name: "mismatched header", method: http.MethodDelete, path: "/api/v1/users",
+			sessionCookie: mc.JwtToken, csrfCookie: "token", csrfHeader: "other", wantStatus: http.StatusForbidden},
+		{name: "no csrf cookie", method: http.MethodPost, path: "/api/v1/users/weight",
+			sessionCookie: mc.JwtToken, csrfHeader: "token", wantStatus: http.StatusForbidden},
+		{name: "refresh cookie only", method: http.MethodPost, path: "/api/v1/token/refresh",
+			sessionCookie: mc.RefreshToken, csrfCookie: "token", wantStatus: http.StatusForbidden},
+		{name: "matching header", method: http.MethodPut, path: "/api/v1/users/weight",
+			sessionCookie: mc.JwtToken, csrfCookie: "token", csrfHeader: "token", wantStatus: http.StatusOK},
+		{name: "safe method", method: http.MethodGet, path: "/api/v1/users",
+			sessionCookie: mc.JwtToken, csrfCookie: "token", wantStatus: http.StatusOK},
+		{name: "options", method: http.MethodOptions, path: "/api/v1/users",
+			sessionCookie: mc.JwtToken, wantStatus: http.StatusOK},
+		{name: "authorization header", method: http.MethodPost, path: "/api/v1/users/weight",
+			sessionCookie: mc.JwtToken, csrfCookie: "token", authorization: "Bearer token", wantStatus: http.StatusOK},
+		{name: "no session cookie", method: http.MethodPost, path: "/api/v1/users/weight", wantStatus: http.StatusOK},
+		{name: "exempt sign in", method: http.MethodPost, path: "/api/v1/signin",
+			sessionCookie: mc.JwtToken, csrfCookie: "token", wantStatus: http.StatusOK},
+		{name: "exempt second factor", method: http.MethodPost, path: "/api/v1/signin/2fa",
+			sessionCookie: mc.RefreshToken, wantStatus: http.StatusOK},
+		{name: "sign out is not exempt", method: http.MethodPost, path: "/api/v1/signout",
+			sessionCookie: mc.JwtToken, csrfCookie: "token", wantStatus: http.StatusForbidden},
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.sessionCookie != "" {
+				r.AddCookie(&http.Cookie{Name: tt.sessionCookie, Value: "session"})
+			}
+			if tt.csrfCookie != "" {
+				r.AddCookie(&http.Cookie{Name: mc.CsrfToken, Value: tt.csrfCookie})
+			}
+			if tt.csrfHeader != "" {
+				r.Header.Set(mc.XCsrfToken, tt.csrfHeader)
+			}
+			if tt.authorization != "" {
+				r.Header.Set(mc.Authorization, tt.authorization)
+			}
+			w := httptest.NewRecorder()
+
+			Csrf(next, zap.NewNop()).ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("Csrf() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
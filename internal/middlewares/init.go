@@ -3,15 +3,19 @@ package middlewares
 import (
 	"net/http"
 
+	ucApiKey "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/apikey"
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 )
 
 // Init инициализирует цепочку middlewares.
-func Init(r *mux.Router, logger *zap.Logger) (h http.Handler) {
-	h = JwtVerification(r, logger)
-	h = Cors(h)
+func Init(r *mux.Router, ucApiKey ucApiKey.Usecase, logger *zap.Logger) (h http.Handler) {
+	h = JwtVerification(r, ucApiKey, logger)
+	h = Csrf(h, logger)
+	h = Cors(h, r)
 	h = Recover(h, logger)
+	h = Metrics(h, r)
 	h = Access(h, logger)
+	h = Trace(h, r)
 	return h
 }
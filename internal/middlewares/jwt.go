@@ -3,21 +3,14 @@ package middlewares
 
 import (
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
-	"strings"
-	"time"
 
 	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	ucApiKey "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/apikey"
 	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
-	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
 	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
-	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
@@ -36,29 +29,58 @@ import (
 // }
 
 // JwtVerification
-// Needed for authentication.
-func JwtVerification(h http.Handler, logger *zap.Logger) http.Handler {
+// Needed for authentication. Checks short-lived access token, refresh token is handled by /token/refresh.
+// Вместо jwt-токена в заголовке Authorization можно передать персональный API-ключ, тогда запрос выполняется
+// от имени владельца ключа в пределах прав ключа.
+func JwtVerification(h http.Handler, ucApiKey ucApiKey.Usecase, logger *zap.Logger) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		requestID, err := f.GetCtxRequestID(r)
-		if err != nil {
-			logger.Error(err.Error(), zap.String(mc.RequestID, requestID))
-		}
-
 		jwtToken, err := f.GetJWtToken(r)
+		if errors.Is(err, me.ErrInvalidAuthHeader) {
+			logger.Info(err.Error(), f.LogFields(r)...)
+			f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusUnauthorized)
+			return
+		}
 		if err != nil && !errors.Is(err, http.ErrNoCookie) {
-			logger.Error(fmt.Sprintf("error while jwt getting: %v", err), zap.String(mc.RequestID, requestID))
+			logger.Error(fmt.Sprintf("error while jwt getting: %v", err), f.LogFields(r)...)
 			f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
 			return
 		}
+		if isApiKey(jwtToken) {
+			key, err := ucApiKey.Authenticate(r.Context(), jwtToken)
+			if errors.Is(err, me.ErrInvalidApiKey) {
+				logger.Info(err.Error(), f.LogFields(r)...)
+				f.Response(w, dto.ResponseError{Error: err.Error()}, http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				logger.Error(fmt.Sprintf("error while api key verification: %v", err), f.LogFields(r)...)
+				f.Response(w, dto.ResponseError{Error: me.ErrInternal.Error()}, http.StatusInternalServerError)
+				return
+			}
+			// права ключа проверяются в middleware Authorize того маршрута, который вызывается
+			ctx := context.WithValue(r.Context(), "username", key.Username)
+			ctx = context.WithValue(ctx, "role", key.OwnerRole)
+			ctx = context.WithValue(ctx, "api_key_scopes", key.Scopes)
+			h.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
 		if jwtToken != "" {
-			username, err := jwtTokenIsValid(jwtToken)
+			payload, err := jwtTokenIsValid(jwtToken)
+			// просроченный access-токен не является ошибкой: запрос обрабатывается как анонимный,
+			// а клиент должен обновить пару токенов через /token/refresh
+			if errors.Is(err, me.ErrAccessTokenExpired) {
+				logger.Info(err.Error(), f.LogFields(r)...)
+				h.ServeHTTP(w, r)
+				return
+			}
 			if err != nil {
 				f.FlashCookie(w, r)
-				logger.Error(fmt.Sprintf("error while jwt verification: %v", err), zap.String(mc.RequestID, requestID))
+				logger.Error(fmt.Sprintf("error while jwt verification: %v", err), f.LogFields(r)...)
 				f.Response(w, dto.ResponseError{Error: me.ErrInvalidJwt.Error()}, http.StatusUnauthorized)
 				return
 			}
-			ctx := context.WithValue(r.Context(), "username", username)
+			ctx := context.WithValue(r.Context(), "username", payload.Username)
+			ctx = context.WithValue(ctx, "role", payload.Role)
 			r = r.WithContext(ctx)
 		}
 		// Decode payload and use data.
@@ -68,62 +90,14 @@ func JwtVerification(h http.Handler, logger *zap.Logger) http.Handler {
 
 // jwtTokenIsValid
 // Needed for validation jwt-token.
-func jwtTokenIsValid(token string) (string, error) {
-	// check time validation of token
-	// if all is okey, return true
-	parts := strings.Split(token, ".")
-	if len(parts) != 3 {
-		return "", me.ErrInvalidJwt
-	}
-	signatureHash, err := hashWithStatement(parts[0] + "." + parts[1]) // header + "." + payload)
-	if err != nil {
-		return "", err
-	}
-	signature := hex.EncodeToString([]byte(signatureHash))
-	if signature != parts[2] {
-		return "", me.ErrInvalidJwt
-	}
-
-	dataHeader, err := hex.DecodeString(parts[0])
-	if err != nil {
-		return "", err
-	}
-	var h dto.JwtTokenHeader
-	err = json.Unmarshal(dataHeader, &h)
-	if err != nil {
-		return "", err
-	}
-
-	dataPayload, err := hex.DecodeString(parts[1])
-	if err != nil {
-		return "", err
-	}
-	var p dto.JwtTokenPayload
-	err = json.Unmarshal(dataPayload, &p)
-	if err != nil {
-		return "", err
-	}
-
-	// "02.01.2006 15:04:05 UTC-07" template
-	jwtDate, err := time.Parse("02.01.2006 15:04:05 UTC-07", h.Exp)
+func jwtTokenIsValid(token string) (*dto.JwtTokenPayload, error) {
+	h, p, err := f.ParseJwtToken(token)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	dateNow := time.Now()
-	if jwtDate.Equal(dateNow) || dateNow.After(jwtDate) {
-		return "", me.ErrInvalidJwt
-	}
-	return p.Username, nil
-}
-
-// hashWithStatement
-// Returns hash that is transmitted in the client-server model by custom header.
-func hashWithStatement(statement string) (string, error) {
-	secretKey := viper.GetString("secret_key")
-	mac := hmac.New(sha256.New, []byte(secretKey))
-	_, err := mac.Write([]byte(statement))
-	if err != nil {
-		return "", err
+	// служебные токены (например, токен вызова второго фактора) не дают доступа к ресурсам
+	if h.Typ != "" {
+		return nil, me.ErrInvalidJwt
 	}
-	return string(mac.Sum(nil)), nil
+	return p, nil
 }
@@ -0,0 +1,59 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+func TestJwtVerification(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("secret_key", "secret")
+	newToken := func(typ string, ttl time.Duration) string {
+		token, err := f.NewJwtToken(f.NewJwtTokenProps{Username: "ivan", Typ: typ}, time.Now().Add(ttl))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	tests := []struct {
+		name         string
+		token        string
+		wantStatus   int
+		wantUsername string
+	}{
+		{name: "access token", token: newToken("", time.Minute), wantStatus: http.StatusOK, wantUsername: "ivan"},
+		{name: "expired access token is anonymous", token: newToken("", -time.Minute), wantStatus: http.StatusOK},
+		{name: "mfa challenge token", token: newToken(mc.MfaChallengeType, time.Minute), wantStatus: http.StatusUnauthorized},
+		{name: "forged token", token: newToken("", time.Minute) + "00", wantStatus: http.StatusUnauthorized},
+		{name: "no token", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var username string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				username = f.GetUsernameCtx(r)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+			if tt.token != "" {
+				req.Header.Set(mc.Authorization, mc.BearerScheme+" "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			JwtVerification(next, nil, zap.NewNop()).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if username != tt.wantUsername {
+				t.Errorf("username = %q, want %q", username, tt.wantUsername)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/metrics"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/recorder"
+	"github.com/gorilla/mux"
+)
+
+// routeUnmatched метка route запросов, для которых не нашлось маршрута.
+const routeUnmatched = "unmatched"
+
+// Metrics middleware, который считает запросы и их длительность по шаблону маршрута, методу и статусу ответа.
+func Metrics(h http.Handler, r *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		metrics.HTTPInFlight.Inc()
+		defer metrics.HTTPInFlight.Dec()
+
+		rec := recorder.NewResponseWriter(w)
+		start := time.Now()
+		h.ServeHTTP(rec, req)
+		elapsed := time.Since(start).Seconds()
+
+		route := routeTemplate(r, req)
+		status := strconv.Itoa(rec.StatusCode)
+		metrics.HTTPRequests.WithLabelValues(req.Method, route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(req.Method, route, status).Observe(elapsed)
+	})
+}
+
+// routeTemplate шаблон маршрута запроса (/api/v1/groups/{name}) или routeUnmatched.
+func routeTemplate(r *mux.Router, req *http.Request) string {
+	var match mux.RouteMatch
+	if r.Match(req, &match) && match.Route != nil {
+		if tmpl, err := match.Route.GetPathTemplate(); err == nil {
+			return tmpl
+		}
+	}
+	return routeUnmatched
+}
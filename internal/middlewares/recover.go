@@ -7,6 +7,8 @@ import (
 	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
 	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
 	e "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -16,6 +18,9 @@ func Recover(h http.Handler, logger *zap.Logger) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
+				span := trace.SpanFromContext(r.Context())
+				span.RecordError(fmt.Errorf("panic: %v", err))
+				span.SetStatus(codes.Error, "panic")
 				logger.Error(fmt.Sprintf("error while handling request: %v", err))
 				f.Response(w, dto.ResponseError{Error: e.ErrInternal.Error()}, http.StatusInternalServerError)
 				return
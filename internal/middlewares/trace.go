@@ -0,0 +1,19 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Trace middleware, который записывает запрос спаном OpenTelemetry. Если клиент или прокси передал
+// traceparent, спан продолжает его трассировку, иначе начинается новая. Спан называется по шаблону
+// маршрута (GET /api/v1/groups/{name}), а не по пути запроса.
+func Trace(h http.Handler, r *mux.Router) http.Handler {
+	return otelhttp.NewHandler(h, "HTTP",
+		otelhttp.WithSpanNameFormatter(func(_ string, req *http.Request) string {
+			return req.Method + " " + routeTemplate(r, req)
+		}),
+	)
+}
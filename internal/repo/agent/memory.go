@@ -0,0 +1,171 @@
+package agent
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/memory"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	repoErr "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+)
+
+var _ Repo = (*RepoMemory)(nil)
+
+type RepoMemory struct {
+	store *memory.Store
+}
+
+// NewRepoMemory возвращает repository агентов, который хранит данные в памяти процесса (storage: memory).
+func NewRepoMemory(store *memory.Store) *RepoMemory {
+	return &RepoMemory{
+		store: store,
+	}
+}
+
+func (r *RepoMemory) Create(ctx context.Context, name string) (*ent.Agent, error) {
+	var a ent.Agent
+	err := r.store.Write(ctx, func() error {
+		if _, ok := r.agentByName(name); ok {
+			return repoErr.ErrAgentAlreadyExist
+		}
+		a = ent.Agent{ID: r.store.NextID(), Name: name, CreatedAt: time.Now()}
+		r.store.Agents[a.ID] = a
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *RepoMemory) GetByName(ctx context.Context, name string) (*ent.Agent, error) {
+	var a ent.Agent
+	err := r.store.Read(ctx, func() error {
+		agent, ok := r.agentByName(name)
+		if !ok {
+			return sql.ErrNoRows
+		}
+		a = agent
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *RepoMemory) List(ctx context.Context) ([]*ent.Agent, error) {
+	agents := make([]*ent.Agent, 0)
+	err := r.store.Read(ctx, func() error {
+		for _, a := range r.store.Agents {
+			agents = append(agents, &a)
+		}
+		return nil
+	})
+	slices.SortFunc(agents, func(a, b *ent.Agent) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return agents, err
+}
+
+func (r *RepoMemory) DeleteByName(ctx context.Context, name string) error {
+	return r.store.Write(ctx, func() error {
+		a, ok := r.agentByName(name)
+		if !ok {
+			return repoErr.ErrNoRowsAffected
+		}
+		r.store.DeleteAgent(a.ID)
+		return nil
+	})
+}
+
+func (r *RepoMemory) GrantUser(ctx context.Context, agentID int, username string) error {
+	return r.store.Write(ctx, func() error {
+		u, ok := r.store.Users[username]
+		if !ok {
+			return repoErr.ErrNoRowsAffected
+		}
+		if _, ok := r.store.Agents[agentID]; !ok {
+			return repoErr.ErrAgentNotExist
+		}
+		r.store.UserPrivileges[memory.UserPrivilege{AgentID: agentID, UserID: u.ID}] = struct{}{}
+		return nil
+	})
+}
+
+func (r *RepoMemory) RevokeUser(ctx context.Context, agentID int, username string) error {
+	return r.store.Write(ctx, func() error {
+		u, ok := r.store.Users[username]
+		if !ok {
+			return repoErr.ErrNoRowsAffected
+		}
+		key := memory.UserPrivilege{AgentID: agentID, UserID: u.ID}
+		if _, ok := r.store.UserPrivileges[key]; !ok {
+			return repoErr.ErrNoRowsAffected
+		}
+		delete(r.store.UserPrivileges, key)
+		return nil
+	})
+}
+
+func (r *RepoMemory) GrantGroup(ctx context.Context, agentID, groupID int) error {
+	return r.store.Write(ctx, func() error {
+		if _, ok := r.store.Agents[agentID]; !ok {
+			return repoErr.ErrAgentNotExist
+		}
+		if _, ok := r.store.Groups[groupID]; !ok {
+			return repoErr.ErrGroupNotExist
+		}
+		r.store.GroupPrivileges[memory.GroupPrivilege{AgentID: agentID, GroupID: groupID}] = struct{}{}
+		return nil
+	})
+}
+
+func (r *RepoMemory) RevokeGroup(ctx context.Context, agentID, groupID int) error {
+	return r.store.Write(ctx, func() error {
+		key := memory.GroupPrivilege{AgentID: agentID, GroupID: groupID}
+		if _, ok := r.store.GroupPrivileges[key]; !ok {
+			return repoErr.ErrNoRowsAffected
+		}
+		delete(r.store.GroupPrivileges, key)
+		return nil
+	})
+}
+
+func (r *RepoMemory) GetAccessSources(ctx context.Context, agentID int, username string) ([]string, error) {
+	sources := make([]string, 0)
+	err := r.store.Read(ctx, func() error {
+		u, ok := r.store.Users[username]
+		if !ok {
+			return nil
+		}
+		if _, ok := r.store.UserPrivileges[memory.UserPrivilege{AgentID: agentID, UserID: u.ID}]; ok {
+			sources = append(sources, mc.AccessSourceDirect)
+		}
+		for p := range r.store.GroupPrivileges {
+			if p.AgentID != agentID {
+				continue
+			}
+			if _, ok := r.store.Participation[memory.GroupMember{GroupID: p.GroupID, UserID: u.ID}]; ok {
+				sources = append(sources, mc.AccessSourceGroup+r.store.Groups[p.GroupID].Name)
+			}
+		}
+		return nil
+	})
+	slices.Sort(sources)
+	return sources, err
+}
+
+// agentByName должен вызываться под блокировкой хранилища.
+func (r *RepoMemory) agentByName(name string) (ent.Agent, bool) {
+	for _, a := range r.store.Agents {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return ent.Agent{}, false
+}
@@ -0,0 +1,211 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	repoErr "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repo interface {
+	Create(ctx context.Context, name string) (*ent.Agent, error)
+	GetByName(ctx context.Context, name string) (*ent.Agent, error)
+	List(ctx context.Context) ([]*ent.Agent, error)
+	DeleteByName(ctx context.Context, name string) error
+
+	GrantUser(ctx context.Context, agentID int, username string) error
+	RevokeUser(ctx context.Context, agentID int, username string) error
+	GrantGroup(ctx context.Context, agentID, groupID int) error
+	RevokeGroup(ctx context.Context, agentID, groupID int) error
+	GetAccessSources(ctx context.Context, agentID int, username string) ([]string, error)
+}
+
+var _ Repo = (*RepoLayer)(nil)
+
+type RepoLayer struct {
+	dbConn *transaction.Conn
+}
+
+// NewRepoLayer возвращает структуру уровня repository. Позволяет работать с агентами и доступом к ним.
+func NewRepoLayer(dbConn *pgxpool.Pool) *RepoLayer {
+	return &RepoLayer{
+		dbConn: transaction.NewConn(dbConn),
+	}
+}
+
+var (
+	agent_fields = "id, name, created_at"
+)
+
+var (
+	sqlRowCreateAgent = fmt.Sprintf(
+		`INSERT INTO agent (name) VALUES ($1) RETURNING %s`,
+		agent_fields,
+	)
+
+	sqlRowGetByName = fmt.Sprintf(
+		`SELECT %s FROM agent WHERE name = $1`,
+		agent_fields,
+	)
+
+	sqlRowsList = fmt.Sprintf(
+		`SELECT %s FROM agent ORDER BY name`,
+		agent_fields,
+	)
+
+	// доступ пользователя складывается из его собственных прав и прав всех групп, в которых он состоит;
+	// прямое право идет первым, права групп — по названию группы
+	sqlRowsAccessSources = fmt.Sprintf(`
+		SELECT '%s' FROM privelege_user pu
+		JOIN "user" u ON u.id = pu.user_id
+		WHERE pu.agent_id = $1 AND u.username = $2
+		UNION ALL
+		SELECT '%s' || g.name FROM privelege_group pg
+		JOIN "group" g ON g.id = pg.group_id
+		JOIN participation p ON p.group_id = pg.group_id
+		JOIN "user" u ON u.id = p.user_id
+		WHERE pg.agent_id = $1 AND u.username = $2
+		ORDER BY 1`,
+		mc.AccessSourceDirect, mc.AccessSourceGroup,
+	)
+)
+
+// Create создает агента. Если агент с таким названием уже есть, возвращает ErrAgentAlreadyExist.
+func (r *RepoLayer) Create(ctx context.Context, name string) (*ent.Agent, error) {
+	a, err := scanAgent(r.dbConn.QueryRow(ctx, sqlRowCreateAgent, name))
+	if f.IsPgError(err, mc.PgUniqueViolation) {
+		return nil, repoErr.ErrAgentAlreadyExist
+	}
+	return a, err
+}
+
+// GetByName позволяет получить агента по названию.
+func (r *RepoLayer) GetByName(ctx context.Context, name string) (*ent.Agent, error) {
+	return scanAgent(r.dbConn.QueryRow(ctx, sqlRowGetByName, name))
+}
+
+// List возвращает всех агентов.
+func (r *RepoLayer) List(ctx context.Context) ([]*ent.Agent, error) {
+	rows, err := r.dbConn.Query(ctx, sqlRowsList)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	agents := make([]*ent.Agent, 0)
+	for rows.Next() {
+		a, err := scanAgent(rows)
+		if err != nil {
+			return nil, err
+		}
+		agents = append(agents, a)
+	}
+	return agents, rows.Err()
+}
+
+// DeleteByName удаляет агента вместе со всеми правами на него.
+func (r *RepoLayer) DeleteByName(ctx context.Context, name string) error {
+	row, err := r.dbConn.Exec(ctx, `DELETE FROM agent WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+	if row.RowsAffected() == 0 {
+		return repoErr.ErrNoRowsAffected
+	}
+	return nil
+}
+
+// GrantUser выдает пользователю прямой доступ к агенту. Повторная выдача ничего не меняет (DO UPDATE вместо
+// DO NOTHING, чтобы отличить ее от несуществующего пользователя). Если пользователя нет, возвращает
+// ErrNoRowsAffected.
+func (r *RepoLayer) GrantUser(ctx context.Context, agentID int, username string) error {
+	row, err := r.dbConn.Exec(ctx,
+		`INSERT INTO privelege_user (agent_id, user_id) SELECT $1::int, id FROM "user" WHERE username = $2
+		ON CONFLICT (agent_id, user_id) DO UPDATE SET agent_id = EXCLUDED.agent_id`,
+		agentID, username,
+	)
+	if err != nil {
+		return err
+	}
+	if row.RowsAffected() == 0 {
+		return repoErr.ErrNoRowsAffected
+	}
+	return nil
+}
+
+// RevokeUser отзывает прямой доступ пользователя к агенту. Если доступа не было, возвращает ErrNoRowsAffected.
+func (r *RepoLayer) RevokeUser(ctx context.Context, agentID int, username string) error {
+	row, err := r.dbConn.Exec(ctx,
+		`DELETE FROM privelege_user pu USING "user" u
+		WHERE pu.user_id = u.id AND pu.agent_id = $1 AND u.username = $2`,
+		agentID, username,
+	)
+	if err != nil {
+		return err
+	}
+	if row.RowsAffected() == 0 {
+		return repoErr.ErrNoRowsAffected
+	}
+	return nil
+}
+
+// GrantGroup выдает группе доступ к агенту. Повторная выдача ничего не меняет.
+func (r *RepoLayer) GrantGroup(ctx context.Context, agentID, groupID int) error {
+	_, err := r.dbConn.Exec(ctx,
+		`INSERT INTO privelege_group (agent_id, group_id) VALUES ($1, $2) ON CONFLICT (agent_id, group_id) DO NOTHING`,
+		agentID, groupID,
+	)
+	return err
+}
+
+// RevokeGroup отзывает доступ группы к агенту. Если доступа не было, возвращает ErrNoRowsAffected.
+func (r *RepoLayer) RevokeGroup(ctx context.Context, agentID, groupID int) error {
+	row, err := r.dbConn.Exec(ctx,
+		`DELETE FROM privelege_group WHERE agent_id = $1 AND group_id = $2`,
+		agentID, groupID,
+	)
+	if err != nil {
+		return err
+	}
+	if row.RowsAffected() == 0 {
+		return repoErr.ErrNoRowsAffected
+	}
+	return nil
+}
+
+// GetAccessSources возвращает, откуда у пользователя есть доступ к агенту: direct — прямое право,
+// group:<название> — право группы. Пустой список означает, что доступа нет.
+func (r *RepoLayer) GetAccessSources(ctx context.Context, agentID int, username string) ([]string, error) {
+	rows, err := r.dbConn.Query(ctx, sqlRowsAccessSources, agentID, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sources := make([]string, 0)
+	for rows.Next() {
+		var source string
+		if err := rows.Scan(&source); err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+	return sources, rows.Err()
+}
+
+func scanAgent(row pgx.Row) (*ent.Agent, error) {
+	var a ent.Agent
+	err := row.Scan(
+		&a.ID,
+		&a.Name,
+		&a.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
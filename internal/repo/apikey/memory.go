@@ -0,0 +1,134 @@
+package apikey
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/memory"
+	repoErr "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/satori/uuid"
+)
+
+var _ Repo = (*RepoMemory)(nil)
+
+type RepoMemory struct {
+	store *memory.Store
+}
+
+// NewRepoMemory возвращает repository API-ключей, который хранит данные в памяти процесса (storage: memory).
+func NewRepoMemory(store *memory.Store) *RepoMemory {
+	return &RepoMemory{
+		store: store,
+	}
+}
+
+func (r *RepoMemory) Create(ctx context.Context, initData *ent.ApiKey) (*ent.ApiKey, error) {
+	var k ent.ApiKey
+	err := r.store.Write(ctx, func() error {
+		if _, ok := r.store.Users[initData.Username]; !ok {
+			return repoErr.ErrUserNotExist
+		}
+		k = ent.ApiKey{
+			ID:        uuid.NewV4().String(),
+			Username:  initData.Username,
+			Name:      initData.Name,
+			Prefix:    initData.Prefix,
+			KeyHash:   initData.KeyHash,
+			Scopes:    slices.Clone(initData.Scopes),
+			CreatedAt: time.Now(),
+		}
+		r.store.ApiKeys[k.ID] = k
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// GetByHash возвращает ключ вместе с ролью владельца, ключи заблокированного пользователя не принимаются.
+func (r *RepoMemory) GetByHash(ctx context.Context, keyHash string) (*ent.ApiKey, error) {
+	var k ent.ApiKey
+	err := r.store.Read(ctx, func() error {
+		for _, key := range r.store.ApiKeys {
+			if key.KeyHash != keyHash {
+				continue
+			}
+			u, ok := r.store.Users[key.Username]
+			if !ok || u.LockedAt != nil {
+				break
+			}
+			k = key
+			k.OwnerRole = u.Role
+			return nil
+		}
+		return sql.ErrNoRows
+	})
+	if err != nil {
+		return nil, err
+	}
+	k.Scopes = slices.Clone(k.Scopes)
+	return &k, nil
+}
+
+func (r *RepoMemory) ListByUsername(ctx context.Context, username string) ([]*ent.ApiKey, error) {
+	keys := make([]*ent.ApiKey, 0)
+	err := r.store.Read(ctx, func() error {
+		for _, k := range r.store.ApiKeys {
+			if k.Username == username && k.RevokedAt == nil {
+				k.Scopes = slices.Clone(k.Scopes)
+				keys = append(keys, &k)
+			}
+		}
+		return nil
+	})
+	slices.SortFunc(keys, func(a, b *ent.ApiKey) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return keys, err
+}
+
+func (r *RepoMemory) CountActive(ctx context.Context, username string) (int, error) {
+	count := 0
+	err := r.store.Read(ctx, func() error {
+		for _, k := range r.store.ApiKeys {
+			if k.Username == username && k.RevokedAt == nil {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (r *RepoMemory) Revoke(ctx context.Context, id, username string) error {
+	return r.store.Write(ctx, func() error {
+		k, ok := r.store.ApiKeys[id]
+		if !ok || k.Username != username || k.RevokedAt != nil {
+			return repoErr.ErrNoRowsAffected
+		}
+		timeNow := time.Now()
+		k.RevokedAt = &timeNow
+		r.store.ApiKeys[id] = k
+		return nil
+	})
+}
+
+func (r *RepoMemory) TouchLastUsed(ctx context.Context, id string) error {
+	return r.store.Write(ctx, func() error {
+		k, ok := r.store.ApiKeys[id]
+		if !ok {
+			return nil
+		}
+		timeNow := time.Now()
+		if k.LastUsedAt == nil || k.LastUsedAt.Before(timeNow.Add(-time.Minute)) {
+			k.LastUsedAt = &timeNow
+			r.store.ApiKeys[id] = k
+		}
+		return nil
+	})
+}
@@ -0,0 +1,158 @@
+package apikey
+
+import (
+	"context"
+	"fmt"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
+	repoErr "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repo interface {
+	Create(ctx context.Context, initData *ent.ApiKey) (*ent.ApiKey, error)
+	GetByHash(ctx context.Context, keyHash string) (*ent.ApiKey, error)
+	ListByUsername(ctx context.Context, username string) ([]*ent.ApiKey, error)
+	CountActive(ctx context.Context, username string) (int, error)
+	Revoke(ctx context.Context, id, username string) error
+	TouchLastUsed(ctx context.Context, id string) error
+}
+
+var _ Repo = (*RepoLayer)(nil)
+
+type RepoLayer struct {
+	dbConn *transaction.Conn
+}
+
+// NewRepoLayer возвращает структуру уровня repository. Позволяет работать с API-ключами пользователей.
+func NewRepoLayer(dbConn *pgxpool.Pool) *RepoLayer {
+	return &RepoLayer{
+		dbConn: transaction.NewConn(dbConn),
+	}
+}
+
+var (
+	api_key_fields = "id, username, name, prefix, key_hash, scopes, last_used_at, revoked_at, created_at"
+)
+
+var (
+	sqlRowCreateApiKey = fmt.Sprintf(`
+		INSERT INTO api_key (
+			username,
+			name,
+			prefix,
+			key_hash,
+			scopes
+		) VALUES ($1, $2, $3, $4, $5) RETURNING %s`, api_key_fields)
+
+	// ключи заблокированного пользователя не принимаются
+	sqlRowGetByHash = fmt.Sprintf(
+		`SELECT %s, u.role FROM api_key JOIN "user" u USING (username) WHERE key_hash=$1 AND u.locked_at IS NULL`,
+		api_key_fields,
+	)
+
+	sqlRowsListByUsername = fmt.Sprintf(
+		`SELECT %s FROM api_key WHERE username=$1 AND revoked_at IS NULL ORDER BY created_at`,
+		api_key_fields,
+	)
+)
+
+// Create сохраняет новый API-ключ.
+func (r *RepoLayer) Create(ctx context.Context, initData *ent.ApiKey) (*ent.ApiKey, error) {
+	row := r.dbConn.QueryRow(ctx, sqlRowCreateApiKey,
+		initData.Username,
+		initData.Name,
+		initData.Prefix,
+		initData.KeyHash,
+		initData.Scopes,
+	)
+	return scanApiKey(row)
+}
+
+// GetByHash позволяет получить API-ключ по его хэшу вместе с ролью владельца.
+func (r *RepoLayer) GetByHash(ctx context.Context, keyHash string) (*ent.ApiKey, error) {
+	var ownerRole string
+	k, err := scanApiKey(r.dbConn.QueryRow(ctx, sqlRowGetByHash, keyHash), &ownerRole)
+	if err != nil {
+		return nil, err
+	}
+	k.OwnerRole = ownerRole
+	return k, nil
+}
+
+// ListByUsername возвращает действующие API-ключи пользователя.
+func (r *RepoLayer) ListByUsername(ctx context.Context, username string) ([]*ent.ApiKey, error) {
+	rows, err := r.dbConn.Query(ctx, sqlRowsListByUsername, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys := make([]*ent.ApiKey, 0)
+	for rows.Next() {
+		k, err := scanApiKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// CountActive возвращает количество действующих API-ключей пользователя.
+func (r *RepoLayer) CountActive(ctx context.Context, username string) (int, error) {
+	var count int
+	err := r.dbConn.QueryRow(ctx,
+		`SELECT count(*) FROM api_key WHERE username=$1 AND revoked_at IS NULL`,
+		username,
+	).Scan(&count)
+	return count, err
+}
+
+// Revoke отзывает API-ключ пользователя. Если ключ не найден или уже отозван, возвращает ErrNoRowsAffected.
+func (r *RepoLayer) Revoke(ctx context.Context, id, username string) error {
+	row, err := r.dbConn.Exec(ctx,
+		`UPDATE api_key SET revoked_at = now() WHERE id = $1 AND username = $2 AND revoked_at IS NULL`,
+		id, username,
+	)
+	if err != nil {
+		return err
+	}
+	if row.RowsAffected() == 0 {
+		return repoErr.ErrNoRowsAffected
+	}
+	return nil
+}
+
+// TouchLastUsed обновляет время последнего использования ключа. Чтобы не писать в базу на каждый запрос,
+// время обновляется не чаще раза в минуту.
+func (r *RepoLayer) TouchLastUsed(ctx context.Context, id string) error {
+	_, err := r.dbConn.Exec(ctx,
+		`UPDATE api_key SET last_used_at = now()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')`,
+		id,
+	)
+	return err
+}
+
+// scanApiKey сканирует поля api_key_fields, extra принимает значения дополнительных колонок запроса.
+func scanApiKey(row pgx.Row, extra ...any) (*ent.ApiKey, error) {
+	var k ent.ApiKey
+	dest := []any{
+		&k.ID,
+		&k.Username,
+		&k.Name,
+		&k.Prefix,
+		&k.KeyHash,
+		&k.Scopes,
+		&k.LastUsedAt,
+		&k.RevokedAt,
+		&k.CreatedAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
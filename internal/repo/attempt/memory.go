@@ -0,0 +1,77 @@
+package attempt
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+)
+
+var _ Repo = (*RepoMemory)(nil)
+
+type memoryItem struct {
+	attempts  ent.LoginAttempts
+	expiresAt time.Time
+}
+
+type RepoMemory struct {
+	mu        sync.Mutex
+	items     map[string]memoryItem
+	lastSweep time.Time
+}
+
+// sweepInterval как часто удаляются просроченные счетчики.
+const sweepInterval = time.Minute
+
+// NewRepoMemory возвращает хранилище счетчиков в памяти процесса. Используется, когда Memcached отключен.
+// Счетчики не разделяются между экземплярами сервиса.
+func NewRepoMemory() *RepoMemory {
+	return &RepoMemory{
+		items: make(map[string]memoryItem),
+	}
+}
+
+// Get возвращает счетчик попыток. Если счетчика нет, возвращает пустой счетчик.
+func (r *RepoMemory) Get(ctx context.Context, key string) (*ent.LoginAttempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a := r.get(key, time.Now())
+	return &a, nil
+}
+
+// Update атомарно изменяет счетчик функцией fn.
+func (r *RepoMemory) Update(ctx context.Context, key string, ttl time.Duration, fn func(a *ent.LoginAttempts)) (*ent.LoginAttempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	timeNow := time.Now()
+	a := r.get(key, timeNow)
+	fn(&a)
+	r.items[key] = memoryItem{attempts: a, expiresAt: timeNow.Add(ttl)}
+	return &a, nil
+}
+
+// Delete сбрасывает счетчик.
+func (r *RepoMemory) Delete(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.items, key)
+	return nil
+}
+
+// get должен вызываться под мьютексом. Периодически удаляет просроченные счетчики, чтобы map не рос бесконечно.
+func (r *RepoMemory) get(key string, timeNow time.Time) ent.LoginAttempts {
+	if timeNow.Sub(r.lastSweep) > sweepInterval {
+		for k, item := range r.items {
+			if timeNow.After(item.expiresAt) {
+				delete(r.items, k)
+			}
+		}
+		r.lastSweep = timeNow
+	}
+	item, ok := r.items[key]
+	if !ok || timeNow.After(item.expiresAt) {
+		return ent.LoginAttempts{}
+	}
+	return item.attempts
+}
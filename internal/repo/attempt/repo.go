@@ -0,0 +1,105 @@
+package attempt
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+)
+
+type Repo interface {
+	Get(ctx context.Context, key string) (*ent.LoginAttempts, error)
+	Update(ctx context.Context, key string, ttl time.Duration, fn func(a *ent.LoginAttempts)) (*ent.LoginAttempts, error)
+	Delete(ctx context.Context, key string) error
+}
+
+var _ Repo = (*RepoLayer)(nil)
+
+type RepoLayer struct {
+	mcClient *memcache.Client
+}
+
+// NewRepoLayer возвращает структуру уровня repository. Хранит счетчики неудачных попыток входа в Memcached.
+func NewRepoLayer(mcClient *memcache.Client) *RepoLayer {
+	return &RepoLayer{
+		mcClient: mcClient,
+	}
+}
+
+// maxCasAttempts количество попыток обновить счетчик, если его параллельно изменил другой запрос.
+const maxCasAttempts = 5
+
+// Get возвращает счетчик попыток. Если счетчика нет, возвращает пустой счетчик.
+func (r *RepoLayer) Get(ctx context.Context, key string) (*ent.LoginAttempts, error) {
+	item, err := r.mcClient.Get(memcacheKey(key))
+	if err != nil {
+		if errors.Is(err, memcache.ErrCacheMiss) {
+			return &ent.LoginAttempts{}, nil
+		}
+		return nil, err
+	}
+	var a ent.LoginAttempts
+	err = json.Unmarshal(item.Value, &a)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Update атомарно (compare-and-swap) изменяет счетчик функцией fn.
+func (r *RepoLayer) Update(ctx context.Context, key string, ttl time.Duration, fn func(a *ent.LoginAttempts)) (*ent.LoginAttempts, error) {
+	mKey := memcacheKey(key)
+	for i := 0; i < maxCasAttempts; i++ {
+		item, err := r.mcClient.Get(mKey)
+		if err != nil && !errors.Is(err, memcache.ErrCacheMiss) {
+			return nil, err
+		}
+		var a ent.LoginAttempts
+		if item != nil {
+			err = json.Unmarshal(item.Value, &a)
+			if err != nil {
+				return nil, err
+			}
+		}
+		fn(&a)
+		value, err := json.Marshal(a)
+		if err != nil {
+			return nil, err
+		}
+		if item == nil {
+			err = r.mcClient.Add(&memcache.Item{Key: mKey, Value: value, Expiration: int32(ttl.Seconds())})
+		} else {
+			item.Value = value
+			item.Expiration = int32(ttl.Seconds())
+			err = r.mcClient.CompareAndSwap(item)
+		}
+		if errors.Is(err, memcache.ErrNotStored) || errors.Is(err, memcache.ErrCASConflict) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &a, nil
+	}
+	return nil, memcache.ErrCASConflict
+}
+
+// Delete сбрасывает счетчик.
+func (r *RepoLayer) Delete(ctx context.Context, key string) error {
+	err := r.mcClient.Delete(memcacheKey(key))
+	if err != nil && !errors.Is(err, memcache.ErrCacheMiss) {
+		return err
+	}
+	return nil
+}
+
+// memcacheKey ключи Memcached ограничены 250 байтами и не могут содержать пробелы, поэтому храним хэш.
+func memcacheKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "login_attempts:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,221 @@
+package attempt
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+)
+
+type fakeItem struct {
+	value []byte
+	cas   uint64
+}
+
+// fakeMemcached сервер с текстовым протоколом Memcached, которого достаточно для RepoLayer: gets, add, cas и
+// delete. Перед каждой из первых conflicts команд cas значение подменяется concurrent, как если бы его
+// изменил другой экземпляр сервиса.
+type fakeMemcached struct {
+	mu         sync.Mutex
+	items      map[string]fakeItem
+	seq        uint64
+	conflicts  int
+	concurrent []byte
+}
+
+func newFakeMemcached(t *testing.T) (*fakeMemcached, *memcache.Client) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	fake := &fakeMemcached{items: make(map[string]fakeItem)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go fake.serve(conn)
+		}
+	}()
+	return fake, memcache.New(ln.Addr().String())
+}
+
+func (f *fakeMemcached) serve(conn net.Conn) {
+	defer conn.Close()
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return
+		}
+		var data []byte
+		if fields[0] == "add" || fields[0] == "cas" {
+			size, _ := strconv.Atoi(fields[4])
+			data = make([]byte, size+2)
+			if _, err := io.ReadFull(rw, data); err != nil {
+				return
+			}
+			data = data[:size]
+		}
+		f.mu.Lock()
+		switch fields[0] {
+		case "gets", "get":
+			for _, key := range fields[1:] {
+				if item, ok := f.items[key]; ok {
+					fmt.Fprintf(rw, "VALUE %s 0 %d %d\r\n%s\r\n", key, len(item.value), item.cas, item.value)
+				}
+			}
+			fmt.Fprint(rw, "END\r\n")
+		case "add":
+			if _, ok := f.items[fields[1]]; ok {
+				fmt.Fprint(rw, "NOT_STORED\r\n")
+				break
+			}
+			f.store(fields[1], data)
+			fmt.Fprint(rw, "STORED\r\n")
+		case "cas":
+			if f.conflicts > 0 {
+				f.conflicts--
+				f.store(fields[1], f.concurrent)
+			}
+			item, ok := f.items[fields[1]]
+			cas, _ := strconv.ParseUint(fields[5], 10, 64)
+			switch {
+			case !ok:
+				fmt.Fprint(rw, "NOT_FOUND\r\n")
+			case item.cas != cas:
+				fmt.Fprint(rw, "EXISTS\r\n")
+			default:
+				f.store(fields[1], data)
+				fmt.Fprint(rw, "STORED\r\n")
+			}
+		case "delete":
+			delete(f.items, fields[1])
+			fmt.Fprint(rw, "DELETED\r\n")
+		default:
+			fmt.Fprint(rw, "ERROR\r\n")
+		}
+		f.mu.Unlock()
+		if rw.Flush() != nil {
+			return
+		}
+	}
+}
+
+// store должен вызываться под мьютексом.
+func (f *fakeMemcached) store(key string, value []byte) {
+	f.seq++
+	f.items[key] = fakeItem{value: value, cas: f.seq}
+}
+
+func increment(calls *int) func(a *ent.LoginAttempts) {
+	return func(a *ent.LoginAttempts) {
+		*calls++
+		a.Failures++
+	}
+}
+
+func TestRepoLayerUpdate(t *testing.T) {
+	_, client := newFakeMemcached(t)
+	r := NewRepoLayer(client)
+	ctx := context.Background()
+
+	var calls int
+	for want := 1; want <= 3; want++ {
+		a, err := r.Update(ctx, "account:ivan", time.Hour, increment(&calls))
+		if err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+		if a.Failures != want {
+			t.Fatalf("Update() failures = %d, want %d", a.Failures, want)
+		}
+	}
+	a, err := r.Get(ctx, "account:ivan")
+	if err != nil || a.Failures != 3 {
+		t.Fatalf("Get() = %+v, %v, want 3 failures", a, err)
+	}
+	if err := r.Delete(ctx, "account:ivan"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if a, err := r.Get(ctx, "account:ivan"); err != nil || a.Failures != 0 {
+		t.Fatalf("Get() after Delete() = %+v, %v, want empty counter", a, err)
+	}
+}
+
+// При конфликте записи счетчик перечитывается, и изменение применяется к значению другого экземпляра.
+func TestRepoLayerUpdateRetriesOnConflict(t *testing.T) {
+	fake, client := newFakeMemcached(t)
+	r := NewRepoLayer(client)
+	ctx := context.Background()
+	var calls int
+	if _, err := r.Update(ctx, "account:ivan", time.Hour, increment(&calls)); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.mu.Lock()
+	fake.conflicts, fake.concurrent = 2, []byte(`{"failures":5}`)
+	fake.mu.Unlock()
+	calls = 0
+	a, err := r.Update(ctx, "account:ivan", time.Hour, increment(&calls))
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if a.Failures != 6 || calls != 3 {
+		t.Fatalf("Update() = %+v after %d calls, want 6 failures after 3 calls", a, calls)
+	}
+}
+
+func TestRepoLayerUpdateGivesUp(t *testing.T) {
+	fake, client := newFakeMemcached(t)
+	r := NewRepoLayer(client)
+	ctx := context.Background()
+	var calls int
+	if _, err := r.Update(ctx, "account:ivan", time.Hour, increment(&calls)); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.mu.Lock()
+	fake.conflicts, fake.concurrent = maxCasAttempts, []byte(`{"failures":5}`)
+	fake.mu.Unlock()
+	calls = 0
+	_, err := r.Update(ctx, "account:ivan", time.Hour, increment(&calls))
+	if !errors.Is(err, memcache.ErrCASConflict) {
+		t.Fatalf("Update() error = %v, want %v", err, memcache.ErrCASConflict)
+	}
+	if calls != maxCasAttempts {
+		t.Errorf("fn called %d times, want %d", calls, maxCasAttempts)
+	}
+}
+
+func TestRepoMemoryExpiry(t *testing.T) {
+	r := NewRepoMemory()
+	ctx := context.Background()
+	var calls int
+	if _, err := r.Update(ctx, "ip:10.0.0.1", 20*time.Millisecond, increment(&calls)); err != nil {
+		t.Fatal(err)
+	}
+	if a, _ := r.Get(ctx, "ip:10.0.0.1"); a.Failures != 1 {
+		t.Fatalf("Get() failures = %d, want 1", a.Failures)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if a, _ := r.Get(ctx, "ip:10.0.0.1"); a.Failures != 0 {
+		t.Errorf("Get() after ttl failures = %d, want 0", a.Failures)
+	}
+}
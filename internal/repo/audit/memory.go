@@ -0,0 +1,78 @@
+package audit
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/memory"
+)
+
+var _ Repo = (*RepoMemory)(nil)
+
+type RepoMemory struct {
+	store *memory.Store
+}
+
+// NewRepoMemory возвращает repository журнала действий, который хранит данные в памяти процесса (storage: memory).
+func NewRepoMemory(store *memory.Store) *RepoMemory {
+	return &RepoMemory{
+		store: store,
+	}
+}
+
+func (r *RepoMemory) Create(ctx context.Context, e *ent.AuditEvent) error {
+	return r.store.Write(ctx, func() error {
+		event := *e
+		event.ID = int64(r.store.NextID())
+		event.Details = maps.Clone(e.Details)
+		event.CreatedAt = time.Now()
+		r.store.Audit = append(r.store.Audit, event)
+		return nil
+	})
+}
+
+// List возвращает записи в том же порядке, что и RepoLayer.List. Записи добавляются в хронологическом порядке,
+// поэтому журнал читается с конца.
+func (r *RepoMemory) List(ctx context.Context, filter *ent.AuditFilter) ([]*ent.AuditEvent, int, error) {
+	events := make([]*ent.AuditEvent, 0)
+	total := 0
+	err := r.store.Read(ctx, func() error {
+		for i := len(r.store.Audit) - 1; i >= 0; i-- {
+			e := r.store.Audit[i]
+			if !matchEvent(&e, filter) {
+				continue
+			}
+			if total >= filter.Offset && len(events) < filter.Limit {
+				e.Details = maps.Clone(e.Details)
+				events = append(events, &e)
+			}
+			total++
+		}
+		return nil
+	})
+	return events, total, err
+}
+
+// matchEvent проверяет запись по тем же условиям, что и auditFilterCondition.
+func matchEvent(e *ent.AuditEvent, filter *ent.AuditFilter) bool {
+	if filter.Actor != "" && e.Actor != filter.Actor {
+		return false
+	}
+	if len(filter.Subjects) > 0 && !slices.Contains(filter.Subjects, e.Subject) {
+		return false
+	}
+	if filter.Action != "" && e.Action != filter.Action && !strings.HasPrefix(e.Action, filter.Action+".") {
+		return false
+	}
+	if filter.From != nil && e.CreatedAt.Before(*filter.From) {
+		return false
+	}
+	if filter.To != nil && !e.CreatedAt.Before(*filter.To) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repo interface {
+	Create(ctx context.Context, e *ent.AuditEvent) error
+	List(ctx context.Context, filter *ent.AuditFilter) ([]*ent.AuditEvent, int, error)
+}
+
+var _ Repo = (*RepoLayer)(nil)
+
+type RepoLayer struct {
+	dbConn *transaction.Conn
+}
+
+// NewRepoLayer возвращает структуру уровня repository. Позволяет вести журнал действий.
+func NewRepoLayer(dbConn *pgxpool.Pool) *RepoLayer {
+	return &RepoLayer{
+		dbConn: transaction.NewConn(dbConn),
+	}
+}
+
+var (
+	audit_event_fields = "id, COALESCE(actor, ''), action, COALESCE(subject, ''), COALESCE(ip, ''), " +
+		"COALESCE(user_agent, ''), COALESCE(request_id, ''), details, created_at"
+)
+
+// Create добавляет запись в журнал. Записи журнала нельзя изменить или удалить.
+func (r *RepoLayer) Create(ctx context.Context, e *ent.AuditEvent) error {
+	_, err := r.dbConn.Exec(ctx, `
+		INSERT INTO audit_event (actor, action, subject, ip, user_agent, request_id, details)
+		VALUES (NULLIF($1, ''), $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7)`,
+		e.Actor, e.Action, e.Subject, e.IP, e.UserAgent, e.RequestID, e.Details,
+	)
+	return err
+}
+
+// List возвращает страницу записей журнала, подходящих под фильтр, начиная с последней, и общее количество
+// таких записей.
+func (r *RepoLayer) List(ctx context.Context, filter *ent.AuditFilter) ([]*ent.AuditEvent, int, error) {
+	where, args := auditFilterCondition(filter)
+	var total int
+	err := r.dbConn.QueryRow(ctx, `SELECT count(*) FROM audit_event WHERE `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	rows, err := r.dbConn.Query(ctx,
+		fmt.Sprintf(`SELECT %s FROM audit_event WHERE %s ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d`,
+			audit_event_fields, where, len(args)-1, len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	events := make([]*ent.AuditEvent, 0)
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		events = append(events, e)
+	}
+	return events, total, rows.Err()
+}
+
+// auditFilterCondition собирает условие WHERE и его параметры из фильтра.
+func auditFilterCondition(filter *ent.AuditFilter) (string, []any) {
+	conditions := []string{"true"}
+	args := make([]any, 0)
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Actor != "" {
+		add(`actor = $%d`, filter.Actor)
+	}
+	if len(filter.Subjects) > 0 {
+		add(`subject = ANY($%d)`, filter.Subjects)
+	}
+	// действие ищется точно или по группе: "auth" найдет auth.signin.success и auth.signout
+	if filter.Action != "" {
+		add(`(action = $%[1]d OR starts_with(action, $%[1]d || '.'))`, filter.Action)
+	}
+	if filter.From != nil {
+		add(`created_at >= $%d`, *filter.From)
+	}
+	if filter.To != nil {
+		add(`created_at < $%d`, *filter.To)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+func scanEvent(row pgx.Row) (*ent.AuditEvent, error) {
+	var e ent.AuditEvent
+	err := row.Scan(
+		&e.ID,
+		&e.Actor,
+		&e.Action,
+		&e.Subject,
+		&e.IP,
+		&e.UserAgent,
+		&e.RequestID,
+		&e.Details,
+		&e.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
@@ -0,0 +1,242 @@
+package coach
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/memory"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	repoErr "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/satori/uuid"
+)
+
+var _ Repo = (*RepoMemory)(nil)
+
+type RepoMemory struct {
+	store *memory.Store
+}
+
+// NewRepoMemory возвращает repository связей тренеров и клиентов, который хранит данные в памяти процесса
+// (storage: memory).
+func NewRepoMemory(store *memory.Store) *RepoMemory {
+	return &RepoMemory{
+		store: store,
+	}
+}
+
+func (r *RepoMemory) Create(ctx context.Context, clientUsername, coachUsername string, scopes []string) (*ent.CoachLink, error) {
+	var l ent.CoachLink
+	err := r.store.Write(ctx, func() error {
+		_, clientOk := r.store.Users[clientUsername]
+		_, coachOk := r.store.Users[coachUsername]
+		if !clientOk || !coachOk {
+			return repoErr.ErrUserNotExist
+		}
+		if _, ok := r.link(clientUsername, coachUsername); ok {
+			return repoErr.ErrCoachLinkExist
+		}
+		timeNow := time.Now()
+		l = ent.CoachLink{
+			ID:             uuid.NewV4().String(),
+			ClientUsername: clientUsername,
+			CoachUsername:  coachUsername,
+			Status:         mc.CoachLinkPending,
+			Scopes:         slices.Clone(scopes),
+			CreatedAt:      timeNow,
+			UpdatedAt:      timeNow,
+		}
+		r.store.CoachLinks[l.ID] = l
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cloneLink(l), nil
+}
+
+func (r *RepoMemory) Get(ctx context.Context, clientUsername, coachUsername string) (*ent.CoachLink, error) {
+	var l ent.CoachLink
+	err := r.store.Read(ctx, func() error {
+		link, ok := r.link(clientUsername, coachUsername)
+		if !ok {
+			return sql.ErrNoRows
+		}
+		l = link
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cloneLink(l), nil
+}
+
+func (r *RepoMemory) ListByClient(ctx context.Context, clientUsername string) ([]*ent.CoachLink, error) {
+	return r.listLinks(ctx, func(l *ent.CoachLink) bool {
+		return l.ClientUsername == clientUsername && l.Status != mc.CoachLinkRevoked
+	})
+}
+
+func (r *RepoMemory) ListByCoach(ctx context.Context, coachUsername, status string) ([]*ent.CoachLink, error) {
+	return r.listLinks(ctx, func(l *ent.CoachLink) bool {
+		return l.CoachUsername == coachUsername && l.Status == status
+	})
+}
+
+func (r *RepoMemory) GetCalorieTarget(ctx context.Context, clientUsername string) (*ent.CoachLink, error) {
+	links, err := r.listLinks(ctx, func(l *ent.CoachLink) bool {
+		return l.ClientUsername == clientUsername && l.Status == mc.CoachLinkActive && l.CalorieTarget != nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(links) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return slices.MaxFunc(links, func(a, b *ent.CoachLink) int {
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	}), nil
+}
+
+func (r *RepoMemory) Activate(ctx context.Context, id string) (*ent.CoachLink, error) {
+	return r.update(ctx, id, func(l *ent.CoachLink) bool {
+		if l.Status != mc.CoachLinkPending {
+			return false
+		}
+		l.Status = mc.CoachLinkActive
+		return true
+	})
+}
+
+func (r *RepoMemory) Revoke(ctx context.Context, id string) error {
+	_, err := r.update(ctx, id, func(l *ent.CoachLink) bool {
+		if l.Status == mc.CoachLinkRevoked {
+			return false
+		}
+		l.Status = mc.CoachLinkRevoked
+		return true
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return repoErr.ErrNoRowsAffected
+	}
+	return err
+}
+
+func (r *RepoMemory) UpdateScopes(ctx context.Context, id string, scopes []string) (*ent.CoachLink, error) {
+	return r.update(ctx, id, func(l *ent.CoachLink) bool {
+		if l.Status == mc.CoachLinkRevoked {
+			return false
+		}
+		l.Scopes = slices.Clone(scopes)
+		return true
+	})
+}
+
+func (r *RepoMemory) UpdateCalorieTarget(ctx context.Context, id string, target *float32) (*ent.CoachLink, error) {
+	return r.update(ctx, id, func(l *ent.CoachLink) bool {
+		if l.Status != mc.CoachLinkActive {
+			return false
+		}
+		l.CalorieTarget = nil
+		if target != nil {
+			value := *target
+			l.CalorieTarget = &value
+		}
+		return true
+	})
+}
+
+func (r *RepoMemory) CreateComment(ctx context.Context, linkID, text string) (*ent.CoachComment, error) {
+	var c ent.CoachComment
+	err := r.store.Write(ctx, func() error {
+		l, ok := r.store.CoachLinks[linkID]
+		if !ok {
+			return repoErr.ErrCoachLinkNotExist
+		}
+		comment := memory.CoachComment{ID: r.store.NextID(), LinkID: linkID, Text: text, CreatedAt: time.Now()}
+		r.store.CoachComments = append(r.store.CoachComments, comment)
+		c = ent.CoachComment{ID: comment.ID, CoachUsername: l.CoachUsername, Text: text, CreatedAt: comment.CreatedAt}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *RepoMemory) ListComments(ctx context.Context, linkID string) ([]*ent.CoachComment, error) {
+	comments := make([]*ent.CoachComment, 0)
+	err := r.store.Read(ctx, func() error {
+		l := r.store.CoachLinks[linkID]
+		for i := len(r.store.CoachComments) - 1; i >= 0; i-- {
+			c := r.store.CoachComments[i]
+			if c.LinkID == linkID {
+				comments = append(comments, &ent.CoachComment{
+					ID:            c.ID,
+					CoachUsername: l.CoachUsername,
+					Text:          c.Text,
+					CreatedAt:     c.CreatedAt,
+				})
+			}
+		}
+		return nil
+	})
+	return comments, err
+}
+
+// update изменяет связь функцией fn, которая возвращает false, если связь в этом статусе менять нельзя.
+// Тогда, как и при отсутствии связи, возвращает sql.ErrNoRows.
+func (r *RepoMemory) update(ctx context.Context, id string, fn func(l *ent.CoachLink) bool) (*ent.CoachLink, error) {
+	var l ent.CoachLink
+	err := r.store.Write(ctx, func() error {
+		link, ok := r.store.CoachLinks[id]
+		if !ok || !fn(&link) {
+			return sql.ErrNoRows
+		}
+		link.UpdatedAt = time.Now()
+		r.store.CoachLinks[id] = link
+		l = link
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cloneLink(l), nil
+}
+
+// listLinks возвращает связи, для которых match возвращает true, в порядке создания.
+func (r *RepoMemory) listLinks(ctx context.Context, match func(l *ent.CoachLink) bool) ([]*ent.CoachLink, error) {
+	links := make([]*ent.CoachLink, 0)
+	err := r.store.Read(ctx, func() error {
+		for _, l := range r.store.CoachLinks {
+			if match(&l) {
+				links = append(links, cloneLink(l))
+			}
+		}
+		return nil
+	})
+	slices.SortFunc(links, func(a, b *ent.CoachLink) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return links, err
+}
+
+// link возвращает действующую связь или приглашение пары, должен вызываться под блокировкой хранилища.
+func (r *RepoMemory) link(clientUsername, coachUsername string) (ent.CoachLink, bool) {
+	for _, l := range r.store.CoachLinks {
+		if l.ClientUsername == clientUsername && l.CoachUsername == coachUsername && l.Status != mc.CoachLinkRevoked {
+			return l, true
+		}
+	}
+	return ent.CoachLink{}, false
+}
+
+// cloneLink копирует связь вместе со списком открытых данных, чтобы вызывающая сторона не изменила хранилище.
+func cloneLink(l ent.CoachLink) *ent.CoachLink {
+	l.Scopes = slices.Clone(l.Scopes)
+	return &l
+}
@@ -0,0 +1,236 @@
+package coach
+
+import (
+	"context"
+	"fmt"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	repoErr "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repo interface {
+	Create(ctx context.Context, clientUsername, coachUsername string, scopes []string) (*ent.CoachLink, error)
+	Get(ctx context.Context, clientUsername, coachUsername string) (*ent.CoachLink, error)
+	ListByClient(ctx context.Context, clientUsername string) ([]*ent.CoachLink, error)
+	ListByCoach(ctx context.Context, coachUsername, status string) ([]*ent.CoachLink, error)
+	GetCalorieTarget(ctx context.Context, clientUsername string) (*ent.CoachLink, error)
+
+	Activate(ctx context.Context, id string) (*ent.CoachLink, error)
+	Revoke(ctx context.Context, id string) error
+	UpdateScopes(ctx context.Context, id string, scopes []string) (*ent.CoachLink, error)
+	UpdateCalorieTarget(ctx context.Context, id string, target *float32) (*ent.CoachLink, error)
+
+	CreateComment(ctx context.Context, linkID, text string) (*ent.CoachComment, error)
+	ListComments(ctx context.Context, linkID string) ([]*ent.CoachComment, error)
+}
+
+var _ Repo = (*RepoLayer)(nil)
+
+type RepoLayer struct {
+	dbConn *transaction.Conn
+}
+
+// NewRepoLayer возвращает структуру уровня repository. Позволяет работать со связями тренеров и клиентов.
+func NewRepoLayer(dbConn *pgxpool.Pool) *RepoLayer {
+	return &RepoLayer{
+		dbConn: transaction.NewConn(dbConn),
+	}
+}
+
+var (
+	coach_link_fields = "id, client_username, coach_username, status, scopes, calorie_target, created_at, updated_at"
+)
+
+var (
+	sqlRowCreateLink = fmt.Sprintf(`
+		INSERT INTO coach_link (
+			client_username,
+			coach_username,
+			scopes
+		) VALUES ($1, $2, $3) RETURNING %s`, coach_link_fields)
+
+	// в выборках участвуют только действующие связи и приглашения, отозванные хранятся для истории
+	sqlRowGetLink = fmt.Sprintf(
+		`SELECT %s FROM coach_link WHERE client_username = $1 AND coach_username = $2 AND status <> 'revoked'`,
+		coach_link_fields,
+	)
+
+	sqlRowsByClient = fmt.Sprintf(
+		`SELECT %s FROM coach_link WHERE client_username = $1 AND status <> 'revoked' ORDER BY created_at`,
+		coach_link_fields,
+	)
+
+	sqlRowsByCoach = fmt.Sprintf(
+		`SELECT %s FROM coach_link WHERE coach_username = $1 AND status = $2 ORDER BY created_at`,
+		coach_link_fields,
+	)
+
+	sqlRowCalorieTarget = fmt.Sprintf(`
+		SELECT %s FROM coach_link
+		WHERE client_username = $1 AND status = 'active' AND calorie_target IS NOT NULL
+		ORDER BY updated_at DESC LIMIT 1`, coach_link_fields)
+
+	sqlRowActivate = fmt.Sprintf(
+		`UPDATE coach_link SET status = 'active' WHERE id = $1 AND status = 'pending' RETURNING %s`,
+		coach_link_fields,
+	)
+
+	sqlRowUpdateScopes = fmt.Sprintf(
+		`UPDATE coach_link SET scopes = $2 WHERE id = $1 AND status <> 'revoked' RETURNING %s`,
+		coach_link_fields,
+	)
+
+	sqlRowUpdateCalorieTarget = fmt.Sprintf(
+		`UPDATE coach_link SET calorie_target = $2 WHERE id = $1 AND status = 'active' RETURNING %s`,
+		coach_link_fields,
+	)
+)
+
+// Create создает приглашение тренеру. Если у пары уже есть приглашение или связь, возвращает ErrCoachLinkExist.
+func (r *RepoLayer) Create(ctx context.Context, clientUsername, coachUsername string, scopes []string) (*ent.CoachLink, error) {
+	l, err := scanLink(r.dbConn.QueryRow(ctx, sqlRowCreateLink, clientUsername, coachUsername, scopes))
+	if f.IsPgError(err, mc.PgUniqueViolation) {
+		return nil, repoErr.ErrCoachLinkExist
+	}
+	return l, err
+}
+
+// Get возвращает действующую связь или приглашение пары клиент-тренер.
+func (r *RepoLayer) Get(ctx context.Context, clientUsername, coachUsername string) (*ent.CoachLink, error) {
+	return scanLink(r.dbConn.QueryRow(ctx, sqlRowGetLink, clientUsername, coachUsername))
+}
+
+// ListByClient возвращает тренеров клиента и отправленные им приглашения.
+func (r *RepoLayer) ListByClient(ctx context.Context, clientUsername string) ([]*ent.CoachLink, error) {
+	return r.listLinks(ctx, sqlRowsByClient, clientUsername)
+}
+
+// ListByCoach возвращает связи тренера с указанным статусом.
+func (r *RepoLayer) ListByCoach(ctx context.Context, coachUsername, status string) ([]*ent.CoachLink, error) {
+	return r.listLinks(ctx, sqlRowsByCoach, coachUsername, status)
+}
+
+// GetCalorieTarget возвращает связь, норма калорий которой действует для клиента. Если норм задано несколько,
+// действует последняя измененная.
+func (r *RepoLayer) GetCalorieTarget(ctx context.Context, clientUsername string) (*ent.CoachLink, error) {
+	return scanLink(r.dbConn.QueryRow(ctx, sqlRowCalorieTarget, clientUsername))
+}
+
+// Activate принимает приглашение. Если приглашения нет, возвращает sql.ErrNoRows.
+func (r *RepoLayer) Activate(ctx context.Context, id string) (*ent.CoachLink, error) {
+	return scanLink(r.dbConn.QueryRow(ctx, sqlRowActivate, id))
+}
+
+// Revoke разрывает связь или отклоняет приглашение. Если связь уже разорвана, возвращает ErrNoRowsAffected.
+func (r *RepoLayer) Revoke(ctx context.Context, id string) error {
+	row, err := r.dbConn.Exec(ctx,
+		`UPDATE coach_link SET status = 'revoked' WHERE id = $1 AND status <> 'revoked'`,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	if row.RowsAffected() == 0 {
+		return repoErr.ErrNoRowsAffected
+	}
+	return nil
+}
+
+// UpdateScopes меняет данные, которые клиент открыл тренеру.
+func (r *RepoLayer) UpdateScopes(ctx context.Context, id string, scopes []string) (*ent.CoachLink, error) {
+	return scanLink(r.dbConn.QueryRow(ctx, sqlRowUpdateScopes, id, scopes))
+}
+
+// UpdateCalorieTarget задает норму калорий клиента, nil отменяет норму тренера.
+func (r *RepoLayer) UpdateCalorieTarget(ctx context.Context, id string, target *float32) (*ent.CoachLink, error) {
+	return scanLink(r.dbConn.QueryRow(ctx, sqlRowUpdateCalorieTarget, id, target))
+}
+
+// CreateComment сохраняет комментарий тренера.
+func (r *RepoLayer) CreateComment(ctx context.Context, linkID, text string) (*ent.CoachComment, error) {
+	row := r.dbConn.QueryRow(ctx, `
+		WITH c AS (
+			INSERT INTO coach_comment (link_id, text) VALUES ($1, $2) RETURNING *
+		)
+		SELECT c.id, l.coach_username, c.text, c.created_at FROM c JOIN coach_link l ON l.id = c.link_id`,
+		linkID, text,
+	)
+	return scanComment(row)
+}
+
+// ListComments возвращает комментарии тренера клиенту, начиная с последнего.
+func (r *RepoLayer) ListComments(ctx context.Context, linkID string) ([]*ent.CoachComment, error) {
+	rows, err := r.dbConn.Query(ctx, `
+		SELECT c.id, l.coach_username, c.text, c.created_at FROM coach_comment c
+		JOIN coach_link l ON l.id = c.link_id
+		WHERE c.link_id = $1 ORDER BY c.created_at DESC`,
+		linkID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	comments := make([]*ent.CoachComment, 0)
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+func (r *RepoLayer) listLinks(ctx context.Context, query string, args ...any) ([]*ent.CoachLink, error) {
+	rows, err := r.dbConn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	links := make([]*ent.CoachLink, 0)
+	for rows.Next() {
+		l, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+func scanLink(row pgx.Row) (*ent.CoachLink, error) {
+	var l ent.CoachLink
+	err := row.Scan(
+		&l.ID,
+		&l.ClientUsername,
+		&l.CoachUsername,
+		&l.Status,
+		&l.Scopes,
+		&l.CalorieTarget,
+		&l.CreatedAt,
+		&l.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func scanComment(row pgx.Row) (*ent.CoachComment, error) {
+	var c ent.CoachComment
+	err := row.Scan(
+		&c.ID,
+		&c.CoachUsername,
+		&c.Text,
+		&c.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,952 @@
+package repo_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/attempt"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/measurement"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	mongodb "github.com/cantylv/hackathon-bmstu-2024-healthcheck/services/mongo"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/services/postgres/migrations"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+)
+
+// postgresDSNEnv, mongoDSNEnv и memcachedAddrEnv переменные окружения с адресами PostgreSQL, MongoDB и Memcached
+// для контрактных тестов. Без них тесты соответствующего хранилища пропускаются.
+const (
+	postgresDSNEnv   = "HC_TEST_POSTGRES_DSN"
+	mongoDSNEnv      = "HC_TEST_MONGO_DSN"
+	memcachedAddrEnv = "HC_TEST_MEMCACHED_ADDR"
+)
+
+// Контракт репозиториев одинаков для всех хранилищ: хранилище в памяти должно вести себя как PostgreSQL,
+// иначе режим разработки проверяет не то, что работает в production.
+var contract = []struct {
+	name string
+	run  func(t *testing.T, r *repo.Repos)
+}{
+	{name: "user create and get", run: testUserCreateAndGet},
+	{name: "user unique username and email", run: testUserUnique},
+	{name: "user update and delete", run: testUserUpdateAndDelete},
+	{name: "user list", run: testUserList},
+	{name: "measurements", run: testMeasurements},
+	{name: "coach links", run: testCoachLinks},
+	{name: "refresh tokens", run: testRefreshTokens},
+	{name: "totp and recovery codes", run: testMfa},
+	{name: "api keys", run: testApiKeys},
+	{name: "external identities", run: testIdentities},
+	{name: "group bids", run: testGroupBids},
+	{name: "group members", run: testGroupMembers},
+	{name: "agents and access", run: testAgents},
+	{name: "audit events", run: testAudit},
+	{name: "transaction rollback", run: testTransactionRollback},
+	{name: "transaction commit", run: testTransactionCommit},
+}
+
+func runContract(t *testing.T, newRepos func(t *testing.T) *repo.Repos) {
+	for _, tt := range contract {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepos(t))
+		})
+	}
+}
+
+func TestMemoryRepos(t *testing.T) {
+	runContract(t, func(*testing.T) *repo.Repos {
+		return repo.NewMemory()
+	})
+}
+
+func TestPostgresRepos(t *testing.T) {
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", postgresDSNEnv)
+	}
+	runContract(t, func(t *testing.T) *repo.Repos {
+		return repo.NewPostgres(newPostgresPool(t, dsn))
+	})
+}
+
+// Измерения в MongoDB проверяются тем же контрактом. Остальные репозитории хранятся в памяти: измерения
+// MongoDB не связаны с ними внешними ключами.
+func TestMongoRepos(t *testing.T) {
+	dsn := os.Getenv(mongoDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", mongoDSNEnv)
+	}
+	client, err := mongo.Connect(options.Client().ApplyURI(dsn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := client.Disconnect(context.Background()); err != nil {
+			t.Errorf("disconnect: %v", err)
+		}
+	})
+	runContract(t, func(t *testing.T) *repo.Repos {
+		r := repo.NewMemory()
+		r.Measurement = measurement.NewRepoMongo(newMongoCollection(t, client))
+		return r
+	})
+}
+
+// Счетчики попыток входа хранятся не в Repos: в памяти процесса или в Memcached, поэтому у них свой запуск
+// контракта.
+func TestMemoryAttempts(t *testing.T) {
+	testAttempts(t, attempt.NewRepoMemory())
+}
+
+func TestMemcachedAttempts(t *testing.T) {
+	addr := os.Getenv(memcachedAddrEnv)
+	if addr == "" {
+		t.Skipf("%s is not set", memcachedAddrEnv)
+	}
+	testAttempts(t, attempt.NewRepoLayer(memcache.New(addr)))
+}
+
+// newPostgresPool создает для теста отдельную схему, применяет к ней миграции и возвращает пул, соединения
+// которого работают в этой схеме. Схема удаляется после теста.
+func newPostgresPool(t *testing.T, dsn string) *pgxpool.Pool {
+	t.Helper()
+	ctx := context.Background()
+	admin, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(admin.Close)
+	schema := fmt.Sprintf("contract_%d", time.Now().UnixNano())
+	if _, err = admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE"); err != nil {
+			t.Errorf("drop schema %s: %v", schema, err)
+		}
+	})
+
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	migrator, err := migrations.New(pool, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = migrator.Up(ctx, false); err != nil {
+		t.Fatalf("migrations: %v", err)
+	}
+	return pool
+}
+
+// newMongoCollection создает для теста коллекцию временных рядов в отдельной базе. База удаляется после теста.
+func newMongoCollection(t *testing.T, client *mongo.Client) *mongo.Collection {
+	t.Helper()
+	ctx := context.Background()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("mongo.database", fmt.Sprintf("contract_%d", time.Now().UnixNano()))
+	viper.Set("measurements.mongo.collection", "measurements")
+	viper.Set("measurements.mongo.granularity", "hours")
+
+	coll, err := mongodb.EnsureTimeSeries(ctx, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := coll.Database().Drop(ctx); err != nil {
+			t.Errorf("drop database %s: %v", coll.Database().Name(), err)
+		}
+	})
+	return coll
+}
+
+func createUser(t *testing.T, r *repo.Repos, username, email string) *ent.User {
+	t.Helper()
+	u, err := r.User.Create(context.Background(), &ent.User{
+		Username: username, Email: email, FirstName: "Pavel", Weight: 80, Height: 180, Age: 30, Sex: "M",
+		PhysicalActivity: "MA", DayCalories: 2500.7, Password: "hash",
+	})
+	if err != nil {
+		t.Fatalf("Create(%s) error = %v", username, err)
+	}
+	return u
+}
+
+func wantErr(t *testing.T, op string, err, want error) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Fatalf("%s error = %v, want %v", op, err, want)
+	}
+}
+
+func testUserCreateAndGet(t *testing.T, r *repo.Repos) {
+	ctx := context.Background()
+	created := createUser(t, r, "ivan", "ivan@bmstu.ru")
+	if created.ID == "" || created.Role != mc.RoleUser || created.DayCalories != 2500 || created.CreatedAt.IsZero() {
+		t.Fatalf("Create() = %+v, want id, role user, whole day calories and creation time", created)
+	}
+
+	u, err := r.User.GetByUsername(ctx, "ivan")
+	if err != nil {
+		t.Fatalf("GetByUsername() error = %v", err)
+	}
+	if u.ID != created.ID || u.Email != "ivan@bmstu.ru" || u.Weight != 80 || u.Password != "hash" {
+		t.Fatalf("GetByUsername() = %+v, want created user", u)
+	}
+	profile, err := r.User.GetProfile(ctx, "ivan")
+	if err != nil || profile.Username != "ivan" || profile.Password != "" {
+		t.Fatalf("GetProfile() = %+v, %v, want user without password hash", profile, err)
+	}
+	u, err = r.User.GetByEmail(ctx, "ivan@bmstu.ru")
+	if err != nil || u.Username != "ivan" {
+		t.Fatalf("GetByEmail() = %+v, %v", u, err)
+	}
+
+	_, err = r.User.GetByUsername(ctx, "unknown")
+	wantErr(t, "GetByUsername(unknown)", err, sql.ErrNoRows)
+	_, err = r.User.GetByEmail(ctx, "unknown@bmstu.ru")
+	wantErr(t, "GetByEmail(unknown)", err, sql.ErrNoRows)
+}
+
+func testUserUnique(t *testing.T, r *repo.Repos) {
+	ctx := context.Background()
+	createUser(t, r, "ivan", "ivan@bmstu.ru")
+	_, err := r.User.Create(ctx, &ent.User{
+		Username: "ivan", Email: "another@bmstu.ru", FirstName: "Ivan", Weight: 80, Height: 180, Age: 30, Sex: "M",
+		PhysicalActivity: "MA", Password: "hash",
+	})
+	wantErr(t, "Create(same username)", err, me.ErrUserAlreadyExist)
+	_, err = r.User.Create(ctx, &ent.User{
+		Username: "petr", Email: "ivan@bmstu.ru", FirstName: "Petr", Weight: 80, Height: 180, Age: 30, Sex: "M",
+		PhysicalActivity: "MA", Password: "hash",
+	})
+	wantErr(t, "Create(same email)", err, me.ErrUserAlreadyExist)
+
+	// почта не обязательна, пользователи без почты не конфликтуют друг с другом
+	createUser(t, r, "petr", "")
+	createUser(t, r, "oleg", "")
+}
+
+func testUserUpdateAndDelete(t *testing.T, r *repo.Repos) {
+	ctx := context.Background()
+	createUser(t, r, "ivan", "ivan@bmstu.ru")
+
+	u, err := r.User.UpdateWeight(ctx, 75.5, 2300.9, "ivan")
+	if err != nil || u.Weight != 75.5 || u.DayCalories != 2300 {
+		t.Fatalf("UpdateWeight() = %+v, %v, want weight 75.5 and 2300 calories", u, err)
+	}
+	_, err = r.User.UpdateWeight(ctx, 70, 2000, "unknown")
+	wantErr(t, "UpdateWeight(unknown)", err, sql.ErrNoRows)
+
+	if err = r.User.UpdatePassword(ctx, "new-hash", "ivan"); err != nil {
+		t.Fatalf("UpdatePassword() error = %v", err)
+	}
+	if u, err = r.User.GetByUsername(ctx, "ivan"); err != nil || u.Password != "new-hash" {
+		t.Fatalf("GetByUsername() = %+v, %v, want new password hash", u, err)
+	}
+	wantErr(t, "UpdatePassword(unknown)", r.User.UpdatePassword(ctx, "hash", "unknown"), me.ErrNoRowsAffected)
+
+	if err = r.User.DeleteByUsername(ctx, "ivan"); err != nil {
+		t.Fatalf("DeleteByUsername() error = %v", err)
+	}
+	_, err = r.User.GetByUsername(ctx, "ivan")
+	wantErr(t, "GetByUsername(deleted)", err, sql.ErrNoRows)
+	wantErr(t, "DeleteByUsername(deleted)", r.User.DeleteByUsername(ctx, "ivan"), me.ErrNoRowsAffected)
+}
+
+func testUserList(t *testing.T, r *repo.Repos) {
+	ctx := context.Background()
+	createUser(t, r, "ivan", "ivan@bmstu.ru")
+	createUser(t, r, "ivanna", "anna@bmstu.ru")
+	createUser(t, r, "petr", "petr@bmstu.ru")
+
+	users, total, err := r.User.List(ctx, &ent.UserFilter{Query: "IVAN", Limit: 1})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if total != 2 || len(users) != 1 {
+		t.Fatalf("List() = %d users of %d, want 1 of 2", len(users), total)
+	}
+	users, total, err = r.User.List(ctx, &ent.UserFilter{Query: "anna@", Limit: 10})
+	if err != nil || total != 1 || users[0].Username != "ivanna" {
+		t.Fatalf("List(by email) = %+v, %d, %v, want ivanna", users, total, err)
+	}
+	users, total, err = r.User.List(ctx, &ent.UserFilter{Activity: "EA", Limit: 10})
+	if err != nil || total != 0 || len(users) != 0 {
+		t.Fatalf("List(activity) = %+v, %d, %v, want none", users, total, err)
+	}
+	users, total, err = r.User.List(ctx, &ent.UserFilter{Limit: 10, Offset: 2})
+	if err != nil || total != 3 || len(users) != 1 {
+		t.Fatalf("List(offset) = %d users of %d, %v, want 1 of 3", len(users), total, err)
+	}
+}
+
+func testMeasurements(t *testing.T, r *repo.Repos) {
+	ctx := context.Background()
+	createUser(t, r, "ivan", "")
+	createUser(t, r, "petr", "")
+	// PostgreSQL хранит время с точностью до микросекунд
+	now := time.Now().UTC().Truncate(time.Second)
+	err := r.Measurement.Add(ctx, []*ent.Measurement{
+		{Username: "ivan", Kind: mc.MeasureWeight, Value: 81.5, RecordedAt: now.Add(-2 * time.Hour)},
+		{Username: "ivan", Kind: mc.MeasureWeight, Value: 80.5, RecordedAt: now.Add(-time.Hour)},
+		{Username: "ivan", Kind: mc.MeasureSteps, Value: 5400, RecordedAt: now},
+		{Username: "petr", Kind: mc.MeasureWeight, Value: 90, RecordedAt: now},
+	})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	list, err := r.Measurement.List(ctx, &ent.MeasurementFilter{Username: "ivan", Limit: 10})
+	if err != nil || len(list) != 3 || list[0].Kind != mc.MeasureSteps {
+		t.Fatalf("List() = %+v, %v, want 3 measurements, latest first", list, err)
+	}
+	list, err = r.Measurement.List(ctx, &ent.MeasurementFilter{Username: "ivan", Kind: mc.MeasureWeight, Limit: 1})
+	if err != nil || len(list) != 1 || list[0].Value != 80.5 || !list[0].RecordedAt.Equal(now.Add(-time.Hour)) {
+		t.Fatalf("List(weight, limit 1) = %+v, %v, want latest weight 80.5", list, err)
+	}
+	from, to := now.Add(-2*time.Hour), now.Add(-time.Hour)
+	list, err = r.Measurement.List(ctx, &ent.MeasurementFilter{Username: "ivan", From: &from, To: &to, Limit: 10})
+	if err != nil || len(list) != 1 || list[0].Value != 81.5 {
+		t.Fatalf("List(from, to) = %+v, %v, want 81.5: from is inclusive, to is exclusive", list, err)
+	}
+
+	if err = r.Measurement.DeleteByUsername(ctx, "ivan"); err != nil {
+		t.Fatalf("DeleteByUsername() error = %v", err)
+	}
+	list, err = r.Measurement.List(ctx, &ent.MeasurementFilter{Username: "ivan", Limit: 10})
+	if err != nil || len(list) != 0 {
+		t.Fatalf("List(deleted) = %+v, %v, want none", list, err)
+	}
+	list, err = r.Measurement.List(ctx, &ent.MeasurementFilter{Username: "petr", Limit: 10})
+	if err != nil || len(list) != 1 {
+		t.Fatalf("List(petr) = %+v, %v, want measurements of other users kept", list, err)
+	}
+}
+
+func testCoachLinks(t *testing.T, r *repo.Repos) {
+	ctx := context.Background()
+	createUser(t, r, "ivan", "")
+	createUser(t, r, "petr", "")
+
+	link, err := r.Coach.Create(ctx, "ivan", "petr", []string{mc.CoachScopeProfile})
+	if err != nil || link.ID == "" || link.Status != mc.CoachLinkPending {
+		t.Fatalf("Create() = %+v, %v, want pending link", link, err)
+	}
+	_, err = r.Coach.Create(ctx, "ivan", "petr", []string{mc.CoachScopeWeight})
+	wantErr(t, "Create(same pair)", err, me.ErrCoachLinkExist)
+	if got, err := r.Coach.Get(ctx, "ivan", "petr"); err != nil || got.ID != link.ID {
+		t.Fatalf("Get() = %+v, %v, want created link", got, err)
+	}
+	_, err = r.Coach.Get(ctx, "petr", "ivan")
+	wantErr(t, "Get(reversed pair)", err, sql.ErrNoRows)
+
+	// норму калорий задает только тренер, принявший приглашение
+	target := float32(1800)
+	_, err = r.Coach.UpdateCalorieTarget(ctx, link.ID, &target)
+	wantErr(t, "UpdateCalorieTarget(pending)", err, sql.ErrNoRows)
+	if link, err = r.Coach.Activate(ctx, link.ID); err != nil || link.Status != mc.CoachLinkActive {
+		t.Fatalf("Activate() = %+v, %v, want active link", link, err)
+	}
+	_, err = r.Coach.Activate(ctx, link.ID)
+	wantErr(t, "Activate(active)", err, sql.ErrNoRows)
+	_, err = r.Coach.GetCalorieTarget(ctx, "ivan")
+	wantErr(t, "GetCalorieTarget(no target)", err, sql.ErrNoRows)
+	if link, err = r.Coach.UpdateCalorieTarget(ctx, link.ID, &target); err != nil || *link.CalorieTarget != target {
+		t.Fatalf("UpdateCalorieTarget() = %+v, %v", link, err)
+	}
+	if got, err := r.Coach.GetCalorieTarget(ctx, "ivan"); err != nil || *got.CalorieTarget != target {
+		t.Fatalf("GetCalorieTarget() = %+v, %v, want %v", got, err, target)
+	}
+	scopes := []string{mc.CoachScopeProfile, mc.CoachScopeVitals}
+	if link, err = r.Coach.UpdateScopes(ctx, link.ID, scopes); err != nil || len(link.Scopes) != 2 || link.Scopes[1] != mc.CoachScopeVitals {
+		t.Fatalf("UpdateScopes() = %+v, %v, want %v", link, err, scopes)
+	}
+	links, err := r.Coach.ListByCoach(ctx, "petr", mc.CoachLinkActive)
+	if err != nil || len(links) != 1 {
+		t.Fatalf("ListByCoach() = %+v, %v, want one active link", links, err)
+	}
+
+	for _, text := range []string{"first", "second"} {
+		comment, err := r.Coach.CreateComment(ctx, link.ID, text)
+		if err != nil || comment.CoachUsername != "petr" || comment.Text != text {
+			t.Fatalf("CreateComment() = %+v, %v", comment, err)
+		}
+	}
+	comments, err := r.Coach.ListComments(ctx, link.ID)
+	if err != nil || len(comments) != 2 {
+		t.Fatalf("ListComments() = %+v, %v, want two comments", comments, err)
+	}
+
+	if err = r.Coach.Revoke(ctx, link.ID); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	wantErr(t, "Revoke(revoked)", r.Coach.Revoke(ctx, link.ID), me.ErrNoRowsAffected)
+	_, err = r.Coach.Get(ctx, "ivan", "petr")
+	wantErr(t, "Get(revoked)", err, sql.ErrNoRows)
+	links, err = r.Coach.ListByClient(ctx, "ivan")
+	if err != nil || len(links) != 0 {
+		t.Fatalf("ListByClient() = %+v, %v, want revoked link hidden", links, err)
+	}
+	// разорванная связь не мешает пригласить тренера снова
+	if _, err = r.Coach.Create(ctx, "ivan", "petr", nil); err != nil {
+		t.Fatalf("Create(after revoke) error = %v", err)
+	}
+}
+
+func testRefreshTokens(t *testing.T, r *repo.Repos) {
+	ctx := context.Background()
+	createUser(t, r, "ivan", "")
+	createUser(t, r, "petr", "")
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	newToken := func(username, familyID, hash string) *ent.RefreshToken {
+		t.Helper()
+		tok, err := r.Token.Create(ctx, &ent.RefreshToken{
+			FamilyID: familyID, Username: username, TokenHash: hash, ExpiresAt: expiresAt,
+		})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		return tok
+	}
+	const familyID = "5b0f7a34-4c1e-4b8e-9a52-6f1d2c3b4a59"
+	first := newToken("ivan", familyID, "hash-1")
+	second := newToken("ivan", familyID, "hash-2")
+	other := newToken("petr", "0c5d1e1b-2a3f-4e6d-8b7c-9d0e1f2a3b4c", "hash-3")
+
+	tok, err := r.Token.GetByHash(ctx, "hash-1")
+	if err != nil || tok.ID != first.ID || tok.RevokedAt != nil || !tok.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("GetByHash() = %+v, %v, want active first token", tok, err)
+	}
+	_, err = r.Token.GetByHash(ctx, "unknown")
+	wantErr(t, "GetByHash(unknown)", err, sql.ErrNoRows)
+
+	if err = r.Token.Rotate(ctx, first.ID, second.ID); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if tok, err = r.Token.GetByHash(ctx, "hash-1"); err != nil || tok.RevokedAt == nil || *tok.ReplacedBy != second.ID {
+		t.Fatalf("GetByHash(rotated) = %+v, %v, want revoked and replaced by second token", tok, err)
+	}
+	wantErr(t, "Rotate(rotated)", r.Token.Rotate(ctx, first.ID, second.ID), me.ErrNoRowsAffected)
+
+	if err = r.Token.RevokeFamily(ctx, familyID); err != nil {
+		t.Fatalf("RevokeFamily() error = %v", err)
+	}
+	if tok, err = r.Token.GetByHash(ctx, "hash-2"); err != nil || tok.RevokedAt == nil {
+		t.Fatalf("GetByHash(family) = %+v, %v, want revoked", tok, err)
+	}
+	if tok, err = r.Token.GetByHash(ctx, "hash-3"); err != nil || tok.RevokedAt != nil {
+		t.Fatalf("GetByHash(other family) = %+v, %v, want active", tok, err)
+	}
+	if err = r.Token.RevokeByUsername(ctx, other.Username); err != nil {
+		t.Fatalf("RevokeByUsername() error = %v", err)
+	}
+	if tok, err = r.Token.GetByHash(ctx, "hash-3"); err != nil || tok.RevokedAt == nil {
+		t.Fatalf("GetByHash(revoked by username) = %+v, %v, want revoked", tok, err)
+	}
+}
+
+func testMfa(t *testing.T, r *repo.Repos) {
+	ctx := context.Background()
+	createUser(t, r, "ivan", "")
+
+	_, err := r.Mfa.GetByUsername(ctx, "ivan")
+	wantErr(t, "GetByUsername(no totp)", err, sql.ErrNoRows)
+	totp, err := r.Mfa.Upsert(ctx, "ivan", "secret-1")
+	if err != nil || totp.Enabled || totp.SecretEncrypted != "secret-1" {
+		t.Fatalf("Upsert() = %+v, %v, want disabled totp", totp, err)
+	}
+	if totp, err = r.Mfa.Upsert(ctx, "ivan", "secret-2"); err != nil || totp.SecretEncrypted != "secret-2" {
+		t.Fatalf("Upsert(not enabled) = %+v, %v, want secret replaced", totp, err)
+	}
+	if err = r.Mfa.Enable(ctx, "ivan"); err != nil {
+		t.Fatalf("Enable() error = %v", err)
+	}
+	wantErr(t, "Enable(unknown)", r.Mfa.Enable(ctx, "unknown"), me.ErrNoRowsAffected)
+	// включенный второй фактор не перезаписывается
+	_, err = r.Mfa.Upsert(ctx, "ivan", "secret-3")
+	wantErr(t, "Upsert(enabled)", err, sql.ErrNoRows)
+
+	if err = r.Mfa.UseStep(ctx, "ivan", 10); err != nil {
+		t.Fatalf("UseStep() error = %v", err)
+	}
+	wantErr(t, "UseStep(same step)", r.Mfa.UseStep(ctx, "ivan", 10), me.ErrNoRowsAffected)
+	wantErr(t, "UseStep(earlier step)", r.Mfa.UseStep(ctx, "ivan", 9), me.ErrNoRowsAffected)
+	if err = r.Mfa.UseStep(ctx, "ivan", 11); err != nil {
+		t.Fatalf("UseStep(next step) error = %v", err)
+	}
+
+	if err = r.Mfa.ReplaceRecoveryCodes(ctx, "ivan", []string{"code-1", "code-2"}); err != nil {
+		t.Fatalf("ReplaceRecoveryCodes() error = %v", err)
+	}
+	if err = r.Mfa.UseRecoveryCode(ctx, "ivan", "code-1"); err != nil {
+		t.Fatalf("UseRecoveryCode() error = %v", err)
+	}
+	wantErr(t, "UseRecoveryCode(used)", r.Mfa.UseRecoveryCode(ctx, "ivan", "code-1"), me.ErrNoRowsAffected)
+	if err = r.Mfa.ReplaceRecoveryCodes(ctx, "ivan", []string{"code-3"}); err != nil {
+		t.Fatalf("ReplaceRecoveryCodes() error = %v", err)
+	}
+	wantErr(t, "UseRecoveryCode(replaced)", r.Mfa.UseRecoveryCode(ctx, "ivan", "code-2"), me.ErrNoRowsAffected)
+
+	if err = r.Mfa.DeleteByUsername(ctx, "ivan"); err != nil {
+		t.Fatalf("DeleteByUsername() error = %v", err)
+	}
+	_, err = r.Mfa.GetByUsername(ctx, "ivan")
+	wantErr(t, "GetByUsername(deleted)", err, sql.ErrNoRows)
+	wantErr(t, "UseRecoveryCode(deleted)", r.Mfa.UseRecoveryCode(ctx, "ivan", "code-3"), me.ErrNoRowsAffected)
+}
+
+func testApiKeys(t *testing.T, r *repo.Repos) {
+	ctx := context.Background()
+	createUser(t, r, "ivan", "")
+
+	key, err := r.ApiKey.Create(ctx, &ent.ApiKey{
+		Username: "ivan", Name: "scales", Prefix: "hc_1234", KeyHash: "key-hash", Scopes: []string{"measurements:write"},
+	})
+	if err != nil || key.ID == "" || len(key.Scopes) != 1 {
+		t.Fatalf("Create() = %+v, %v", key, err)
+	}
+	got, err := r.ApiKey.GetByHash(ctx, "key-hash")
+	if err != nil || got.ID != key.ID || got.OwnerRole != mc.RoleUser {
+		t.Fatalf("GetByHash() = %+v, %v, want key with owner role", got, err)
+	}
+	_, err = r.ApiKey.GetByHash(ctx, "unknown")
+	wantErr(t, "GetByHash(unknown)", err, sql.ErrNoRows)
+
+	// ключи заблокированного пользователя не принимаются
+	if err = r.User.SetLocked(ctx, "ivan", true); err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.ApiKey.GetByHash(ctx, "key-hash")
+	wantErr(t, "GetByHash(locked owner)", err, sql.ErrNoRows)
+	if err = r.User.SetLocked(ctx, "ivan", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if keys, err := r.ApiKey.ListByUsername(ctx, "ivan"); err != nil || len(keys) != 1 {
+		t.Fatalf("ListByUsername() = %+v, %v, want one key", keys, err)
+	}
+	wantErr(t, "Revoke(another owner)", r.ApiKey.Revoke(ctx, key.ID, "petr"), me.ErrNoRowsAffected)
+	if err = r.ApiKey.Revoke(ctx, key.ID, "ivan"); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	wantErr(t, "Revoke(revoked)", r.ApiKey.Revoke(ctx, key.ID, "ivan"), me.ErrNoRowsAffected)
+	if count, err := r.ApiKey.CountActive(ctx, "ivan"); err != nil || count != 0 {
+		t.Fatalf("CountActive() = %d, %v, want 0", count, err)
+	}
+	if keys, err := r.ApiKey.ListByUsername(ctx, "ivan"); err != nil || len(keys) != 0 {
+		t.Fatalf("ListByUsername() = %+v, %v, want revoked key hidden", keys, err)
+	}
+}
+
+func testIdentities(t *testing.T, r *repo.Repos) {
+	ctx := context.Background()
+	createUser(t, r, "ivan", "")
+	createUser(t, r, "petr", "")
+
+	_, err := r.Identity.Create(ctx, &ent.ExternalIdentity{Provider: "google", Subject: "subject-1", Username: "ivan"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	identity, err := r.Identity.GetByProviderSubject(ctx, "google", "subject-1")
+	if err != nil || identity.Username != "ivan" {
+		t.Fatalf("GetByProviderSubject() = %+v, %v, want identity of ivan", identity, err)
+	}
+	_, err = r.Identity.GetByProviderSubject(ctx, "yandex", "subject-1")
+	wantErr(t, "GetByProviderSubject(another provider)", err, sql.ErrNoRows)
+	_, err = r.Identity.Create(ctx, &ent.ExternalIdentity{Provider: "google", Subject: "subject-1", Username: "petr"})
+	wantErr(t, "Create(linked subject)", err, me.ErrIdentityAlreadyLinked)
+}
+
+// approveGroup создает группу через одобренную заявку, автор заявки становится ее участником.
+func approveGroup(t *testing.T, r *repo.Repos, name, username string) *ent.Group {
+	t.Helper()
+	ctx := context.Background()
+	b, err := r.Group.CreateBid(ctx, name, username)
+	if err != nil {
+		t.Fatalf("CreateBid(%s) error = %v", name, err)
+	}
+	g, err := r.Group.ApproveBid(ctx, b.ID)
+	if err != nil {
+		t.Fatalf("ApproveBid(%s) error = %v", name, err)
+	}
+	return g
+}
+
+func testGroupBids(t *testing.T, r *repo.Repos) {
+	ctx := context.Background()
+	createUser(t, r, "ivan", "")
+	createUser(t, r, "petr", "")
+
+	runners, err := r.Group.CreateBid(ctx, "runners", "ivan")
+	if err != nil || runners.ID == 0 || runners.Username != "ivan" || runners.Status != mc.BidStatusInProgress {
+		t.Fatalf("CreateBid() = %+v, %v, want bid of ivan in progress", runners, err)
+	}
+	_, err = r.Group.CreateBid(ctx, "runners", "petr")
+	wantErr(t, "CreateBid(same name in progress)", err, me.ErrBidAlreadyExist)
+	_, err = r.Group.CreateBid(ctx, "cyclists", "unknown")
+	wantErr(t, "CreateBid(unknown user)", err, sql.ErrNoRows)
+	swimmers, err := r.Group.CreateBid(ctx, "swimmers", "petr")
+	if err != nil {
+		t.Fatalf("CreateBid() error = %v", err)
+	}
+
+	bids, err := r.Group.ListBidsByStatus(ctx, mc.BidStatusInProgress)
+	if err != nil || len(bids) != 2 || bids[0].ID != runners.ID || bids[1].ID != swimmers.ID {
+		t.Fatalf("ListBidsByStatus() = %+v, %v, want runners and swimmers in order of creation", bids, err)
+	}
+	if bids, err = r.Group.ListBidsByUsername(ctx, "ivan"); err != nil || len(bids) != 1 || bids[0].GroupName != "runners" {
+		t.Fatalf("ListBidsByUsername() = %+v, %v, want runners", bids, err)
+	}
+
+	g, err := r.Group.ApproveBid(ctx, runners.ID)
+	if err != nil || g.Name != "runners" || g.OwnerUsername != "ivan" {
+		t.Fatalf("ApproveBid() = %+v, %v, want group runners of ivan", g, err)
+	}
+	_, err = r.Group.ApproveBid(ctx, runners.ID)
+	wantErr(t, "ApproveBid(approved)", err, me.ErrNoRowsAffected)
+	if members, err := r.Group.ListMembers(ctx, g.ID); err != nil || !slices.Equal(members, []string{"ivan"}) {
+		t.Fatalf("ListMembers() = %v, %v, want author of the bid", members, err)
+	}
+
+	b, err := r.Group.RejectBid(ctx, swimmers.ID)
+	if err != nil || b.Status != mc.BidStatusRejected || b.Username != "petr" {
+		t.Fatalf("RejectBid() = %+v, %v, want rejected bid of petr", b, err)
+	}
+	_, err = r.Group.RejectBid(ctx, swimmers.ID)
+	wantErr(t, "RejectBid(rejected)", err, sql.ErrNoRows)
+
+	// рассмотренная заявка не мешает подать новую, но группа с таким названием уже есть
+	again, err := r.Group.CreateBid(ctx, "runners", "petr")
+	if err != nil {
+		t.Fatalf("CreateBid(after approval) error = %v", err)
+	}
+	_, err = r.Group.ApproveBid(ctx, again.ID)
+	wantErr(t, "ApproveBid(existing group)", err, me.ErrGroupAlreadyExist)
+	if bids, err = r.Group.ListBidsByStatus(ctx, mc.BidStatusInProgress); err != nil || len(bids) != 1 || bids[0].ID != again.ID {
+		t.Fatalf("ListBidsByStatus() = %+v, %v, want failed approval rolled back", bids, err)
+	}
+}
+
+func testGroupMembers(t *testing.T, r *repo.Repos) {
+	ctx := context.Background()
+	createUser(t, r, "ivan", "")
+	createUser(t, r, "petr", "")
+	g := approveGroup(t, r, "runners", "ivan")
+	approveGroup(t, r, "cyclists", "petr")
+
+	got, err := r.Group.GetByName(ctx, "runners")
+	if err != nil || got.ID != g.ID || got.OwnerUsername != "ivan" {
+		t.Fatalf("GetByName() = %+v, %v, want runners of ivan", got, err)
+	}
+	_, err = r.Group.GetByName(ctx, "unknown")
+	wantErr(t, "GetByName(unknown)", err, sql.ErrNoRows)
+
+	if err = r.Group.AddMember(ctx, g.ID, "petr"); err != nil {
+		t.Fatalf("AddMember() error = %v", err)
+	}
+	wantErr(t, "AddMember(member)", r.Group.AddMember(ctx, g.ID, "petr"), me.ErrAlreadyMember)
+	wantErr(t, "AddMember(unknown user)", r.Group.AddMember(ctx, g.ID, "unknown"), me.ErrNoRowsAffected)
+	if members, err := r.Group.ListMembers(ctx, g.ID); err != nil || !slices.Equal(members, []string{"ivan", "petr"}) {
+		t.Fatalf("ListMembers() = %v, %v, want ivan and petr", members, err)
+	}
+	groups, err := r.Group.ListByUsername(ctx, "petr")
+	if err != nil || len(groups) != 2 || groups[0].Name != "cyclists" || groups[1].Name != "runners" {
+		t.Fatalf("ListByUsername() = %+v, %v, want cyclists and runners", groups, err)
+	}
+
+	if err = r.Group.RemoveMember(ctx, g.ID, "petr"); err != nil {
+		t.Fatalf("RemoveMember() error = %v", err)
+	}
+	wantErr(t, "RemoveMember(removed)", r.Group.RemoveMember(ctx, g.ID, "petr"), me.ErrNoRowsAffected)
+
+	if err = r.Group.DeleteByName(ctx, "runners"); err != nil {
+		t.Fatalf("DeleteByName() error = %v", err)
+	}
+	wantErr(t, "DeleteByName(deleted)", r.Group.DeleteByName(ctx, "runners"), me.ErrNoRowsAffected)
+	if members, err := r.Group.ListMembers(ctx, g.ID); err != nil || len(members) != 0 {
+		t.Fatalf("ListMembers(deleted group) = %v, %v, want none", members, err)
+	}
+	if groups, err = r.Group.ListByUsername(ctx, "ivan"); err != nil || len(groups) != 0 {
+		t.Fatalf("ListByUsername(ivan) = %+v, %v, want none", groups, err)
+	}
+}
+
+func testAgents(t *testing.T, r *repo.Repos) {
+	ctx := context.Background()
+	createUser(t, r, "ivan", "")
+	createUser(t, r, "petr", "")
+	runners := approveGroup(t, r, "runners", "ivan")
+	cyclists := approveGroup(t, r, "cyclists", "petr")
+	if err := r.Group.AddMember(ctx, cyclists.ID, "ivan"); err != nil {
+		t.Fatal(err)
+	}
+
+	scales, err := r.Agent.Create(ctx, "scales")
+	if err != nil || scales.ID == 0 || scales.Name != "scales" {
+		t.Fatalf("Create() = %+v, %v", scales, err)
+	}
+	_, err = r.Agent.Create(ctx, "scales")
+	wantErr(t, "Create(same name)", err, me.ErrAgentAlreadyExist)
+	if _, err = r.Agent.Create(ctx, "apnea"); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	agents, err := r.Agent.List(ctx)
+	if err != nil || len(agents) != 2 || agents[0].Name != "apnea" || agents[1].Name != "scales" {
+		t.Fatalf("List() = %+v, %v, want apnea and scales", agents, err)
+	}
+	if got, err := r.Agent.GetByName(ctx, "scales"); err != nil || got.ID != scales.ID {
+		t.Fatalf("GetByName() = %+v, %v, want scales", got, err)
+	}
+	_, err = r.Agent.GetByName(ctx, "unknown")
+	wantErr(t, "GetByName(unknown)", err, sql.ErrNoRows)
+
+	for i := 0; i < 2; i++ {
+		if err = r.Agent.GrantUser(ctx, scales.ID, "ivan"); err != nil {
+			t.Fatalf("GrantUser() #%d error = %v", i+1, err)
+		}
+		if err = r.Agent.GrantGroup(ctx, scales.ID, runners.ID); err != nil {
+			t.Fatalf("GrantGroup() #%d error = %v", i+1, err)
+		}
+	}
+	wantErr(t, "GrantUser(unknown user)", r.Agent.GrantUser(ctx, scales.ID, "unknown"), me.ErrNoRowsAffected)
+	if err = r.Agent.GrantGroup(ctx, scales.ID, cyclists.ID); err != nil {
+		t.Fatalf("GrantGroup() error = %v", err)
+	}
+
+	// источники доступа упорядочены: сначала прямое право, затем группы по названию
+	for _, tt := range []struct {
+		username string
+		want     []string
+	}{
+		{username: "ivan", want: []string{mc.AccessSourceDirect, mc.AccessSourceGroup + "cyclists", mc.AccessSourceGroup + "runners"}},
+		{username: "petr", want: []string{mc.AccessSourceGroup + "cyclists"}},
+		{username: "unknown", want: []string{}},
+	} {
+		if sources, err := r.Agent.GetAccessSources(ctx, scales.ID, tt.username); err != nil || !slices.Equal(sources, tt.want) {
+			t.Fatalf("GetAccessSources(%s) = %v, %v, want %v", tt.username, sources, err, tt.want)
+		}
+	}
+
+	if err = r.Agent.RevokeUser(ctx, scales.ID, "ivan"); err != nil {
+		t.Fatalf("RevokeUser() error = %v", err)
+	}
+	wantErr(t, "RevokeUser(revoked)", r.Agent.RevokeUser(ctx, scales.ID, "ivan"), me.ErrNoRowsAffected)
+	if err = r.Agent.RevokeGroup(ctx, scales.ID, runners.ID); err != nil {
+		t.Fatalf("RevokeGroup() error = %v", err)
+	}
+	wantErr(t, "RevokeGroup(revoked)", r.Agent.RevokeGroup(ctx, scales.ID, runners.ID), me.ErrNoRowsAffected)
+	want := []string{mc.AccessSourceGroup + "cyclists"}
+	if sources, err := r.Agent.GetAccessSources(ctx, scales.ID, "ivan"); err != nil || !slices.Equal(sources, want) {
+		t.Fatalf("GetAccessSources(after revoke) = %v, %v, want %v", sources, err, want)
+	}
+
+	if err = r.Agent.DeleteByName(ctx, "scales"); err != nil {
+		t.Fatalf("DeleteByName() error = %v", err)
+	}
+	wantErr(t, "DeleteByName(deleted)", r.Agent.DeleteByName(ctx, "scales"), me.ErrNoRowsAffected)
+	if sources, err := r.Agent.GetAccessSources(ctx, scales.ID, "petr"); err != nil || len(sources) != 0 {
+		t.Fatalf("GetAccessSources(deleted agent) = %v, %v, want none", sources, err)
+	}
+}
+
+func testAudit(t *testing.T, r *repo.Repos) {
+	ctx := context.Background()
+	events := []*ent.AuditEvent{
+		{Actor: "ivan", Action: "auth.signin.success", Subject: "ivan", IP: "10.0.0.1", UserAgent: "curl",
+			RequestID: "request-1", Details: map[string]any{"method": "password"}},
+		{Actor: "admin", Action: "admin.user.lock", Subject: "ivan", Details: map[string]any{}},
+		{Actor: "petr", Action: "auth.signout", Subject: "petr", Details: map[string]any{}},
+		// группа действий auth не включает authz
+		{Actor: "petr", Action: "authz.denied", Subject: "petr", Details: map[string]any{}},
+	}
+	for _, e := range events {
+		if err := r.Audit.Create(ctx, e); err != nil {
+			t.Fatalf("Create(%s) error = %v", e.Action, err)
+		}
+	}
+
+	all, total, err := r.Audit.List(ctx, &ent.AuditFilter{Limit: 10})
+	if err != nil || total != 4 || len(all) != 4 {
+		t.Fatalf("List() = %d events of %d, %v, want 4", len(all), total, err)
+	}
+	for i, e := range all {
+		if want := events[len(events)-1-i].Action; e.Action != want {
+			t.Fatalf("List()[%d].Action = %s, want %s: latest first", i, e.Action, want)
+		}
+	}
+	first := all[3]
+	if first.IP != "10.0.0.1" || first.UserAgent != "curl" || first.RequestID != "request-1" || first.Details["method"] != "password" {
+		t.Fatalf("List()[3] = %+v, want stored request data and details", first)
+	}
+	if all[2].IP != "" || all[2].RequestID != "" {
+		t.Fatalf("List()[2] = %+v, want empty request data", all[2])
+	}
+
+	for _, tt := range []struct {
+		name        string
+		filter      ent.AuditFilter
+		wantActions []string
+		wantTotal   int
+	}{
+		{name: "action group", filter: ent.AuditFilter{Action: "auth", Limit: 10},
+			wantActions: []string{"auth.signout", "auth.signin.success"}, wantTotal: 2},
+		{name: "exact action", filter: ent.AuditFilter{Action: "auth.signout", Limit: 10},
+			wantActions: []string{"auth.signout"}, wantTotal: 1},
+		{name: "actor", filter: ent.AuditFilter{Actor: "admin", Limit: 10},
+			wantActions: []string{"admin.user.lock"}, wantTotal: 1},
+		{name: "subjects", filter: ent.AuditFilter{Subjects: []string{"ivan", "ivan@bmstu.ru"}, Limit: 10},
+			wantActions: []string{"admin.user.lock", "auth.signin.success"}, wantTotal: 2},
+		{name: "page", filter: ent.AuditFilter{Limit: 1, Offset: 1},
+			wantActions: []string{"auth.signout"}, wantTotal: 4},
+		{name: "time range", filter: ent.AuditFilter{From: &all[2].CreatedAt, To: &all[1].CreatedAt, Limit: 10},
+			wantActions: []string{"admin.user.lock"}, wantTotal: 1},
+	} {
+		got, total, err := r.Audit.List(ctx, &tt.filter)
+		if err != nil {
+			t.Fatalf("List(%s) error = %v", tt.name, err)
+		}
+		actions := make([]string, 0, len(got))
+		for _, e := range got {
+			actions = append(actions, e.Action)
+		}
+		if total != tt.wantTotal || !slices.Equal(actions, tt.wantActions) {
+			t.Fatalf("List(%s) = %v of %d, want %v of %d", tt.name, actions, total, tt.wantActions, tt.wantTotal)
+		}
+	}
+}
+
+func testAttempts(t *testing.T, r attempt.Repo) {
+	ctx := context.Background()
+	// ключи уникальны, чтобы повторный запуск на том же Memcached не видел счетчики прошлого запуска
+	key := fmt.Sprintf("ivan:%d", time.Now().UnixNano())
+	other := key + ":10.0.0.1"
+	increment := func(a *ent.LoginAttempts) { a.Failures++ }
+
+	a, err := r.Get(ctx, key)
+	if err != nil || a.Failures != 0 || a.Locked {
+		t.Fatalf("Get(unknown) = %+v, %v, want empty counter", a, err)
+	}
+	for want := 1; want <= 2; want++ {
+		if a, err = r.Update(ctx, key, time.Minute, increment); err != nil || a.Failures != want {
+			t.Fatalf("Update() = %+v, %v, want %d failures", a, err, want)
+		}
+	}
+	blockedUntil := time.Now().Add(time.Minute).UTC().Truncate(time.Second)
+	a, err = r.Update(ctx, key, time.Minute, func(a *ent.LoginAttempts) {
+		a.BlockedUntil, a.Locked = blockedUntil, true
+	})
+	if err != nil || a.Failures != 2 || !a.Locked {
+		t.Fatalf("Update() = %+v, %v, want counter changed in place", a, err)
+	}
+	if a, err = r.Get(ctx, key); err != nil || a.Failures != 2 || !a.Locked || !a.BlockedUntil.Equal(blockedUntil) {
+		t.Fatalf("Get() = %+v, %v, want stored counter", a, err)
+	}
+	if a, err = r.Get(ctx, other); err != nil || a.Failures != 0 {
+		t.Fatalf("Get(other key) = %+v, %v, want empty counter", a, err)
+	}
+
+	if err = r.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if a, err = r.Get(ctx, key); err != nil || a.Failures != 0 || a.Locked {
+		t.Fatalf("Get(deleted) = %+v, %v, want empty counter", a, err)
+	}
+	if err = r.Delete(ctx, other); err != nil {
+		t.Fatalf("Delete(unknown) error = %v, want nil", err)
+	}
+}
+
+func testTransactionRollback(t *testing.T, r *repo.Repos) {
+	ctx := context.Background()
+	createUser(t, r, "ivan", "")
+	errAbort := errors.New("abort")
+	committed := false
+
+	err := r.TxManager.Do(ctx, pgx.Serializable, func(txCtx context.Context) error {
+		_, err := r.User.Create(txCtx, &ent.User{
+			Username: "petr", FirstName: "Petr", Weight: 90, Height: 185, Age: 35, Sex: "M",
+			PhysicalActivity: "LA", Password: "hash",
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := r.User.UpdateWeight(txCtx, 70, 2000, "ivan"); err != nil {
+			return err
+		}
+		transaction.AfterCommit(txCtx, func() { committed = true })
+		return errAbort
+	})
+	wantErr(t, "Do()", err, errAbort)
+	if committed {
+		t.Fatal("AfterCommit hook ran after rollback")
+	}
+	u, err := r.User.GetByUsername(ctx, "ivan")
+	if err != nil || u.Weight != 80 {
+		t.Fatalf("GetByUsername() = %+v, %v, want weight 80 restored", u, err)
+	}
+	_, err = r.User.GetByUsername(ctx, "petr")
+	wantErr(t, "GetByUsername(created in rolled back transaction)", err, sql.ErrNoRows)
+}
+
+func testTransactionCommit(t *testing.T, r *repo.Repos) {
+	ctx := context.Background()
+	createUser(t, r, "ivan", "")
+	var hookWeights []float32
+
+	err := r.TxManager.Do(ctx, pgx.Serializable, func(txCtx context.Context) error {
+		if _, err := r.User.UpdateWeight(txCtx, 70, 2000, "ivan"); err != nil {
+			return err
+		}
+		err := r.Measurement.Add(txCtx, []*ent.Measurement{
+			{Username: "ivan", Kind: mc.MeasureWeight, Value: 70, RecordedAt: time.Now().UTC()},
+		})
+		// хук видит зафиксированные данные
+		transaction.AfterCommit(txCtx, func() {
+			u, err := r.User.GetByUsername(ctx, "ivan")
+			if err != nil {
+				t.Errorf("GetByUsername() in AfterCommit hook error = %v", err)
+				return
+			}
+			hookWeights = append(hookWeights, u.Weight)
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if len(hookWeights) != 1 || hookWeights[0] != 70 {
+		t.Fatalf("AfterCommit hook saw weights %v, want 70 once after commit", hookWeights)
+	}
+	u, err := r.User.GetByUsername(ctx, "ivan")
+	if err != nil || u.Weight != 70 {
+		t.Fatalf("GetByUsername() = %+v, %v, want weight 70", u, err)
+	}
+	list, err := r.Measurement.List(ctx, &ent.MeasurementFilter{Username: "ivan", Limit: 10})
+	if err != nil || len(list) != 1 {
+		t.Fatalf("List() = %+v, %v, want measurement added in transaction", list, err)
+	}
+}
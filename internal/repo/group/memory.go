@@ -0,0 +1,262 @@
+package group
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/memory"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	repoErr "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+)
+
+var _ Repo = (*RepoMemory)(nil)
+
+type RepoMemory struct {
+	store *memory.Store
+}
+
+// NewRepoMemory возвращает repository групп и заявок, который хранит данные в памяти процесса (storage: memory).
+func NewRepoMemory(store *memory.Store) *RepoMemory {
+	return &RepoMemory{
+		store: store,
+	}
+}
+
+func (r *RepoMemory) CreateBid(ctx context.Context, groupName, username string) (*ent.Bid, error) {
+	var b ent.Bid
+	err := r.store.Write(ctx, func() error {
+		u, ok := r.store.Users[username]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		for _, bid := range r.store.Bids {
+			if bid.GroupName == groupName && bid.Status == mc.BidStatusInProgress {
+				return repoErr.ErrBidAlreadyExist
+			}
+		}
+		timeNow := time.Now()
+		b = ent.Bid{
+			ID:        r.store.NextID(),
+			GroupName: groupName,
+			UserID:    u.ID,
+			Status:    mc.BidStatusInProgress,
+			CreatedAt: timeNow,
+			UpdatedAt: timeNow,
+		}
+		r.store.Bids[b.ID] = b
+		b.Username = u.Username
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (r *RepoMemory) ListBidsByUsername(ctx context.Context, username string) ([]*ent.Bid, error) {
+	bids, err := r.listBids(ctx, func(b *ent.Bid) bool {
+		return b.Username == username
+	})
+	slices.Reverse(bids)
+	return bids, err
+}
+
+func (r *RepoMemory) ListBidsByStatus(ctx context.Context, status string) ([]*ent.Bid, error) {
+	return r.listBids(ctx, func(b *ent.Bid) bool {
+		return b.Status == status
+	})
+}
+
+func (r *RepoMemory) ApproveBid(ctx context.Context, id int) (*ent.Group, error) {
+	var g ent.Group
+	err := r.store.Write(ctx, func() error {
+		b, ok := r.store.Bids[id]
+		if !ok || b.Status != mc.BidStatusInProgress {
+			return repoErr.ErrNoRowsAffected
+		}
+		if _, ok := r.groupByName(b.GroupName); ok {
+			return repoErr.ErrGroupAlreadyExist
+		}
+		timeNow := time.Now()
+		b.Status, b.UpdatedAt = mc.BidStatusApproved, timeNow
+		r.store.Bids[id] = b
+		g = ent.Group{
+			ID:        r.store.NextID(),
+			Name:      b.GroupName,
+			OwnerID:   b.UserID,
+			CreatedAt: timeNow,
+			UpdatedAt: timeNow,
+		}
+		r.store.Groups[g.ID] = g
+		r.store.Participation[memory.GroupMember{GroupID: g.ID, UserID: b.UserID}] = struct{}{}
+		g = r.withOwner(g)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (r *RepoMemory) RejectBid(ctx context.Context, id int) (*ent.Bid, error) {
+	var b ent.Bid
+	err := r.store.Write(ctx, func() error {
+		bid, ok := r.store.Bids[id]
+		if !ok || bid.Status != mc.BidStatusInProgress {
+			return sql.ErrNoRows
+		}
+		bid.Status, bid.UpdatedAt = mc.BidStatusRejected, time.Now()
+		r.store.Bids[id] = bid
+		b = r.withUsername(bid)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (r *RepoMemory) GetByName(ctx context.Context, name string) (*ent.Group, error) {
+	var g ent.Group
+	err := r.store.Read(ctx, func() error {
+		group, ok := r.groupByName(name)
+		if !ok {
+			return sql.ErrNoRows
+		}
+		g = r.withOwner(group)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (r *RepoMemory) ListByUsername(ctx context.Context, username string) ([]*ent.Group, error) {
+	groups := make([]*ent.Group, 0)
+	err := r.store.Read(ctx, func() error {
+		u, ok := r.store.Users[username]
+		if !ok {
+			return nil
+		}
+		for m := range r.store.Participation {
+			if m.UserID == u.ID {
+				g := r.withOwner(r.store.Groups[m.GroupID])
+				groups = append(groups, &g)
+			}
+		}
+		return nil
+	})
+	slices.SortFunc(groups, func(a, b *ent.Group) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return groups, err
+}
+
+func (r *RepoMemory) DeleteByName(ctx context.Context, name string) error {
+	return r.store.Write(ctx, func() error {
+		g, ok := r.groupByName(name)
+		if !ok {
+			return repoErr.ErrNoRowsAffected
+		}
+		r.store.DeleteGroup(g.ID)
+		return nil
+	})
+}
+
+func (r *RepoMemory) ListMembers(ctx context.Context, groupID int) ([]string, error) {
+	members := make([]string, 0)
+	err := r.store.Read(ctx, func() error {
+		for m := range r.store.Participation {
+			if m.GroupID != groupID {
+				continue
+			}
+			if u, ok := r.store.UserByID(m.UserID); ok {
+				members = append(members, u.Username)
+			}
+		}
+		return nil
+	})
+	slices.Sort(members)
+	return members, err
+}
+
+func (r *RepoMemory) AddMember(ctx context.Context, groupID int, username string) error {
+	return r.store.Write(ctx, func() error {
+		u, ok := r.store.Users[username]
+		if !ok {
+			return repoErr.ErrNoRowsAffected
+		}
+		if _, ok := r.store.Groups[groupID]; !ok {
+			return repoErr.ErrGroupNotExist
+		}
+		key := memory.GroupMember{GroupID: groupID, UserID: u.ID}
+		if _, ok := r.store.Participation[key]; ok {
+			return repoErr.ErrAlreadyMember
+		}
+		r.store.Participation[key] = struct{}{}
+		return nil
+	})
+}
+
+func (r *RepoMemory) RemoveMember(ctx context.Context, groupID int, username string) error {
+	return r.store.Write(ctx, func() error {
+		u, ok := r.store.Users[username]
+		if !ok {
+			return repoErr.ErrNoRowsAffected
+		}
+		key := memory.GroupMember{GroupID: groupID, UserID: u.ID}
+		if _, ok := r.store.Participation[key]; !ok {
+			return repoErr.ErrNoRowsAffected
+		}
+		delete(r.store.Participation, key)
+		return nil
+	})
+}
+
+// listBids возвращает заявки, для которых match возвращает true, в порядке подачи.
+func (r *RepoMemory) listBids(ctx context.Context, match func(b *ent.Bid) bool) ([]*ent.Bid, error) {
+	bids := make([]*ent.Bid, 0)
+	err := r.store.Read(ctx, func() error {
+		for _, bid := range r.store.Bids {
+			b := r.withUsername(bid)
+			if match(&b) {
+				bids = append(bids, &b)
+			}
+		}
+		return nil
+	})
+	slices.SortFunc(bids, func(a, b *ent.Bid) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return bids, err
+}
+
+// groupByName, withOwner и withUsername должны вызываться под блокировкой хранилища.
+func (r *RepoMemory) groupByName(name string) (ent.Group, bool) {
+	for _, g := range r.store.Groups {
+		if g.Name == name {
+			return g, true
+		}
+	}
+	return ent.Group{}, false
+}
+
+// withOwner заполняет никнейм ответственного за группу, как JOIN "user" в запросах RepoLayer.
+func (r *RepoMemory) withOwner(g ent.Group) ent.Group {
+	u, _ := r.store.UserByID(g.OwnerID)
+	g.OwnerUsername = u.Username
+	return g
+}
+
+// withUsername заполняет никнейм автора заявки.
+func (r *RepoMemory) withUsername(b ent.Bid) ent.Bid {
+	u, _ := r.store.UserByID(b.UserID)
+	b.Username = u.Username
+	return b
+}
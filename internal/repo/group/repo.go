@@ -0,0 +1,294 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	repoErr "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repo interface {
+	CreateBid(ctx context.Context, groupName, username string) (*ent.Bid, error)
+	ListBidsByUsername(ctx context.Context, username string) ([]*ent.Bid, error)
+	ListBidsByStatus(ctx context.Context, status string) ([]*ent.Bid, error)
+	ApproveBid(ctx context.Context, id int) (*ent.Group, error)
+	RejectBid(ctx context.Context, id int) (*ent.Bid, error)
+
+	GetByName(ctx context.Context, name string) (*ent.Group, error)
+	ListByUsername(ctx context.Context, username string) ([]*ent.Group, error)
+	DeleteByName(ctx context.Context, name string) error
+
+	ListMembers(ctx context.Context, groupID int) ([]string, error)
+	AddMember(ctx context.Context, groupID int, username string) error
+	RemoveMember(ctx context.Context, groupID int, username string) error
+}
+
+var _ Repo = (*RepoLayer)(nil)
+
+type RepoLayer struct {
+	dbConn *transaction.Conn
+}
+
+// NewRepoLayer возвращает структуру уровня repository. Позволяет работать с группами, их участниками
+// и заявками на создание групп.
+func NewRepoLayer(dbConn *pgxpool.Pool) *RepoLayer {
+	return &RepoLayer{
+		dbConn: transaction.NewConn(dbConn),
+	}
+}
+
+var (
+	bid_fields   = "b.id, b.group_name, b.user_id, u.username, b.status, b.created_at, b.updated_at"
+	group_fields = "g.id, g.name, g.owner_id, u.username, g.created_at, g.updated_at"
+)
+
+var (
+	sqlRowCreateBid = fmt.Sprintf(`
+		WITH b AS (
+			INSERT INTO bid (group_name, user_id, status)
+			SELECT $1::text, id, 'in_progress'::status_type FROM "user" WHERE username = $2
+			RETURNING *
+		)
+		SELECT %s FROM b JOIN "user" u ON u.id = b.user_id`, bid_fields)
+
+	sqlRowsBidsByUsername = fmt.Sprintf(
+		`SELECT %s FROM bid b JOIN "user" u ON u.id = b.user_id WHERE u.username = $1 ORDER BY b.created_at DESC`,
+		bid_fields,
+	)
+
+	sqlRowsBidsByStatus = fmt.Sprintf(
+		`SELECT %s FROM bid b JOIN "user" u ON u.id = b.user_id WHERE b.status = $1 ORDER BY b.created_at`,
+		bid_fields,
+	)
+
+	sqlRowSetBidStatus = fmt.Sprintf(`
+		WITH b AS (
+			UPDATE bid SET status = $2 WHERE id = $1 AND status = 'in_progress'
+			RETURNING *
+		)
+		SELECT %s FROM b JOIN "user" u ON u.id = b.user_id`, bid_fields)
+
+	sqlRowCreateGroup = fmt.Sprintf(`
+		WITH g AS (
+			INSERT INTO "group" (name, owner_id) VALUES ($1, $2)
+			RETURNING *
+		)
+		SELECT %s FROM g JOIN "user" u ON u.id = g.owner_id`, group_fields)
+
+	sqlRowGetByName = fmt.Sprintf(
+		`SELECT %s FROM "group" g JOIN "user" u ON u.id = g.owner_id WHERE g.name = $1`,
+		group_fields,
+	)
+
+	sqlRowsByUsername = fmt.Sprintf(`
+		SELECT %s FROM "group" g
+		JOIN "user" u ON u.id = g.owner_id
+		JOIN participation p ON p.group_id = g.id
+		JOIN "user" m ON m.id = p.user_id
+		WHERE m.username = $1 ORDER BY g.name`, group_fields)
+)
+
+// CreateBid создает заявку на создание группы. Если заявка на это название уже рассматривается,
+// возвращает ErrBidAlreadyExist, если пользователя нет — sql.ErrNoRows.
+func (r *RepoLayer) CreateBid(ctx context.Context, groupName, username string) (*ent.Bid, error) {
+	b, err := scanBid(r.dbConn.QueryRow(ctx, sqlRowCreateBid, groupName, username))
+	if f.IsPgError(err, mc.PgUniqueViolation) {
+		return nil, repoErr.ErrBidAlreadyExist
+	}
+	return b, err
+}
+
+// ListBidsByUsername возвращает заявки пользователя, начиная с последней.
+func (r *RepoLayer) ListBidsByUsername(ctx context.Context, username string) ([]*ent.Bid, error) {
+	return r.listBids(ctx, sqlRowsBidsByUsername, username)
+}
+
+// ListBidsByStatus возвращает заявки с указанным статусом в порядке подачи.
+func (r *RepoLayer) ListBidsByStatus(ctx context.Context, status string) ([]*ent.Bid, error) {
+	return r.listBids(ctx, sqlRowsBidsByStatus, status)
+}
+
+// ApproveBid одобряет заявку: в одной транзакции меняет ее статус, создает группу и добавляет в нее автора
+// заявки. Если заявка не найдена или уже рассмотрена, возвращает ErrNoRowsAffected.
+func (r *RepoLayer) ApproveBid(ctx context.Context, id int) (*ent.Group, error) {
+	tx, err := r.dbConn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	b, err := scanBid(tx.QueryRow(ctx, sqlRowSetBidStatus, id, mc.BidStatusApproved))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repoErr.ErrNoRowsAffected
+		}
+		return nil, err
+	}
+	g, err := scanGroup(tx.QueryRow(ctx, sqlRowCreateGroup, b.GroupName, b.UserID))
+	if err != nil {
+		if f.IsPgError(err, mc.PgUniqueViolation) {
+			return nil, repoErr.ErrGroupAlreadyExist
+		}
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO participation (user_id, group_id) VALUES ($1, $2)`, g.OwnerID, g.ID)
+	if err != nil {
+		return nil, err
+	}
+	return g, tx.Commit(ctx)
+}
+
+// RejectBid отклоняет заявку. Если заявка не найдена или уже рассмотрена, возвращает sql.ErrNoRows.
+func (r *RepoLayer) RejectBid(ctx context.Context, id int) (*ent.Bid, error) {
+	return scanBid(r.dbConn.QueryRow(ctx, sqlRowSetBidStatus, id, mc.BidStatusRejected))
+}
+
+// GetByName позволяет получить группу по названию.
+func (r *RepoLayer) GetByName(ctx context.Context, name string) (*ent.Group, error) {
+	return scanGroup(r.dbConn.QueryRow(ctx, sqlRowGetByName, name))
+}
+
+// ListByUsername возвращает группы, в которых состоит пользователь.
+func (r *RepoLayer) ListByUsername(ctx context.Context, username string) ([]*ent.Group, error) {
+	rows, err := r.dbConn.Query(ctx, sqlRowsByUsername, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	groups := make([]*ent.Group, 0)
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// DeleteByName удаляет группу вместе с ее участниками и правами.
+func (r *RepoLayer) DeleteByName(ctx context.Context, name string) error {
+	row, err := r.dbConn.Exec(ctx, `DELETE FROM "group" WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+	if row.RowsAffected() == 0 {
+		return repoErr.ErrNoRowsAffected
+	}
+	return nil
+}
+
+// ListMembers возвращает никнеймы участников группы.
+func (r *RepoLayer) ListMembers(ctx context.Context, groupID int) ([]string, error) {
+	rows, err := r.dbConn.Query(ctx,
+		`SELECT u.username FROM participation p JOIN "user" u ON u.id = p.user_id
+		WHERE p.group_id = $1 ORDER BY u.username`,
+		groupID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	members := make([]string, 0)
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			return nil, err
+		}
+		members = append(members, username)
+	}
+	return members, rows.Err()
+}
+
+// AddMember добавляет пользователя в группу. Если пользователь уже состоит в группе, возвращает
+// ErrAlreadyMember, если пользователя нет — ErrNoRowsAffected.
+func (r *RepoLayer) AddMember(ctx context.Context, groupID int, username string) error {
+	row, err := r.dbConn.Exec(ctx,
+		`INSERT INTO participation (user_id, group_id) SELECT id, $1::int FROM "user" WHERE username = $2`,
+		groupID, username,
+	)
+	if err != nil {
+		if f.IsPgError(err, mc.PgUniqueViolation) {
+			return repoErr.ErrAlreadyMember
+		}
+		return err
+	}
+	if row.RowsAffected() == 0 {
+		return repoErr.ErrNoRowsAffected
+	}
+	return nil
+}
+
+// RemoveMember исключает пользователя из группы. Если пользователь не состоит в группе, возвращает
+// ErrNoRowsAffected.
+func (r *RepoLayer) RemoveMember(ctx context.Context, groupID int, username string) error {
+	row, err := r.dbConn.Exec(ctx,
+		`DELETE FROM participation p USING "user" u
+		WHERE p.user_id = u.id AND p.group_id = $1 AND u.username = $2`,
+		groupID, username,
+	)
+	if err != nil {
+		return err
+	}
+	if row.RowsAffected() == 0 {
+		return repoErr.ErrNoRowsAffected
+	}
+	return nil
+}
+
+func (r *RepoLayer) listBids(ctx context.Context, query string, arg any) ([]*ent.Bid, error) {
+	rows, err := r.dbConn.Query(ctx, query, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	bids := make([]*ent.Bid, 0)
+	for rows.Next() {
+		b, err := scanBid(rows)
+		if err != nil {
+			return nil, err
+		}
+		bids = append(bids, b)
+	}
+	return bids, rows.Err()
+}
+
+func scanBid(row pgx.Row) (*ent.Bid, error) {
+	var b ent.Bid
+	err := row.Scan(
+		&b.ID,
+		&b.GroupName,
+		&b.UserID,
+		&b.Username,
+		&b.Status,
+		&b.CreatedAt,
+		&b.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func scanGroup(row pgx.Row) (*ent.Group, error) {
+	var g ent.Group
+	err := row.Scan(
+		&g.ID,
+		&g.Name,
+		&g.OwnerID,
+		&g.OwnerUsername,
+		&g.CreatedAt,
+		&g.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
@@ -0,0 +1,64 @@
+package identity
+
+import (
+	"context"
+	"database/sql"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/memory"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+)
+
+var _ Repo = (*RepoMemory)(nil)
+
+type RepoMemory struct {
+	store *memory.Store
+}
+
+// NewRepoMemory возвращает repository привязанных учетных записей внешних провайдеров, который хранит данные
+// в памяти процесса (storage: memory).
+func NewRepoMemory(store *memory.Store) *RepoMemory {
+	return &RepoMemory{
+		store: store,
+	}
+}
+
+func (r *RepoMemory) GetByProviderSubject(ctx context.Context, provider, subject string) (*ent.ExternalIdentity, error) {
+	var i ent.ExternalIdentity
+	err := r.store.Read(ctx, func() error {
+		identity, ok := r.store.Identities[memory.IdentityKey{Provider: provider, Subject: subject}]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		i = identity
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+func (r *RepoMemory) Create(ctx context.Context, initData *ent.ExternalIdentity) (*ent.ExternalIdentity, error) {
+	i := ent.ExternalIdentity{
+		Provider: initData.Provider,
+		Subject:  initData.Subject,
+		Username: initData.Username,
+		Email:    initData.Email,
+	}
+	err := r.store.Write(ctx, func() error {
+		if _, ok := r.store.Users[i.Username]; !ok {
+			return me.ErrUserNotExist
+		}
+		key := memory.IdentityKey{Provider: i.Provider, Subject: i.Subject}
+		if _, ok := r.store.Identities[key]; ok {
+			return me.ErrIdentityAlreadyLinked
+		}
+		r.store.Identities[key] = i
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
@@ -0,0 +1,89 @@
+package identity
+
+import (
+	"context"
+	"fmt"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repo interface {
+	GetByProviderSubject(ctx context.Context, provider, subject string) (*ent.ExternalIdentity, error)
+	Create(ctx context.Context, initData *ent.ExternalIdentity) (*ent.ExternalIdentity, error)
+}
+
+var _ Repo = (*RepoLayer)(nil)
+
+type RepoLayer struct {
+	dbConn *transaction.Conn
+}
+
+// NewRepoLayer возвращает структуру уровня repository. Позволяет работать с привязанными к пользователям
+// учетными записями внешних провайдеров.
+func NewRepoLayer(dbConn *pgxpool.Pool) *RepoLayer {
+	return &RepoLayer{
+		dbConn: transaction.NewConn(dbConn),
+	}
+}
+
+var (
+	identity_fields = "provider, subject, username, COALESCE(email, '')"
+)
+
+var (
+	sqlRowGetByProviderSubject = fmt.Sprintf(
+		`SELECT %s FROM user_identity WHERE provider=$1 AND subject=$2`,
+		identity_fields,
+	)
+	sqlRowCreateIdentity = fmt.Sprintf(`
+		INSERT INTO user_identity (
+			provider,
+			subject,
+			username,
+			email
+		) VALUES ($1, $2, $3, NULLIF($4, '')) RETURNING %s`, identity_fields)
+)
+
+// GetByProviderSubject позволяет найти привязку по провайдеру и идентификатору пользователя у провайдера.
+func (r *RepoLayer) GetByProviderSubject(ctx context.Context, provider, subject string) (*ent.ExternalIdentity, error) {
+	row := r.dbConn.QueryRow(ctx, sqlRowGetByProviderSubject, provider, subject)
+	return scanIdentity(row)
+}
+
+// Create привязывает внешнюю учетную запись к пользователю.
+func (r *RepoLayer) Create(ctx context.Context, initData *ent.ExternalIdentity) (*ent.ExternalIdentity, error) {
+	row := r.dbConn.QueryRow(ctx, sqlRowCreateIdentity,
+		initData.Provider,
+		initData.Subject,
+		initData.Username,
+		initData.Email,
+	)
+	i, err := scanIdentity(row)
+	if err != nil {
+		if f.IsPgError(err, mc.PgUniqueViolation) {
+			return nil, me.ErrIdentityAlreadyLinked
+		}
+		return nil, err
+	}
+	return i, nil
+}
+
+func scanIdentity(row pgx.Row) (*ent.ExternalIdentity, error) {
+	var i ent.ExternalIdentity
+	err := row.Scan(
+		&i.Provider,
+		&i.Subject,
+		&i.Username,
+		&i.Email,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
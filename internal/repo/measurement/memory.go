@@ -0,0 +1,74 @@
+package measurement
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/memory"
+)
+
+var _ Repo = (*RepoMemory)(nil)
+
+type RepoMemory struct {
+	store *memory.Store
+}
+
+// NewRepoMemory возвращает repository измерений, который хранит данные в памяти процесса (storage: memory).
+func NewRepoMemory(store *memory.Store) *RepoMemory {
+	return &RepoMemory{
+		store: store,
+	}
+}
+
+func (r *RepoMemory) Add(ctx context.Context, measurements []*ent.Measurement) error {
+	return r.store.Write(ctx, func() error {
+		for _, m := range measurements {
+			r.store.Measurements = append(r.store.Measurements, *m)
+		}
+		return nil
+	})
+}
+
+func (r *RepoMemory) List(ctx context.Context, filter *ent.MeasurementFilter) ([]*ent.Measurement, error) {
+	result := make([]*ent.Measurement, 0)
+	err := r.store.Read(ctx, func() error {
+		for _, m := range r.store.Measurements {
+			if matchMeasurement(&m, filter) {
+				result = append(result, &m)
+			}
+		}
+		return nil
+	})
+	slices.SortStableFunc(result, func(a, b *ent.Measurement) int {
+		return cmp.Compare(b.RecordedAt.UnixNano(), a.RecordedAt.UnixNano())
+	})
+	return result[:min(len(result), filter.Limit)], err
+}
+
+// matchMeasurement проверяет измерение по тем же условиям, что и RepoLayer.List.
+func matchMeasurement(m *ent.Measurement, filter *ent.MeasurementFilter) bool {
+	if m.Username != filter.Username {
+		return false
+	}
+	if filter.Kind != "" && m.Kind != filter.Kind {
+		return false
+	}
+	if filter.From != nil && m.RecordedAt.Before(*filter.From) {
+		return false
+	}
+	if filter.To != nil && !m.RecordedAt.Before(*filter.To) {
+		return false
+	}
+	return true
+}
+
+func (r *RepoMemory) DeleteByUsername(ctx context.Context, username string) error {
+	return r.store.Write(ctx, func() error {
+		r.store.Measurements = slices.DeleteFunc(r.store.Measurements, func(m ent.Measurement) bool {
+			return m.Username == username
+		})
+		return nil
+	})
+}
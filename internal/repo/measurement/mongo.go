@@ -0,0 +1,100 @@
+package measurement
+
+import (
+	"context"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var _ Repo = (*RepoMongo)(nil)
+
+type RepoMongo struct {
+	coll *mongo.Collection
+}
+
+// NewRepoMongo возвращает структуру уровня repository. Хранит измерения в коллекции временных рядов MongoDB,
+// коллекцию создает mongodb.EnsureTimeSeries.
+func NewRepoMongo(coll *mongo.Collection) *RepoMongo {
+	return &RepoMongo{
+		coll: coll,
+	}
+}
+
+// measurementDoc документ коллекции временных рядов. MongoDB группирует измерения в блоки по meta, поэтому
+// в meta лежит то, по чему измерения ищутся: пользователь и вид измерения.
+type measurementDoc struct {
+	Meta       measurementMeta `bson:"meta"`
+	Value      float64         `bson:"value"`
+	RecordedAt time.Time       `bson:"recorded_at"`
+}
+
+type measurementMeta struct {
+	Username string `bson:"username"`
+	Kind     string `bson:"kind"`
+}
+
+func (r *RepoMongo) Add(ctx context.Context, measurements []*ent.Measurement) error {
+	docs := make([]measurementDoc, 0, len(measurements))
+	for _, m := range measurements {
+		docs = append(docs, measurementDoc{
+			Meta:       measurementMeta{Username: m.Username, Kind: m.Kind},
+			Value:      m.Value,
+			RecordedAt: m.RecordedAt,
+		})
+	}
+	// в отличие от PostgreSQL вставка пачки не атомарна: при ошибке часть измерений может сохраниться
+	_, err := r.coll.InsertMany(ctx, docs)
+	return err
+}
+
+func (r *RepoMongo) List(ctx context.Context, filter *ent.MeasurementFilter) ([]*ent.Measurement, error) {
+	query := bson.D{{Key: "meta.username", Value: filter.Username}}
+	if filter.Kind != "" {
+		query = append(query, bson.E{Key: "meta.kind", Value: filter.Kind})
+	}
+	recordedAt := bson.D{}
+	if filter.From != nil {
+		recordedAt = append(recordedAt, bson.E{Key: "$gte", Value: *filter.From})
+	}
+	if filter.To != nil {
+		recordedAt = append(recordedAt, bson.E{Key: "$lt", Value: *filter.To})
+	}
+	if len(recordedAt) > 0 {
+		query = append(query, bson.E{Key: "recorded_at", Value: recordedAt})
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "recorded_at", Value: -1}}).
+		SetLimit(int64(filter.Limit))
+
+	cursor, err := r.coll.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	result := make([]*ent.Measurement, 0)
+	for cursor.Next(ctx) {
+		var doc measurementDoc
+		err = cursor.Decode(&doc)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &ent.Measurement{
+			Username:   doc.Meta.Username,
+			Kind:       doc.Meta.Kind,
+			Value:      doc.Value,
+			RecordedAt: doc.RecordedAt,
+		})
+	}
+	return result, cursor.Err()
+}
+
+// DeleteByUsername удаляет измерения пользователя. Внешних ключей в MongoDB нет, поэтому usecase вызывает
+// метод при удалении пользователя. Коллекции временных рядов поддерживают удаление по полям meta.
+func (r *RepoMongo) DeleteByUsername(ctx context.Context, username string) error {
+	_, err := r.coll.DeleteMany(ctx, bson.D{{Key: "meta.username", Value: username}})
+	return err
+}
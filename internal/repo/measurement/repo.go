@@ -0,0 +1,102 @@
+package measurement
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repo хранилище измерений. Реализации: PostgreSQL (RepoLayer) и временные ряды MongoDB (RepoMongo),
+// выбирается параметром measurements.storage. В режиме разработки (storage: memory) — RepoMemory.
+type Repo interface {
+	// Add сохраняет пачку измерений целиком.
+	Add(ctx context.Context, measurements []*ent.Measurement) error
+	// List возвращает измерения по фильтру, начиная с последних.
+	List(ctx context.Context, filter *ent.MeasurementFilter) ([]*ent.Measurement, error)
+	// DeleteByUsername удаляет все измерения пользователя.
+	DeleteByUsername(ctx context.Context, username string) error
+}
+
+var _ Repo = (*RepoLayer)(nil)
+
+type RepoLayer struct {
+	dbConn *transaction.Conn
+}
+
+// NewRepoLayer возвращает структуру уровня repository. Хранит измерения в таблице measurement.
+func NewRepoLayer(dbConn *pgxpool.Pool) *RepoLayer {
+	return &RepoLayer{
+		dbConn: transaction.NewConn(dbConn),
+	}
+}
+
+// пачка вставляется одним запросом: массивы столбцов разворачиваются в строки
+var sqlRowAddMeasurements = `
+	INSERT INTO measurement (username, kind, value, recorded_at)
+	SELECT * FROM unnest($1::TEXT[], $2::TEXT[], $3::DOUBLE PRECISION[], $4::TIMESTAMPTZ[])`
+
+func (r *RepoLayer) Add(ctx context.Context, measurements []*ent.Measurement) error {
+	usernames := make([]string, 0, len(measurements))
+	kinds := make([]string, 0, len(measurements))
+	values := make([]float64, 0, len(measurements))
+	recordedAt := make([]time.Time, 0, len(measurements))
+	for _, m := range measurements {
+		usernames = append(usernames, m.Username)
+		kinds = append(kinds, m.Kind)
+		values = append(values, m.Value)
+		recordedAt = append(recordedAt, m.RecordedAt)
+	}
+	_, err := r.dbConn.Exec(ctx, sqlRowAddMeasurements, usernames, kinds, values, recordedAt)
+	return err
+}
+
+func (r *RepoLayer) List(ctx context.Context, filter *ent.MeasurementFilter) ([]*ent.Measurement, error) {
+	conditions := []string{"username = $1"}
+	args := []any{filter.Username}
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Kind != "" {
+		add(`kind = $%d`, filter.Kind)
+	}
+	if filter.From != nil {
+		add(`recorded_at >= $%d`, *filter.From)
+	}
+	if filter.To != nil {
+		add(`recorded_at < $%d`, *filter.To)
+	}
+	args = append(args, filter.Limit)
+	query := fmt.Sprintf(
+		`SELECT username, kind, value, recorded_at FROM measurement WHERE %s ORDER BY recorded_at DESC LIMIT $%d`,
+		strings.Join(conditions, " AND "), len(args),
+	)
+
+	rows, err := r.dbConn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := make([]*ent.Measurement, 0)
+	for rows.Next() {
+		var m ent.Measurement
+		err = rows.Scan(&m.Username, &m.Kind, &m.Value, &m.RecordedAt)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &m)
+	}
+	return result, rows.Err()
+}
+
+// DeleteByUsername удаляет измерения пользователя. В PostgreSQL они удаляются вместе с пользователем
+// (ON DELETE CASCADE), метод нужен для единообразия с хранилищами без внешних ключей.
+func (r *RepoLayer) DeleteByUsername(ctx context.Context, username string) error {
+	_, err := r.dbConn.Exec(ctx, `DELETE FROM measurement WHERE username = $1`, username)
+	return err
+}
@@ -0,0 +1,227 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
+)
+
+var _ transaction.Snapshotter = (*Store)(nil)
+
+// Store база данных в памяти процесса для режима разработки (storage: memory). Таблицы повторяют схему
+// PostgreSQL, репозитории (RepoMemory в пакетах repo) работают с ними так же, как с таблицами базы: внешние
+// ключи и каскадное удаление реализованы методами Store. Данные теряются при остановке сервиса, а каждая
+// транзакция копирует таблицы для отката, поэтому хранилище рассчитано только на данные разработчика.
+//
+// Репозитории читают таблицы под RLock, а изменяют под Lock. Внутри транзакции (transaction.ManagerMemory)
+// хранилище уже заблокировано, поэтому методы Read и Write блокировку не берут.
+type Store struct {
+	sync.RWMutex
+	seq int
+
+	// пользователи и их данные, ключ — никнейм
+	Users         map[string]ent.User
+	TOTP          map[string]ent.TOTP
+	RecoveryCodes map[string][]RecoveryCode
+	// ключ — id записи
+	Tokens     map[string]ent.RefreshToken
+	ApiKeys    map[string]ent.ApiKey
+	Identities map[IdentityKey]ent.ExternalIdentity
+	Audit      []ent.AuditEvent
+
+	// группы и доступ к агентам, ключ — id записи
+	Agents          map[int]ent.Agent
+	Groups          map[int]ent.Group
+	Bids            map[int]ent.Bid
+	Participation   map[GroupMember]struct{}
+	UserPrivileges  map[UserPrivilege]struct{}
+	GroupPrivileges map[GroupPrivilege]struct{}
+
+	CoachLinks    map[string]ent.CoachLink
+	CoachComments []CoachComment
+	Measurements  []ent.Measurement
+}
+
+// RecoveryCode код восстановления второго фактора.
+type RecoveryCode struct {
+	CodeHash string
+	Used     bool
+}
+
+// IdentityKey уникальная пара провайдер — идентификатор пользователя у провайдера.
+type IdentityKey struct {
+	Provider string
+	Subject  string
+}
+
+// GroupMember участие пользователя (id) в группе.
+type GroupMember struct {
+	GroupID int
+	UserID  string
+}
+
+// UserPrivilege прямой доступ пользователя (id) к агенту.
+type UserPrivilege struct {
+	AgentID int
+	UserID  string
+}
+
+// GroupPrivilege доступ группы к агенту.
+type GroupPrivilege struct {
+	AgentID int
+	GroupID int
+}
+
+// CoachComment комментарий тренера в рамках связи LinkID.
+type CoachComment struct {
+	ID        int
+	LinkID    string
+	Text      string
+	CreatedAt time.Time
+}
+
+// NewStore возвращает пустое хранилище.
+func NewStore() *Store {
+	return &Store{
+		Users:           make(map[string]ent.User),
+		TOTP:            make(map[string]ent.TOTP),
+		RecoveryCodes:   make(map[string][]RecoveryCode),
+		Tokens:          make(map[string]ent.RefreshToken),
+		ApiKeys:         make(map[string]ent.ApiKey),
+		Identities:      make(map[IdentityKey]ent.ExternalIdentity),
+		Agents:          make(map[int]ent.Agent),
+		Groups:          make(map[int]ent.Group),
+		Bids:            make(map[int]ent.Bid),
+		Participation:   make(map[GroupMember]struct{}),
+		UserPrivileges:  make(map[UserPrivilege]struct{}),
+		GroupPrivileges: make(map[GroupPrivilege]struct{}),
+		CoachLinks:      make(map[string]ent.CoachLink),
+	}
+}
+
+// Read выполняет fn с блокировкой хранилища на чтение.
+func (s *Store) Read(ctx context.Context, fn func() error) error {
+	if !transaction.InTx(ctx) {
+		s.RLock()
+		defer s.RUnlock()
+	}
+	return fn()
+}
+
+// Write выполняет fn с блокировкой хранилища на запись.
+func (s *Store) Write(ctx context.Context, fn func() error) error {
+	if !transaction.InTx(ctx) {
+		s.Lock()
+		defer s.Unlock()
+	}
+	return fn()
+}
+
+// NextID возвращает следующее значение последовательности для целочисленных id (GENERATED AS IDENTITY).
+// Вызывается под блокировкой на запись.
+func (s *Store) NextID() int {
+	s.seq++
+	return s.seq
+}
+
+// Snapshot копирует таблицы. Записи таблиц хранятся по значению, а срезы внутри записей (scopes) при
+// изменении заменяются целиком, поэтому копии карт и срезов достаточно.
+func (s *Store) Snapshot() func() {
+	snapshot := Store{
+		seq:             s.seq,
+		Users:           maps.Clone(s.Users),
+		TOTP:            maps.Clone(s.TOTP),
+		RecoveryCodes:   cloneSlices(s.RecoveryCodes),
+		Tokens:          maps.Clone(s.Tokens),
+		ApiKeys:         maps.Clone(s.ApiKeys),
+		Identities:      maps.Clone(s.Identities),
+		Audit:           slices.Clone(s.Audit),
+		Agents:          maps.Clone(s.Agents),
+		Groups:          maps.Clone(s.Groups),
+		Bids:            maps.Clone(s.Bids),
+		Participation:   maps.Clone(s.Participation),
+		UserPrivileges:  maps.Clone(s.UserPrivileges),
+		GroupPrivileges: maps.Clone(s.GroupPrivileges),
+		CoachLinks:      maps.Clone(s.CoachLinks),
+		CoachComments:   slices.Clone(s.CoachComments),
+		Measurements:    slices.Clone(s.Measurements),
+	}
+	return func() {
+		s.seq = snapshot.seq
+		s.Users, s.TOTP, s.RecoveryCodes = snapshot.Users, snapshot.TOTP, snapshot.RecoveryCodes
+		s.Tokens, s.ApiKeys, s.Identities, s.Audit = snapshot.Tokens, snapshot.ApiKeys, snapshot.Identities, snapshot.Audit
+		s.Agents, s.Groups, s.Bids = snapshot.Agents, snapshot.Groups, snapshot.Bids
+		s.Participation, s.UserPrivileges, s.GroupPrivileges = snapshot.Participation, snapshot.UserPrivileges, snapshot.GroupPrivileges
+		s.CoachLinks, s.CoachComments, s.Measurements = snapshot.CoachLinks, snapshot.CoachComments, snapshot.Measurements
+	}
+}
+
+func cloneSlices[K comparable, V any](m map[K][]V) map[K][]V {
+	result := make(map[K][]V, len(m))
+	for k, v := range m {
+		result[k] = slices.Clone(v)
+	}
+	return result
+}
+
+// UserByID возвращает пользователя по id. Вызывается под блокировкой.
+func (s *Store) UserByID(id string) (ent.User, bool) {
+	for _, u := range s.Users {
+		if u.ID == id {
+			return u, true
+		}
+	}
+	return ent.User{}, false
+}
+
+// OwnsGroups сообщает, что пользователь отвечает за группу: такого пользователя нельзя удалить
+// (ON DELETE RESTRICT). Вызывается под блокировкой.
+func (s *Store) OwnsGroups(userID string) bool {
+	for _, g := range s.Groups {
+		if g.OwnerID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteUser удаляет пользователя вместе со всеми его данными (ON DELETE CASCADE). Вызывается под блокировкой
+// на запись.
+func (s *Store) DeleteUser(u ent.User) {
+	delete(s.Users, u.Username)
+	delete(s.TOTP, u.Username)
+	delete(s.RecoveryCodes, u.Username)
+	maps.DeleteFunc(s.Tokens, func(_ string, t ent.RefreshToken) bool { return t.Username == u.Username })
+	maps.DeleteFunc(s.ApiKeys, func(_ string, k ent.ApiKey) bool { return k.Username == u.Username })
+	maps.DeleteFunc(s.Identities, func(_ IdentityKey, i ent.ExternalIdentity) bool { return i.Username == u.Username })
+	maps.DeleteFunc(s.Bids, func(_ int, b ent.Bid) bool { return b.UserID == u.ID })
+	maps.DeleteFunc(s.Participation, func(m GroupMember, _ struct{}) bool { return m.UserID == u.ID })
+	maps.DeleteFunc(s.UserPrivileges, func(p UserPrivilege, _ struct{}) bool { return p.UserID == u.ID })
+	maps.DeleteFunc(s.CoachLinks, func(id string, l ent.CoachLink) bool {
+		if l.ClientUsername != u.Username && l.CoachUsername != u.Username {
+			return false
+		}
+		s.CoachComments = slices.DeleteFunc(s.CoachComments, func(c CoachComment) bool { return c.LinkID == id })
+		return true
+	})
+	s.Measurements = slices.DeleteFunc(s.Measurements, func(m ent.Measurement) bool { return m.Username == u.Username })
+}
+
+// DeleteGroup удаляет группу вместе с ее участниками и правами. Вызывается под блокировкой на запись.
+func (s *Store) DeleteGroup(id int) {
+	delete(s.Groups, id)
+	maps.DeleteFunc(s.Participation, func(m GroupMember, _ struct{}) bool { return m.GroupID == id })
+	maps.DeleteFunc(s.GroupPrivileges, func(p GroupPrivilege, _ struct{}) bool { return p.GroupID == id })
+}
+
+// DeleteAgent удаляет агента вместе со всеми правами на него. Вызывается под блокировкой на запись.
+func (s *Store) DeleteAgent(id int) {
+	delete(s.Agents, id)
+	maps.DeleteFunc(s.UserPrivileges, func(p UserPrivilege, _ struct{}) bool { return p.AgentID == id })
+	maps.DeleteFunc(s.GroupPrivileges, func(p GroupPrivilege, _ struct{}) bool { return p.AgentID == id })
+}
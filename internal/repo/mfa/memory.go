@@ -0,0 +1,117 @@
+package mfa
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/memory"
+	repoErr "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+)
+
+var _ Repo = (*RepoMemory)(nil)
+
+type RepoMemory struct {
+	store *memory.Store
+}
+
+// NewRepoMemory возвращает repository второго фактора, который хранит данные в памяти процесса (storage: memory).
+func NewRepoMemory(store *memory.Store) *RepoMemory {
+	return &RepoMemory{
+		store: store,
+	}
+}
+
+func (r *RepoMemory) GetByUsername(ctx context.Context, username string) (*ent.TOTP, error) {
+	var t ent.TOTP
+	err := r.store.Read(ctx, func() error {
+		totp, ok := r.store.TOTP[username]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		t = totp
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Upsert сохраняет новый секрет. Включенный второй фактор не перезаписывается, тогда возвращает sql.ErrNoRows,
+// как и RepoLayer.
+func (r *RepoMemory) Upsert(ctx context.Context, username, secretEncrypted string) (*ent.TOTP, error) {
+	var t ent.TOTP
+	err := r.store.Write(ctx, func() error {
+		if _, ok := r.store.Users[username]; !ok {
+			return repoErr.ErrUserNotExist
+		}
+		if totp, ok := r.store.TOTP[username]; ok && totp.Enabled {
+			return sql.ErrNoRows
+		}
+		t = ent.TOTP{Username: username, SecretEncrypted: secretEncrypted, CreatedAt: time.Now()}
+		r.store.TOTP[username] = t
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *RepoMemory) Enable(ctx context.Context, username string) error {
+	return r.store.Write(ctx, func() error {
+		t, ok := r.store.TOTP[username]
+		if !ok {
+			return repoErr.ErrNoRowsAffected
+		}
+		t.Enabled = true
+		r.store.TOTP[username] = t
+		return nil
+	})
+}
+
+func (r *RepoMemory) UseStep(ctx context.Context, username string, step int64) error {
+	return r.store.Write(ctx, func() error {
+		t, ok := r.store.TOTP[username]
+		if !ok || t.LastUsedStep >= step {
+			return repoErr.ErrNoRowsAffected
+		}
+		t.LastUsedStep = step
+		r.store.TOTP[username] = t
+		return nil
+	})
+}
+
+func (r *RepoMemory) DeleteByUsername(ctx context.Context, username string) error {
+	return r.store.Write(ctx, func() error {
+		delete(r.store.RecoveryCodes, username)
+		delete(r.store.TOTP, username)
+		return nil
+	})
+}
+
+func (r *RepoMemory) ReplaceRecoveryCodes(ctx context.Context, username string, codeHashes []string) error {
+	return r.store.Write(ctx, func() error {
+		codes := make([]memory.RecoveryCode, 0, len(codeHashes))
+		for _, codeHash := range codeHashes {
+			codes = append(codes, memory.RecoveryCode{CodeHash: codeHash})
+		}
+		r.store.RecoveryCodes[username] = codes
+		return nil
+	})
+}
+
+func (r *RepoMemory) UseRecoveryCode(ctx context.Context, username, codeHash string) error {
+	return r.store.Write(ctx, func() error {
+		codes := r.store.RecoveryCodes[username]
+		for i, c := range codes {
+			if c.CodeHash == codeHash && !c.Used {
+				codes[i].Used = true
+				return nil
+			}
+		}
+		return repoErr.ErrNoRowsAffected
+	})
+}
@@ -0,0 +1,142 @@
+package mfa
+
+import (
+	"context"
+	"fmt"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
+	repoErr "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repo interface {
+	GetByUsername(ctx context.Context, username string) (*ent.TOTP, error)
+	Upsert(ctx context.Context, username, secretEncrypted string) (*ent.TOTP, error)
+	Enable(ctx context.Context, username string) error
+	UseStep(ctx context.Context, username string, step int64) error
+	DeleteByUsername(ctx context.Context, username string) error
+	ReplaceRecoveryCodes(ctx context.Context, username string, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, username, codeHash string) error
+}
+
+var _ Repo = (*RepoLayer)(nil)
+
+type RepoLayer struct {
+	dbConn *transaction.Conn
+}
+
+// NewRepoLayer возвращает структуру уровня repository. Позволяет работать со вторым фактором пользователя.
+func NewRepoLayer(dbConn *pgxpool.Pool) *RepoLayer {
+	return &RepoLayer{
+		dbConn: transaction.NewConn(dbConn),
+	}
+}
+
+var (
+	totp_fields = "username, secret_encrypted, enabled, last_used_step, created_at"
+)
+
+var (
+	sqlRowGetTOTPByUsername = fmt.Sprintf(
+		`SELECT %s FROM user_totp WHERE username=$1`,
+		totp_fields,
+	)
+	// включенный второй фактор нельзя перезаписать повторной регистрацией
+	sqlRowUpsertTOTP = fmt.Sprintf(`
+		INSERT INTO user_totp (username, secret_encrypted) VALUES ($1, $2)
+		ON CONFLICT (username) DO UPDATE
+		SET secret_encrypted = EXCLUDED.secret_encrypted, last_used_step = 0, created_at = now()
+		WHERE user_totp.enabled = false
+		RETURNING %s`, totp_fields)
+)
+
+// GetByUsername позволяет получить настройки второго фактора пользователя.
+func (r *RepoLayer) GetByUsername(ctx context.Context, username string) (*ent.TOTP, error) {
+	row := r.dbConn.QueryRow(ctx, sqlRowGetTOTPByUsername, username)
+	return scanTOTP(row)
+}
+
+// Upsert сохраняет новый (еще не подтвержденный) секрет пользователя.
+func (r *RepoLayer) Upsert(ctx context.Context, username, secretEncrypted string) (*ent.TOTP, error) {
+	row := r.dbConn.QueryRow(ctx, sqlRowUpsertTOTP, username, secretEncrypted)
+	return scanTOTP(row)
+}
+
+// Enable включает второй фактор после подтверждения кодом.
+func (r *RepoLayer) Enable(ctx context.Context, username string) error {
+	row, err := r.dbConn.Exec(ctx, `UPDATE user_totp SET enabled = true WHERE username = $1`, username)
+	if err != nil {
+		return err
+	}
+	if row.RowsAffected() == 0 {
+		return repoErr.ErrNoRowsAffected
+	}
+	return nil
+}
+
+// UseStep запоминает шаг последнего принятого кода. Если код этого или более позднего шага уже был
+// использован, возвращает ErrNoRowsAffected, тем самым не давая использовать один код дважды.
+func (r *RepoLayer) UseStep(ctx context.Context, username string, step int64) error {
+	row, err := r.dbConn.Exec(ctx,
+		`UPDATE user_totp SET last_used_step = $2 WHERE username = $1 AND last_used_step < $2`,
+		username, step,
+	)
+	if err != nil {
+		return err
+	}
+	if row.RowsAffected() == 0 {
+		return repoErr.ErrNoRowsAffected
+	}
+	return nil
+}
+
+// DeleteByUsername отключает второй фактор и удаляет коды восстановления.
+func (r *RepoLayer) DeleteByUsername(ctx context.Context, username string) error {
+	batch := &pgx.Batch{}
+	batch.Queue(`DELETE FROM recovery_code WHERE username = $1`, username)
+	batch.Queue(`DELETE FROM user_totp WHERE username = $1`, username)
+	return r.dbConn.SendBatch(ctx, batch).Close()
+}
+
+// ReplaceRecoveryCodes заменяет коды восстановления пользователя новым набором.
+func (r *RepoLayer) ReplaceRecoveryCodes(ctx context.Context, username string, codeHashes []string) error {
+	batch := &pgx.Batch{}
+	batch.Queue(`DELETE FROM recovery_code WHERE username = $1`, username)
+	for _, codeHash := range codeHashes {
+		batch.Queue(`INSERT INTO recovery_code (username, code_hash) VALUES ($1, $2)`, username, codeHash)
+	}
+	return r.dbConn.SendBatch(ctx, batch).Close()
+}
+
+// UseRecoveryCode помечает код восстановления использованным. Если кода нет или он уже был использован,
+// возвращает ErrNoRowsAffected.
+func (r *RepoLayer) UseRecoveryCode(ctx context.Context, username, codeHash string) error {
+	row, err := r.dbConn.Exec(ctx,
+		`UPDATE recovery_code SET used_at = now() WHERE username = $1 AND code_hash = $2 AND used_at IS NULL`,
+		username, codeHash,
+	)
+	if err != nil {
+		return err
+	}
+	if row.RowsAffected() == 0 {
+		return repoErr.ErrNoRowsAffected
+	}
+	return nil
+}
+
+func scanTOTP(row pgx.Row) (*ent.TOTP, error) {
+	var t ent.TOTP
+	err := row.Scan(
+		&t.Username,
+		&t.SecretEncrypted,
+		&t.Enabled,
+		&t.LastUsedStep,
+		&t.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,73 @@
+package repo
+
+import (
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/agent"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/apikey"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/audit"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/coach"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/group"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/identity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/measurement"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/memory"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/mfa"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/token"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Repos репозитории сервиса и менеджер транзакций над одним хранилищем. Хранилище выбирается параметром
+// storage: PostgreSQL (NewPostgres) или память процесса для разработки (NewMemory).
+type Repos struct {
+	User        user.Repo
+	Token       token.Repo
+	Mfa         mfa.Repo
+	Identity    identity.Repo
+	ApiKey      apikey.Repo
+	Audit       audit.Repo
+	Group       group.Repo
+	Agent       agent.Repo
+	Coach       coach.Repo
+	Measurement measurement.Repo
+	TxManager   transaction.Manager
+	// DBStat состояние пула соединений с базой, nil для хранилища в памяти.
+	DBStat func() *pgxpool.Stat
+}
+
+// NewPostgres возвращает репозитории, которые хранят данные в PostgreSQL. Измерения тоже хранятся в PostgreSQL,
+// при measurements.storage: mongo вызывающая сторона заменяет Measurement.
+func NewPostgres(pool *pgxpool.Pool) *Repos {
+	return &Repos{
+		User:        user.NewRepoLayer(pool),
+		Token:       token.NewRepoLayer(pool),
+		Mfa:         mfa.NewRepoLayer(pool),
+		Identity:    identity.NewRepoLayer(pool),
+		ApiKey:      apikey.NewRepoLayer(pool),
+		Audit:       audit.NewRepoLayer(pool),
+		Group:       group.NewRepoLayer(pool),
+		Agent:       agent.NewRepoLayer(pool),
+		Coach:       coach.NewRepoLayer(pool),
+		Measurement: measurement.NewRepoLayer(pool),
+		TxManager:   transaction.NewManager(pool),
+		DBStat:      pool.Stat,
+	}
+}
+
+// NewMemory возвращает репозитории, которые хранят данные в памяти процесса. Режим разработки: сервис
+// запускается без баз данных, данные теряются при остановке.
+func NewMemory() *Repos {
+	store := memory.NewStore()
+	return &Repos{
+		User:        user.NewRepoMemory(store),
+		Token:       token.NewRepoMemory(store),
+		Mfa:         mfa.NewRepoMemory(store),
+		Identity:    identity.NewRepoMemory(store),
+		ApiKey:      apikey.NewRepoMemory(store),
+		Audit:       audit.NewRepoMemory(store),
+		Group:       group.NewRepoMemory(store),
+		Agent:       agent.NewRepoMemory(store),
+		Coach:       coach.NewRepoMemory(store),
+		Measurement: measurement.NewRepoMemory(store),
+		TxManager:   transaction.NewManagerMemory(store),
+	}
+}
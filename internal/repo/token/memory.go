@@ -0,0 +1,104 @@
+package token
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/memory"
+	repoErr "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/satori/uuid"
+)
+
+var _ Repo = (*RepoMemory)(nil)
+
+type RepoMemory struct {
+	store *memory.Store
+}
+
+// NewRepoMemory возвращает repository refresh-токенов, который хранит данные в памяти процесса (storage: memory).
+func NewRepoMemory(store *memory.Store) *RepoMemory {
+	return &RepoMemory{
+		store: store,
+	}
+}
+
+func (r *RepoMemory) Create(ctx context.Context, initData *ent.RefreshToken) (*ent.RefreshToken, error) {
+	var t ent.RefreshToken
+	err := r.store.Write(ctx, func() error {
+		if _, ok := r.store.Users[initData.Username]; !ok {
+			return repoErr.ErrUserNotExist
+		}
+		t = ent.RefreshToken{
+			ID:        uuid.NewV4().String(),
+			FamilyID:  initData.FamilyID,
+			Username:  initData.Username,
+			TokenHash: initData.TokenHash,
+			ExpiresAt: initData.ExpiresAt,
+			CreatedAt: time.Now(),
+		}
+		r.store.Tokens[t.ID] = t
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *RepoMemory) GetByHash(ctx context.Context, tokenHash string) (*ent.RefreshToken, error) {
+	var t ent.RefreshToken
+	err := r.store.Read(ctx, func() error {
+		for _, token := range r.store.Tokens {
+			if token.TokenHash == tokenHash {
+				t = token
+				return nil
+			}
+		}
+		return sql.ErrNoRows
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *RepoMemory) Rotate(ctx context.Context, id, replacedBy string) error {
+	return r.store.Write(ctx, func() error {
+		t, ok := r.store.Tokens[id]
+		if !ok || t.RevokedAt != nil {
+			return repoErr.ErrNoRowsAffected
+		}
+		timeNow := time.Now()
+		t.RevokedAt, t.ReplacedBy = &timeNow, &replacedBy
+		r.store.Tokens[id] = t
+		return nil
+	})
+}
+
+func (r *RepoMemory) RevokeFamily(ctx context.Context, familyID string) error {
+	return r.revoke(ctx, func(t *ent.RefreshToken) bool {
+		return t.FamilyID == familyID
+	})
+}
+
+func (r *RepoMemory) RevokeByUsername(ctx context.Context, username string) error {
+	return r.revoke(ctx, func(t *ent.RefreshToken) bool {
+		return t.Username == username
+	})
+}
+
+// revoke отзывает действующие токены, для которых match возвращает true.
+func (r *RepoMemory) revoke(ctx context.Context, match func(t *ent.RefreshToken) bool) error {
+	return r.store.Write(ctx, func() error {
+		timeNow := time.Now()
+		for id, t := range r.store.Tokens {
+			if t.RevokedAt == nil && match(&t) {
+				t.RevokedAt = &timeNow
+				r.store.Tokens[id] = t
+			}
+		}
+		return nil
+	})
+}
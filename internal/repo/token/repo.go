@@ -0,0 +1,121 @@
+package token
+
+import (
+	"context"
+	"fmt"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
+	repoErr "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repo interface {
+	Create(ctx context.Context, initData *ent.RefreshToken) (*ent.RefreshToken, error)
+	GetByHash(ctx context.Context, tokenHash string) (*ent.RefreshToken, error)
+	Rotate(ctx context.Context, id, replacedBy string) error
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeByUsername(ctx context.Context, username string) error
+}
+
+var _ Repo = (*RepoLayer)(nil)
+
+type RepoLayer struct {
+	dbConn *transaction.Conn
+}
+
+// NewRepoLayer возвращает структуру уровня repository. Позволяет работать с refresh-токенами пользователей.
+func NewRepoLayer(dbConn *pgxpool.Pool) *RepoLayer {
+	return &RepoLayer{
+		dbConn: transaction.NewConn(dbConn),
+	}
+}
+
+var (
+	token_fields = "id, family_id, username, token_hash, expires_at, revoked_at, replaced_by, created_at"
+)
+
+var (
+	sqlRowCreateToken = fmt.Sprintf(`
+		INSERT INTO refresh_token (
+			family_id,
+			username,
+			token_hash,
+			expires_at
+		) VALUES ($1, $2, $3, $4) RETURNING %s`, token_fields)
+
+	sqlRowGetByHash = fmt.Sprintf(
+		`SELECT %s FROM refresh_token WHERE token_hash=$1`,
+		token_fields,
+	)
+)
+
+// Create сохраняет новый refresh-токен.
+func (r *RepoLayer) Create(ctx context.Context, initData *ent.RefreshToken) (*ent.RefreshToken, error) {
+	row := r.dbConn.QueryRow(ctx, sqlRowCreateToken,
+		initData.FamilyID,
+		initData.Username,
+		initData.TokenHash,
+		initData.ExpiresAt,
+	)
+	return scanToken(row)
+}
+
+// GetByHash позволяет получить refresh-токен по его хэшу.
+func (r *RepoLayer) GetByHash(ctx context.Context, tokenHash string) (*ent.RefreshToken, error) {
+	row := r.dbConn.QueryRow(ctx, sqlRowGetByHash, tokenHash)
+	return scanToken(row)
+}
+
+// Rotate помечает токен использованным и запоминает, каким токеном он был заменен. Если токен уже был
+// отозван (например, параллельным запросом), возвращает ErrNoRowsAffected.
+func (r *RepoLayer) Rotate(ctx context.Context, id, replacedBy string) error {
+	row, err := r.dbConn.Exec(ctx,
+		`UPDATE refresh_token SET revoked_at = now(), replaced_by = $2 WHERE id = $1 AND revoked_at IS NULL`,
+		id, replacedBy,
+	)
+	if err != nil {
+		return err
+	}
+	if row.RowsAffected() == 0 {
+		return repoErr.ErrNoRowsAffected
+	}
+	return nil
+}
+
+// RevokeFamily отзывает все токены семейства, т.е. все токены, полученные из одной авторизации.
+func (r *RepoLayer) RevokeFamily(ctx context.Context, familyID string) error {
+	_, err := r.dbConn.Exec(ctx,
+		`UPDATE refresh_token SET revoked_at = now() WHERE family_id = $1 AND revoked_at IS NULL`,
+		familyID,
+	)
+	return err
+}
+
+// RevokeByUsername отзывает все действующие токены пользователя.
+func (r *RepoLayer) RevokeByUsername(ctx context.Context, username string) error {
+	_, err := r.dbConn.Exec(ctx,
+		`UPDATE refresh_token SET revoked_at = now() WHERE username = $1 AND revoked_at IS NULL`,
+		username,
+	)
+	return err
+}
+
+func scanToken(row pgx.Row) (*ent.RefreshToken, error) {
+	var t ent.RefreshToken
+	err := row.Scan(
+		&t.ID,
+		&t.FamilyID,
+		&t.Username,
+		&t.TokenHash,
+		&t.ExpiresAt,
+		&t.RevokedAt,
+		&t.ReplacedBy,
+		&t.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
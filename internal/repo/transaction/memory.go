@@ -0,0 +1,55 @@
+package transaction
+
+import (
+	"context"
+	"sync"
+)
+
+// Snapshotter хранилище в памяти процесса, изменения которого можно откатить.
+type Snapshotter interface {
+	sync.Locker
+	// Snapshot запоминает состояние хранилища и возвращает функцию, которая его восстанавливает. Вызывается
+	// под блокировкой хранилища.
+	Snapshot() (restore func())
+}
+
+var _ Manager = (*ManagerMemory)(nil)
+
+// ManagerMemory менеджер транзакций хранилища в памяти процесса. Транзакции выполняются по очереди под
+// блокировкой хранилища, поэтому уровень изоляции всегда соответствует Serializable, а повторы не нужны.
+type ManagerMemory struct {
+	store Snapshotter
+}
+
+// NewManagerMemory возвращает менеджер транзакций хранилища store.
+func NewManagerMemory(store Snapshotter) *ManagerMemory {
+	return &ManagerMemory{store: store}
+}
+
+// Do выполняет fn под блокировкой хранилища. Если fn вернула ошибку, изменения хранилища откатываются.
+// Репозитории хранилища внутри транзакции (InTx) блокировку не берут.
+func (m *ManagerMemory) Do(ctx context.Context, level Isolation, fn func(ctx context.Context) error) error {
+	if InTx(ctx) {
+		return fn(ctx)
+	}
+	st := &txState{}
+	err := m.do(ctx, st, fn)
+	if err != nil {
+		return err
+	}
+	for _, hook := range st.afterCommit {
+		hook()
+	}
+	return nil
+}
+
+func (m *ManagerMemory) do(ctx context.Context, st *txState, fn func(ctx context.Context) error) error {
+	m.store.Lock()
+	defer m.store.Unlock()
+	restore := m.store.Snapshot()
+	err := fn(context.WithValue(ctx, txKey{}, st))
+	if err != nil {
+		restore()
+	}
+	return err
+}
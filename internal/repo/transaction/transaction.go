@@ -0,0 +1,168 @@
+package transaction
+
+import (
+	"context"
+	"time"
+
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/spf13/viper"
+)
+
+// Isolation уровень изоляции транзакции.
+type Isolation = pgx.TxIsoLevel
+
+const (
+	ReadCommitted  Isolation = pgx.ReadCommitted
+	RepeatableRead Isolation = pgx.RepeatableRead
+	Serializable   Isolation = pgx.Serializable
+)
+
+// Manager выполняет вызовы нескольких репозиториев в одной транзакции. Транзакция передается через
+// контекст: репозитории, созданные через NewConn, сами выполняют запросы в транзакции из контекста.
+type Manager interface {
+	// Do выполняет fn в транзакции с уровнем изоляции level. Если fn вернула ошибку, транзакция
+	// откатывается. Ошибки сериализации и взаимоблокировки приводят к повтору fn, поэтому fn не должна
+	// иметь побочных эффектов вне базы. Вложенный вызов Do выполняет fn в уже открытой транзакции.
+	Do(ctx context.Context, level Isolation, fn func(ctx context.Context) error) error
+}
+
+var _ Manager = (*ManagerLayer)(nil)
+
+type ManagerLayer struct {
+	pool *pgxpool.Pool
+}
+
+// NewManager возвращает менеджер транзакций.
+func NewManager(pool *pgxpool.Pool) *ManagerLayer {
+	return &ManagerLayer{pool: pool}
+}
+
+type txKey struct{}
+
+// txState транзакция из контекста и действия, отложенные до ее фиксации.
+type txState struct {
+	tx          pgx.Tx
+	afterCommit []func()
+}
+
+func txFromContext(ctx context.Context) (*txState, bool) {
+	st, ok := ctx.Value(txKey{}).(*txState)
+	return st, ok
+}
+
+// InTx сообщает, что ctx содержит открытую транзакцию.
+func InTx(ctx context.Context) bool {
+	_, ok := txFromContext(ctx)
+	return ok
+}
+
+// AfterCommit выполняет fn после фиксации транзакции из контекста, а вне транзакции — сразу. Если транзакция
+// откатилась, fn не выполняется. Используется для действий вне базы, например сброса кэша: до фиксации
+// параллельные запросы еще видят старые данные и могли бы снова положить их в кэш.
+func AfterCommit(ctx context.Context, fn func()) {
+	if st, ok := txFromContext(ctx); ok {
+		st.afterCommit = append(st.afterCommit, fn)
+		return
+	}
+	fn()
+}
+
+func (m *ManagerLayer) Do(ctx context.Context, level Isolation, fn func(ctx context.Context) error) error {
+	if InTx(ctx) {
+		return fn(ctx)
+	}
+	maxRetries := viper.GetInt("postgres.tx.max_retries")
+	backoff := viper.GetDuration("postgres.tx.retry_backoff")
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = m.do(ctx, level, fn)
+		if err == nil || !isRetryable(err) || attempt >= maxRetries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff * time.Duration(attempt+1)):
+		}
+	}
+}
+
+func (m *ManagerLayer) do(ctx context.Context, level Isolation, fn func(ctx context.Context) error) error {
+	tx, err := m.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: level})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	st := &txState{tx: tx}
+	err = fn(context.WithValue(ctx, txKey{}, st))
+	if err != nil {
+		return err
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	for _, hook := range st.afterCommit {
+		hook()
+	}
+	return nil
+}
+
+// isRetryable сообщает, что транзакцию можно повторить: она откатилась из-за параллельной транзакции.
+func isRetryable(err error) bool {
+	return f.IsPgError(err, mc.PgSerializationFailure) || f.IsPgError(err, mc.PgDeadlockDetected)
+}
+
+// DB методы пула соединений и транзакции, которыми пользуются репозитории.
+type DB interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+var _ DB = (*Conn)(nil)
+
+// Conn выполняет запросы в транзакции из контекста, а вне транзакции — через пул соединений.
+type Conn struct {
+	pool *pgxpool.Pool
+}
+
+// NewConn возвращает соединение для репозитория.
+func NewConn(pool *pgxpool.Pool) *Conn {
+	return &Conn{pool: pool}
+}
+
+func (c *Conn) db(ctx context.Context) DB {
+	if st, ok := txFromContext(ctx); ok {
+		return st.tx
+	}
+	return c.pool
+}
+
+func (c *Conn) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return c.db(ctx).Exec(ctx, sql, args...)
+}
+
+func (c *Conn) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return c.db(ctx).Query(ctx, sql, args...)
+}
+
+func (c *Conn) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return c.db(ctx).QueryRow(ctx, sql, args...)
+}
+
+func (c *Conn) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return c.db(ctx).SendBatch(ctx, b)
+}
+
+// Begin начинает транзакцию, а внутри транзакции из контекста — вложенную (точку сохранения).
+func (c *Conn) Begin(ctx context.Context) (pgx.Tx, error) {
+	return c.db(ctx).Begin(ctx)
+}
@@ -0,0 +1,253 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
+	"golang.org/x/sync/singleflight"
+)
+
+var _ Repo = (*RepoCache)(nil)
+
+// profileLoadTimeout ограничение чтения профиля из базы при промахе. Чтение общее для всех ожидающих
+// запросов, поэтому не зависит от контекста запроса, который его начал.
+const profileLoadTimeout = 5 * time.Second
+
+// RepoCache кэширует профили пользователей (GetProfile) в Memcached. Остальные методы чтения идут в базу,
+// методы изменения после фиксации транзакции увеличивают версию профиля и удаляют его из кэша. Профиль
+// в кэше действителен, только пока его версия совпадает с текущей: профиль, прочитанный из базы до
+// изменения, не вернется в кэш, даже если запишется после удаления. Одновременные промахи по одному
+// пользователю объединяются в один запрос к базе.
+type RepoCache struct {
+	Repo
+	mcClient *memcache.Client
+	ttl      time.Duration
+	group    singleflight.Group
+
+	hits     atomic.Uint64
+	misses   atomic.Uint64
+	failures atomic.Uint64
+}
+
+// NewRepoCache возвращает repo с кэшем профилей поверх repo, профиль хранится в кэше не дольше ttl.
+func NewRepoCache(repo Repo, mcClient *memcache.Client, ttl time.Duration) *RepoCache {
+	return &RepoCache{
+		Repo:     repo,
+		mcClient: mcClient,
+		ttl:      ttl,
+	}
+}
+
+// cachedUser профиль пользователя в кэше, хэш пароля в кэш не попадает. Version — версия профиля на момент
+// чтения из базы.
+type cachedUser struct {
+	Version               string     `json:"version"`
+	ID                    string     `json:"id"`
+	Email                 string     `json:"email"`
+	Username              string     `json:"username"`
+	FirstName             string     `json:"first_name"`
+	Weight                float32    `json:"weight"`
+	Height                int        `json:"height"`
+	Age                   int        `json:"age"`
+	Sex                   string     `json:"sex"`
+	PhysicalActivity      string     `json:"physical_activity"`
+	DayCalories           float32    `json:"day_calories"`
+	Role                  string     `json:"role"`
+	LockedAt              *time.Time `json:"locked_at"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	CreatedAt             time.Time  `json:"created_at"`
+}
+
+// Stats возвращает счетчики попаданий и промахов кэша.
+func (r *RepoCache) Stats() ent.CacheStats {
+	return ent.CacheStats{
+		Hits:   r.hits.Load(),
+		Misses: r.misses.Load(),
+		Errors: r.failures.Load(),
+	}
+}
+
+// GetProfile возвращает профиль из кэша, а при промахе читает его из базы и кладет в кэш. Внутри транзакции
+// кэш не используется: транзакция должна видеть свои изменения.
+func (r *RepoCache) GetProfile(ctx context.Context, username string) (*ent.User, error) {
+	if transaction.InTx(ctx) {
+		return r.Repo.GetProfile(ctx, username)
+	}
+	key, vKey := profileKey(username), versionKey(username)
+	// версия читается до обращения к базе: если профиль изменят во время чтения, записанный профиль
+	// получит прежнюю версию и не будет использован
+	var version string
+	items, err := r.mcClient.GetMulti([]string{key, vKey})
+	if err == nil {
+		if item, ok := items[vKey]; ok {
+			version = string(item.Value)
+		}
+		if item, ok := items[key]; ok {
+			var cu cachedUser
+			if json.Unmarshal(item.Value, &cu) != nil {
+				r.failures.Add(1)
+			} else if cu.Version == version {
+				r.hits.Add(1)
+				return newUserFromCache(&cu), nil
+			}
+		}
+	} else {
+		// недоступный кэш не должен мешать запросу
+		r.failures.Add(1)
+	}
+	r.misses.Add(1)
+
+	v, err, _ := r.group.Do(key, func() (any, error) {
+		// отмена запроса, который начал чтение, не должна прерывать его для остальных ожидающих
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), profileLoadTimeout)
+		defer cancel()
+		uDB, err := r.Repo.GetProfile(loadCtx, username)
+		if err != nil {
+			return nil, err
+		}
+		cu := newCachedUser(uDB)
+		cu.Version = version
+		value, err := json.Marshal(cu)
+		if err == nil {
+			err = r.mcClient.Set(&memcache.Item{Key: key, Value: value, Expiration: int32(r.ttl.Seconds())})
+		}
+		if err != nil {
+			r.failures.Add(1)
+		}
+		return uDB, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// результат общий для всех ожидавших запросов, поэтому каждый получает свою копию
+	uDB := *v.(*ent.User)
+	return &uDB, nil
+}
+
+func (r *RepoCache) DeleteByUsername(ctx context.Context, username string) error {
+	err := r.Repo.DeleteByUsername(ctx, username)
+	r.invalidate(ctx, username)
+	return err
+}
+
+func (r *RepoCache) UpdateWeight(ctx context.Context, weight float32, dayCalories float64, username string) (*ent.User, error) {
+	uDB, err := r.Repo.UpdateWeight(ctx, weight, dayCalories, username)
+	r.invalidate(ctx, username)
+	return uDB, err
+}
+
+func (r *RepoCache) UpdatePassword(ctx context.Context, password, username string) error {
+	err := r.Repo.UpdatePassword(ctx, password, username)
+	r.invalidate(ctx, username)
+	return err
+}
+
+func (r *RepoCache) SetLocked(ctx context.Context, username string, locked bool) error {
+	err := r.Repo.SetLocked(ctx, username, locked)
+	r.invalidate(ctx, username)
+	return err
+}
+
+func (r *RepoCache) SetPasswordResetRequired(ctx context.Context, username string) error {
+	err := r.Repo.SetPasswordResetRequired(ctx, username)
+	r.invalidate(ctx, username)
+	return err
+}
+
+func (r *RepoCache) ResetPassword(ctx context.Context, password, username string) error {
+	err := r.Repo.ResetPassword(ctx, password, username)
+	r.invalidate(ctx, username)
+	return err
+}
+
+func (r *RepoCache) SetRole(ctx context.Context, username, role string) error {
+	err := r.Repo.SetRole(ctx, username, role)
+	r.invalidate(ctx, username)
+	return err
+}
+
+// invalidate после фиксации транзакции увеличивает версию профиля и удаляет профиль из кэша. Делаем это
+// и при ошибке изменения: запись могла успеть примениться, а лишний промах дешевле устаревшего профиля.
+func (r *RepoCache) invalidate(ctx context.Context, username string) {
+	transaction.AfterCommit(ctx, func() {
+		if err := r.bumpVersion(username); err != nil {
+			r.failures.Add(1)
+		}
+		err := r.mcClient.Delete(profileKey(username))
+		if err != nil && !errors.Is(err, memcache.ErrCacheMiss) {
+			r.failures.Add(1)
+		}
+	})
+}
+
+// bumpVersion увеличивает версию профиля. Если версии нет (не создана или вытеснена из кэша), она создается
+// со значением текущего времени, которое не совпадет ни с одной прежней версией.
+func (r *RepoCache) bumpVersion(username string) error {
+	vKey := versionKey(username)
+	_, err := r.mcClient.Increment(vKey, 1)
+	if !errors.Is(err, memcache.ErrCacheMiss) {
+		return err
+	}
+	err = r.mcClient.Add(&memcache.Item{Key: vKey, Value: []byte(strconv.FormatInt(time.Now().UnixNano(), 10))})
+	if errors.Is(err, memcache.ErrNotStored) {
+		// версию успело создать параллельное изменение
+		_, err = r.mcClient.Increment(vKey, 1)
+	}
+	return err
+}
+
+// profileKey ключ профиля в Memcached. Никнейм не содержит пробелов и управляющих символов, поэтому
+// подходит для ключа без кодирования.
+func profileKey(username string) string {
+	return "user:profile:" + username
+}
+
+// versionKey ключ версии профиля в Memcached.
+func versionKey(username string) string {
+	return "user:profile-version:" + username
+}
+
+func newCachedUser(u *ent.User) *cachedUser {
+	return &cachedUser{
+		ID:                    u.ID,
+		Email:                 u.Email,
+		Username:              u.Username,
+		FirstName:             u.FirstName,
+		Weight:                u.Weight,
+		Height:                u.Height,
+		Age:                   u.Age,
+		Sex:                   u.Sex,
+		PhysicalActivity:      u.PhysicalActivity,
+		DayCalories:           u.DayCalories,
+		Role:                  u.Role,
+		LockedAt:              u.LockedAt,
+		PasswordResetRequired: u.PasswordResetRequired,
+		CreatedAt:             u.CreatedAt,
+	}
+}
+
+func newUserFromCache(cu *cachedUser) *ent.User {
+	return &ent.User{
+		ID:                    cu.ID,
+		Email:                 cu.Email,
+		Username:              cu.Username,
+		FirstName:             cu.FirstName,
+		Weight:                cu.Weight,
+		Height:                cu.Height,
+		Age:                   cu.Age,
+		Sex:                   cu.Sex,
+		PhysicalActivity:      cu.PhysicalActivity,
+		DayCalories:           cu.DayCalories,
+		Role:                  cu.Role,
+		LockedAt:              cu.LockedAt,
+		PasswordResetRequired: cu.PasswordResetRequired,
+		CreatedAt:             cu.CreatedAt,
+	}
+}
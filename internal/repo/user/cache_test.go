@@ -0,0 +1,202 @@
+package user
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/memory"
+)
+
+// fakeMemcached сервер с текстовым протоколом Memcached, которого достаточно для RepoCache: gets, set, add,
+// delete и incr. Срок хранения не учитывается.
+type fakeMemcached struct {
+	mu    sync.Mutex
+	items map[string][]byte
+}
+
+func newFakeMemcached(t *testing.T) *memcache.Client {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	fake := &fakeMemcached{items: make(map[string][]byte)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go fake.serve(conn)
+		}
+	}()
+	return memcache.New(ln.Addr().String())
+}
+
+func (f *fakeMemcached) serve(conn net.Conn) {
+	defer conn.Close()
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return
+		}
+		var data []byte
+		if fields[0] == "set" || fields[0] == "add" {
+			size, _ := strconv.Atoi(fields[4])
+			data = make([]byte, size+2)
+			if _, err := io.ReadFull(rw, data); err != nil {
+				return
+			}
+			data = data[:size]
+		}
+		f.mu.Lock()
+		switch cmd := fields[0]; cmd {
+		case "gets", "get":
+			for _, key := range fields[1:] {
+				if value, ok := f.items[key]; ok {
+					fmt.Fprintf(rw, "VALUE %s 0 %d 1\r\n%s\r\n", key, len(value), value)
+				}
+			}
+			fmt.Fprint(rw, "END\r\n")
+		case "set", "add":
+			if _, exists := f.items[fields[1]]; cmd == "add" && exists {
+				fmt.Fprint(rw, "NOT_STORED\r\n")
+				break
+			}
+			f.items[fields[1]] = data
+			fmt.Fprint(rw, "STORED\r\n")
+		case "delete":
+			if _, ok := f.items[fields[1]]; !ok {
+				fmt.Fprint(rw, "NOT_FOUND\r\n")
+				break
+			}
+			delete(f.items, fields[1])
+			fmt.Fprint(rw, "DELETED\r\n")
+		case "incr":
+			value, ok := f.items[fields[1]]
+			if !ok {
+				fmt.Fprint(rw, "NOT_FOUND\r\n")
+				break
+			}
+			n, _ := strconv.ParseUint(string(value), 10, 64)
+			delta, _ := strconv.ParseUint(fields[2], 10, 64)
+			f.items[fields[1]] = []byte(strconv.FormatUint(n+delta, 10))
+			fmt.Fprintf(rw, "%d\r\n", n+delta)
+		default:
+			fmt.Fprint(rw, "ERROR\r\n")
+		}
+		f.mu.Unlock()
+		if rw.Flush() != nil {
+			return
+		}
+	}
+}
+
+// slowRepo задерживает первое чтение профиля: прочитав профиль из базы, ждет release и возвращает ошибку
+// контекста, если контекст чтения отменен.
+type slowRepo struct {
+	Repo
+	once    sync.Once
+	loaded  chan struct{}
+	release chan struct{}
+}
+
+func (r *slowRepo) GetProfile(ctx context.Context, username string) (*ent.User, error) {
+	u, err := r.Repo.GetProfile(ctx, username)
+	r.once.Do(func() {
+		close(r.loaded)
+		<-r.release
+	})
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return u, err
+}
+
+func newCacheFixture(t *testing.T) (*RepoCache, *slowRepo) {
+	t.Helper()
+	repo := NewRepoMemory(memory.NewStore())
+	_, err := repo.Create(context.Background(), &ent.User{
+		Username: "ivan", FirstName: "Ivan", Weight: 80, Height: 180, Age: 30, Sex: "M",
+		PhysicalActivity: "MA", Password: "hash",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slow := &slowRepo{Repo: repo, loaded: make(chan struct{}), release: make(chan struct{})}
+	return NewRepoCache(slow, newFakeMemcached(t), time.Minute), slow
+}
+
+// Профиль, прочитанный из базы до изменения, не должен вернуться в кэш, даже если записан после удаления.
+func TestRepoCacheSkipsProfileLoadedBeforeInvalidation(t *testing.T) {
+	cache, slow := newCacheFixture(t)
+	ctx := context.Background()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := cache.GetProfile(ctx, "ivan")
+		done <- err
+	}()
+	<-slow.loaded
+	if _, err := cache.UpdateWeight(ctx, 75, 2000, "ivan"); err != nil {
+		t.Fatalf("UpdateWeight() error = %v", err)
+	}
+	close(slow.release)
+	if err := <-done; err != nil {
+		t.Fatalf("GetProfile() error = %v", err)
+	}
+
+	for range 2 {
+		u, err := cache.GetProfile(ctx, "ivan")
+		if err != nil {
+			t.Fatalf("GetProfile() error = %v", err)
+		}
+		if u.Weight != 75 {
+			t.Fatalf("GetProfile() weight = %v, want 75", u.Weight)
+		}
+	}
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Errors != 0 {
+		t.Errorf("Stats() = %+v, want one hit and no errors", stats)
+	}
+}
+
+// Отмена запроса, который начал чтение профиля, не прерывает чтение.
+func TestRepoCacheLoadIgnoresCallerCancel(t *testing.T) {
+	cache, slow := newCacheFixture(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := cache.GetProfile(ctx, "ivan")
+		done <- err
+	}()
+	<-slow.loaded
+	cancel()
+	close(slow.release)
+	if err := <-done; err != nil {
+		t.Fatalf("GetProfile() error = %v, want profile read despite cancelled caller", err)
+	}
+	u, err := cache.GetProfile(context.Background(), "ivan")
+	if err != nil || u.Weight != 80 {
+		t.Fatalf("GetProfile() = %+v, %v", u, err)
+	}
+	if stats := cache.Stats(); stats.Hits != 1 {
+		t.Errorf("Stats() = %+v, want the second read from cache", stats)
+	}
+}
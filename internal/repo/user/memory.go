@@ -0,0 +1,238 @@
+package user
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/memory"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	repoErr "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/satori/uuid"
+)
+
+var _ Repo = (*RepoMemory)(nil)
+
+type RepoMemory struct {
+	store *memory.Store
+}
+
+// NewRepoMemory возвращает repository пользователей, который хранит данные в памяти процесса (storage: memory).
+func NewRepoMemory(store *memory.Store) *RepoMemory {
+	return &RepoMemory{
+		store: store,
+	}
+}
+
+func (r *RepoMemory) GetByUsername(ctx context.Context, username string) (*ent.User, error) {
+	var uDB ent.User
+	err := r.store.Read(ctx, func() error {
+		u, ok := r.store.Users[username]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		uDB = u
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &uDB, nil
+}
+
+func (r *RepoMemory) GetProfile(ctx context.Context, username string) (*ent.User, error) {
+	uDB, err := r.GetByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	uDB.Password = ""
+	return uDB, nil
+}
+
+func (r *RepoMemory) GetByEmail(ctx context.Context, email string) (*ent.User, error) {
+	var uDB ent.User
+	err := r.store.Read(ctx, func() error {
+		for _, u := range r.store.Users {
+			if email != "" && u.Email == email {
+				uDB = u
+				return nil
+			}
+		}
+		return sql.ErrNoRows
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &uDB, nil
+}
+
+func (r *RepoMemory) DeleteByUsername(ctx context.Context, username string) error {
+	return r.store.Write(ctx, func() error {
+		u, ok := r.store.Users[username]
+		if !ok {
+			return repoErr.ErrNoRowsAffected
+		}
+		if r.store.OwnsGroups(u.ID) {
+			return repoErr.ErrUserOwnsGroups
+		}
+		r.store.DeleteUser(u)
+		return nil
+	})
+}
+
+func (r *RepoMemory) Create(ctx context.Context, initData *ent.User) (*ent.User, error) {
+	var uDB ent.User
+	err := r.store.Write(ctx, func() error {
+		if _, ok := r.store.Users[initData.Username]; ok {
+			return repoErr.ErrUserAlreadyExist
+		}
+		for _, u := range r.store.Users {
+			if initData.Email != "" && u.Email == initData.Email {
+				return repoErr.ErrUserAlreadyExist
+			}
+		}
+		timeNow := time.Now()
+		uDB = ent.User{
+			ID:               uuid.NewV4().String(),
+			Email:            initData.Email,
+			Username:         initData.Username,
+			FirstName:        initData.FirstName,
+			Weight:           initData.Weight,
+			Height:           initData.Height,
+			Age:              initData.Age,
+			Sex:              initData.Sex,
+			PhysicalActivity: initData.PhysicalActivity,
+			DayCalories:      float32(int(initData.DayCalories)),
+			Password:         initData.Password,
+			Role:             cmp.Or(initData.Role, mc.RoleUser),
+			CreatedAt:        timeNow,
+		}
+		r.store.Users[uDB.Username] = uDB
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &uDB, nil
+}
+
+func (r *RepoMemory) UpdateWeight(ctx context.Context, weight float32, dayCalories float64, username string) (*ent.User, error) {
+	var uDB ent.User
+	err := r.store.Write(ctx, func() error {
+		u, ok := r.store.Users[username]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		u.Weight, u.DayCalories = weight, float32(int(dayCalories))
+		r.store.Users[username] = u
+		uDB = u
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &uDB, nil
+}
+
+func (r *RepoMemory) UpdatePassword(ctx context.Context, password, username string) error {
+	return r.update(ctx, username, func(u *ent.User) {
+		u.Password = password
+	})
+}
+
+func (r *RepoMemory) List(ctx context.Context, filter *ent.UserFilter) ([]*ent.User, int, error) {
+	var matched []ent.User
+	err := r.store.Read(ctx, func() error {
+		for _, u := range r.store.Users {
+			if matchUser(&u, filter) {
+				matched = append(matched, u)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	slices.SortFunc(matched, func(a, b ent.User) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), strings.Compare(a.Username, b.Username))
+	})
+	users := make([]*ent.User, 0)
+	for i := filter.Offset; i < len(matched) && len(users) < filter.Limit; i++ {
+		users = append(users, &matched[i])
+	}
+	return users, len(matched), nil
+}
+
+// matchUser проверяет пользователя по тем же условиям, что и userFilterCondition.
+func matchUser(u *ent.User, filter *ent.UserFilter) bool {
+	if q := strings.ToLower(filter.Query); q != "" &&
+		!strings.Contains(strings.ToLower(u.Username), q) &&
+		!strings.Contains(strings.ToLower(u.Email), q) &&
+		!strings.Contains(strings.ToLower(u.FirstName), q) {
+		return false
+	}
+	if filter.Activity != "" && u.PhysicalActivity != filter.Activity {
+		return false
+	}
+	bmi := u.Weight * 10000 / float32(u.Height*u.Height)
+	if filter.BMIMin != nil && bmi < *filter.BMIMin {
+		return false
+	}
+	if filter.BMIMax != nil && bmi >= *filter.BMIMax {
+		return false
+	}
+	if filter.CreatedFrom != nil && u.CreatedAt.Before(*filter.CreatedFrom) {
+		return false
+	}
+	if filter.CreatedTo != nil && !u.CreatedAt.Before(*filter.CreatedTo) {
+		return false
+	}
+	return true
+}
+
+func (r *RepoMemory) SetLocked(ctx context.Context, username string, locked bool) error {
+	return r.update(ctx, username, func(u *ent.User) {
+		if !locked {
+			u.LockedAt = nil
+		} else if u.LockedAt == nil {
+			timeNow := time.Now()
+			u.LockedAt = &timeNow
+		}
+	})
+}
+
+func (r *RepoMemory) SetPasswordResetRequired(ctx context.Context, username string) error {
+	return r.update(ctx, username, func(u *ent.User) {
+		u.PasswordResetRequired = true
+	})
+}
+
+func (r *RepoMemory) ResetPassword(ctx context.Context, password, username string) error {
+	return r.update(ctx, username, func(u *ent.User) {
+		u.Password = password
+		u.PasswordResetRequired = false
+	})
+}
+
+func (r *RepoMemory) SetRole(ctx context.Context, username, role string) error {
+	return r.update(ctx, username, func(u *ent.User) {
+		u.Role = role
+	})
+}
+
+// update изменяет пользователя функцией fn. Если пользователя нет, возвращает ErrNoRowsAffected.
+func (r *RepoMemory) update(ctx context.Context, username string, fn func(u *ent.User)) error {
+	return r.store.Write(ctx, func() error {
+		u, ok := r.store.Users[username]
+		if !ok {
+			return repoErr.ErrNoRowsAffected
+		}
+		fn(&u)
+		r.store.Users[username] = u
+		return nil
+	})
+}
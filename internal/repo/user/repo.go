@@ -3,37 +3,51 @@ package user
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
 	repoErr "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
 	"github.com/jackc/pgx/v5"
-	"go.mongodb.org/mongo-driver/v2/mongo"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Repo interface {
 	GetByUsername(ctx context.Context, username string) (*ent.User, error)
+	// GetProfile возвращает пользователя без хэша пароля, для отображения профиля. Результат может быть
+	// закэширован, поэтому для проверки пароля и изменения данных нужен GetByUsername.
+	GetProfile(ctx context.Context, username string) (*ent.User, error)
 	GetByEmail(ctx context.Context, email string) (*ent.User, error)
 	DeleteByUsername(ctx context.Context, username string) error
 	Create(ctx context.Context, initData *ent.User) (*ent.User, error)
 	UpdateWeight(ctx context.Context, weight float32, dayCalories float64, username string) (*ent.User, error)
+	UpdatePassword(ctx context.Context, password, username string) error
+
+	List(ctx context.Context, filter *ent.UserFilter) ([]*ent.User, int, error)
+	SetLocked(ctx context.Context, username string, locked bool) error
+	SetPasswordResetRequired(ctx context.Context, username string) error
+	ResetPassword(ctx context.Context, password, username string) error
+	SetRole(ctx context.Context, username, role string) error
 }
 
 var _ Repo = (*RepoLayer)(nil)
 
 type RepoLayer struct {
-	dbConn    *pgx.Conn
-	nosqlConn *mongo.Client
+	dbConn *transaction.Conn
 }
 
 // NewRepoLayer возвращает структуру уровня repository. Позволяет работать с пользователем (crd).
-func NewRepoLayer(dbConn *pgx.Conn) *RepoLayer {
+func NewRepoLayer(dbConn *pgxpool.Pool) *RepoLayer {
 	return &RepoLayer{
-		dbConn:    dbConn,
+		dbConn: transaction.NewConn(dbConn),
 	}
 }
 
 var (
-	user_fields = "id, username, first_name, weight, height, age, sex, physical_activity, day_calories, password"
+	user_fields = "id, COALESCE(email, ''), username, first_name, weight, height, age, sex, physical_activity, day_calories, password, role, " +
+		"locked_at, password_reset_required, created_at"
 )
 
 var (
@@ -41,8 +55,13 @@ var (
 		`SELECT %s FROM "user" WHERE username=$1`,
 		user_fields,
 	)
+	sqlRowGetByEmail = fmt.Sprintf(
+		`SELECT %s FROM "user" WHERE email=$1`,
+		user_fields,
+	)
 	sqlRowCreateUser = fmt.Sprintf(`
 		INSERT INTO "user" (
+			email,
 			username,
 			first_name,  
 			weight,
@@ -51,8 +70,10 @@ var (
 			sex, 
 			physical_activity,
 			day_calories,
-			password   
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING %s`, user_fields)
+			password,
+			role
+		) VALUES (NULLIF($1, ''), $2, $3, $4, $5, $6, $7, $8, $9, $10, COALESCE(NULLIF($11, ''), 'user')::user_role)
+		RETURNING %s`, user_fields)
 
 	sqlRowUpdateWeight = fmt.Sprintf(`
 		UPDATE "user"
@@ -63,51 +84,34 @@ var (
 // GetByUsername позволяет получить пользователя с помощью никнейма.
 func (r *RepoLayer) GetByUsername(ctx context.Context, username string) (*ent.User, error) {
 	row := r.dbConn.QueryRow(ctx, sqlRowGetByUsername, username)
-	var u ent.User
-	err := row.Scan(
-		&u.ID,
-		&u.Username,
-		&u.FirstName,
-		&u.Weight,
-		&u.Height,
-		&u.Age,
-		&u.Sex,
-		&u.PhysicalActivity,
-		&u.DayCalories,
-		&u.Password,
-	)
+	return scanUser(row)
+}
+
+// GetProfile позволяет получить профиль пользователя без хэша пароля.
+func (r *RepoLayer) GetProfile(ctx context.Context, username string) (*ent.User, error) {
+	uDB, err := r.GetByUsername(ctx, username)
 	if err != nil {
 		return nil, err
 	}
-	return &u, nil
+	uDB.Password = ""
+	return uDB, nil
 }
 
 // GetByEmail позволяет получить пользователя с помощью почты пользователя.
 func (r *RepoLayer) GetByEmail(ctx context.Context, email string) (*ent.User, error) {
-	row := r.dbConn.QueryRow(ctx, sqlRowGetByUsername, email)
-	var u ent.User
-	err := row.Scan(
-		&u.ID,
-		&u.Username,
-		&u.FirstName,
-		&u.Weight,
-		&u.Height,
-		&u.Age,
-		&u.Sex,
-		&u.PhysicalActivity,
-		&u.DayCalories,
-		&u.Password,
-	)
-	if err != nil {
-		return nil, err
-	}
-	return &u, nil
+	row := r.dbConn.QueryRow(ctx, sqlRowGetByEmail, email)
+	return scanUser(row)
 }
 
-// DeleteByUsername позволяет удалить пользователя из системы.
+// DeleteByUsername позволяет удалить пользователя из системы. Если пользователь отвечает за группу,
+// возвращает ErrUserOwnsGroups.
 func (r *RepoLayer) DeleteByUsername(ctx context.Context, username string) error {
 	row, err := r.dbConn.Exec(ctx, `DELETE FROM "user" WHERE username = $1`, username)
 	if err != nil {
+		// удалению мешает группа, за которую отвечает пользователь
+		if f.IsPgError(err, mc.PgForeignKeyViolation) {
+			return repoErr.ErrUserOwnsGroups
+		}
 		return err
 	}
 	if row.RowsAffected() == 0 {
@@ -119,6 +123,7 @@ func (r *RepoLayer) DeleteByUsername(ctx context.Context, username string) error
 // Create позволяет создать пользователя.
 func (r *RepoLayer) Create(ctx context.Context, initData *ent.User) (*ent.User, error) {
 	row := r.dbConn.QueryRow(ctx, sqlRowCreateUser,
+		initData.Email,
 		initData.Username,
 		initData.FirstName,
 		initData.Weight,
@@ -128,31 +133,160 @@ func (r *RepoLayer) Create(ctx context.Context, initData *ent.User) (*ent.User,
 		initData.PhysicalActivity,
 		int(initData.DayCalories),
 		initData.Password,
+		initData.Role,
 	)
-	var u ent.User
-	err := row.Scan(
-		&u.ID,
-		&u.Username,
-		&u.FirstName,
-		&u.Weight,
-		&u.Height,
-		&u.Age,
-		&u.Sex,
-		&u.PhysicalActivity,
-		&u.DayCalories,
-		&u.Password,
-	)
+	uDB, err := scanUser(row)
 	if err != nil {
+		// никнейм или почту успел занять параллельный запрос
+		if f.IsPgError(err, mc.PgUniqueViolation) {
+			return nil, repoErr.ErrUserAlreadyExist
+		}
 		return nil, err
 	}
-	return &u, nil
+	return uDB, nil
 }
 
 func (r *RepoLayer) UpdateWeight(ctx context.Context, weight float32, dayCalories float64, username string) (*ent.User, error) {
 	row := r.dbConn.QueryRow(ctx, sqlRowUpdateWeight, weight, int(dayCalories), username)
+	return scanUser(row)
+}
+
+// UpdatePassword позволяет заменить хэш пароля пользователя.
+func (r *RepoLayer) UpdatePassword(ctx context.Context, password, username string) error {
+	row, err := r.dbConn.Exec(ctx, `UPDATE "user" SET password = $1 WHERE username = $2`, password, username)
+	if err != nil {
+		return err
+	}
+	if row.RowsAffected() == 0 {
+		return repoErr.ErrNoRowsAffected
+	}
+	return nil
+}
+
+// List возвращает страницу пользователей, подходящих под фильтр, начиная с последних зарегистрированных,
+// и общее количество таких пользователей.
+func (r *RepoLayer) List(ctx context.Context, filter *ent.UserFilter) ([]*ent.User, int, error) {
+	where, args := userFilterCondition(filter)
+	var total int
+	err := r.dbConn.QueryRow(ctx, `SELECT count(*) FROM "user" WHERE `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	rows, err := r.dbConn.Query(ctx,
+		fmt.Sprintf(`SELECT %s FROM "user" WHERE %s ORDER BY created_at DESC, username LIMIT $%d OFFSET $%d`,
+			user_fields, where, len(args)-1, len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	users := make([]*ent.User, 0)
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, u)
+	}
+	return users, total, rows.Err()
+}
+
+// userFilterCondition собирает условие WHERE и его параметры из фильтра.
+func userFilterCondition(filter *ent.UserFilter) (string, []any) {
+	conditions := []string{"true"}
+	args := make([]any, 0)
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Query != "" {
+		add(`(username ILIKE $%[1]d OR email ILIKE $%[1]d OR first_name ILIKE $%[1]d)`, "%"+escapeLike(filter.Query)+"%")
+	}
+	if filter.Activity != "" {
+		add(`physical_activity = $%d`, filter.Activity)
+	}
+	// индекс массы тела считается так же, как в entity.BMIType: масса (кг) / рост (м)^2
+	if filter.BMIMin != nil {
+		add(`weight * 10000 / (height * height) >= $%d`, *filter.BMIMin)
+	}
+	if filter.BMIMax != nil {
+		add(`weight * 10000 / (height * height) < $%d`, *filter.BMIMax)
+	}
+	if filter.CreatedFrom != nil {
+		add(`created_at >= $%d`, *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		add(`created_at < $%d`, *filter.CreatedTo)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// SetLocked блокирует или разблокирует учетную запись. Повторная блокировка не меняет время блокировки.
+func (r *RepoLayer) SetLocked(ctx context.Context, username string, locked bool) error {
+	query := `UPDATE "user" SET locked_at = NULL WHERE username = $1`
+	if locked {
+		query = `UPDATE "user" SET locked_at = COALESCE(locked_at, now()) WHERE username = $1`
+	}
+	row, err := r.dbConn.Exec(ctx, query, username)
+	if err != nil {
+		return err
+	}
+	if row.RowsAffected() == 0 {
+		return repoErr.ErrNoRowsAffected
+	}
+	return nil
+}
+
+// SetPasswordResetRequired требует от пользователя сменить пароль при следующем входе.
+func (r *RepoLayer) SetPasswordResetRequired(ctx context.Context, username string) error {
+	row, err := r.dbConn.Exec(ctx, `UPDATE "user" SET password_reset_required = true WHERE username = $1`, username)
+	if err != nil {
+		return err
+	}
+	if row.RowsAffected() == 0 {
+		return repoErr.ErrNoRowsAffected
+	}
+	return nil
+}
+
+// ResetPassword заменяет пароль, который администратор потребовал сменить, и снимает это требование.
+func (r *RepoLayer) ResetPassword(ctx context.Context, password, username string) error {
+	row, err := r.dbConn.Exec(ctx,
+		`UPDATE "user" SET password = $1, password_reset_required = false WHERE username = $2`,
+		password, username,
+	)
+	if err != nil {
+		return err
+	}
+	if row.RowsAffected() == 0 {
+		return repoErr.ErrNoRowsAffected
+	}
+	return nil
+}
+
+// SetRole назначает пользователю роль.
+func (r *RepoLayer) SetRole(ctx context.Context, username, role string) error {
+	row, err := r.dbConn.Exec(ctx, `UPDATE "user" SET role = $1::user_role WHERE username = $2`, role, username)
+	if err != nil {
+		return err
+	}
+	if row.RowsAffected() == 0 {
+		return repoErr.ErrNoRowsAffected
+	}
+	return nil
+}
+
+func scanUser(row pgx.Row) (*ent.User, error) {
 	var u ent.User
 	err := row.Scan(
 		&u.ID,
+		&u.Email,
 		&u.Username,
 		&u.FirstName,
 		&u.Weight,
@@ -162,6 +296,10 @@ func (r *RepoLayer) UpdateWeight(ctx context.Context, weight float32, dayCalorie
 		&u.PhysicalActivity,
 		&u.DayCalories,
 		&u.Password,
+		&u.Role,
+		&u.LockedAt,
+		&u.PasswordResetRequired,
+		&u.CreatedAt,
 	)
 	if err != nil {
 		return nil, err
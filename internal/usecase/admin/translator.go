@@ -0,0 +1,33 @@
+package admin
+
+import (
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+)
+
+func newUser(data *dto.CreateData, hashedPassword, role string) *ent.User {
+	return &ent.User{
+		Username:         data.Username,
+		FirstName:        data.FirstName,
+		Weight:           data.Weight,
+		Height:           data.Height,
+		Age:              data.Age,
+		Sex:              data.Sex,
+		PhysicalActivity: data.PhysicalActivity,
+		DayCalories:      float32(f.GetDayCalories(data)),
+		Password:         hashedPassword,
+		Role:             role,
+	}
+}
+
+// mergeDetails дополняет details из запроса данными, которые известны только usecase.
+func mergeDetails(details map[string]any, extra map[string]any) map[string]any {
+	if details == nil {
+		details = make(map[string]any, len(extra))
+	}
+	for k, v := range extra {
+		details[k] = v
+	}
+	return details
+}
@@ -0,0 +1,340 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/audit"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/measurement"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/token"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/lockout"
+	ucMeasurement "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/measurement"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/tracing"
+	"go.uber.org/zap"
+)
+
+// Все методы записывают действие администратора в журнал. event содержит данные запроса (кто, откуда,
+// request_id), details заполняются здесь. Действие, которое не удалось записать в журнал, считается
+// неудавшимся: администратор получит ошибку, а причина попадет в лог сервиса. Изменения и запись в журнал
+// выполняются в одной транзакции.
+type Usecase interface {
+	ListUsers(ctx context.Context, filter *ent.UserFilter, event *ent.AuditEvent) ([]*ent.User, int, error)
+	GetUser(ctx context.Context, username string, event *ent.AuditEvent) (*ent.User, error)
+	Lock(ctx context.Context, username string, event *ent.AuditEvent) error
+	Unlock(ctx context.Context, username string, event *ent.AuditEvent) error
+	ForcePasswordReset(ctx context.Context, username string, event *ent.AuditEvent) error
+	DeleteUser(ctx context.Context, username string, event *ent.AuditEvent) error
+	CreateUser(ctx context.Context, data *dto.CreateData, role string, event *ent.AuditEvent) (*ent.User, error)
+	SetRole(ctx context.Context, username, role string, event *ent.AuditEvent) error
+	SetTemporaryPassword(ctx context.Context, username, password string, event *ent.AuditEvent) error
+}
+
+var _ Usecase = (*UsecaseLayer)(nil)
+
+type UsecaseLayer struct {
+	repoUser        user.Repo
+	repoToken       token.Repo
+	repoAudit       audit.Repo
+	repoMeasurement measurement.Repo
+	ucLockout       lockout.Usecase
+	txManager       transaction.Manager
+	logger          *zap.Logger
+}
+
+// NewUsecaseLayer возращает структуру уровня usecase для управления пользователями администратором.
+func NewUsecaseLayer(repoUser user.Repo, repoToken token.Repo, repoAudit audit.Repo, repoMeasurement measurement.Repo,
+	ucLockout lockout.Usecase, txManager transaction.Manager, logger *zap.Logger) *UsecaseLayer {
+	return &UsecaseLayer{
+		repoUser:        repoUser,
+		repoToken:       repoToken,
+		repoAudit:       repoAudit,
+		repoMeasurement: repoMeasurement,
+		ucLockout:       ucLockout,
+		txManager:       txManager,
+		logger:          logger,
+	}
+}
+
+// ListUsers ищет пользователей по фильтру.
+func (u *UsecaseLayer) ListUsers(ctx context.Context, filter *ent.UserFilter, event *ent.AuditEvent) (_ []*ent.User, _ int, err error) {
+	ctx, span := tracing.Start(ctx, "admin.ListUsers")
+	defer tracing.End(span, &err)
+
+	users, total, err := u.repoUser.List(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	event.Details = filterDetails(filter)
+	err = u.repoAudit.Create(ctx, event)
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// GetUser возвращает профиль пользователя.
+func (u *UsecaseLayer) GetUser(ctx context.Context, username string, event *ent.AuditEvent) (_ *ent.User, err error) {
+	ctx, span := tracing.Start(ctx, "admin.GetUser")
+	defer tracing.End(span, &err)
+
+	uDB, err := u.getUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	err = u.repoAudit.Create(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	return uDB, nil
+}
+
+// Lock блокирует учетную запись: пользователь не сможет войти и обновить токены, его API-ключи перестанут
+// приниматься. Уже выданные access-токены действуют до окончания своего срока.
+func (u *UsecaseLayer) Lock(ctx context.Context, username string, event *ent.AuditEvent) (err error) {
+	ctx, span := tracing.Start(ctx, "admin.Lock")
+	defer tracing.End(span, &err)
+
+	if username == event.Actor {
+		return me.ErrAdminSelf
+	}
+	return u.txManager.Do(ctx, transaction.ReadCommitted, func(ctx context.Context) error {
+		err := u.setLocked(ctx, username, true)
+		if err != nil {
+			return err
+		}
+		err = u.repoToken.RevokeByUsername(ctx, username)
+		if err != nil {
+			return err
+		}
+		return u.repoAudit.Create(ctx, event)
+	})
+}
+
+// Unlock снимает блокировку администратора, а также блокировку входа после неудачных попыток.
+func (u *UsecaseLayer) Unlock(ctx context.Context, username string, event *ent.AuditEvent) (err error) {
+	ctx, span := tracing.Start(ctx, "admin.Unlock")
+	defer tracing.End(span, &err)
+
+	var uDB *ent.User
+	err = u.txManager.Do(ctx, transaction.ReadCommitted, func(ctx context.Context) error {
+		var err error
+		uDB, err = u.getUser(ctx, username)
+		if err != nil {
+			return err
+		}
+		err = u.setLocked(ctx, username, false)
+		if err != nil {
+			return err
+		}
+		return u.repoAudit.Create(ctx, event)
+	})
+	if err != nil {
+		return err
+	}
+	// счетчики неудачных входов ведутся по логину, которым может быть и никнейм, и почта
+	logins := []string{uDB.Username}
+	if uDB.Email != "" {
+		logins = append(logins, uDB.Email)
+	}
+	for _, login := range logins {
+		err = u.ucLockout.Reset(ctx, login)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForcePasswordReset требует от пользователя сменить пароль при следующем входе и завершает все его сессии.
+func (u *UsecaseLayer) ForcePasswordReset(ctx context.Context, username string, event *ent.AuditEvent) (err error) {
+	ctx, span := tracing.Start(ctx, "admin.ForcePasswordReset")
+	defer tracing.End(span, &err)
+
+	return u.txManager.Do(ctx, transaction.ReadCommitted, func(ctx context.Context) error {
+		err := u.repoUser.SetPasswordResetRequired(ctx, username)
+		if err != nil {
+			if errors.Is(err, me.ErrNoRowsAffected) {
+				return me.ErrUserNotExist
+			}
+			return err
+		}
+		err = u.repoToken.RevokeByUsername(ctx, username)
+		if err != nil {
+			return err
+		}
+		return u.repoAudit.Create(ctx, event)
+	})
+}
+
+// DeleteUser удаляет пользователя. Измерения могут храниться вне PostgreSQL, где их не удалит внешний ключ,
+// поэтому они удаляются после фиксации транзакции: откат или повтор транзакции не затронет их. Ошибка
+// удаления измерений не возвращается, пользователь к этому моменту уже удален.
+func (u *UsecaseLayer) DeleteUser(ctx context.Context, username string, event *ent.AuditEvent) (err error) {
+	ctx, span := tracing.Start(ctx, "admin.DeleteUser")
+	defer tracing.End(span, &err)
+
+	if username == event.Actor {
+		return me.ErrAdminSelf
+	}
+	return u.txManager.Do(ctx, transaction.ReadCommitted, func(txCtx context.Context) error {
+		err := u.repoUser.DeleteByUsername(txCtx, username)
+		if err != nil {
+			if errors.Is(err, me.ErrNoRowsAffected) {
+				return me.ErrUserNotExist
+			}
+			return err
+		}
+		err = u.repoAudit.Create(txCtx, event)
+		if err != nil {
+			return err
+		}
+		transaction.AfterCommit(txCtx, func() {
+			ucMeasurement.DeleteOfDeletedUser(ctx, u.repoMeasurement, username, u.logger)
+		})
+		return nil
+	})
+}
+
+// CreateUser создает пользователя с указанной ролью. Данные профиля должны быть уже проверены.
+func (u *UsecaseLayer) CreateUser(ctx context.Context, data *dto.CreateData, role string, event *ent.AuditEvent) (_ *ent.User, err error) {
+	ctx, span := tracing.Start(ctx, "admin.CreateUser")
+	defer tracing.End(span, &err)
+
+	if _, ok := mc.RolePermissions[role]; !ok {
+		return nil, me.ErrInvalidRole
+	}
+	// хэширование занимает заметное время, поэтому выполняется до начала транзакции
+	hashedPassword, err := f.GetHashedPassword(data.Password)
+	if err != nil {
+		return nil, err
+	}
+	var uDB *ent.User
+	err = u.txManager.Do(ctx, transaction.ReadCommitted, func(ctx context.Context) error {
+		var err error
+		uDB, err = u.repoUser.Create(ctx, newUser(data, hashedPassword, role))
+		if err != nil {
+			return err
+		}
+		event.Subject = uDB.Username
+		event.Details = mergeDetails(event.Details, map[string]any{"role": role})
+		return u.repoAudit.Create(ctx, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uDB, nil
+}
+
+// SetRole назначает пользователю роль. Уже выданные access-токены сохраняют прежние права до окончания
+// своего срока, поэтому сессии пользователя завершаются.
+func (u *UsecaseLayer) SetRole(ctx context.Context, username, role string, event *ent.AuditEvent) (err error) {
+	ctx, span := tracing.Start(ctx, "admin.SetRole")
+	defer tracing.End(span, &err)
+
+	if _, ok := mc.RolePermissions[role]; !ok {
+		return me.ErrInvalidRole
+	}
+	if username == event.Actor {
+		return me.ErrAdminSelf
+	}
+	return u.txManager.Do(ctx, transaction.ReadCommitted, func(ctx context.Context) error {
+		err := u.repoUser.SetRole(ctx, username, role)
+		if err != nil {
+			if errors.Is(err, me.ErrNoRowsAffected) {
+				return me.ErrUserNotExist
+			}
+			return err
+		}
+		err = u.repoToken.RevokeByUsername(ctx, username)
+		if err != nil {
+			return err
+		}
+		event.Details = mergeDetails(event.Details, map[string]any{"role": role})
+		return u.repoAudit.Create(ctx, event)
+	})
+}
+
+// SetTemporaryPassword заменяет пароль пользователя временным: при следующем входе пользователь должен будет
+// его сменить. Все сессии пользователя завершаются.
+func (u *UsecaseLayer) SetTemporaryPassword(ctx context.Context, username, password string, event *ent.AuditEvent) (err error) {
+	ctx, span := tracing.Start(ctx, "admin.SetTemporaryPassword")
+	defer tracing.End(span, &err)
+
+	hashedPassword, err := f.GetHashedPassword(password)
+	if err != nil {
+		return err
+	}
+	return u.txManager.Do(ctx, transaction.ReadCommitted, func(ctx context.Context) error {
+		err := u.repoUser.UpdatePassword(ctx, hashedPassword, username)
+		if err != nil {
+			if errors.Is(err, me.ErrNoRowsAffected) {
+				return me.ErrUserNotExist
+			}
+			return err
+		}
+		err = u.repoUser.SetPasswordResetRequired(ctx, username)
+		if err != nil {
+			return err
+		}
+		err = u.repoToken.RevokeByUsername(ctx, username)
+		if err != nil {
+			return err
+		}
+		event.Details = mergeDetails(event.Details, map[string]any{"temporary_password": true})
+		return u.repoAudit.Create(ctx, event)
+	})
+}
+
+func (u *UsecaseLayer) getUser(ctx context.Context, username string) (*ent.User, error) {
+	uDB, err := u.repoUser.GetByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, me.ErrUserNotExist
+		}
+		return nil, err
+	}
+	return uDB, nil
+}
+
+func (u *UsecaseLayer) setLocked(ctx context.Context, username string, locked bool) error {
+	err := u.repoUser.SetLocked(ctx, username, locked)
+	if errors.Is(err, me.ErrNoRowsAffected) {
+		return me.ErrUserNotExist
+	}
+	return err
+}
+
+// filterDetails условия поиска для записи в журнал.
+func filterDetails(filter *ent.UserFilter) map[string]any {
+	details := map[string]any{
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	}
+	if filter.Query != "" {
+		details["query"] = filter.Query
+	}
+	if filter.Activity != "" {
+		details["activity"] = filter.Activity
+	}
+	if filter.BMIMin != nil {
+		details["bmi_min"] = *filter.BMIMin
+	}
+	if filter.BMIMax != nil {
+		details["bmi_max"] = *filter.BMIMax
+	}
+	if filter.CreatedFrom != nil {
+		details["created_from"] = *filter.CreatedFrom
+	}
+	if filter.CreatedTo != nil {
+		details["created_to"] = *filter.CreatedTo
+	}
+	return details
+}
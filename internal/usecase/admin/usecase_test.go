@@ -0,0 +1,273 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/attempt"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/audit"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/measurement"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/memory"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/token"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/lockout"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+var errAuditDown = errors.New("audit is down")
+
+// failingAudit имитирует сбой записи в журнал.
+type failingAudit struct {
+	audit.Repo
+}
+
+func (r *failingAudit) Create(context.Context, *ent.AuditEvent) error {
+	return errAuditDown
+}
+
+type fixture struct {
+	store     *memory.Store
+	repoUser  user.Repo
+	repoToken token.Repo
+	repoAudit audit.Repo
+	uc        *UsecaseLayer
+}
+
+// newFixture создает пользователей: ivan (MA, ИМТ 24.7), petr (LA, ИМТ 30.9), anna (MA, ИМТ 19.5).
+// Если auditFails, запись в журнал не удается.
+func newFixture(t *testing.T, auditFails bool) *fixture {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("auth.refresh_token_ttl", 24*time.Hour)
+	viper.Set("auth.argon2.memory", 1024)
+	viper.Set("auth.argon2.time", 1)
+	viper.Set("auth.argon2.threads", 1)
+	viper.Set("auth.argon2.key_length", 32)
+	viper.Set("auth.argon2.salt_length", 16)
+
+	ctx := context.Background()
+	store := memory.NewStore()
+	repoUser := user.NewRepoMemory(store)
+	users := []ent.User{
+		{Username: "ivan", Email: "ivan@example.com", FirstName: "Ivan", Weight: 80, Height: 180, PhysicalActivity: "MA"},
+		{Username: "petr", Email: "petr@example.com", FirstName: "Petr", Weight: 100, Height: 180, PhysicalActivity: "LA"},
+		{Username: "anna", Email: "anna@example.com", FirstName: "Anna", Weight: 50, Height: 160, PhysicalActivity: "MA"},
+	}
+	for _, u := range users {
+		u.Age, u.Sex, u.Password = 30, "M", "hash"
+		if _, err := repoUser.Create(ctx, &u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repoToken := token.NewRepoMemory(store)
+	var repoAudit audit.Repo = audit.NewRepoMemory(store)
+	fx := &fixture{store: store, repoUser: repoUser, repoToken: repoToken, repoAudit: repoAudit}
+	if auditFails {
+		repoAudit = &failingAudit{Repo: repoAudit}
+	}
+	fx.uc = NewUsecaseLayer(repoUser, repoToken, repoAudit, measurement.NewRepoMemory(store),
+		lockout.NewUsecaseLayer(attempt.NewRepoMemory(), nil), transaction.NewManagerMemory(store), zap.NewNop())
+	return fx
+}
+
+func event(action, subject string) *ent.AuditEvent {
+	return &ent.AuditEvent{Actor: "root", Action: action, Subject: subject, RequestID: "request"}
+}
+
+// issue выдает ivan refresh-токен и возвращает его.
+func (fx *fixture) issue(t *testing.T) string {
+	t.Helper()
+	refreshToken, err := f.NewRefreshToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fx.repoToken.Create(context.Background(), &ent.RefreshToken{
+		FamilyID:  "family",
+		Username:  "ivan",
+		TokenHash: f.HashToken(refreshToken),
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return refreshToken
+}
+
+func (fx *fixture) events(t *testing.T) []*ent.AuditEvent {
+	t.Helper()
+	events, _, err := fx.repoAudit.List(context.Background(), &ent.AuditFilter{Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func usernames(users []*ent.User) []string {
+	names := make([]string, 0, len(users))
+	for _, u := range users {
+		names = append(names, u.Username)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func float32Ptr(v float32) *float32 {
+	return &v
+}
+
+func TestListUsers(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    ent.UserFilter
+		wantLen   int
+		wantUsers []string // nil — состав страницы не проверяется
+		wantTotal int
+	}{
+		{name: "all", filter: ent.UserFilter{Limit: 10}, wantLen: 3, wantUsers: []string{"anna", "ivan", "petr"}, wantTotal: 3},
+		{name: "first page", filter: ent.UserFilter{Limit: 2}, wantLen: 2, wantTotal: 3},
+		{name: "last page", filter: ent.UserFilter{Limit: 2, Offset: 2}, wantLen: 1, wantTotal: 3},
+		{name: "offset past the end", filter: ent.UserFilter{Limit: 2, Offset: 5}, wantTotal: 3},
+		{name: "query by email", filter: ent.UserFilter{Query: "PETR@", Limit: 10}, wantLen: 1, wantUsers: []string{"petr"}, wantTotal: 1},
+		{name: "activity", filter: ent.UserFilter{Activity: "MA", Limit: 10}, wantLen: 2, wantUsers: []string{"anna", "ivan"}, wantTotal: 2},
+		{name: "bmi range", filter: ent.UserFilter{BMIMin: float32Ptr(20), BMIMax: float32Ptr(30), Limit: 10}, wantLen: 1, wantUsers: []string{"ivan"}, wantTotal: 1},
+		{name: "activity and page", filter: ent.UserFilter{Activity: "MA", Limit: 1, Offset: 1}, wantLen: 1, wantTotal: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fx := newFixture(t, false)
+
+			users, total, err := fx.uc.ListUsers(context.Background(), &tt.filter, event(mc.AuditAdminUsersList, ""))
+			if err != nil {
+				t.Fatalf("ListUsers() error = %v", err)
+			}
+			if total != tt.wantTotal || len(users) != tt.wantLen {
+				t.Errorf("ListUsers() = %d users of %d, want %d of %d", len(users), total, tt.wantLen, tt.wantTotal)
+			}
+			if got := usernames(users); tt.wantUsers != nil && !slices.Equal(got, tt.wantUsers) {
+				t.Errorf("ListUsers() = %v, want %v", got, tt.wantUsers)
+			}
+			events := fx.events(t)
+			if len(events) != 1 || events[0].Action != mc.AuditAdminUsersList || events[0].Details["limit"] != tt.filter.Limit {
+				t.Errorf("audit events = %+v, want one search with limit %d", events, tt.filter.Limit)
+			}
+		})
+	}
+}
+
+// Страницы не пересекаются и вместе дают всех пользователей.
+func TestListUsersPages(t *testing.T) {
+	fx := newFixture(t, false)
+	ctx := context.Background()
+	var seen []*ent.User
+	for offset := 0; offset < 3; offset += 2 {
+		users, _, err := fx.uc.ListUsers(ctx, &ent.UserFilter{Limit: 2, Offset: offset}, event(mc.AuditAdminUsersList, ""))
+		if err != nil {
+			t.Fatalf("ListUsers() error = %v", err)
+		}
+		seen = append(seen, users...)
+	}
+	if got := usernames(seen); !slices.Equal(got, []string{"anna", "ivan", "petr"}) {
+		t.Errorf("pages = %v, want every user once", got)
+	}
+}
+
+func TestLockRecordsAudit(t *testing.T) {
+	fx := newFixture(t, false)
+	ctx := context.Background()
+	refreshToken := fx.issue(t)
+
+	if err := fx.uc.Lock(ctx, "ivan", event(mc.AuditAdminUserLock, "ivan")); err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	uDB, err := fx.repoUser.GetByUsername(ctx, "ivan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uDB.LockedAt == nil {
+		t.Error("Lock() did not lock the user")
+	}
+	tDB, err := fx.repoToken.GetByHash(ctx, f.HashToken(refreshToken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tDB.RevokedAt == nil {
+		t.Error("Lock() did not revoke the refresh tokens")
+	}
+	events := fx.events(t)
+	if len(events) != 1 || events[0].Action != mc.AuditAdminUserLock || events[0].Actor != "root" || events[0].Subject != "ivan" {
+		t.Errorf("audit events = %+v, want one lock of ivan by root", events)
+	}
+}
+
+// Если запись в журнал не удалась, действие администратора откатывается.
+func TestAuditFailureRollsBack(t *testing.T) {
+	tests := []struct {
+		name string
+		do   func(uc *UsecaseLayer) error
+	}{
+		{name: "lock", do: func(uc *UsecaseLayer) error {
+			return uc.Lock(context.Background(), "ivan", event(mc.AuditAdminUserLock, "ivan"))
+		}},
+		{name: "password reset", do: func(uc *UsecaseLayer) error {
+			return uc.ForcePasswordReset(context.Background(), "ivan", event(mc.AuditAdminPasswordReset, "ivan"))
+		}},
+		{name: "temporary password", do: func(uc *UsecaseLayer) error {
+			return uc.SetTemporaryPassword(context.Background(), "ivan", "Temp0rary!", event(mc.AuditAdminPasswordReset, "ivan"))
+		}},
+		{name: "role", do: func(uc *UsecaseLayer) error {
+			return uc.SetRole(context.Background(), "ivan", mc.RoleCoach, event(mc.AuditAdminUserRole, "ivan"))
+		}},
+		{name: "delete", do: func(uc *UsecaseLayer) error {
+			return uc.DeleteUser(context.Background(), "ivan", event(mc.AuditAdminUserDelete, "ivan"))
+		}},
+		{name: "create", do: func(uc *UsecaseLayer) error {
+			_, err := uc.CreateUser(context.Background(), &dto.CreateData{
+				Username: "olga", FirstName: "Olga", Weight: 60, Height: 170, Age: 25, Sex: "F",
+				PhysicalActivity: "MA", Password: "Str0ng!pass",
+			}, mc.RoleCoach, event(mc.AuditAdminUserCreate, ""))
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fx := newFixture(t, true)
+			ctx := context.Background()
+			refreshToken := fx.issue(t)
+			before, err := fx.repoUser.GetByUsername(ctx, "ivan")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.do(fx.uc); !errors.Is(err, errAuditDown) {
+				t.Fatalf("error = %v, want %v", err, errAuditDown)
+			}
+			after, err := fx.repoUser.GetByUsername(ctx, "ivan")
+			if err != nil {
+				t.Fatalf("user is deleted: %v", err)
+			}
+			if after.LockedAt != nil || after.PasswordResetRequired || after.Role != before.Role || after.Password != before.Password {
+				t.Errorf("user = %+v, want unchanged %+v", after, before)
+			}
+			tDB, err := fx.repoToken.GetByHash(ctx, f.HashToken(refreshToken))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tDB.RevokedAt != nil {
+				t.Error("refresh token is revoked")
+			}
+			if _, err := fx.repoUser.GetByUsername(ctx, "olga"); err == nil {
+				t.Error("user olga is created")
+			}
+		})
+	}
+}
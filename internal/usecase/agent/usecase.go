@@ -0,0 +1,187 @@
+package agent
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/agent"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/group"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/tracing"
+)
+
+type Usecase interface {
+	Create(ctx context.Context, name string) (*ent.Agent, error)
+	List(ctx context.Context) ([]*ent.Agent, error)
+	Delete(ctx context.Context, name string) error
+
+	GrantUser(ctx context.Context, agentName, username string) error
+	RevokeUser(ctx context.Context, agentName, username string) error
+	GrantGroup(ctx context.Context, agentName, groupName string) error
+	RevokeGroup(ctx context.Context, agentName, groupName string) error
+
+	Check(ctx context.Context, username, agentName string) (*ent.Access, error)
+}
+
+var _ Usecase = (*UsecaseLayer)(nil)
+
+type UsecaseLayer struct {
+	repoAgent agent.Repo
+	repoGroup group.Repo
+	repoUser  user.Repo
+}
+
+// NewUsecaseLayer возращает структуру уровня usecase для работы с агентами и доступом к ним.
+func NewUsecaseLayer(repoAgent agent.Repo, repoGroup group.Repo, repoUser user.Repo) *UsecaseLayer {
+	return &UsecaseLayer{
+		repoAgent: repoAgent,
+		repoGroup: repoGroup,
+		repoUser:  repoUser,
+	}
+}
+
+// Create добавляет агента. Сразу после добавления доступ к нему есть только у root.
+func (u *UsecaseLayer) Create(ctx context.Context, name string) (_ *ent.Agent, err error) {
+	ctx, span := tracing.Start(ctx, "agent.Create")
+	defer tracing.End(span, &err)
+
+	return u.repoAgent.Create(ctx, name)
+}
+
+// List возвращает всех агентов.
+func (u *UsecaseLayer) List(ctx context.Context) (_ []*ent.Agent, err error) {
+	ctx, span := tracing.Start(ctx, "agent.List")
+	defer tracing.End(span, &err)
+
+	return u.repoAgent.List(ctx)
+}
+
+// Delete удаляет агента вместе со всеми правами на него.
+func (u *UsecaseLayer) Delete(ctx context.Context, name string) (err error) {
+	ctx, span := tracing.Start(ctx, "agent.Delete")
+	defer tracing.End(span, &err)
+
+	err = u.repoAgent.DeleteByName(ctx, name)
+	if errors.Is(err, me.ErrNoRowsAffected) {
+		return me.ErrAgentNotExist
+	}
+	return err
+}
+
+// GrantUser выдает пользователю прямой доступ к агенту.
+func (u *UsecaseLayer) GrantUser(ctx context.Context, agentName, username string) (err error) {
+	ctx, span := tracing.Start(ctx, "agent.GrantUser")
+	defer tracing.End(span, &err)
+
+	a, err := u.getAgent(ctx, agentName)
+	if err != nil {
+		return err
+	}
+	err = u.repoAgent.GrantUser(ctx, a.ID, username)
+	if errors.Is(err, me.ErrNoRowsAffected) {
+		return me.ErrUserNotExist
+	}
+	return err
+}
+
+// RevokeUser отзывает прямой доступ пользователя к агенту. Доступ через группы при этом сохраняется.
+func (u *UsecaseLayer) RevokeUser(ctx context.Context, agentName, username string) (err error) {
+	ctx, span := tracing.Start(ctx, "agent.RevokeUser")
+	defer tracing.End(span, &err)
+
+	a, err := u.getAgent(ctx, agentName)
+	if err != nil {
+		return err
+	}
+	err = u.repoAgent.RevokeUser(ctx, a.ID, username)
+	if errors.Is(err, me.ErrNoRowsAffected) {
+		return me.ErrPrivilegeNotExist
+	}
+	return err
+}
+
+// GrantGroup выдает группе доступ к агенту, его наследуют все участники группы.
+func (u *UsecaseLayer) GrantGroup(ctx context.Context, agentName, groupName string) (err error) {
+	ctx, span := tracing.Start(ctx, "agent.GrantGroup")
+	defer tracing.End(span, &err)
+
+	a, g, err := u.getAgentAndGroup(ctx, agentName, groupName)
+	if err != nil {
+		return err
+	}
+	return u.repoAgent.GrantGroup(ctx, a.ID, g.ID)
+}
+
+// RevokeGroup отзывает доступ группы к агенту.
+func (u *UsecaseLayer) RevokeGroup(ctx context.Context, agentName, groupName string) (err error) {
+	ctx, span := tracing.Start(ctx, "agent.RevokeGroup")
+	defer tracing.End(span, &err)
+
+	a, g, err := u.getAgentAndGroup(ctx, agentName, groupName)
+	if err != nil {
+		return err
+	}
+	err = u.repoAgent.RevokeGroup(ctx, a.ID, g.ID)
+	if errors.Is(err, me.ErrNoRowsAffected) {
+		return me.ErrPrivilegeNotExist
+	}
+	return err
+}
+
+// Check проверяет доступ пользователя к агенту. Доступ есть, если он выдан пользователю напрямую или любой из
+// его групп; у root есть доступ ко всем агентам.
+func (u *UsecaseLayer) Check(ctx context.Context, username, agentName string) (_ *ent.Access, err error) {
+	ctx, span := tracing.Start(ctx, "agent.Check")
+	defer tracing.End(span, &err)
+
+	uDB, err := u.repoUser.GetByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, me.ErrUserNotExist
+		}
+		return nil, err
+	}
+	a, err := u.getAgent(ctx, agentName)
+	if err != nil {
+		return nil, err
+	}
+	sources, err := u.repoAgent.GetAccessSources(ctx, a.ID, uDB.Username)
+	if err != nil {
+		return nil, err
+	}
+	if f.RoleHasPermission(uDB.Role, mc.PermAgentsManage) {
+		sources = append([]string{mc.AccessSourceRoot}, sources...)
+	}
+	return &ent.Access{Username: uDB.Username, Agent: a.Name, Sources: sources}, nil
+}
+
+func (u *UsecaseLayer) getAgent(ctx context.Context, name string) (*ent.Agent, error) {
+	a, err := u.repoAgent.GetByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, me.ErrAgentNotExist
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+func (u *UsecaseLayer) getAgentAndGroup(ctx context.Context, agentName, groupName string) (*ent.Agent, *ent.Group, error) {
+	a, err := u.getAgent(ctx, agentName)
+	if err != nil {
+		return nil, nil, err
+	}
+	g, err := u.repoGroup.GetByName(ctx, groupName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, me.ErrGroupNotExist
+		}
+		return nil, nil, err
+	}
+	return a, g, nil
+}
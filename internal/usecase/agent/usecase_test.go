@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/agent"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/group"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/memory"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+)
+
+// newTestUsecase возвращает usecase с агентом grafana, группой runners (участники ivan и petr) и
+// пользователями anna и root. Группе runners и пользователю ivan выдан доступ к grafana.
+func newTestUsecase(t *testing.T) *UsecaseLayer {
+	t.Helper()
+	ctx := context.Background()
+	store := memory.NewStore()
+	repoUser := user.NewRepoMemory(store)
+	for _, username := range []string{"ivan", "petr", "anna", "root"} {
+		_, err := repoUser.Create(ctx, &ent.User{
+			Username: username, FirstName: username, Weight: 80, Height: 180, Age: 30, Sex: "M",
+			PhysicalActivity: "MA", Password: "hash",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repoUser.SetRole(ctx, "root", mc.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	repoGroup := group.NewRepoMemory(store)
+	b, err := repoGroup.CreateBid(ctx, "runners", "ivan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := repoGroup.ApproveBid(ctx, b.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repoGroup.AddMember(ctx, g.ID, "petr"); err != nil {
+		t.Fatal(err)
+	}
+
+	uc := NewUsecaseLayer(agent.NewRepoMemory(store), repoGroup, repoUser)
+	if _, err := uc.Create(ctx, "grafana"); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := uc.GrantUser(ctx, "grafana", "ivan"); err != nil {
+		t.Fatalf("GrantUser() error = %v", err)
+	}
+	if err := uc.GrantGroup(ctx, "grafana", "runners"); err != nil {
+		t.Fatalf("GrantGroup() error = %v", err)
+	}
+	return uc
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		username    string
+		agent       string
+		wantSources []string
+		wantErr     error
+	}{
+		{name: "direct and group", username: "ivan", agent: "grafana", wantSources: []string{mc.AccessSourceDirect, mc.AccessSourceGroup + "runners"}},
+		{name: "group", username: "petr", agent: "grafana", wantSources: []string{mc.AccessSourceGroup + "runners"}},
+		{name: "root", username: "root", agent: "grafana", wantSources: []string{mc.AccessSourceRoot}},
+		{name: "no access", username: "anna", agent: "grafana", wantSources: []string{}},
+		{name: "unknown user", username: "unknown", agent: "grafana", wantErr: me.ErrUserNotExist},
+		{name: "unknown agent", username: "ivan", agent: "unknown", wantErr: me.ErrAgentNotExist},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := newTestUsecase(t)
+
+			access, err := uc.Check(context.Background(), tt.username, tt.agent)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Check() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !slices.Equal(access.Sources, tt.wantSources) {
+				t.Errorf("Check() sources = %v, want %v", access.Sources, tt.wantSources)
+			}
+		})
+	}
+}
+
+// Отзыв прямого доступа не затрагивает доступ через группу, и наоборот.
+func TestCheckAfterRevoke(t *testing.T) {
+	uc := newTestUsecase(t)
+	ctx := context.Background()
+
+	if err := uc.RevokeUser(ctx, "grafana", "ivan"); err != nil {
+		t.Fatalf("RevokeUser() error = %v", err)
+	}
+	access, err := uc.Check(ctx, "ivan", "grafana")
+	if err != nil || !slices.Equal(access.Sources, []string{mc.AccessSourceGroup + "runners"}) {
+		t.Errorf("Check() after RevokeUser = %+v, %v, want access through runners", access, err)
+	}
+	if err := uc.RevokeUser(ctx, "grafana", "ivan"); !errors.Is(err, me.ErrPrivilegeNotExist) {
+		t.Errorf("RevokeUser() twice error = %v, want %v", err, me.ErrPrivilegeNotExist)
+	}
+
+	if err := uc.GrantUser(ctx, "grafana", "petr"); err != nil {
+		t.Fatalf("GrantUser() error = %v", err)
+	}
+	if err := uc.RevokeGroup(ctx, "grafana", "runners"); err != nil {
+		t.Fatalf("RevokeGroup() error = %v", err)
+	}
+	access, err = uc.Check(ctx, "petr", "grafana")
+	if err != nil || !slices.Equal(access.Sources, []string{mc.AccessSourceDirect}) {
+		t.Errorf("Check() after RevokeGroup = %+v, %v, want direct access", access, err)
+	}
+	access, err = uc.Check(ctx, "ivan", "grafana")
+	if err != nil || len(access.Sources) != 0 {
+		t.Errorf("Check() after revoking everything = %+v, %v, want no access", access, err)
+	}
+}
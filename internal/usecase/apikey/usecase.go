@@ -0,0 +1,109 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/apikey"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/tracing"
+	"github.com/spf13/viper"
+)
+
+type Usecase interface {
+	Create(ctx context.Context, username, name string, scopes []string) (*ent.ApiKey, string, error)
+	List(ctx context.Context, username string) ([]*ent.ApiKey, error)
+	Revoke(ctx context.Context, id, username string) error
+	Authenticate(ctx context.Context, rawKey string) (*ent.ApiKey, error)
+}
+
+var _ Usecase = (*UsecaseLayer)(nil)
+
+type UsecaseLayer struct {
+	repoApiKey apikey.Repo
+}
+
+// NewUsecaseLayer возращает структуру уровня usecase для работы с API-ключами.
+func NewUsecaseLayer(repoApiKey apikey.Repo) *UsecaseLayer {
+	return &UsecaseLayer{
+		repoApiKey: repoApiKey,
+	}
+}
+
+// Create выпускает новый API-ключ. Ключ в открытом виде возвращается только здесь, в базе хранится его хэш.
+func (u *UsecaseLayer) Create(ctx context.Context, username, name string, scopes []string) (_ *ent.ApiKey, _ string, err error) {
+	ctx, span := tracing.Start(ctx, "apikey.Create")
+	defer tracing.End(span, &err)
+
+	count, err := u.repoApiKey.CountActive(ctx, username)
+	if err != nil {
+		return nil, "", err
+	}
+	if count >= viper.GetInt("auth.api_keys.max_per_user") {
+		return nil, "", me.ErrApiKeyLimit
+	}
+	secret, err := f.NewRefreshToken()
+	if err != nil {
+		return nil, "", err
+	}
+	rawKey := mc.ApiKeyPrefix + secret
+	k, err := u.repoApiKey.Create(ctx, &ent.ApiKey{
+		Username: username,
+		Name:     name,
+		Prefix:   rawKey[:mc.ApiKeyShownLen],
+		KeyHash:  f.HashToken(rawKey),
+		Scopes:   scopes,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return k, rawKey, nil
+}
+
+// List возвращает действующие API-ключи пользователя.
+func (u *UsecaseLayer) List(ctx context.Context, username string) (_ []*ent.ApiKey, err error) {
+	ctx, span := tracing.Start(ctx, "apikey.List")
+	defer tracing.End(span, &err)
+
+	return u.repoApiKey.ListByUsername(ctx, username)
+}
+
+// Revoke отзывает API-ключ пользователя.
+func (u *UsecaseLayer) Revoke(ctx context.Context, id, username string) (err error) {
+	ctx, span := tracing.Start(ctx, "apikey.Revoke")
+	defer tracing.End(span, &err)
+
+	err = u.repoApiKey.Revoke(ctx, id, username)
+	if errors.Is(err, me.ErrNoRowsAffected) {
+		return me.ErrApiKeyNotFound
+	}
+	return err
+}
+
+// Authenticate находит действующий API-ключ и отмечает время его использования.
+func (u *UsecaseLayer) Authenticate(ctx context.Context, rawKey string) (_ *ent.ApiKey, err error) {
+	ctx, span := tracing.Start(ctx, "apikey.Authenticate")
+	defer tracing.End(span, &err)
+
+	if !strings.HasPrefix(rawKey, mc.ApiKeyPrefix) {
+		return nil, me.ErrInvalidApiKey
+	}
+	k, err := u.repoApiKey.GetByHash(ctx, f.HashToken(rawKey))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, me.ErrInvalidApiKey
+		}
+		return nil, err
+	}
+	if k.RevokedAt != nil {
+		return nil, me.ErrInvalidApiKey
+	}
+	// время последнего использования носит справочный характер, его ошибка не должна мешать запросу
+	_ = u.repoApiKey.TouchLastUsed(ctx, k.ID)
+	return k, nil
+}
@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/audit"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/tracing"
+)
+
+type Usecase interface {
+	Record(ctx context.Context, e *ent.AuditEvent) error
+	List(ctx context.Context, filter *ent.AuditFilter) ([]*ent.AuditEvent, int, error)
+	ListSecurityActivity(ctx context.Context, username string, limit, offset int) ([]*ent.AuditEvent, int, error)
+}
+
+var _ Usecase = (*UsecaseLayer)(nil)
+
+type UsecaseLayer struct {
+	repoAudit audit.Repo
+	repoUser  user.Repo
+}
+
+// NewUsecaseLayer возращает структуру уровня usecase для работы с журналом действий.
+func NewUsecaseLayer(repoAudit audit.Repo, repoUser user.Repo) *UsecaseLayer {
+	return &UsecaseLayer{
+		repoAudit: repoAudit,
+		repoUser:  repoUser,
+	}
+}
+
+// Record записывает действие в журнал.
+func (u *UsecaseLayer) Record(ctx context.Context, e *ent.AuditEvent) (err error) {
+	ctx, span := tracing.Start(ctx, "audit.Record")
+	defer tracing.End(span, &err)
+
+	return u.repoAudit.Create(ctx, e)
+}
+
+// List ищет записи журнала по фильтру.
+func (u *UsecaseLayer) List(ctx context.Context, filter *ent.AuditFilter) (_ []*ent.AuditEvent, _ int, err error) {
+	ctx, span := tracing.Start(ctx, "audit.List")
+	defer tracing.End(span, &err)
+
+	return u.repoAudit.List(ctx, filter)
+}
+
+// ListSecurityActivity возвращает записи журнала об учетной записи пользователя. Неудачные входы по почте
+// записываются с почтой в качестве subject, поэтому ищем и по никнейму, и по почте.
+func (u *UsecaseLayer) ListSecurityActivity(ctx context.Context, username string, limit, offset int) (_ []*ent.AuditEvent, _ int, err error) {
+	ctx, span := tracing.Start(ctx, "audit.ListSecurityActivity")
+	defer tracing.End(span, &err)
+
+	uDB, err := u.repoUser.GetByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, 0, me.ErrUserNotExist
+		}
+		return nil, 0, err
+	}
+	subjects := []string{uDB.Username}
+	if uDB.Email != "" {
+		subjects = append(subjects, uDB.Email)
+	}
+	return u.repoAudit.List(ctx, &ent.AuditFilter{Subjects: subjects, Limit: limit, Offset: offset})
+}
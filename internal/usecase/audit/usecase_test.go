@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/audit"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/memory"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+)
+
+// newTestUsecase создает пользователя ivan и журнал: вход ivan, неудачный вход по его почте, блокировка ivan
+// администратором и вход petr.
+func newTestUsecase(t *testing.T) *UsecaseLayer {
+	t.Helper()
+	ctx := context.Background()
+	store := memory.NewStore()
+	repoUser := user.NewRepoMemory(store)
+	_, err := repoUser.Create(ctx, &ent.User{
+		Username: "ivan", Email: "ivan@example.com", FirstName: "Ivan", Weight: 80, Height: 180, Age: 30, Sex: "M",
+		PhysicalActivity: "MA", Password: "hash",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc := NewUsecaseLayer(audit.NewRepoMemory(store), repoUser)
+	events := []*ent.AuditEvent{
+		{Actor: "ivan", Action: mc.AuditSignInSuccess, Subject: "ivan"},
+		{Action: mc.AuditSignInFailure, Subject: "ivan@example.com"},
+		{Actor: "root", Action: mc.AuditAdminUserLock, Subject: "ivan"},
+		{Actor: "petr", Action: mc.AuditSignInSuccess, Subject: "petr"},
+	}
+	for _, e := range events {
+		if err := uc.Record(ctx, e); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	return uc
+}
+
+func TestListSecurityActivity(t *testing.T) {
+	uc := newTestUsecase(t)
+
+	events, total, err := uc.ListSecurityActivity(context.Background(), "ivan", 10, 0)
+	if err != nil {
+		t.Fatalf("ListSecurityActivity() error = %v", err)
+	}
+	if total != 3 || len(events) != 3 {
+		t.Fatalf("ListSecurityActivity() = %d events of %d, want 3 of 3", len(events), total)
+	}
+	// записи начинаются с последней
+	wantActions := []string{mc.AuditAdminUserLock, mc.AuditSignInFailure, mc.AuditSignInSuccess}
+	for i, e := range events {
+		if e.Action != wantActions[i] || e.Subject == "petr" {
+			t.Errorf("event %d = %+v, want %s about ivan", i, e, wantActions[i])
+		}
+	}
+}
+
+func TestListSecurityActivityPage(t *testing.T) {
+	uc := newTestUsecase(t)
+
+	events, total, err := uc.ListSecurityActivity(context.Background(), "ivan", 2, 2)
+	if err != nil {
+		t.Fatalf("ListSecurityActivity() error = %v", err)
+	}
+	if total != 3 || len(events) != 1 || events[0].Action != mc.AuditSignInSuccess {
+		t.Errorf("ListSecurityActivity() = %+v of %d, want the first sign-in of 3", events, total)
+	}
+}
+
+func TestListSecurityActivityUnknownUser(t *testing.T) {
+	uc := newTestUsecase(t)
+
+	_, _, err := uc.ListSecurityActivity(context.Background(), "unknown", 10, 0)
+	if !errors.Is(err, me.ErrUserNotExist) {
+		t.Errorf("ListSecurityActivity() error = %v, want %v", err, me.ErrUserNotExist)
+	}
+}
@@ -11,11 +11,14 @@ import (
 	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
 	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
 	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/tracing"
 )
 
 type Usecase interface {
 	SignUp(ctx context.Context, signUpData *dto.CreateData) (*ent.User, error)
-	SignIn(ctx context.Context, authData *dto.AuthData) (*ent.User, error)
+	SignIn(ctx context.Context, authData *dto.AuthData) (*ent.SignInResult, error)
+	ResetPassword(ctx context.Context, u *ent.User, newPasswordHash string) error
+	ResolveLogin(ctx context.Context, login string) (string, error)
 }
 
 var _ Usecase = (*UsecaseLayer)(nil)
@@ -32,9 +35,13 @@ func NewUsecaseLayer(repoUser user.Repo) *UsecaseLayer {
 }
 
 // SignUp регистрирует пользователя.
-func (u *UsecaseLayer) SignUp(ctx context.Context, authData *dto.CreateData) (*ent.User, error) {
+func (u *UsecaseLayer) SignUp(ctx context.Context, authData *dto.CreateData) (_ *ent.User, err error) {
+	ctx, span := tracing.Start(ctx, "auth.SignUp")
+	defer tracing.End(span, &err)
+
 	// проверяем, существует ли пользователь c таким некнеймом
-	// если да, то возвращаем ошибку
+	// если да, то возвращаем ошибку. Параллельную регистрацию с тем же никнеймом отклонит уникальный индекс,
+	// тогда Create тоже вернет ErrUserAlreadyExist
 	uDB, err := u.repoUser.GetByUsername(ctx, authData.Username)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return nil, err
@@ -55,8 +62,12 @@ func (u *UsecaseLayer) SignUp(ctx context.Context, authData *dto.CreateData) (*e
 	return userNew, nil
 }
 
-// SignIn авторизует пользователя.
-func (u *UsecaseLayer) SignIn(ctx context.Context, authData *dto.AuthData) (*ent.User, error) {
+// SignIn проверяет пароль пользователя. Если администратор потребовал сменить пароль, новый пароль только
+// проверяется и хэшируется: сохранить его нужно через ResetPassword, когда вход будет завершен.
+func (u *UsecaseLayer) SignIn(ctx context.Context, authData *dto.AuthData) (_ *ent.SignInResult, err error) {
+	ctx, span := tracing.Start(ctx, "auth.SignIn")
+	defer tracing.End(span, &err)
+
 	var dbUser *ent.User
 	loginIsEmail := govalidator.IsEmail(authData.Login)
 	// если авторизация была по почте, то:
@@ -79,8 +90,84 @@ func (u *UsecaseLayer) SignIn(ctx context.Context, authData *dto.AuthData) (*ent
 		}
 		dbUser = uDB
 	}
-	if !f.IsPasswordsEqual(authData.Password, dbUser.Password) {
+	ok, needsRehash, err := f.VerifyPassword(authData.Password, dbUser.Password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
 		return nil, me.ErrIncorrectPwdOrLogin
 	}
-	return dbUser, nil
+	if dbUser.LockedAt != nil {
+		return nil, me.ErrAccountLocked
+	}
+	// администратор потребовал сменить пароль: вход возможен только вместе с новым паролем
+	if dbUser.PasswordResetRequired {
+		newPasswordHash, err := newPasswordHash(dbUser, authData)
+		if err != nil {
+			return nil, err
+		}
+		return &ent.SignInResult{User: dbUser, NewPasswordHash: newPasswordHash}, nil
+	}
+	if authData.NewPassword != "" {
+		return nil, me.ErrPasswordResetNotNeeded
+	}
+	// пароль сохранен в старом формате или с устаревшими параметрами: пока он у нас в открытом виде,
+	// перехэшируем его. Ошибка здесь не должна мешать входу, пароль перехэшируется при следующем входе.
+	if needsRehash {
+		hashedPassword, err := f.GetHashedPassword(authData.Password)
+		if err == nil && u.repoUser.UpdatePassword(ctx, hashedPassword, dbUser.Username) == nil {
+			dbUser.Password = hashedPassword
+		}
+	}
+	return &ent.SignInResult{User: dbUser}, nil
+}
+
+// ResetPassword сохраняет новый пароль, который администратор потребовал сменить, и снимает это требование.
+func (u *UsecaseLayer) ResetPassword(ctx context.Context, uDB *ent.User, newPasswordHash string) (err error) {
+	ctx, span := tracing.Start(ctx, "auth.ResetPassword")
+	defer tracing.End(span, &err)
+
+	err = u.repoUser.ResetPassword(ctx, newPasswordHash, uDB.Username)
+	if err != nil {
+		return err
+	}
+	uDB.Password = newPasswordHash
+	uDB.PasswordResetRequired = false
+	return nil
+}
+
+// ResolveLogin возвращает никнейм пользователя, который входит с логином login (почта или никнейм). Если
+// такого пользователя нет, возвращается сам login, чтобы перебор несуществующих логинов тоже ограничивался.
+func (u *UsecaseLayer) ResolveLogin(ctx context.Context, login string) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "auth.ResolveLogin")
+	defer tracing.End(span, &err)
+
+	var dbUser *ent.User
+	if govalidator.IsEmail(login) {
+		dbUser, err = u.repoUser.GetByEmail(ctx, login)
+	} else {
+		dbUser, err = u.repoUser.GetByUsername(ctx, login)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return login, nil
+		}
+		return "", err
+	}
+	return dbUser.Username, nil
+}
+
+// newPasswordHash проверяет новый пароль пользователя, которому нужно сменить пароль, и возвращает его хэш.
+func newPasswordHash(dbUser *ent.User, authData *dto.AuthData) (string, error) {
+	if authData.NewPassword == "" {
+		return "", me.ErrPasswordResetNeeded
+	}
+	if authData.NewPassword == authData.Password {
+		return "", me.ErrPasswordNotChanged
+	}
+	err := dto.ValidateNewPassword(authData.NewPassword, dto.PersonalData(dbUser.Username, dbUser.Email, dbUser.FirstName)...)
+	if err != nil {
+		return "", err
+	}
+	return f.GetHashedPassword(authData.NewPassword)
 }
@@ -0,0 +1,265 @@
+package coach
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/coach"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/measurement"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/tracing"
+)
+
+// clientMeasurementsLimit сколько последних измерений клиента одного вида видит тренер.
+const clientMeasurementsLimit = 100
+
+type Usecase interface {
+	// методы клиента
+	Invite(ctx context.Context, clientUsername, coachUsername string, scopes []string) (*ent.CoachLink, error)
+	ListCoaches(ctx context.Context, clientUsername string) ([]*ent.CoachLink, error)
+	UpdateScopes(ctx context.Context, clientUsername, coachUsername string, scopes []string) (*ent.CoachLink, error)
+	ListComments(ctx context.Context, clientUsername, coachUsername string) ([]*ent.CoachComment, error)
+	// методы тренера
+	ListInvitations(ctx context.Context, coachUsername string) ([]*ent.CoachLink, error)
+	Accept(ctx context.Context, coachUsername, clientUsername string) (*ent.CoachLink, error)
+	ListClients(ctx context.Context, coachUsername string) ([]*ent.CoachLink, error)
+	GetClientProfile(ctx context.Context, coachUsername, clientUsername string) (*ent.User, error)
+	GetClientMeasurements(ctx context.Context, coachUsername, clientUsername, kind string) ([]*ent.Measurement, error)
+	AddComment(ctx context.Context, coachUsername, clientUsername, text string) (*ent.CoachComment, error)
+	SetCalorieTarget(ctx context.Context, coachUsername, clientUsername string, target *float32) (*ent.CoachLink, error)
+	// общие методы
+	Revoke(ctx context.Context, clientUsername, coachUsername string) error
+}
+
+var _ Usecase = (*UsecaseLayer)(nil)
+
+type UsecaseLayer struct {
+	repoCoach       coach.Repo
+	repoUser        user.Repo
+	repoMeasurement measurement.Repo
+}
+
+// NewUsecaseLayer возращает структуру уровня usecase для работы со связями тренеров и клиентов.
+func NewUsecaseLayer(repoCoach coach.Repo, repoUser user.Repo, repoMeasurement measurement.Repo) *UsecaseLayer {
+	return &UsecaseLayer{
+		repoCoach:       repoCoach,
+		repoUser:        repoUser,
+		repoMeasurement: repoMeasurement,
+	}
+}
+
+// Invite отправляет приглашение тренеру. Тренер увидит данные клиента только после того, как примет его.
+func (u *UsecaseLayer) Invite(ctx context.Context, clientUsername, coachUsername string, scopes []string) (_ *ent.CoachLink, err error) {
+	ctx, span := tracing.Start(ctx, "coach.Invite")
+	defer tracing.End(span, &err)
+
+	if clientUsername == coachUsername {
+		return nil, me.ErrCoachSelf
+	}
+	coachDB, err := u.repoUser.GetByUsername(ctx, coachUsername)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, me.ErrUserNotExist
+		}
+		return nil, err
+	}
+	if !f.RoleHasPermission(coachDB.Role, mc.PermClientsRead) {
+		return nil, me.ErrNotCoach
+	}
+	return u.repoCoach.Create(ctx, clientUsername, coachUsername, scopes)
+}
+
+// ListCoaches возвращает тренеров клиента и отправленные им приглашения.
+func (u *UsecaseLayer) ListCoaches(ctx context.Context, clientUsername string) (_ []*ent.CoachLink, err error) {
+	ctx, span := tracing.Start(ctx, "coach.ListCoaches")
+	defer tracing.End(span, &err)
+
+	return u.repoCoach.ListByClient(ctx, clientUsername)
+}
+
+// UpdateScopes меняет данные, которые клиент открыл тренеру. Изменение действует сразу.
+func (u *UsecaseLayer) UpdateScopes(ctx context.Context, clientUsername, coachUsername string, scopes []string) (_ *ent.CoachLink, err error) {
+	ctx, span := tracing.Start(ctx, "coach.UpdateScopes")
+	defer tracing.End(span, &err)
+
+	l, err := u.getLink(ctx, clientUsername, coachUsername)
+	if err != nil {
+		return nil, err
+	}
+	l, err = u.repoCoach.UpdateScopes(ctx, l.ID, scopes)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, me.ErrCoachLinkNotExist
+	}
+	return l, err
+}
+
+// ListComments возвращает комментарии тренера клиенту.
+func (u *UsecaseLayer) ListComments(ctx context.Context, clientUsername, coachUsername string) (_ []*ent.CoachComment, err error) {
+	ctx, span := tracing.Start(ctx, "coach.ListComments")
+	defer tracing.End(span, &err)
+
+	l, err := u.getLink(ctx, clientUsername, coachUsername)
+	if err != nil {
+		return nil, err
+	}
+	return u.repoCoach.ListComments(ctx, l.ID)
+}
+
+// ListInvitations возвращает приглашения, которые тренер еще не принял.
+func (u *UsecaseLayer) ListInvitations(ctx context.Context, coachUsername string) (_ []*ent.CoachLink, err error) {
+	ctx, span := tracing.Start(ctx, "coach.ListInvitations")
+	defer tracing.End(span, &err)
+
+	return u.repoCoach.ListByCoach(ctx, coachUsername, mc.CoachLinkPending)
+}
+
+// Accept принимает приглашение клиента.
+func (u *UsecaseLayer) Accept(ctx context.Context, coachUsername, clientUsername string) (_ *ent.CoachLink, err error) {
+	ctx, span := tracing.Start(ctx, "coach.Accept")
+	defer tracing.End(span, &err)
+
+	l, err := u.getLink(ctx, clientUsername, coachUsername)
+	if err != nil {
+		return nil, err
+	}
+	l, err = u.repoCoach.Activate(ctx, l.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, me.ErrCoachLinkNotExist
+	}
+	return l, err
+}
+
+// ListClients возвращает клиентов тренера.
+func (u *UsecaseLayer) ListClients(ctx context.Context, coachUsername string) (_ []*ent.CoachLink, err error) {
+	ctx, span := tracing.Start(ctx, "coach.ListClients")
+	defer tracing.End(span, &err)
+
+	return u.repoCoach.ListByCoach(ctx, coachUsername, mc.CoachLinkActive)
+}
+
+// GetClientProfile возвращает профиль клиента, если клиент открыл его тренеру. Норма калорий в профиле
+// учитывает норму, заданную тренером.
+func (u *UsecaseLayer) GetClientProfile(ctx context.Context, coachUsername, clientUsername string) (_ *ent.User, err error) {
+	ctx, span := tracing.Start(ctx, "coach.GetClientProfile")
+	defer tracing.End(span, &err)
+
+	_, err = u.getConsentedLink(ctx, coachUsername, clientUsername, mc.CoachScopeProfile)
+	if err != nil {
+		return nil, err
+	}
+	uDB, err := u.repoUser.GetByUsername(ctx, clientUsername)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, me.ErrUserNotExist
+		}
+		return nil, err
+	}
+	target, err := u.repoCoach.GetCalorieTarget(ctx, clientUsername)
+	if err == nil {
+		uDB.DayCalories = *target.CalorieTarget
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return uDB, nil
+}
+
+// GetClientMeasurements возвращает последние измерения клиента вида kind, начиная с последнего, если клиент
+// открыл их тренеру: массу тела — правом weight, шаги и пульс — правом vitals. Вид уже проверен.
+func (u *UsecaseLayer) GetClientMeasurements(ctx context.Context, coachUsername, clientUsername, kind string) (_ []*ent.Measurement, err error) {
+	ctx, span := tracing.Start(ctx, "coach.GetClientMeasurements")
+	defer tracing.End(span, &err)
+
+	_, err = u.getConsentedLink(ctx, coachUsername, clientUsername, mc.MeasureCoachScopes[kind])
+	if err != nil {
+		return nil, err
+	}
+	return u.repoMeasurement.List(ctx, &ent.MeasurementFilter{
+		Username: clientUsername,
+		Kind:     kind,
+		Limit:    clientMeasurementsLimit,
+	})
+}
+
+// AddComment оставляет комментарий клиенту.
+func (u *UsecaseLayer) AddComment(ctx context.Context, coachUsername, clientUsername, text string) (_ *ent.CoachComment, err error) {
+	ctx, span := tracing.Start(ctx, "coach.AddComment")
+	defer tracing.End(span, &err)
+
+	l, err := u.getActiveLink(ctx, coachUsername, clientUsername)
+	if err != nil {
+		return nil, err
+	}
+	return u.repoCoach.CreateComment(ctx, l.ID, text)
+}
+
+// SetCalorieTarget задает клиенту норму калорий, которая заменяет рассчитанную. nil отменяет норму тренера.
+func (u *UsecaseLayer) SetCalorieTarget(ctx context.Context, coachUsername, clientUsername string, target *float32) (_ *ent.CoachLink, err error) {
+	ctx, span := tracing.Start(ctx, "coach.SetCalorieTarget")
+	defer tracing.End(span, &err)
+
+	l, err := u.getActiveLink(ctx, coachUsername, clientUsername)
+	if err != nil {
+		return nil, err
+	}
+	l, err = u.repoCoach.UpdateCalorieTarget(ctx, l.ID, target)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, me.ErrCoachLinkNotExist
+	}
+	return l, err
+}
+
+// Revoke разрывает связь или отзывает (отклоняет) приглашение. Доступно и клиенту, и тренеру.
+func (u *UsecaseLayer) Revoke(ctx context.Context, clientUsername, coachUsername string) (err error) {
+	ctx, span := tracing.Start(ctx, "coach.Revoke")
+	defer tracing.End(span, &err)
+
+	l, err := u.getLink(ctx, clientUsername, coachUsername)
+	if err != nil {
+		return err
+	}
+	err = u.repoCoach.Revoke(ctx, l.ID)
+	if errors.Is(err, me.ErrNoRowsAffected) {
+		return me.ErrCoachLinkNotExist
+	}
+	return err
+}
+
+// getConsentedLink возвращает действующую связь, если клиент открыл тренеру данные scope.
+func (u *UsecaseLayer) getConsentedLink(ctx context.Context, coachUsername, clientUsername, scope string) (*ent.CoachLink, error) {
+	l, err := u.getActiveLink(ctx, coachUsername, clientUsername)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(l.Scopes, scope) {
+		return nil, me.ErrCoachNoConsent
+	}
+	return l, nil
+}
+
+func (u *UsecaseLayer) getActiveLink(ctx context.Context, coachUsername, clientUsername string) (*ent.CoachLink, error) {
+	l, err := u.getLink(ctx, clientUsername, coachUsername)
+	if err != nil {
+		return nil, err
+	}
+	if l.Status != mc.CoachLinkActive {
+		return nil, me.ErrCoachLinkNotExist
+	}
+	return l, nil
+}
+
+func (u *UsecaseLayer) getLink(ctx context.Context, clientUsername, coachUsername string) (*ent.CoachLink, error) {
+	l, err := u.repoCoach.Get(ctx, clientUsername, coachUsername)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, me.ErrCoachLinkNotExist
+		}
+		return nil, err
+	}
+	return l, nil
+}
@@ -0,0 +1,145 @@
+package coach
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/coach"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/measurement"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/memory"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	ucUser "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/user"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"go.uber.org/zap"
+)
+
+type fixture struct {
+	uc              *UsecaseLayer
+	ucUser          *ucUser.UsecaseLayer
+	repoMeasurement measurement.Repo
+}
+
+// newFixture создает клиента ivan и тренера petr, который принял приглашение с правами scopes.
+func newFixture(t *testing.T, scopes ...string) *fixture {
+	t.Helper()
+	ctx := context.Background()
+	store := memory.NewStore()
+	repoUser := user.NewRepoMemory(store)
+	repoCoach := coach.NewRepoMemory(store)
+	repoMeasurement := measurement.NewRepoMemory(store)
+	fx := &fixture{
+		uc:              NewUsecaseLayer(repoCoach, repoUser, repoMeasurement),
+		ucUser:          ucUser.NewUsecaseLayer(repoUser, repoCoach, repoMeasurement, transaction.NewManagerMemory(store), zap.NewNop()),
+		repoMeasurement: repoMeasurement,
+	}
+	for username, role := range map[string]string{"ivan": mc.RoleUser, "petr": mc.RoleCoach} {
+		_, err := repoUser.Create(ctx, &ent.User{
+			Username: username, FirstName: "Ivan", Weight: 80, Height: 180, Age: 30, Sex: "M",
+			PhysicalActivity: "MA", Password: "hash", Role: role,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := fx.uc.Invite(ctx, "ivan", "petr", scopes); err != nil {
+		t.Fatalf("Invite() error = %v", err)
+	}
+	if _, err := fx.uc.Accept(ctx, "petr", "ivan"); err != nil {
+		t.Fatalf("Accept() error = %v", err)
+	}
+	return fx
+}
+
+func TestGetClientMeasurementsScopes(t *testing.T) {
+	tests := []struct {
+		name    string
+		scopes  []string
+		kind    string
+		wantErr error
+	}{
+		{name: "weight with weight scope", scopes: []string{mc.CoachScopeWeight}, kind: mc.MeasureWeight},
+		{name: "weight without weight scope", scopes: []string{mc.CoachScopeVitals}, kind: mc.MeasureWeight, wantErr: me.ErrCoachNoConsent},
+		{name: "steps with vitals scope", scopes: []string{mc.CoachScopeVitals}, kind: mc.MeasureSteps},
+		{name: "heart rate with vitals scope", scopes: []string{mc.CoachScopeVitals}, kind: mc.MeasureHeartRate},
+		{name: "steps without vitals scope", scopes: []string{mc.CoachScopeProfile, mc.CoachScopeWeight}, kind: mc.MeasureSteps, wantErr: me.ErrCoachNoConsent},
+		{name: "calories with diary scope", scopes: []string{mc.CoachScopeDiary}, kind: mc.MeasureCalories},
+		{name: "calories without diary scope", scopes: []string{mc.CoachScopeVitals}, kind: mc.MeasureCalories, wantErr: me.ErrCoachNoConsent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fx := newFixture(t, tt.scopes...)
+			ctx := context.Background()
+			err := fx.repoMeasurement.Add(ctx, []*ent.Measurement{
+				{Username: "ivan", Kind: mc.MeasureWeight, Value: 81, RecordedAt: time.Now().UTC()},
+				{Username: "ivan", Kind: mc.MeasureSteps, Value: 5400, RecordedAt: time.Now().UTC()},
+				{Username: "ivan", Kind: mc.MeasureHeartRate, Value: 64, RecordedAt: time.Now().UTC()},
+				{Username: "ivan", Kind: mc.MeasureCalories, Value: 450, RecordedAt: time.Now().UTC()},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			measurements, err := fx.uc.GetClientMeasurements(ctx, "petr", "ivan", tt.kind)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetClientMeasurements() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(measurements) != 1 || measurements[0].Kind != tt.kind {
+				t.Fatalf("GetClientMeasurements() = %+v, want one measurement %s", measurements, tt.kind)
+			}
+		})
+	}
+}
+
+// Изменение массы тела в профиле попадает в историю массы тела, которую видит тренер: история хранится
+// только в измерениях.
+func TestUpdateWeightRecordsMeasurement(t *testing.T) {
+	fx := newFixture(t, mc.CoachScopeWeight)
+	ctx := context.Background()
+	err := fx.repoMeasurement.Add(ctx, []*ent.Measurement{
+		{Username: "ivan", Kind: mc.MeasureWeight, Value: 81, RecordedAt: time.Now().Add(-time.Hour).UTC()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := fx.ucUser.UpdateWeight(ctx, 80.3, "ivan")
+	if err != nil {
+		t.Fatalf("UpdateWeight() error = %v", err)
+	}
+	if u.Weight != 80.3 {
+		t.Errorf("profile weight = %v, want 80.3", u.Weight)
+	}
+
+	history, err := fx.uc.GetClientMeasurements(ctx, "petr", "ivan", mc.MeasureWeight)
+	if err != nil {
+		t.Fatalf("GetClientMeasurements() error = %v", err)
+	}
+	if len(history) != 2 || history[0].Value != 80.3 || history[1].Value != 81 {
+		t.Fatalf("weight history = %+v, want 80.3 and 81, latest first", history)
+	}
+}
+
+func TestUpdateWeightUnknownUser(t *testing.T) {
+	fx := newFixture(t, mc.CoachScopeWeight)
+	ctx := context.Background()
+
+	_, err := fx.ucUser.UpdateWeight(ctx, 70, "unknown")
+	if !errors.Is(err, me.ErrUserNotExist) {
+		t.Fatalf("UpdateWeight() error = %v, want %v", err, me.ErrUserNotExist)
+	}
+	measurements, err := fx.repoMeasurement.List(ctx, &ent.MeasurementFilter{Username: "unknown", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(measurements) != 0 {
+		t.Fatalf("measurements of unknown user = %+v, want none", measurements)
+	}
+}
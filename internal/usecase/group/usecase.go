@@ -0,0 +1,211 @@
+package group
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/group"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/tracing"
+)
+
+type Usecase interface {
+	CreateBid(ctx context.Context, username, groupName string) (*ent.Bid, error)
+	ListBids(ctx context.Context, username string) ([]*ent.Bid, error)
+	ListPendingBids(ctx context.Context) ([]*ent.Bid, error)
+	ApproveBid(ctx context.Context, id int) (*ent.Group, error)
+	RejectBid(ctx context.Context, id int) (*ent.Bid, error)
+
+	ListGroups(ctx context.Context, username string) ([]*ent.Group, error)
+	DeleteGroup(ctx context.Context, name string) error
+
+	ListMembers(ctx context.Context, actor Actor, groupName string) ([]string, error)
+	AddMember(ctx context.Context, actor Actor, groupName, username string) error
+	RemoveMember(ctx context.Context, actor Actor, groupName, username string) error
+}
+
+// Actor пользователь, который выполняет действие с группой. CanManage — право управлять любыми группами (root).
+type Actor struct {
+	Username  string
+	CanManage bool
+}
+
+var _ Usecase = (*UsecaseLayer)(nil)
+
+type UsecaseLayer struct {
+	repoGroup group.Repo
+}
+
+// NewUsecaseLayer возращает структуру уровня usecase для работы с группами и заявками на их создание.
+func NewUsecaseLayer(repoGroup group.Repo) *UsecaseLayer {
+	return &UsecaseLayer{
+		repoGroup: repoGroup,
+	}
+}
+
+// CreateBid создает заявку на создание группы, которую рассматривает root.
+func (u *UsecaseLayer) CreateBid(ctx context.Context, username, groupName string) (_ *ent.Bid, err error) {
+	ctx, span := tracing.Start(ctx, "group.CreateBid")
+	defer tracing.End(span, &err)
+
+	_, err = u.repoGroup.GetByName(ctx, groupName)
+	if err == nil {
+		return nil, me.ErrGroupAlreadyExist
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	b, err := u.repoGroup.CreateBid(ctx, groupName, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, me.ErrUserNotExist
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+// ListBids возвращает заявки пользователя.
+func (u *UsecaseLayer) ListBids(ctx context.Context, username string) (_ []*ent.Bid, err error) {
+	ctx, span := tracing.Start(ctx, "group.ListBids")
+	defer tracing.End(span, &err)
+
+	return u.repoGroup.ListBidsByUsername(ctx, username)
+}
+
+// ListPendingBids возвращает нерассмотренные заявки.
+func (u *UsecaseLayer) ListPendingBids(ctx context.Context) (_ []*ent.Bid, err error) {
+	ctx, span := tracing.Start(ctx, "group.ListPendingBids")
+	defer tracing.End(span, &err)
+
+	return u.repoGroup.ListBidsByStatus(ctx, mc.BidStatusInProgress)
+}
+
+// ApproveBid одобряет заявку, автор заявки становится ответственным за созданную группу.
+func (u *UsecaseLayer) ApproveBid(ctx context.Context, id int) (_ *ent.Group, err error) {
+	ctx, span := tracing.Start(ctx, "group.ApproveBid")
+	defer tracing.End(span, &err)
+
+	g, err := u.repoGroup.ApproveBid(ctx, id)
+	if err != nil {
+		if errors.Is(err, me.ErrNoRowsAffected) {
+			return nil, me.ErrBidNotExist
+		}
+		return nil, err
+	}
+	return g, nil
+}
+
+// RejectBid отклоняет заявку.
+func (u *UsecaseLayer) RejectBid(ctx context.Context, id int) (_ *ent.Bid, err error) {
+	ctx, span := tracing.Start(ctx, "group.RejectBid")
+	defer tracing.End(span, &err)
+
+	b, err := u.repoGroup.RejectBid(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, me.ErrBidNotExist
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+// ListGroups возвращает группы, в которых состоит пользователь.
+func (u *UsecaseLayer) ListGroups(ctx context.Context, username string) (_ []*ent.Group, err error) {
+	ctx, span := tracing.Start(ctx, "group.ListGroups")
+	defer tracing.End(span, &err)
+
+	return u.repoGroup.ListByUsername(ctx, username)
+}
+
+// DeleteGroup удаляет группу, ее участники теряют права группы.
+func (u *UsecaseLayer) DeleteGroup(ctx context.Context, name string) (err error) {
+	ctx, span := tracing.Start(ctx, "group.DeleteGroup")
+	defer tracing.End(span, &err)
+
+	err = u.repoGroup.DeleteByName(ctx, name)
+	if errors.Is(err, me.ErrNoRowsAffected) {
+		return me.ErrGroupNotExist
+	}
+	return err
+}
+
+// ListMembers возвращает участников группы. Список видят ответственный за группу и root.
+func (u *UsecaseLayer) ListMembers(ctx context.Context, actor Actor, groupName string) (_ []string, err error) {
+	ctx, span := tracing.Start(ctx, "group.ListMembers")
+	defer tracing.End(span, &err)
+
+	g, err := u.getManagedGroup(ctx, actor, groupName)
+	if err != nil {
+		return nil, err
+	}
+	return u.repoGroup.ListMembers(ctx, g.ID)
+}
+
+// AddMember добавляет пользователя в группу, после чего он получает права группы.
+func (u *UsecaseLayer) AddMember(ctx context.Context, actor Actor, groupName, username string) (err error) {
+	ctx, span := tracing.Start(ctx, "group.AddMember")
+	defer tracing.End(span, &err)
+
+	g, err := u.getManagedGroup(ctx, actor, groupName)
+	if err != nil {
+		return err
+	}
+	err = u.repoGroup.AddMember(ctx, g.ID, username)
+	if errors.Is(err, me.ErrNoRowsAffected) {
+		return me.ErrUserNotExist
+	}
+	return err
+}
+
+// RemoveMember исключает пользователя из группы. Участник может выйти из группы сам, ответственного
+// исключить нельзя.
+func (u *UsecaseLayer) RemoveMember(ctx context.Context, actor Actor, groupName, username string) (err error) {
+	ctx, span := tracing.Start(ctx, "group.RemoveMember")
+	defer tracing.End(span, &err)
+
+	var g *ent.Group
+	if actor.Username == username {
+		g, err = u.getGroup(ctx, groupName)
+	} else {
+		g, err = u.getManagedGroup(ctx, actor, groupName)
+	}
+	if err != nil {
+		return err
+	}
+	if g.OwnerUsername == username {
+		return me.ErrCannotRemoveOwner
+	}
+	err = u.repoGroup.RemoveMember(ctx, g.ID, username)
+	if errors.Is(err, me.ErrNoRowsAffected) {
+		return me.ErrNotMember
+	}
+	return err
+}
+
+// getManagedGroup возвращает группу, если actor может управлять ее участниками.
+func (u *UsecaseLayer) getManagedGroup(ctx context.Context, actor Actor, groupName string) (*ent.Group, error) {
+	g, err := u.getGroup(ctx, groupName)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.CanManage && g.OwnerUsername != actor.Username {
+		return nil, me.ErrNotGroupOwner
+	}
+	return g, nil
+}
+
+func (u *UsecaseLayer) getGroup(ctx context.Context, groupName string) (*ent.Group, error) {
+	g, err := u.repoGroup.GetByName(ctx, groupName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, me.ErrGroupNotExist
+		}
+		return nil, err
+	}
+	return g, nil
+}
@@ -0,0 +1,183 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/group"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/memory"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+)
+
+var (
+	owner  = Actor{Username: "ivan"}
+	member = Actor{Username: "petr"}
+	root   = Actor{Username: "root", CanManage: true}
+)
+
+// newTestUsecase возвращает usecase с пользователями ivan, petr, anna и root.
+func newTestUsecase(t *testing.T) *UsecaseLayer {
+	t.Helper()
+	store := memory.NewStore()
+	repoUser := user.NewRepoMemory(store)
+	for _, username := range []string{"ivan", "petr", "anna", "root"} {
+		_, err := repoUser.Create(context.Background(), &ent.User{
+			Username: username, FirstName: username, Weight: 80, Height: 180, Age: 30, Sex: "M",
+			PhysicalActivity: "MA", Password: "hash",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewUsecaseLayer(group.NewRepoMemory(store))
+}
+
+// newTestGroup создает группу runners, ответственный ivan, участник petr.
+func newTestGroup(t *testing.T) *UsecaseLayer {
+	t.Helper()
+	uc := newTestUsecase(t)
+	ctx := context.Background()
+	b, err := uc.CreateBid(ctx, "ivan", "runners")
+	if err != nil {
+		t.Fatalf("CreateBid() error = %v", err)
+	}
+	if _, err := uc.ApproveBid(ctx, b.ID); err != nil {
+		t.Fatalf("ApproveBid() error = %v", err)
+	}
+	if err := uc.AddMember(ctx, owner, "runners", "petr"); err != nil {
+		t.Fatalf("AddMember() error = %v", err)
+	}
+	return uc
+}
+
+func TestApproveBid(t *testing.T) {
+	uc := newTestUsecase(t)
+	ctx := context.Background()
+	b, err := uc.CreateBid(ctx, "ivan", "runners")
+	if err != nil {
+		t.Fatalf("CreateBid() error = %v", err)
+	}
+	if b.Status != mc.BidStatusInProgress {
+		t.Errorf("CreateBid() status = %q, want %q", b.Status, mc.BidStatusInProgress)
+	}
+
+	g, err := uc.ApproveBid(ctx, b.ID)
+	if err != nil {
+		t.Fatalf("ApproveBid() error = %v", err)
+	}
+	if g.Name != "runners" || g.OwnerUsername != "ivan" {
+		t.Errorf("ApproveBid() = %+v, want group runners owned by ivan", g)
+	}
+	bids, err := uc.ListBids(ctx, "ivan")
+	if err != nil || len(bids) != 1 || bids[0].Status != mc.BidStatusApproved {
+		t.Errorf("ListBids() = %+v, %v, want one approved bid", bids, err)
+	}
+	pending, err := uc.ListPendingBids(ctx)
+	if err != nil || len(pending) != 0 {
+		t.Errorf("ListPendingBids() = %+v, %v, want none", pending, err)
+	}
+	members, err := uc.ListMembers(ctx, owner, "runners")
+	if err != nil || !slices.Equal(members, []string{"ivan"}) {
+		t.Errorf("ListMembers() = %v, %v, want [ivan]", members, err)
+	}
+	if _, err := uc.ApproveBid(ctx, b.ID); !errors.Is(err, me.ErrBidNotExist) {
+		t.Errorf("ApproveBid() of an approved bid error = %v, want %v", err, me.ErrBidNotExist)
+	}
+	if _, err := uc.CreateBid(ctx, "petr", "runners"); !errors.Is(err, me.ErrGroupAlreadyExist) {
+		t.Errorf("CreateBid() for an existing group error = %v, want %v", err, me.ErrGroupAlreadyExist)
+	}
+}
+
+func TestRejectBid(t *testing.T) {
+	uc := newTestUsecase(t)
+	ctx := context.Background()
+	b, err := uc.CreateBid(ctx, "ivan", "runners")
+	if err != nil {
+		t.Fatalf("CreateBid() error = %v", err)
+	}
+
+	rejected, err := uc.RejectBid(ctx, b.ID)
+	if err != nil {
+		t.Fatalf("RejectBid() error = %v", err)
+	}
+	if rejected.Status != mc.BidStatusRejected {
+		t.Errorf("RejectBid() status = %q, want %q", rejected.Status, mc.BidStatusRejected)
+	}
+	if _, err := uc.ApproveBid(ctx, b.ID); !errors.Is(err, me.ErrBidNotExist) {
+		t.Errorf("ApproveBid() of a rejected bid error = %v, want %v", err, me.ErrBidNotExist)
+	}
+	if _, err := uc.RejectBid(ctx, b.ID); !errors.Is(err, me.ErrBidNotExist) {
+		t.Errorf("RejectBid() of a rejected bid error = %v, want %v", err, me.ErrBidNotExist)
+	}
+	groups, err := uc.ListGroups(ctx, "ivan")
+	if err != nil || len(groups) != 0 {
+		t.Errorf("ListGroups() = %+v, %v, want none", groups, err)
+	}
+}
+
+func TestManageMembers(t *testing.T) {
+	uc := newTestGroup(t)
+	ctx := context.Background()
+
+	if err := uc.AddMember(ctx, member, "runners", "anna"); !errors.Is(err, me.ErrNotGroupOwner) {
+		t.Errorf("AddMember() by a member error = %v, want %v", err, me.ErrNotGroupOwner)
+	}
+	if _, err := uc.ListMembers(ctx, member, "runners"); !errors.Is(err, me.ErrNotGroupOwner) {
+		t.Errorf("ListMembers() by a member error = %v, want %v", err, me.ErrNotGroupOwner)
+	}
+	if err := uc.AddMember(ctx, root, "runners", "anna"); err != nil {
+		t.Errorf("AddMember() by root error = %v", err)
+	}
+	if err := uc.AddMember(ctx, owner, "runners", "unknown"); !errors.Is(err, me.ErrUserNotExist) {
+		t.Errorf("AddMember() of an unknown user error = %v, want %v", err, me.ErrUserNotExist)
+	}
+	if err := uc.AddMember(ctx, owner, "unknown", "anna"); !errors.Is(err, me.ErrGroupNotExist) {
+		t.Errorf("AddMember() to an unknown group error = %v, want %v", err, me.ErrGroupNotExist)
+	}
+	members, err := uc.ListMembers(ctx, owner, "runners")
+	slices.Sort(members)
+	if err != nil || !slices.Equal(members, []string{"anna", "ivan", "petr"}) {
+		t.Errorf("ListMembers() = %v, %v, want [anna ivan petr]", members, err)
+	}
+}
+
+func TestRemoveMember(t *testing.T) {
+	tests := []struct {
+		name       string
+		actor      Actor
+		username   string
+		wantErr    error
+		wantMember bool
+	}{
+		{name: "member leaves", actor: member, username: "petr"},
+		{name: "owner removes member", actor: owner, username: "petr"},
+		{name: "root removes member", actor: root, username: "petr"},
+		{name: "member removes another", actor: Actor{Username: "anna"}, username: "petr", wantErr: me.ErrNotGroupOwner, wantMember: true},
+		{name: "owner leaves", actor: owner, username: "ivan", wantErr: me.ErrCannotRemoveOwner, wantMember: true},
+		{name: "root removes owner", actor: root, username: "ivan", wantErr: me.ErrCannotRemoveOwner, wantMember: true},
+		{name: "not a member", actor: owner, username: "anna", wantErr: me.ErrNotMember},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := newTestGroup(t)
+			ctx := context.Background()
+
+			err := uc.RemoveMember(ctx, tt.actor, "runners", tt.username)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RemoveMember() error = %v, want %v", err, tt.wantErr)
+			}
+			members, err := uc.ListMembers(ctx, owner, "runners")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if slices.Contains(members, tt.username) != tt.wantMember {
+				t.Errorf("ListMembers() = %v, want %q as a member = %v", members, tt.username, tt.wantMember)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package lockout
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.uber.org/zap"
+)
+
+// Notifier хук, который вызывается при блокировке учетной записи.
+type Notifier interface {
+	AccountLocked(ctx context.Context, event *ent.AccountLockEvent)
+}
+
+// NewNotifier возвращает хук уведомлений: вебхук, если задан auth.lockout.webhook_url, иначе запись в лог.
+func NewNotifier(logger *zap.Logger) Notifier {
+	if url := viper.GetString("auth.lockout.webhook_url"); url != "" {
+		return &WebhookNotifier{
+			url:    url,
+			client: &http.Client{Timeout: 5 * time.Second, Transport: otelhttp.NewTransport(http.DefaultTransport)},
+			logger: logger,
+		}
+	}
+	return &LogNotifier{logger: logger}
+}
+
+var _ Notifier = (*LogNotifier)(nil)
+
+type LogNotifier struct {
+	logger *zap.Logger
+}
+
+// AccountLocked пишет событие блокировки в лог.
+func (n *LogNotifier) AccountLocked(ctx context.Context, event *ent.AccountLockEvent) {
+	n.logger.Warn("account has been locked",
+		zap.String("login", event.Login),
+		zap.String("ip", event.IP),
+		zap.Int("failures", event.Failures),
+		zap.String("locked-until", f.FormatTime(event.LockedUntil)),
+	)
+}
+
+var _ Notifier = (*WebhookNotifier)(nil)
+
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+	logger *zap.Logger
+}
+
+// AccountLocked отправляет событие блокировки POST-запросом в формате json. Запрос выполняется в отдельной
+// горутине, чтобы не задерживать ответ клиенту.
+func (n *WebhookNotifier) AccountLocked(ctx context.Context, event *ent.AccountLockEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		n.logger.Error(fmt.Sprintf("error while marshalling lock event: %v", err))
+		return
+	}
+	go func() {
+		resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			n.logger.Error(fmt.Sprintf("error while sending lock event: %v", err))
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= http.StatusBadRequest {
+			n.logger.Error(fmt.Sprintf("lock event webhook responded with status %d", resp.StatusCode))
+		}
+	}()
+}
@@ -0,0 +1,181 @@
+package lockout
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/attempt"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/tracing"
+	"github.com/spf13/viper"
+)
+
+type Usecase interface {
+	Check(ctx context.Context, login, ip string) (time.Duration, error)
+	Fail(ctx context.Context, login, ip string) error
+	Reset(ctx context.Context, login string) error
+}
+
+var _ Usecase = (*UsecaseLayer)(nil)
+
+type UsecaseLayer struct {
+	repoAttempt attempt.Repo
+	notifier    Notifier
+}
+
+// NewUsecaseLayer возращает структуру уровня usecase для защиты входа от перебора паролей.
+func NewUsecaseLayer(repoAttempt attempt.Repo, notifier Notifier) *UsecaseLayer {
+	return &UsecaseLayer{
+		repoAttempt: repoAttempt,
+		notifier:    notifier,
+	}
+}
+
+// policy пороги для одного вида счетчика (учетная запись или IP-адрес).
+type policy struct {
+	freeAttempts int
+	threshold    int
+}
+
+func accountPolicy() policy {
+	return policy{
+		freeAttempts: viper.GetInt("auth.lockout.free_attempts"),
+		threshold:    viper.GetInt("auth.lockout.account_threshold"),
+	}
+}
+
+func ipPolicy() policy {
+	return policy{
+		freeAttempts: viper.GetInt("auth.lockout.free_attempts"),
+		threshold:    viper.GetInt("auth.lockout.ip_threshold"),
+	}
+}
+
+// Check проверяет, можно ли сейчас пытаться войти. Если нельзя, возвращает ErrTooManyAttempts и время,
+// через которое можно повторить попытку. Пустой login означает проверку только по IP-адресу.
+func (u *UsecaseLayer) Check(ctx context.Context, login, ip string) (_ time.Duration, err error) {
+	ctx, span := tracing.Start(ctx, "lockout.Check")
+	defer tracing.End(span, &err)
+
+	timeNow := time.Now()
+	var retryAfter time.Duration
+	for _, key := range keys(login, ip) {
+		a, err := u.repoAttempt.Get(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		if wait := a.BlockedUntil.Sub(timeNow); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+	if retryAfter > 0 {
+		return retryAfter, me.ErrTooManyAttempts
+	}
+	return 0, nil
+}
+
+// Fail регистрирует неудачную попытку входа. После нескольких бесплатных попыток каждая следующая
+// откладывается экспоненциально, а после порога учетная запись (или IP-адрес) блокируется на время.
+func (u *UsecaseLayer) Fail(ctx context.Context, login, ip string) (err error) {
+	ctx, span := tracing.Start(ctx, "lockout.Fail")
+	defer tracing.End(span, &err)
+
+	if login != "" {
+		a, lockedNow, err := u.fail(ctx, accountKey(login), accountPolicy())
+		if err != nil {
+			return err
+		}
+		if lockedNow {
+			u.notifier.AccountLocked(ctx, &ent.AccountLockEvent{
+				Login:       login,
+				IP:          ip,
+				Failures:    a.Failures,
+				LockedUntil: a.BlockedUntil,
+			})
+		}
+	}
+	if ip != "" {
+		_, _, err := u.fail(ctx, ipKey(ip), ipPolicy())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reset сбрасывает счетчик учетной записи после успешного входа. Счетчик IP-адреса не сбрасывается,
+// иначе перебор по многим учетным записям с одного адреса можно было бы прерывать своим успешным входом.
+func (u *UsecaseLayer) Reset(ctx context.Context, login string) (err error) {
+	ctx, span := tracing.Start(ctx, "lockout.Reset")
+	defer tracing.End(span, &err)
+
+	return u.repoAttempt.Delete(ctx, accountKey(login))
+}
+
+func (u *UsecaseLayer) fail(ctx context.Context, key string, p policy) (*ent.LoginAttempts, bool, error) {
+	lockoutDuration := viper.GetDuration("auth.lockout.duration")
+	ttl := viper.GetDuration("auth.lockout.window")
+	if ttl < lockoutDuration {
+		ttl = lockoutDuration
+	}
+	var lockedNow bool
+	a, err := u.repoAttempt.Update(ctx, key, ttl, func(a *ent.LoginAttempts) {
+		// при конфликте записи функция вызывается повторно, результат прошлой попытки не должен остаться
+		lockedNow = false
+		timeNow := time.Now()
+		// блокировка закончилась: даем несколько попыток с задержкой, после чего снова блокируем
+		if a.Locked && !timeNow.Before(a.BlockedUntil) {
+			a.Locked = false
+			a.Failures = p.freeAttempts
+		}
+		a.Failures++
+		switch {
+		case a.Failures >= p.threshold:
+			a.BlockedUntil = timeNow.Add(lockoutDuration)
+			if !a.Locked {
+				a.Locked = true
+				lockedNow = true
+			}
+		case a.Failures > p.freeAttempts:
+			a.BlockedUntil = timeNow.Add(backoff(a.Failures - p.freeAttempts))
+		}
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return a, lockedNow, nil
+}
+
+// backoff возвращает задержку base * 2^(n-1), но не больше auth.lockout.backoff_max.
+func backoff(n int) time.Duration {
+	delay := viper.GetDuration("auth.lockout.backoff_base")
+	maxDelay := viper.GetDuration("auth.lockout.backoff_max")
+	for i := 1; i < n && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+func keys(login, ip string) []string {
+	var k []string
+	if login != "" {
+		k = append(k, accountKey(login))
+	}
+	if ip != "" {
+		k = append(k, ipKey(ip))
+	}
+	return k
+}
+
+func accountKey(login string) string {
+	return "account:" + strings.ToLower(login)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
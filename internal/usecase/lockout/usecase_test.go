@@ -0,0 +1,179 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/attempt"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/spf13/viper"
+)
+
+// notifierSpy запоминает события блокировки.
+type notifierSpy struct {
+	events []*ent.AccountLockEvent
+}
+
+func (n *notifierSpy) AccountLocked(ctx context.Context, event *ent.AccountLockEvent) {
+	n.events = append(n.events, event)
+}
+
+func newTestUsecase(t *testing.T, window, duration time.Duration) (*UsecaseLayer, *notifierSpy) {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("auth.lockout.free_attempts", 3)
+	viper.Set("auth.lockout.account_threshold", 6)
+	viper.Set("auth.lockout.ip_threshold", 8)
+	viper.Set("auth.lockout.backoff_base", time.Second)
+	viper.Set("auth.lockout.backoff_max", 4*time.Second)
+	viper.Set("auth.lockout.duration", duration)
+	viper.Set("auth.lockout.window", window)
+	spy := &notifierSpy{}
+	return NewUsecaseLayer(attempt.NewRepoMemory(), spy), spy
+}
+
+// failN регистрирует n неудачных попыток входа login с адреса ip.
+func failN(t *testing.T, uc *UsecaseLayer, n int, login, ip string) {
+	t.Helper()
+	for range n {
+		if err := uc.Fail(context.Background(), login, ip); err != nil {
+			t.Fatalf("Fail() error = %v", err)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	newTestUsecase(t, time.Hour, 15*time.Minute)
+	for n, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 4 * time.Second, 20: 4 * time.Second} {
+		if got := backoff(n); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", n, got, want)
+		}
+	}
+}
+
+func TestFailThresholds(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		wantRetry time.Duration
+		wantLock  bool
+	}{
+		{name: "free attempts", failures: 3},
+		{name: "first delayed attempt", failures: 4, wantRetry: time.Second},
+		{name: "second delayed attempt", failures: 5, wantRetry: 2 * time.Second},
+		{name: "account threshold", failures: 6, wantRetry: 15 * time.Minute, wantLock: true},
+		{name: "after lockout", failures: 7, wantRetry: 15 * time.Minute, wantLock: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, spy := newTestUsecase(t, time.Hour, 15*time.Minute)
+			failN(t, uc, tt.failures, "Ivan", "10.0.0.1")
+
+			retryAfter, err := uc.Check(context.Background(), "ivan", "10.0.0.2")
+			if tt.wantRetry == 0 {
+				if err != nil {
+					t.Fatalf("Check() error = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, me.ErrTooManyAttempts) {
+				t.Fatalf("Check() error = %v, want %v", err, me.ErrTooManyAttempts)
+			}
+			if retryAfter <= tt.wantRetry-time.Second || retryAfter > tt.wantRetry {
+				t.Errorf("Check() retry after = %v, want about %v", retryAfter, tt.wantRetry)
+			}
+			// хук вызывается один раз, в момент блокировки
+			wantEvents := 0
+			if tt.wantLock {
+				wantEvents = 1
+			}
+			if len(spy.events) != wantEvents {
+				t.Fatalf("lock events = %d, want %d", len(spy.events), wantEvents)
+			}
+			if tt.wantLock && (spy.events[0].Login != "Ivan" || spy.events[0].Failures != 6) {
+				t.Errorf("lock event = %+v, want Ivan after 6 failures", spy.events[0])
+			}
+		})
+	}
+}
+
+// Счетчик IP-адреса общий для всех учетных записей и не сбрасывается успешным входом.
+func TestFailIPThreshold(t *testing.T) {
+	uc, spy := newTestUsecase(t, time.Hour, 15*time.Minute)
+	ctx := context.Background()
+	for _, login := range []string{"ivan", "petr", "anna", "olga"} {
+		failN(t, uc, 2, login, "10.0.0.1")
+		if err := uc.Reset(ctx, login); err != nil {
+			t.Fatalf("Reset() error = %v", err)
+		}
+	}
+
+	if _, err := uc.Check(ctx, "", "10.0.0.1"); !errors.Is(err, me.ErrTooManyAttempts) {
+		t.Fatalf("Check() of the ip error = %v, want %v", err, me.ErrTooManyAttempts)
+	}
+	if _, err := uc.Check(ctx, "ivan", "10.0.0.2"); err != nil {
+		t.Errorf("Check() of ivan from another ip error = %v, want nil", err)
+	}
+	if len(spy.events) != 0 {
+		t.Errorf("lock events = %+v, ip lockout is not an account lock", spy.events)
+	}
+}
+
+func TestReset(t *testing.T) {
+	uc, _ := newTestUsecase(t, time.Hour, 15*time.Minute)
+	ctx := context.Background()
+	failN(t, uc, 5, "ivan", "")
+
+	if err := uc.Reset(ctx, "IVAN"); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if _, err := uc.Check(ctx, "ivan", ""); err != nil {
+		t.Fatalf("Check() after Reset() error = %v, want nil", err)
+	}
+	// после сброса снова доступны бесплатные попытки
+	failN(t, uc, 3, "ivan", "")
+	if _, err := uc.Check(ctx, "ivan", ""); err != nil {
+		t.Errorf("Check() error = %v, want nil", err)
+	}
+}
+
+func TestWindowExpiry(t *testing.T) {
+	uc, _ := newTestUsecase(t, 50*time.Millisecond, 20*time.Millisecond)
+	ctx := context.Background()
+	failN(t, uc, 4, "ivan", "")
+
+	time.Sleep(60 * time.Millisecond)
+	failN(t, uc, 3, "ivan", "")
+	if _, err := uc.Check(ctx, "ivan", ""); err != nil {
+		t.Fatalf("Check() error = %v, want counter expired after the window", err)
+	}
+}
+
+// После окончания блокировки бесплатных попыток нет: следующие откладываются, а на пороге учетная запись
+// снова блокируется и хук вызывается еще раз.
+func TestLockoutExpiry(t *testing.T) {
+	uc, spy := newTestUsecase(t, time.Hour, 20*time.Millisecond)
+	ctx := context.Background()
+	failN(t, uc, 6, "ivan", "")
+	if _, err := uc.Check(ctx, "ivan", ""); !errors.Is(err, me.ErrTooManyAttempts) {
+		t.Fatalf("Check() error = %v, want %v", err, me.ErrTooManyAttempts)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, err := uc.Check(ctx, "ivan", ""); err != nil {
+		t.Fatalf("Check() after the lockout error = %v, want nil", err)
+	}
+	failN(t, uc, 1, "ivan", "")
+	retryAfter, err := uc.Check(ctx, "ivan", "")
+	if !errors.Is(err, me.ErrTooManyAttempts) || retryAfter > time.Second {
+		t.Fatalf("Check() = %v, %v, want backoff of the first delayed attempt", retryAfter, err)
+	}
+	failN(t, uc, 2, "ivan", "")
+	if len(spy.events) != 2 {
+		t.Errorf("lock events = %d, want 2", len(spy.events))
+	}
+}
@@ -0,0 +1,72 @@
+package measurement
+
+import (
+	"context"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/measurement"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/tracing"
+	"go.uber.org/zap"
+)
+
+type Usecase interface {
+	Add(ctx context.Context, measurements []*ent.Measurement) error
+	List(ctx context.Context, filter *ent.MeasurementFilter) ([]*ent.Measurement, error)
+}
+
+var _ Usecase = (*UsecaseLayer)(nil)
+
+type UsecaseLayer struct {
+	repoMeasurement measurement.Repo
+}
+
+// NewUsecaseLayer возращает структуру уровня usecase для работы с измерениями пользователя.
+func NewUsecaseLayer(repoMeasurement measurement.Repo) *UsecaseLayer {
+	return &UsecaseLayer{
+		repoMeasurement: repoMeasurement,
+	}
+}
+
+// Add сохраняет пачку измерений. Данные уже проверены.
+func (u *UsecaseLayer) Add(ctx context.Context, measurements []*ent.Measurement) (err error) {
+	ctx, span := tracing.Start(ctx, "measurement.Add")
+	defer tracing.End(span, &err)
+
+	return u.repoMeasurement.Add(ctx, measurements)
+}
+
+// List возвращает измерения пользователя, начиная с последних.
+func (u *UsecaseLayer) List(ctx context.Context, filter *ent.MeasurementFilter) (_ []*ent.Measurement, err error) {
+	ctx, span := tracing.Start(ctx, "measurement.List")
+	defer tracing.End(span, &err)
+
+	return u.repoMeasurement.List(ctx, filter)
+}
+
+// deleteAttempts сколько раз DeleteOfDeletedUser пытается удалить измерения, deleteBackoff — пауза перед
+// повтором, она растет с каждой попыткой.
+const (
+	deleteAttempts = 3
+	deleteBackoff  = 100 * time.Millisecond
+)
+
+// DeleteOfDeletedUser удаляет измерения пользователя, удаление которого уже зафиксировано. Пользователь уже
+// удален, поэтому ошибка не возвращается: удаление повторяется, а если не удалось, в лог пишется ошибка.
+// Оставшиеся измерения нужно удалить вручную, иначе их увидит новый пользователь с тем же никнеймом.
+// Удаление не прерывается, если клиент закрыл соединение.
+func DeleteOfDeletedUser(ctx context.Context, repoMeasurement measurement.Repo, username string, logger *zap.Logger) {
+	ctx = context.WithoutCancel(ctx)
+	var err error
+	for attempt := 1; attempt <= deleteAttempts; attempt++ {
+		err = repoMeasurement.DeleteByUsername(ctx, username)
+		if err == nil {
+			return
+		}
+		if attempt < deleteAttempts {
+			time.Sleep(deleteBackoff * time.Duration(attempt))
+		}
+	}
+	logger.Error("measurements of deleted user are not deleted, delete them manually",
+		zap.String("username", username), zap.Error(err))
+}
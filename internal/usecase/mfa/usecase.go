@@ -0,0 +1,275 @@
+package mfa
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/mfa"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/tracing"
+	"github.com/spf13/viper"
+)
+
+type Usecase interface {
+	Enroll(ctx context.Context, username string) (*ent.TOTPEnrollment, error)
+	Confirm(ctx context.Context, username, code string) ([]string, error)
+	Disable(ctx context.Context, username, password, code string) error
+	IsEnabled(ctx context.Context, username string) (bool, error)
+	NewChallenge(username, newPasswordHash string) (string, time.Time, error)
+	ParseChallenge(challengeToken string) (string, error)
+	VerifyChallenge(ctx context.Context, challengeToken, code string) (*ent.SignInResult, error)
+}
+
+var _ Usecase = (*UsecaseLayer)(nil)
+
+type UsecaseLayer struct {
+	repoMfa  mfa.Repo
+	repoUser user.Repo
+}
+
+// NewUsecaseLayer возращает структуру уровня usecase для работы со вторым фактором аутентификации.
+func NewUsecaseLayer(repoMfa mfa.Repo, repoUser user.Repo) *UsecaseLayer {
+	return &UsecaseLayer{
+		repoMfa:  repoMfa,
+		repoUser: repoUser,
+	}
+}
+
+// Enroll создает новый секрет TOTP. Второй фактор включится только после подтверждения кодом.
+func (u *UsecaseLayer) Enroll(ctx context.Context, username string) (_ *ent.TOTPEnrollment, err error) {
+	ctx, span := tracing.Start(ctx, "mfa.Enroll")
+	defer tracing.End(span, &err)
+
+	secret, err := f.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	secretEncrypted, err := f.Encrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+	_, err = u.repoMfa.Upsert(ctx, username, secretEncrypted)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, me.ErrMfaAlreadyEnabled
+		}
+		return nil, err
+	}
+	return &ent.TOTPEnrollment{
+		Secret:     secret,
+		OtpauthURI: f.GetOtpauthURI(viper.GetString("auth.totp_issuer"), username, secret),
+	}, nil
+}
+
+// Confirm включает второй фактор, если пользователь ввел верный код из приложения. Возвращает коды
+// восстановления, они показываются пользователю один раз.
+func (u *UsecaseLayer) Confirm(ctx context.Context, username, code string) (_ []string, err error) {
+	ctx, span := tracing.Start(ctx, "mfa.Confirm")
+	defer tracing.End(span, &err)
+
+	tDB, err := u.getTOTP(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if tDB.Enabled {
+		return nil, me.ErrMfaAlreadyEnabled
+	}
+	err = u.useTOTPCode(ctx, tDB, code)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := f.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	codeHashes := make([]string, 0, len(codes))
+	for _, c := range codes {
+		codeHashes = append(codeHashes, f.HashToken(c))
+	}
+	err = u.repoMfa.ReplaceRecoveryCodes(ctx, username, codeHashes)
+	if err != nil {
+		return nil, err
+	}
+	err = u.repoMfa.Enable(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable отключает второй фактор. Требует пароль и код (из приложения или код восстановления).
+func (u *UsecaseLayer) Disable(ctx context.Context, username, password, code string) (err error) {
+	ctx, span := tracing.Start(ctx, "mfa.Disable")
+	defer tracing.End(span, &err)
+
+	uDB, err := u.repoUser.GetByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return me.ErrUserNotExist
+		}
+		return err
+	}
+	if !f.IsPasswordsEqual(password, uDB.Password) {
+		return me.ErrIncorrectPwdOrLogin
+	}
+	tDB, err := u.getTOTP(ctx, username)
+	if err != nil {
+		return err
+	}
+	if !tDB.Enabled {
+		return me.ErrMfaNotEnabled
+	}
+	err = u.useCode(ctx, tDB, code)
+	if err != nil {
+		return err
+	}
+	return u.repoMfa.DeleteByUsername(ctx, username)
+}
+
+// IsEnabled сообщает, включен ли у пользователя второй фактор.
+func (u *UsecaseLayer) IsEnabled(ctx context.Context, username string) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "mfa.IsEnabled")
+	defer tracing.End(span, &err)
+
+	tDB, err := u.repoMfa.GetByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return tDB.Enabled, nil
+}
+
+// NewChallenge выдает токен вызова второго фактора. Этот токен не дает доступа к ресурсам, его можно
+// только обменять на пару токенов вместе с кодом. newPasswordHash — новый пароль, который администратор
+// потребовал установить: он хранится в токене в зашифрованном виде и сохраняется только после ввода кода.
+func (u *UsecaseLayer) NewChallenge(username, newPasswordHash string) (string, time.Time, error) {
+	var passwordReset string
+	if newPasswordHash != "" {
+		var err error
+		passwordReset, err = f.Encrypt(newPasswordHash)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+	}
+	dateExp := time.Now().Add(viper.GetDuration("auth.mfa_challenge_ttl"))
+	token, err := f.NewJwtToken(f.NewJwtTokenProps{
+		Username:      username,
+		Typ:           mc.MfaChallengeType,
+		PasswordReset: passwordReset,
+	}, dateExp)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, dateExp, nil
+}
+
+// ParseChallenge проверяет токен вызова второго фактора и возвращает никнейм пользователя.
+func (u *UsecaseLayer) ParseChallenge(challengeToken string) (string, error) {
+	p, err := parseChallenge(challengeToken)
+	if err != nil {
+		return "", err
+	}
+	return p.Username, nil
+}
+
+// VerifyChallenge завершает двухшаговую авторизацию: проверяет токен вызова и код. Если при первом шаге был
+// указан новый пароль, его хэш возвращается в NewPasswordHash.
+func (u *UsecaseLayer) VerifyChallenge(ctx context.Context, challengeToken, code string) (_ *ent.SignInResult, err error) {
+	ctx, span := tracing.Start(ctx, "mfa.VerifyChallenge")
+	defer tracing.End(span, &err)
+
+	payload, err := parseChallenge(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	var newPasswordHash string
+	if payload.PasswordReset != "" {
+		newPasswordHash, err = f.Decrypt(payload.PasswordReset)
+		if err != nil {
+			return nil, me.ErrInvalidMfaChallenge
+		}
+	}
+	username := payload.Username
+	tDB, err := u.getTOTP(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if !tDB.Enabled {
+		return nil, me.ErrInvalidMfaChallenge
+	}
+	err = u.useCode(ctx, tDB, code)
+	if err != nil {
+		return nil, err
+	}
+	uDB, err := u.repoUser.GetByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, me.ErrIncorrectPwdOrLogin
+		}
+		return nil, err
+	}
+	return &ent.SignInResult{User: uDB, NewPasswordHash: newPasswordHash}, nil
+}
+
+func parseChallenge(challengeToken string) (*dto.JwtTokenPayload, error) {
+	h, p, err := f.ParseJwtToken(challengeToken)
+	if err != nil || h.Typ != mc.MfaChallengeType {
+		return nil, me.ErrInvalidMfaChallenge
+	}
+	return p, nil
+}
+
+func (u *UsecaseLayer) getTOTP(ctx context.Context, username string) (*ent.TOTP, error) {
+	tDB, err := u.repoMfa.GetByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, me.ErrMfaNotEnabled
+		}
+		return nil, err
+	}
+	return tDB, nil
+}
+
+// useCode принимает как код из приложения, так и код восстановления.
+func (u *UsecaseLayer) useCode(ctx context.Context, tDB *ent.TOTP, code string) error {
+	if len(code) == mc.TOTPDigits {
+		return u.useTOTPCode(ctx, tDB, code)
+	}
+	err := u.repoMfa.UseRecoveryCode(ctx, tDB.Username, f.HashToken(f.NormalizeRecoveryCode(code)))
+	if err != nil {
+		if errors.Is(err, me.ErrNoRowsAffected) {
+			return me.ErrInvalidMfaCode
+		}
+		return err
+	}
+	return nil
+}
+
+func (u *UsecaseLayer) useTOTPCode(ctx context.Context, tDB *ent.TOTP, code string) error {
+	secret, err := f.Decrypt(tDB.SecretEncrypted)
+	if err != nil {
+		return err
+	}
+	step, ok := f.ValidateTOTPCode(secret, code, time.Now())
+	if !ok {
+		return me.ErrInvalidMfaCode
+	}
+	err = u.repoMfa.UseStep(ctx, tDB.Username, step)
+	if err != nil {
+		if errors.Is(err, me.ErrNoRowsAffected) {
+			// код уже был использован
+			return me.ErrInvalidMfaCode
+		}
+		return err
+	}
+	return nil
+}
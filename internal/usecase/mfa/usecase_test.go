@@ -0,0 +1,230 @@
+package mfa
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/memory"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/mfa"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/spf13/viper"
+)
+
+// newEnabledMfa создает пользователя ivan с паролем password и подтвержденным вторым фактором. Возвращает
+// секрет TOTP, шаг кода подтверждения и коды восстановления.
+func newEnabledMfa(t *testing.T) (uc *UsecaseLayer, secret string, step int64, recoveryCodes []string) {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("secret_key", "secret")
+	viper.Set("auth.mfa_challenge_ttl", 5*time.Minute)
+	viper.Set("auth.totp_issuer", "Healthcheck")
+	viper.Set("auth.argon2.memory", 1024)
+	viper.Set("auth.argon2.time", 1)
+	viper.Set("auth.argon2.threads", 1)
+	viper.Set("auth.argon2.key_length", 32)
+	viper.Set("auth.argon2.salt_length", 16)
+
+	ctx := context.Background()
+	store := memory.NewStore()
+	repoUser := user.NewRepoMemory(store)
+	password, err := f.GetHashedPassword("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repoUser.Create(ctx, &ent.User{
+		Username: "ivan", FirstName: "Ivan", Weight: 80, Height: 180, Age: 30, Sex: "M",
+		PhysicalActivity: "MA", Password: password,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc = NewUsecaseLayer(mfa.NewRepoMemory(store), repoUser)
+
+	enrollment, err := uc.Enroll(ctx, "ivan")
+	if err != nil {
+		t.Fatalf("Enroll() error = %v", err)
+	}
+	if !strings.Contains(enrollment.OtpauthURI, "secret="+enrollment.Secret) {
+		t.Errorf("OtpauthURI = %q, want secret %s", enrollment.OtpauthURI, enrollment.Secret)
+	}
+	step = f.GetTOTPStep(time.Now())
+	recoveryCodes, err = uc.Confirm(ctx, "ivan", code(t, enrollment.Secret, step))
+	if err != nil {
+		t.Fatalf("Confirm() error = %v", err)
+	}
+	return uc, enrollment.Secret, step, recoveryCodes
+}
+
+func code(t *testing.T, secret string, step int64) string {
+	t.Helper()
+	c, err := f.GetTOTPCode(secret, step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestConfirm(t *testing.T) {
+	uc, secret, step, recoveryCodes := newEnabledMfa(t)
+	ctx := context.Background()
+
+	if enabled, err := uc.IsEnabled(ctx, "ivan"); err != nil || !enabled {
+		t.Fatalf("IsEnabled() = %v, %v, want enabled", enabled, err)
+	}
+	if len(recoveryCodes) != 10 {
+		t.Errorf("Confirm() returned %d recovery codes, want 10", len(recoveryCodes))
+	}
+	if _, err := uc.Confirm(ctx, "ivan", code(t, secret, step+1)); !errors.Is(err, me.ErrMfaAlreadyEnabled) {
+		t.Errorf("second Confirm() error = %v, want %v", err, me.ErrMfaAlreadyEnabled)
+	}
+	if _, err := uc.Enroll(ctx, "ivan"); !errors.Is(err, me.ErrMfaAlreadyEnabled) {
+		t.Errorf("Enroll() with enabled mfa error = %v, want %v", err, me.ErrMfaAlreadyEnabled)
+	}
+}
+
+func TestConfirmWrongCode(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	store := memory.NewStore()
+	repoUser := user.NewRepoMemory(store)
+	_, err := repoUser.Create(context.Background(), &ent.User{
+		Username: "ivan", FirstName: "Ivan", Weight: 80, Height: 180, Age: 30, Sex: "M",
+		PhysicalActivity: "MA", Password: "hash",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc := NewUsecaseLayer(mfa.NewRepoMemory(store), repoUser)
+	enrollment, err := uc.Enroll(context.Background(), "ivan")
+	if err != nil {
+		t.Fatalf("Enroll() error = %v", err)
+	}
+
+	wrong := code(t, enrollment.Secret, f.GetTOTPStep(time.Now())+5)
+	if _, err := uc.Confirm(context.Background(), "ivan", wrong); !errors.Is(err, me.ErrInvalidMfaCode) {
+		t.Fatalf("Confirm() error = %v, want %v", err, me.ErrInvalidMfaCode)
+	}
+	if enabled, _ := uc.IsEnabled(context.Background(), "ivan"); enabled {
+		t.Error("mfa is enabled after a wrong code")
+	}
+}
+
+// Код из приложения принимается один раз: повтор того же шага или более раннего отклоняется.
+func TestVerifyChallengeRejectsReplayedStep(t *testing.T) {
+	uc, secret, step, _ := newEnabledMfa(t)
+	ctx := context.Background()
+	challenge, _, err := uc.NewChallenge("ivan", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := uc.VerifyChallenge(ctx, challenge, code(t, secret, step)); !errors.Is(err, me.ErrInvalidMfaCode) {
+		t.Fatalf("VerifyChallenge() with the confirmation code error = %v, want %v", err, me.ErrInvalidMfaCode)
+	}
+	result, err := uc.VerifyChallenge(ctx, challenge, code(t, secret, step+1))
+	if err != nil {
+		t.Fatalf("VerifyChallenge() error = %v", err)
+	}
+	if result.User.Username != "ivan" || result.NewPasswordHash != "" {
+		t.Errorf("VerifyChallenge() = %+v, want ivan without a new password", result)
+	}
+	if _, err := uc.VerifyChallenge(ctx, challenge, code(t, secret, step+1)); !errors.Is(err, me.ErrInvalidMfaCode) {
+		t.Errorf("replayed VerifyChallenge() error = %v, want %v", err, me.ErrInvalidMfaCode)
+	}
+	if _, err := uc.VerifyChallenge(ctx, challenge, code(t, secret, step)); !errors.Is(err, me.ErrInvalidMfaCode) {
+		t.Errorf("VerifyChallenge() with an earlier step error = %v, want %v", err, me.ErrInvalidMfaCode)
+	}
+}
+
+func TestVerifyChallengeRecoveryCodeOnce(t *testing.T) {
+	uc, _, _, recoveryCodes := newEnabledMfa(t)
+	ctx := context.Background()
+	challenge, _, err := uc.NewChallenge("ivan", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// код восстановления можно ввести без дефиса и в нижнем регистре
+	typed := strings.ToLower(strings.ReplaceAll(recoveryCodes[0], "-", ""))
+	if _, err := uc.VerifyChallenge(ctx, challenge, typed); err != nil {
+		t.Fatalf("VerifyChallenge() with a recovery code error = %v", err)
+	}
+	if _, err := uc.VerifyChallenge(ctx, challenge, recoveryCodes[0]); !errors.Is(err, me.ErrInvalidMfaCode) {
+		t.Errorf("VerifyChallenge() with a used recovery code error = %v, want %v", err, me.ErrInvalidMfaCode)
+	}
+	if _, err := uc.VerifyChallenge(ctx, challenge, recoveryCodes[1]); err != nil {
+		t.Errorf("VerifyChallenge() with another recovery code error = %v", err)
+	}
+}
+
+// Новый пароль хранится в токене вызова зашифрованным и возвращается только после верного кода.
+func TestVerifyChallengeCarriesPasswordReset(t *testing.T) {
+	uc, secret, step, _ := newEnabledMfa(t)
+	ctx := context.Background()
+	challenge, _, err := uc.NewChallenge("ivan", "new-password-hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, payload, err := f.ParseJwtToken(challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload.PasswordReset == "" || strings.Contains(payload.PasswordReset, "new-password-hash") {
+		t.Errorf("challenge password_reset = %q, want the encrypted hash", payload.PasswordReset)
+	}
+
+	if _, err := uc.VerifyChallenge(ctx, challenge, "000000"); !errors.Is(err, me.ErrInvalidMfaCode) {
+		t.Fatalf("VerifyChallenge() with a wrong code error = %v, want %v", err, me.ErrInvalidMfaCode)
+	}
+	result, err := uc.VerifyChallenge(ctx, challenge, code(t, secret, step+1))
+	if err != nil {
+		t.Fatalf("VerifyChallenge() error = %v", err)
+	}
+	if result.NewPasswordHash != "new-password-hash" {
+		t.Errorf("VerifyChallenge() new password hash = %q, want new-password-hash", result.NewPasswordHash)
+	}
+}
+
+func TestVerifyChallengeRejectsAccessToken(t *testing.T) {
+	uc, secret, step, _ := newEnabledMfa(t)
+	accessToken, err := f.NewJwtToken(f.NewJwtTokenProps{Username: "ivan"}, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = uc.VerifyChallenge(context.Background(), accessToken, code(t, secret, step+1))
+	if !errors.Is(err, me.ErrInvalidMfaChallenge) {
+		t.Fatalf("VerifyChallenge() error = %v, want %v", err, me.ErrInvalidMfaChallenge)
+	}
+}
+
+func TestDisable(t *testing.T) {
+	uc, secret, step, recoveryCodes := newEnabledMfa(t)
+	ctx := context.Background()
+
+	if err := uc.Disable(ctx, "ivan", "wrong", code(t, secret, step+1)); !errors.Is(err, me.ErrIncorrectPwdOrLogin) {
+		t.Fatalf("Disable() with a wrong password error = %v, want %v", err, me.ErrIncorrectPwdOrLogin)
+	}
+	if err := uc.Disable(ctx, "ivan", "password", "ABCDE-FGHJK"); !errors.Is(err, me.ErrInvalidMfaCode) {
+		t.Fatalf("Disable() with a wrong code error = %v, want %v", err, me.ErrInvalidMfaCode)
+	}
+	if enabled, _ := uc.IsEnabled(ctx, "ivan"); !enabled {
+		t.Fatal("mfa is disabled after rejected attempts")
+	}
+	if err := uc.Disable(ctx, "ivan", "password", recoveryCodes[0]); err != nil {
+		t.Fatalf("Disable() error = %v", err)
+	}
+	if enabled, _ := uc.IsEnabled(ctx, "ivan"); enabled {
+		t.Error("mfa is enabled after Disable()")
+	}
+	if err := uc.Disable(ctx, "ivan", "password", recoveryCodes[1]); !errors.Is(err, me.ErrMfaNotEnabled) {
+		t.Errorf("second Disable() error = %v, want %v", err, me.ErrMfaNotEnabled)
+	}
+}
@@ -0,0 +1,277 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/identity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/tracing"
+	"github.com/spf13/viper"
+)
+
+// Provider клиент внешнего провайдера OpenID Connect.
+type Provider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier string) (string, error)
+	VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*ent.ExternalIdentity, error)
+}
+
+type Usecase interface {
+	Begin(ctx context.Context, providerName string) (string, string, time.Time, error)
+	Callback(ctx context.Context, providerName, code, state, flowCookie, currentUsername string) (*ent.OIDCResult, error)
+	CompleteProfile(ctx context.Context, registrationToken string, profile *dto.CreateData) (*ent.User, error)
+}
+
+var _ Usecase = (*UsecaseLayer)(nil)
+
+type UsecaseLayer struct {
+	repoUser     user.Repo
+	repoIdentity identity.Repo
+	txManager    transaction.Manager
+	providers    map[string]Provider
+}
+
+// NewUsecaseLayer возращает структуру уровня usecase для входа через внешних провайдеров.
+func NewUsecaseLayer(repoUser user.Repo, repoIdentity identity.Repo, txManager transaction.Manager,
+	providers map[string]Provider) *UsecaseLayer {
+	return &UsecaseLayer{
+		repoUser:     repoUser,
+		repoIdentity: repoIdentity,
+		txManager:    txManager,
+		providers:    providers,
+	}
+}
+
+// Begin начинает вход через провайдера. Возвращает адрес страницы входа провайдера и зашифрованное
+// состояние (state, nonce, code_verifier), которое нужно сохранить у клиента до возврата от провайдера.
+func (u *UsecaseLayer) Begin(ctx context.Context, providerName string) (_ string, _ string, _ time.Time, err error) {
+	ctx, span := tracing.Start(ctx, "oidc.Begin")
+	defer tracing.End(span, &err)
+
+	provider, ok := u.providers[providerName]
+	if !ok {
+		return "", "", time.Time{}, me.ErrUnknownProvider
+	}
+	state, err := f.NewRandomString(32)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	nonce, err := f.NewRandomString(32)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	verifier, challenge, err := f.NewPKCE()
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, challenge)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	flow := ent.OIDCFlow{
+		Provider:     providerName,
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(viper.GetDuration("oidc.flow_ttl")),
+	}
+	flowEncrypted, err := encryptJSON(flow)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	return authURL, flowEncrypted, flow.ExpiresAt, nil
+}
+
+// Callback обрабатывает возврат от провайдера. Внешняя учетная запись ищется среди привязанных; если ее нет,
+// то она привязывается к текущему пользователю. Пользователь с той же подтвержденной почтой должен сначала
+// войти в свою учетную запись, а при oidc.link_by_email учетная запись привязывается к нему сразу. Иначе
+// возвращается registration_token, с которым нужно заполнить профиль.
+func (u *UsecaseLayer) Callback(ctx context.Context, providerName, code, state, flowCookie, currentUsername string) (_ *ent.OIDCResult, err error) {
+	ctx, span := tracing.Start(ctx, "oidc.Callback")
+	defer tracing.End(span, &err)
+
+	var flow ent.OIDCFlow
+	err = decryptJSON(flowCookie, &flow)
+	if err != nil || flow.Provider != providerName || time.Now().After(flow.ExpiresAt) ||
+		subtle.ConstantTimeCompare([]byte(flow.State), []byte(state)) != 1 {
+		return nil, me.ErrInvalidOIDCState
+	}
+	provider, ok := u.providers[providerName]
+	if !ok {
+		return nil, me.ErrUnknownProvider
+	}
+	rawIDToken, err := provider.Exchange(ctx, code, flow.CodeVerifier)
+	if err != nil {
+		return nil, errors.Join(me.ErrOIDCLoginFailed, err)
+	}
+	externalIdentity, err := provider.VerifyIDToken(ctx, rawIDToken, flow.Nonce)
+	if err != nil {
+		return nil, errors.Join(me.ErrOIDCLoginFailed, err)
+	}
+
+	linked, err := u.repoIdentity.GetByProviderSubject(ctx, externalIdentity.Provider, externalIdentity.Subject)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if linked != nil {
+		if currentUsername != "" && currentUsername != linked.Username {
+			return nil, me.ErrIdentityAlreadyLinked
+		}
+		uDB, err := u.getUser(ctx, linked.Username)
+		if err != nil {
+			return nil, err
+		}
+		return &ent.OIDCResult{User: uDB}, nil
+	}
+
+	// привязка к текущему пользователю
+	if currentUsername != "" {
+		return u.link(ctx, externalIdentity, currentUsername)
+	}
+	// привязка по почте допустима, только если провайдер подтвердил, что почта принадлежит пользователю,
+	// и только если это явно разрешено: иначе доступ к учетной записи получил бы любой, кто завел почту
+	// с тем же адресом у провайдера
+	if externalIdentity.EmailVerified && externalIdentity.Email != "" {
+		uDB, err := u.repoUser.GetByEmail(ctx, externalIdentity.Email)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		if uDB != nil {
+			if !viper.GetBool("oidc.link_by_email") {
+				return nil, me.ErrOIDCEmailTaken
+			}
+			return u.link(ctx, externalIdentity, uDB.Username)
+		}
+	}
+
+	registrationToken, err := encryptJSON(ent.OIDCRegistration{
+		Identity:  *externalIdentity,
+		ExpiresAt: time.Now().Add(viper.GetDuration("oidc.registration_ttl")),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ent.OIDCResult{RegistrationToken: registrationToken, Identity: externalIdentity}, nil
+}
+
+// CompleteProfile создает пользователя для внешней учетной записи. У такого пользователя нет пароля, которым
+// можно войти: сохраняется хэш случайного пароля.
+func (u *UsecaseLayer) CompleteProfile(ctx context.Context, registrationToken string, profile *dto.CreateData) (_ *ent.User, err error) {
+	ctx, span := tracing.Start(ctx, "oidc.CompleteProfile")
+	defer tracing.End(span, &err)
+
+	var registration ent.OIDCRegistration
+	err = decryptJSON(registrationToken, &registration)
+	if err != nil || time.Now().After(registration.ExpiresAt) {
+		return nil, me.ErrInvalidRegistrationToken
+	}
+	externalIdentity := registration.Identity
+
+	randomPassword, err := f.NewRandomString(32)
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := f.GetHashedPassword(randomPassword)
+	if err != nil {
+		return nil, err
+	}
+	// пользователь и привязка к провайдеру создаются вместе: пользователь без привязки не сможет войти
+	var userNew *ent.User
+	err = u.txManager.Do(ctx, transaction.ReadCommitted, func(ctx context.Context) error {
+		linked, err := u.repoIdentity.GetByProviderSubject(ctx, externalIdentity.Provider, externalIdentity.Subject)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if linked != nil {
+			return me.ErrIdentityAlreadyLinked
+		}
+		uDB, err := u.repoUser.GetByUsername(ctx, profile.Username)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if uDB != nil {
+			return me.ErrUserAlreadyExist
+		}
+		email, err := u.freeVerifiedEmail(ctx, &externalIdentity)
+		if err != nil {
+			return err
+		}
+		userNew, err = u.repoUser.Create(ctx, newUserFromProfile(profile, email, hashedPassword, f.GetDayCalories(profile)))
+		if err != nil {
+			return err
+		}
+		externalIdentity.Username = userNew.Username
+		_, err = u.repoIdentity.Create(ctx, &externalIdentity)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return userNew, nil
+}
+
+func (u *UsecaseLayer) link(ctx context.Context, externalIdentity *ent.ExternalIdentity, username string) (*ent.OIDCResult, error) {
+	uDB, err := u.getUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	externalIdentity.Username = username
+	_, err = u.repoIdentity.Create(ctx, externalIdentity)
+	if err != nil {
+		return nil, err
+	}
+	return &ent.OIDCResult{User: uDB, Linked: true}, nil
+}
+
+func (u *UsecaseLayer) getUser(ctx context.Context, username string) (*ent.User, error) {
+	uDB, err := u.repoUser.GetByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, me.ErrUserNotExist
+		}
+		return nil, err
+	}
+	return uDB, nil
+}
+
+// freeVerifiedEmail возвращает почту внешней учетной записи, если ее можно сохранить у нового пользователя.
+func (u *UsecaseLayer) freeVerifiedEmail(ctx context.Context, externalIdentity *ent.ExternalIdentity) (string, error) {
+	email := externalIdentity.Email
+	if !externalIdentity.EmailVerified || len(email) < 6 || len(email) > 50 {
+		return "", nil
+	}
+	uDB, err := u.repoUser.GetByEmail(ctx, email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+	if uDB != nil {
+		return "", nil
+	}
+	return email, nil
+}
+
+func encryptJSON(value any) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return f.Encrypt(string(data))
+}
+
+func decryptJSON(ciphertext string, dst any) error {
+	data, err := f.Decrypt(ciphertext)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(data), dst)
+}
@@ -0,0 +1,242 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/identity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/memory"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/services/oidc"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/services/oidc/oidctest"
+	"github.com/spf13/viper"
+)
+
+const (
+	testProvider = "mock"
+	testClientID = "healthcheck"
+)
+
+type fixture struct {
+	uc       *UsecaseLayer
+	issuer   *oidctest.Issuer
+	repoUser user.Repo
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("secret_key", "0123456789abcdef0123456789abcdef")
+	viper.Set("oidc.flow_ttl", 10*time.Minute)
+	viper.Set("oidc.registration_ttl", 30*time.Minute)
+	viper.Set("auth.argon2.memory", 1024)
+	viper.Set("auth.argon2.time", 1)
+	viper.Set("auth.argon2.threads", 1)
+	viper.Set("auth.argon2.key_length", 32)
+	viper.Set("auth.argon2.salt_length", 16)
+
+	issuer := oidctest.NewIssuer(t, testClientID)
+	provider := oidc.NewProvider(testProvider, oidc.Config{
+		Issuer:      issuer.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://localhost:8010/api/v1/oidc/mock/callback",
+	}, &http.Client{Timeout: 5 * time.Second})
+	store := memory.NewStore()
+	repoUser := user.NewRepoMemory(store)
+	uc := NewUsecaseLayer(repoUser, identity.NewRepoMemory(store), transaction.NewManagerMemory(store),
+		map[string]Provider{testProvider: provider})
+	return &fixture{uc: uc, issuer: issuer, repoUser: repoUser}
+}
+
+// flow вход, начатый клиентом: зашифрованное состояние и параметры из адреса страницы входа провайдера.
+type flow struct {
+	cookie    string
+	state     string
+	nonce     string
+	challenge string
+}
+
+func (fx *fixture) begin(t *testing.T) flow {
+	t.Helper()
+	authURL, cookie, _, err := fx.uc.Begin(context.Background(), testProvider)
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	return flow{cookie: cookie, state: query.Get("state"), nonce: query.Get("nonce"), challenge: query.Get("code_challenge")}
+}
+
+// code выдает код авторизации для id_token с claims, которые можно изменить функцией modify.
+func (fx *fixture) code(fl flow, modify func(claims map[string]any)) string {
+	claims := fx.issuer.Claims("subject-1", fl.nonce)
+	claims["email"] = "ivan@bmstu.ru"
+	claims["email_verified"] = true
+	claims["given_name"] = "Ivan"
+	if modify != nil {
+		modify(claims)
+	}
+	return fx.issuer.IssueCode(fl.challenge, fx.issuer.Sign(claims))
+}
+
+func (fx *fixture) createUser(t *testing.T, username, email string) {
+	t.Helper()
+	_, err := fx.repoUser.Create(context.Background(), &ent.User{
+		Username: username, Email: email, FirstName: "Ivan", Weight: 80, Height: 180, Age: 30, Sex: "M",
+		PhysicalActivity: "MA", Password: "hash",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCallbackRejectsInvalidFlow(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(fl *flow, code *string)
+		claims  func(claims map[string]any)
+		wantErr error
+	}{
+		{name: "state mismatch", modify: func(fl *flow, _ *string) { fl.state = "another-state" }, wantErr: me.ErrInvalidOIDCState},
+		{name: "tampered flow cookie", modify: func(fl *flow, _ *string) { fl.cookie = "tampered" }, wantErr: me.ErrInvalidOIDCState},
+		{name: "nonce mismatch", claims: func(claims map[string]any) { claims["nonce"] = "another-nonce" }, wantErr: me.ErrOIDCLoginFailed},
+		{name: "expired id_token", claims: func(claims map[string]any) {
+			claims["iat"] = time.Now().Add(-2 * time.Hour).Unix()
+			claims["exp"] = time.Now().Add(-time.Hour).Unix()
+		}, wantErr: me.ErrOIDCLoginFailed},
+		{name: "unknown code", modify: func(_ *flow, code *string) { *code = "unknown-code" }, wantErr: me.ErrOIDCLoginFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fx := newFixture(t)
+			fl := fx.begin(t)
+			code := fx.code(fl, tt.claims)
+			if tt.modify != nil {
+				tt.modify(&fl, &code)
+			}
+
+			result, err := fx.uc.Callback(context.Background(), testProvider, code, fl.state, fl.cookie, "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Callback() = %+v, %v, want error %v", result, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCallbackAnotherProviderFlow(t *testing.T) {
+	fx := newFixture(t)
+	fl := fx.begin(t)
+
+	_, err := fx.uc.Callback(context.Background(), "another", fx.code(fl, nil), fl.state, fl.cookie, "")
+	if !errors.Is(err, me.ErrInvalidOIDCState) {
+		t.Fatalf("Callback() error = %v, want %v", err, me.ErrInvalidOIDCState)
+	}
+}
+
+func TestCallbackRegistersNewUser(t *testing.T) {
+	fx := newFixture(t)
+	ctx := context.Background()
+	fl := fx.begin(t)
+
+	result, err := fx.uc.Callback(ctx, testProvider, fx.code(fl, nil), fl.state, fl.cookie, "")
+	if err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+	if result.User != nil || result.RegistrationToken == "" {
+		t.Fatalf("Callback() = %+v, want registration token", result)
+	}
+
+	profile := &dto.CreateData{Username: "ivan", FirstName: "Ivan", Weight: 80, Height: 180, Age: 30, Sex: "M", PhysicalActivity: "MA"}
+	u, err := fx.uc.CompleteProfile(ctx, result.RegistrationToken, profile)
+	if err != nil {
+		t.Fatalf("CompleteProfile() error = %v", err)
+	}
+	if u.Username != "ivan" || u.Email != "ivan@bmstu.ru" {
+		t.Errorf("user = %s <%s>, want ivan <ivan@bmstu.ru>", u.Username, u.Email)
+	}
+
+	// следующий вход находит привязанную учетную запись
+	fl = fx.begin(t)
+	result, err = fx.uc.Callback(ctx, testProvider, fx.code(fl, nil), fl.state, fl.cookie, "")
+	if err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+	if result.User == nil || result.User.Username != "ivan" {
+		t.Fatalf("Callback() = %+v, want user ivan", result)
+	}
+}
+
+func TestCallbackExistingEmail(t *testing.T) {
+	tests := []struct {
+		name        string
+		linkByEmail bool
+		emailOK     bool
+		wantErr     error
+		wantLinked  bool
+	}{
+		{name: "requires sign in by default", emailOK: true, wantErr: me.ErrOIDCEmailTaken},
+		{name: "links when enabled", linkByEmail: true, emailOK: true, wantLinked: true},
+		{name: "unverified email is never linked", linkByEmail: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fx := newFixture(t)
+			viper.Set("oidc.link_by_email", tt.linkByEmail)
+			fx.createUser(t, "ivan", "ivan@bmstu.ru")
+			fl := fx.begin(t)
+			code := fx.code(fl, func(claims map[string]any) { claims["email_verified"] = tt.emailOK })
+
+			result, err := fx.uc.Callback(context.Background(), testProvider, code, fl.state, fl.cookie, "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Callback() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if tt.wantLinked {
+				if !result.Linked || result.User == nil || result.User.Username != "ivan" {
+					t.Fatalf("Callback() = %+v, want linked to ivan", result)
+				}
+				return
+			}
+			if result.User != nil || result.RegistrationToken == "" {
+				t.Fatalf("Callback() = %+v, want registration token", result)
+			}
+		})
+	}
+}
+
+func TestCallbackLinksSignedInUser(t *testing.T) {
+	fx := newFixture(t)
+	ctx := context.Background()
+	fx.createUser(t, "ivan", "ivan@bmstu.ru")
+	fx.createUser(t, "petr", "petr@bmstu.ru")
+
+	fl := fx.begin(t)
+	result, err := fx.uc.Callback(ctx, testProvider, fx.code(fl, nil), fl.state, fl.cookie, "ivan")
+	if err != nil {
+		t.Fatalf("Callback() error = %v", err)
+	}
+	if !result.Linked || result.User.Username != "ivan" {
+		t.Fatalf("Callback() = %+v, want linked to ivan", result)
+	}
+
+	// учетная запись провайдера уже привязана к ivan
+	fl = fx.begin(t)
+	_, err = fx.uc.Callback(ctx, testProvider, fx.code(fl, nil), fl.state, fl.cookie, "petr")
+	if !errors.Is(err, me.ErrIdentityAlreadyLinked) {
+		t.Fatalf("Callback() error = %v, want %v", err, me.ErrIdentityAlreadyLinked)
+	}
+}
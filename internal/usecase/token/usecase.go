@@ -0,0 +1,180 @@
+package token
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/token"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/tracing"
+	"github.com/satori/uuid"
+	"github.com/spf13/viper"
+)
+
+type Usecase interface {
+	Issue(ctx context.Context, username string) (*ent.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*ent.TokenPair, error)
+	Revoke(ctx context.Context, refreshToken string) error
+	Owner(ctx context.Context, refreshToken string) (string, error)
+}
+
+var _ Usecase = (*UsecaseLayer)(nil)
+
+type UsecaseLayer struct {
+	repoToken token.Repo
+	repoUser  user.Repo
+}
+
+// NewUsecaseLayer возращает структуру уровня usecase для работы с токенами.
+func NewUsecaseLayer(repoToken token.Repo, repoUser user.Repo) *UsecaseLayer {
+	return &UsecaseLayer{
+		repoToken: repoToken,
+		repoUser:  repoUser,
+	}
+}
+
+// Issue выдает пару токенов при авторизации пользователя, тем самым начиная новое семейство refresh-токенов.
+func (u *UsecaseLayer) Issue(ctx context.Context, username string) (_ *ent.TokenPair, err error) {
+	ctx, span := tracing.Start(ctx, "token.Issue")
+	defer tracing.End(span, &err)
+
+	uDB, err := u.repoUser.GetByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, me.ErrUserNotExist
+		}
+		return nil, err
+	}
+	if uDB.LockedAt != nil {
+		return nil, me.ErrAccountLocked
+	}
+	pair, newToken, err := u.newPair(uDB)
+	if err != nil {
+		return nil, err
+	}
+	newToken.FamilyID = uuid.NewV4().String()
+	_, err = u.repoToken.Create(ctx, newToken)
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Refresh обменивает refresh-токен на новую пару токенов. Предъявленный токен становится недействительным.
+// Если предъявлен уже использованный токен, то считаем, что он был украден, и отзываем все семейство.
+func (u *UsecaseLayer) Refresh(ctx context.Context, refreshToken string) (_ *ent.TokenPair, err error) {
+	ctx, span := tracing.Start(ctx, "token.Refresh")
+	defer tracing.End(span, &err)
+
+	tDB, err := u.repoToken.GetByHash(ctx, f.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, me.ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	if tDB.RevokedAt != nil {
+		return nil, u.revokeReused(ctx, tDB.FamilyID)
+	}
+	if !time.Now().Before(tDB.ExpiresAt) {
+		return nil, me.ErrInvalidRefreshToken
+	}
+
+	// роль берется из базы, поэтому ее изменение вступает в силу при следующем обновлении токенов
+	uDB, err := u.repoUser.GetByUsername(ctx, tDB.Username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, me.ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	if uDB.LockedAt != nil {
+		return nil, me.ErrAccountLocked
+	}
+	pair, newToken, err := u.newPair(uDB)
+	if err != nil {
+		return nil, err
+	}
+	newToken.FamilyID = tDB.FamilyID
+	tNew, err := u.repoToken.Create(ctx, newToken)
+	if err != nil {
+		return nil, err
+	}
+	err = u.repoToken.Rotate(ctx, tDB.ID, tNew.ID)
+	if err != nil {
+		if errors.Is(err, me.ErrNoRowsAffected) {
+			// токен успели использовать параллельно
+			return nil, u.revokeReused(ctx, tDB.FamilyID)
+		}
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Revoke отзывает семейство, к которому принадлежит refresh-токен. Используется при завершении сессии.
+func (u *UsecaseLayer) Revoke(ctx context.Context, refreshToken string) (err error) {
+	ctx, span := tracing.Start(ctx, "token.Revoke")
+	defer tracing.End(span, &err)
+
+	tDB, err := u.repoToken.GetByHash(ctx, f.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	return u.repoToken.RevokeFamily(ctx, tDB.FamilyID)
+}
+
+// Owner возвращает никнейм владельца refresh-токена, в том числе отозванного.
+func (u *UsecaseLayer) Owner(ctx context.Context, refreshToken string) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "token.Owner")
+	defer tracing.End(span, &err)
+
+	tDB, err := u.repoToken.GetByHash(ctx, f.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", me.ErrInvalidRefreshToken
+		}
+		return "", err
+	}
+	return tDB.Username, nil
+}
+
+func (u *UsecaseLayer) newPair(uDB *ent.User) (*ent.TokenPair, *ent.RefreshToken, error) {
+	timeNow := time.Now()
+	accessExp := timeNow.Add(viper.GetDuration("auth.access_token_ttl"))
+	accessToken, err := f.NewJwtToken(f.NewJwtTokenProps{Username: uDB.Username, Role: uDB.Role}, accessExp)
+	if err != nil {
+		return nil, nil, err
+	}
+	refreshExp := timeNow.Add(viper.GetDuration("auth.refresh_token_ttl"))
+	refreshToken, err := f.NewRefreshToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	pair := &ent.TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessExp,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExp,
+	}
+	return pair, &ent.RefreshToken{
+		Username:  uDB.Username,
+		TokenHash: f.HashToken(refreshToken),
+		ExpiresAt: refreshExp,
+	}, nil
+}
+
+func (u *UsecaseLayer) revokeReused(ctx context.Context, familyID string) error {
+	err := u.repoToken.RevokeFamily(ctx, familyID)
+	if err != nil {
+		return err
+	}
+	return me.ErrRefreshTokenReused
+}
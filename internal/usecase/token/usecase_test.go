@@ -0,0 +1,247 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/memory"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/token"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/spf13/viper"
+)
+
+// racingRepo имитирует параллельное обновление: перед Rotate токен уже заменен другим запросом.
+type racingRepo struct {
+	token.Repo
+}
+
+func (r *racingRepo) Rotate(ctx context.Context, id, replacedBy string) error {
+	if err := r.Repo.Rotate(ctx, id, replacedBy); err != nil {
+		return err
+	}
+	return me.ErrNoRowsAffected
+}
+
+type fixture struct {
+	store     *memory.Store
+	uc        *UsecaseLayer
+	repoToken token.Repo
+	repoUser  user.Repo
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("auth.access_token_ttl", 15*time.Minute)
+	viper.Set("auth.refresh_token_ttl", 24*time.Hour)
+
+	store := memory.NewStore()
+	repoUser := user.NewRepoMemory(store)
+	_, err := repoUser.Create(context.Background(), &ent.User{
+		Username: "ivan", FirstName: "Ivan", Weight: 80, Height: 180, Age: 30, Sex: "M",
+		PhysicalActivity: "MA", Password: "hash",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repoToken := token.NewRepoMemory(store)
+	return &fixture{store: store, uc: NewUsecaseLayer(repoToken, repoUser), repoToken: repoToken, repoUser: repoUser}
+}
+
+// tokens возвращает все сохраненные refresh-токены.
+func (fx *fixture) tokens(t *testing.T) []ent.RefreshToken {
+	t.Helper()
+	var tokens []ent.RefreshToken
+	err := fx.store.Read(context.Background(), func() error {
+		for _, tDB := range fx.store.Tokens {
+			tokens = append(tokens, tDB)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens
+}
+
+// revoked сообщает, отозван ли refreshToken.
+func (fx *fixture) revoked(t *testing.T, refreshToken string) bool {
+	t.Helper()
+	tDB, err := fx.repoToken.GetByHash(context.Background(), f.HashToken(refreshToken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tDB.RevokedAt != nil
+}
+
+func TestRefreshRotates(t *testing.T) {
+	fx := newFixture(t)
+	ctx := context.Background()
+	first, err := fx.uc.Issue(ctx, "ivan")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+
+	second, err := fx.uc.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == "" {
+		t.Fatalf("Refresh() = %+v, want a new pair", second)
+	}
+	tOld, err := fx.repoToken.GetByHash(ctx, f.HashToken(first.RefreshToken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tNew, err := fx.repoToken.GetByHash(ctx, f.HashToken(second.RefreshToken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tOld.RevokedAt == nil || tOld.ReplacedBy == nil || *tOld.ReplacedBy != tNew.ID {
+		t.Errorf("presented token = %+v, want revoked and replaced by %s", tOld, tNew.ID)
+	}
+	if tNew.RevokedAt != nil || tNew.FamilyID != tOld.FamilyID {
+		t.Errorf("new token = %+v, want active in family %s", tNew, tOld.FamilyID)
+	}
+	if _, err := fx.uc.Refresh(ctx, second.RefreshToken); err != nil {
+		t.Fatalf("Refresh() of the new token error = %v", err)
+	}
+}
+
+// Повторное предъявление замененного токена отзывает все семейство, в том числе токен, выданный взамен.
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	fx := newFixture(t)
+	ctx := context.Background()
+	first, err := fx.uc.Issue(ctx, "ivan")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	other, err := fx.uc.Issue(ctx, "ivan")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	second, err := fx.uc.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	_, err = fx.uc.Refresh(ctx, first.RefreshToken)
+	if !errors.Is(err, me.ErrRefreshTokenReused) {
+		t.Fatalf("Refresh() of a rotated token error = %v, want %v", err, me.ErrRefreshTokenReused)
+	}
+	if !fx.revoked(t, second.RefreshToken) {
+		t.Error("token issued by rotation is still active after reuse")
+	}
+	_, err = fx.uc.Refresh(ctx, second.RefreshToken)
+	if !errors.Is(err, me.ErrRefreshTokenReused) {
+		t.Errorf("Refresh() of a revoked family error = %v, want %v", err, me.ErrRefreshTokenReused)
+	}
+	if fx.revoked(t, other.RefreshToken) {
+		t.Error("token of another sign-in is revoked")
+	}
+}
+
+func TestRefreshExpired(t *testing.T) {
+	fx := newFixture(t)
+	ctx := context.Background()
+	refreshToken, err := f.NewRefreshToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fx.repoToken.Create(ctx, &ent.RefreshToken{
+		FamilyID:  "family",
+		Username:  "ivan",
+		TokenHash: f.HashToken(refreshToken),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = fx.uc.Refresh(ctx, refreshToken)
+	if !errors.Is(err, me.ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh() error = %v, want %v", err, me.ErrInvalidRefreshToken)
+	}
+	if fx.revoked(t, refreshToken) {
+		t.Error("expired token is rotated")
+	}
+}
+
+func TestRefreshUnknown(t *testing.T) {
+	fx := newFixture(t)
+
+	_, err := fx.uc.Refresh(context.Background(), "unknown")
+	if !errors.Is(err, me.ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh() error = %v, want %v", err, me.ErrInvalidRefreshToken)
+	}
+}
+
+func TestRefreshLockedUser(t *testing.T) {
+	fx := newFixture(t)
+	ctx := context.Background()
+	pair, err := fx.uc.Issue(ctx, "ivan")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if err := fx.repoUser.SetLocked(ctx, "ivan", true); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = fx.uc.Refresh(ctx, pair.RefreshToken)
+	if !errors.Is(err, me.ErrAccountLocked) {
+		t.Fatalf("Refresh() error = %v, want %v", err, me.ErrAccountLocked)
+	}
+	if _, err := fx.uc.Issue(ctx, "ivan"); !errors.Is(err, me.ErrAccountLocked) {
+		t.Errorf("Issue() error = %v, want %v", err, me.ErrAccountLocked)
+	}
+}
+
+// Если токен успели заменить параллельно, второй запрос считается повторным использованием.
+func TestRefreshConcurrentRotate(t *testing.T) {
+	fx := newFixture(t)
+	ctx := context.Background()
+	pair, err := fx.uc.Issue(ctx, "ivan")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	uc := NewUsecaseLayer(&racingRepo{Repo: fx.repoToken}, fx.repoUser)
+
+	_, err = uc.Refresh(ctx, pair.RefreshToken)
+	if !errors.Is(err, me.ErrRefreshTokenReused) {
+		t.Fatalf("Refresh() error = %v, want %v", err, me.ErrRefreshTokenReused)
+	}
+	for _, tDB := range fx.tokens(t) {
+		if tDB.RevokedAt == nil {
+			t.Errorf("token %s is active after a concurrent rotation", tDB.ID)
+		}
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	fx := newFixture(t)
+	ctx := context.Background()
+	first, err := fx.uc.Issue(ctx, "ivan")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	second, err := fx.uc.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	if err := fx.uc.Revoke(ctx, first.RefreshToken); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if !fx.revoked(t, second.RefreshToken) {
+		t.Error("Revoke() of a rotated token keeps its family active")
+	}
+	if err := fx.uc.Revoke(ctx, "unknown"); err != nil {
+		t.Errorf("Revoke() of an unknown token error = %v, want nil", err)
+	}
+}
@@ -1,8 +1,12 @@
 package user
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
 	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
 )
 
 func newCreateDataFromUser(data *entity.User, weight float32) *dto.CreateData {
@@ -14,3 +18,15 @@ func newCreateDataFromUser(data *entity.User, weight float32) *dto.CreateData {
 		PhysicalActivity: data.PhysicalActivity,
 	}
 }
+
+// newWeightMeasurement переводит массу тела из профиля в измерение. float32 переводится через десятичную
+// запись, иначе 80.3 сохранится как 80.30000305175781.
+func newWeightMeasurement(username string, weight float32) *entity.Measurement {
+	value, _ := strconv.ParseFloat(strconv.FormatFloat(float64(weight), 'g', -1, 32), 64)
+	return &entity.Measurement{
+		Username:   username,
+		Kind:       mc.MeasureWeight,
+		Value:      value,
+		RecordedAt: time.Now().UTC(),
+	}
+}
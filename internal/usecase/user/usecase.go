@@ -6,9 +6,15 @@ import (
 	"errors"
 
 	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/coach"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/measurement"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
 	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	ucMeasurement "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/usecase/measurement"
 	f "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/functions"
 	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/tracing"
+	"go.uber.org/zap"
 )
 
 type Usecase interface {
@@ -20,51 +26,114 @@ type Usecase interface {
 var _ Usecase = (*UsecaseLayer)(nil)
 
 type UsecaseLayer struct {
-	repoUser user.Repo
+	repoUser        user.Repo
+	repoCoach       coach.Repo
+	repoMeasurement measurement.Repo
+	txManager       transaction.Manager
+	logger          *zap.Logger
 }
 
 // NewUsecaseLayer возращает структуру уровня usecase для работы с пользователями.
-func NewUsecaseLayer(repoUser user.Repo) *UsecaseLayer {
+func NewUsecaseLayer(repoUser user.Repo, repoCoach coach.Repo, repoMeasurement measurement.Repo,
+	txManager transaction.Manager, logger *zap.Logger) *UsecaseLayer {
 	return &UsecaseLayer{
-		repoUser: repoUser,
+		repoUser:        repoUser,
+		repoCoach:       repoCoach,
+		repoMeasurement: repoMeasurement,
+		txManager:       txManager,
+		logger:          logger,
 	}
 }
 
-// Read возвращает данные о пользователе.
-func (u *UsecaseLayer) Read(ctx context.Context, username string) (*ent.User, error) {
-	uDB, err := u.repoUser.GetByUsername(ctx, username)
+// Read возвращает данные о пользователе. Если тренер задал пользователю норму калорий, она заменяет рассчитанную.
+func (u *UsecaseLayer) Read(ctx context.Context, username string) (_ *ent.User, err error) {
+	ctx, span := tracing.Start(ctx, "user.Read")
+	defer tracing.End(span, &err)
+
+	uDB, err := u.repoUser.GetProfile(ctx, username)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, me.ErrUserNotExist
 		}
 		return nil, err
 	}
-	return uDB, nil
-}
-
-// Delete удаляет пользователя из системы.
-func (u *UsecaseLayer) Delete(ctx context.Context, username string) error {
-	// проверка существования пользователя
-	_, err := u.repoUser.GetByUsername(ctx, username)
+	target, err := u.repoCoach.GetCalorieTarget(ctx, username)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return me.ErrUserNotExist
+			return uDB, nil
 		}
-		return err
+		return nil, err
 	}
-	return u.repoUser.DeleteByUsername(ctx, username)
+	uDB.DayCalories = *target.CalorieTarget
+	return uDB, nil
 }
 
-func (u *UsecaseLayer) UpdateWeight(ctx context.Context, weight float32, username string) (*ent.User, error) {
-	// проверка существования пользователя
-	uDB, err := u.repoUser.GetByUsername(ctx, username)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, me.ErrUserNotExist
+// Delete удаляет пользователя из системы. Измерения могут храниться вне PostgreSQL, где их не удалит внешний
+// ключ, поэтому они удаляются после фиксации транзакции: откат или повтор транзакции не затронет их. Ошибка
+// удаления измерений не возвращается, пользователь к этому моменту уже удален.
+func (u *UsecaseLayer) Delete(ctx context.Context, username string) (err error) {
+	ctx, span := tracing.Start(ctx, "user.Delete")
+	defer tracing.End(span, &err)
+
+	return u.txManager.Do(ctx, transaction.RepeatableRead, func(txCtx context.Context) error {
+		// проверка существования пользователя
+		_, err := u.repoUser.GetByUsername(txCtx, username)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return me.ErrUserNotExist
+			}
+			return err
+		}
+		err = u.repoUser.DeleteByUsername(txCtx, username)
+		if err != nil {
+			if errors.Is(err, me.ErrNoRowsAffected) {
+				return me.ErrUserNotExist
+			}
+			return err
 		}
+		transaction.AfterCommit(txCtx, func() {
+			ucMeasurement.DeleteOfDeletedUser(ctx, u.repoMeasurement, username, u.logger)
+		})
+		return nil
+	})
+}
+
+// UpdateWeight изменяет массу тела и пересчитывает норму калорий. Чтение и запись выполняются в одной
+// транзакции, чтобы норма не была посчитана по данным, которые успел изменить параллельный запрос.
+// История массы тела хранится только в измерениях, поэтому новая масса сохраняется и измерением weight.
+// Измерения могут храниться вне PostgreSQL, поэтому измерение сохраняется после фиксации транзакции:
+// повтор транзакции не запишет его дважды.
+func (u *UsecaseLayer) UpdateWeight(ctx context.Context, weight float32, username string) (_ *ent.User, err error) {
+	ctx, span := tracing.Start(ctx, "user.UpdateWeight")
+	defer tracing.End(span, &err)
+
+	var uNew *ent.User
+	var errMeasurement error
+	err = u.txManager.Do(ctx, transaction.RepeatableRead, func(txCtx context.Context) error {
+		// проверка существования пользователя
+		uDB, err := u.repoUser.GetByUsername(txCtx, username)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return me.ErrUserNotExist
+			}
+			return err
+		}
+		// посчитаем новое значение КК
+		newDayCalories := f.GetDayCalories(newCreateDataFromUser(uDB, weight))
+		uNew, err = u.repoUser.UpdateWeight(txCtx, weight, newDayCalories, username)
+		if err != nil {
+			return err
+		}
+		transaction.AfterCommit(txCtx, func() {
+			errMeasurement = u.repoMeasurement.Add(ctx, []*ent.Measurement{newWeightMeasurement(username, weight)})
+		})
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
-	// посчитаем новое значение КК
-	newDayCalories := f.GetDayCalories(newCreateDataFromUser(uDB, weight))
-	return u.repoUser.UpdateWeight(ctx, weight, newDayCalories, username)
+	if errMeasurement != nil {
+		return nil, errMeasurement
+	}
+	return uNew, nil
 }
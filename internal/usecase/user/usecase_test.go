@@ -0,0 +1,132 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/coach"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/measurement"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/memory"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/transaction"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/repo/user"
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// errMeasurementStorage недоступность хранилища измерений.
+var errMeasurementStorage = errors.New("measurement storage is unavailable")
+
+// measurementSpy запоминает, вызывалось ли удаление измерений внутри транзакции. Первые failures удалений
+// завершаются ошибкой.
+type measurementSpy struct {
+	measurement.Repo
+	deletes  int
+	deleteTx bool
+	failures int
+}
+
+func (r *measurementSpy) DeleteByUsername(ctx context.Context, username string) error {
+	r.deletes++
+	r.deleteTx = r.deleteTx || transaction.InTx(ctx)
+	if r.deletes <= r.failures {
+		return errMeasurementStorage
+	}
+	return r.Repo.DeleteByUsername(ctx, username)
+}
+
+func newTestUsecase(t *testing.T) (*UsecaseLayer, *measurementSpy) {
+	t.Helper()
+	return newTestUsecaseWithLogger(t, zap.NewNop())
+}
+
+func newTestUsecaseWithLogger(t *testing.T, logger *zap.Logger) (*UsecaseLayer, *measurementSpy) {
+	t.Helper()
+	store := memory.NewStore()
+	repoUser := user.NewRepoMemory(store)
+	_, err := repoUser.Create(context.Background(), &ent.User{
+		Username: "ivan", FirstName: "Ivan", Weight: 80, Height: 180, Age: 30, Sex: "M",
+		PhysicalActivity: "MA", Password: "hash",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spy := &measurementSpy{Repo: measurement.NewRepoMemory(store)}
+	err = spy.Add(context.Background(), []*ent.Measurement{
+		{Username: "ivan", Kind: mc.MeasureSteps, Value: 5400, RecordedAt: time.Now().UTC()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc := NewUsecaseLayer(repoUser, coach.NewRepoMemory(store), spy, transaction.NewManagerMemory(store), logger)
+	return uc, spy
+}
+
+func TestDeleteRemovesMeasurementsAfterCommit(t *testing.T) {
+	uc, spy := newTestUsecase(t)
+	ctx := context.Background()
+
+	if err := uc.Delete(ctx, "ivan"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if spy.deletes != 1 || spy.deleteTx {
+		t.Fatalf("measurements deleted %d times, inside transaction: %v; want once after commit", spy.deletes, spy.deleteTx)
+	}
+	measurements, err := spy.List(ctx, &ent.MeasurementFilter{Username: "ivan", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(measurements) != 0 {
+		t.Fatalf("measurements after Delete() = %+v, want none", measurements)
+	}
+}
+
+func TestDeleteUnknownUserKeepsMeasurements(t *testing.T) {
+	uc, spy := newTestUsecase(t)
+
+	err := uc.Delete(context.Background(), "unknown")
+	if !errors.Is(err, me.ErrUserNotExist) {
+		t.Fatalf("Delete() error = %v, want %v", err, me.ErrUserNotExist)
+	}
+	if spy.deletes != 0 {
+		t.Fatalf("measurements deleted %d times after a rolled back transaction", spy.deletes)
+	}
+}
+
+// Пользователь удален, даже если измерения удалить не удалось: удаление повторяется, а ошибка пишется в лог.
+func TestDeleteRetriesMeasurementDeletion(t *testing.T) {
+	tests := []struct {
+		name        string
+		failures    int
+		wantDeletes int
+		wantLogged  bool
+	}{
+		{name: "second attempt succeeds", failures: 1, wantDeletes: 2},
+		{name: "all attempts fail", failures: 3, wantDeletes: 3, wantLogged: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.ErrorLevel)
+			uc, spy := newTestUsecaseWithLogger(t, zap.New(core))
+			spy.failures = tt.failures
+			ctx := context.Background()
+
+			if err := uc.Delete(ctx, "ivan"); err != nil {
+				t.Fatalf("Delete() error = %v, want nil", err)
+			}
+			if spy.deletes != tt.wantDeletes {
+				t.Errorf("measurements deleted %d times, want %d", spy.deletes, tt.wantDeletes)
+			}
+			if logged := logs.FilterField(zap.String("username", "ivan")).Len() > 0; logged != tt.wantLogged {
+				t.Errorf("failure logged = %v, want %v", logged, tt.wantLogged)
+			}
+			if _, err := uc.Read(ctx, "ivan"); !errors.Is(err, me.ErrUserNotExist) {
+				t.Errorf("Read() after Delete() error = %v, want %v", err, me.ErrUserNotExist)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package functions
+
+import (
+	"net/http"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+)
+
+// NewAuditEvent возвращает запись журнала о действии текущего пользователя с данными запроса:
+// IP-адресом, User-Agent и request_id.
+func NewAuditEvent(r *http.Request, action, subject string) *ent.AuditEvent {
+	requestID, err := GetCtxRequestID(r)
+	if err != nil {
+		requestID = ""
+	}
+	return &ent.AuditEvent{
+		Actor:     GetUsernameCtx(r),
+		Action:    action,
+		Subject:   subject,
+		IP:        GetClientIP(r),
+		UserAgent: r.UserAgent(),
+		RequestID: requestID,
+	}
+}
+
+// GetAuditEvents переводит записи журнала в формат ответа.
+func GetAuditEvents(events []*ent.AuditEvent) []dto.AuditEvent {
+	result := make([]dto.AuditEvent, 0, len(events))
+	for _, e := range events {
+		result = append(result, dto.AuditEvent{
+			ID:        e.ID,
+			Actor:     e.Actor,
+			Action:    e.Action,
+			Subject:   e.Subject,
+			IP:        e.IP,
+			UserAgent: e.UserAgent,
+			RequestID: e.RequestID,
+			Details:   e.Details,
+			CreatedAt: e.CreatedAt,
+		})
+	}
+	return result
+}
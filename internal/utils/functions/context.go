@@ -5,6 +5,8 @@ import (
 
 	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
 	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 )
 
 func GetCtxRequestID(r *http.Request) (string, error) {
@@ -16,3 +18,18 @@ func GetCtxRequestID(r *http.Request) (string, error) {
 	}
 	return requestID, nil
 }
+
+// LogFields поля, которые пишутся в каждую строку журнала о запросе: request_id, а также trace_id и span_id,
+// по которым строку можно найти в трассировке. Вне трассировки trace_id и span_id пустые.
+func LogFields(r *http.Request) []zap.Field {
+	requestID, _ := GetCtxRequestID(r)
+	var traceID, spanID string
+	if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+		traceID, spanID = sc.TraceID().String(), sc.SpanID().String()
+	}
+	return []zap.Field{
+		zap.String(mc.RequestID, requestID),
+		zap.String(mc.TraceID, traceID),
+		zap.String(mc.SpanID, spanID),
+	}
+}
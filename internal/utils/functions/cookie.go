@@ -3,35 +3,177 @@ package functions
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
 	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/spf13/viper"
 )
 
-// SetCookieAndHeaders Sets up cookie header and csrf header.
-func SetCookieAndHeaders(w http.ResponseWriter, username string) (http.ResponseWriter, error) {
-	dateExp := time.Now().Add(14 * 24 * time.Hour)
-	jwt, _ := NewJwtToken(NewJwtTokenProps{
-		Username: username,
-	}, dateExp)
-	cookie := http.Cookie{
+// SetCookieAndHeaders Sets up access and refresh token cookies. Вместе с ними выдается CSRF-токен: он
+// приходит в cookie, доступной js, и в заголовке ответа, а клиент повторяет его в заголовке X-CSRF-Token.
+func SetCookieAndHeaders(w http.ResponseWriter, tokens *ent.TokenPair) (http.ResponseWriter, error) {
+	csrfToken, err := NewCsrfToken()
+	if err != nil {
+		return w, err
+	}
+	accessCookie := http.Cookie{
 		Name:     mc.JwtToken,
-		Value:    jwt,
-		Expires:  dateExp,
+		Value:    tokens.AccessToken,
+		Expires:  tokens.AccessExpiresAt,
+		HttpOnly: viper.GetBool("cookie.http_only"),
+		Secure:   viper.GetBool("cookie.secure"),
+		SameSite: cookieSameSite(),
+		Path:     "/",
+	}
+	http.SetCookie(w, &accessCookie)
+	// refresh-токен нужен только ручкам сессии, поэтому скрываем его от js и остальных путей
+	refreshCookie := http.Cookie{
+		Name:     mc.RefreshToken,
+		Value:    tokens.RefreshToken,
+		Expires:  tokens.RefreshExpiresAt,
+		HttpOnly: true,
+		Secure:   viper.GetBool("cookie.secure"),
+		SameSite: cookieSameSite(),
+		Path:     mc.RefreshTokenPath,
+	}
+	http.SetCookie(w, &refreshCookie)
+	// CSRF-токен живет столько же, сколько сессия, и обновляется вместе с токенами
+	csrfCookie := http.Cookie{
+		Name:     mc.CsrfToken,
+		Value:    csrfToken,
+		Expires:  tokens.RefreshExpiresAt,
 		HttpOnly: false,
+		Secure:   viper.GetBool("cookie.secure"),
+		SameSite: cookieSameSite(),
 		Path:     "/",
 	}
-	http.SetCookie(w, &cookie)
+	http.SetCookie(w, &csrfCookie)
+	w.Header().Set(mc.XCsrfToken, csrfToken)
 	return w, nil
 }
 
+// GetTokenPair возвращает пару токенов для тела ответа. Так токены получают клиенты, которые не используют
+// cookie (мобильное приложение, скрипты).
+func GetTokenPair(tokens *ent.TokenPair) *dto.TokenPair {
+	return &dto.TokenPair{
+		TokenType:        mc.BearerScheme,
+		AccessToken:      tokens.AccessToken,
+		AccessExpiresAt:  tokens.AccessExpiresAt,
+		RefreshToken:     tokens.RefreshToken,
+		RefreshExpiresAt: tokens.RefreshExpiresAt,
+	}
+}
+
 func FlashCookie(w http.ResponseWriter, r *http.Request) {
 	sessionCookie := &http.Cookie{
 		Name:     mc.JwtToken,
 		Value:    "",
 		MaxAge:   -1,
-		HttpOnly: false,
+		HttpOnly: viper.GetBool("cookie.http_only"),
+		Secure:   viper.GetBool("cookie.secure"),
+		SameSite: cookieSameSite(),
 		Path:     "/",
 	}
 	http.SetCookie(w, sessionCookie)
+	refreshCookie := &http.Cookie{
+		Name:     mc.RefreshToken,
+		Value:    "",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   viper.GetBool("cookie.secure"),
+		SameSite: cookieSameSite(),
+		Path:     mc.RefreshTokenPath,
+	}
+	http.SetCookie(w, refreshCookie)
+	csrfCookie := &http.Cookie{
+		Name:     mc.CsrfToken,
+		Value:    "",
+		MaxAge:   -1,
+		Secure:   viper.GetBool("cookie.secure"),
+		SameSite: cookieSameSite(),
+		Path:     "/",
+	}
+	http.SetCookie(w, csrfCookie)
+}
+
+// HasSessionCookie сообщает, пришли ли с запросом cookie сессии. Такие запросы браузер может отправить
+// и со стороннего сайта, поэтому они требуют CSRF-токен.
+func HasSessionCookie(r *http.Request) bool {
+	for _, name := range []string{mc.JwtToken, mc.RefreshToken} {
+		if _, err := r.Cookie(name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCsrfCookie HTTP Headers "Cookie"
+func GetCsrfCookie(r *http.Request) (string, error) {
+	csrfCookie, err := r.Cookie(mc.CsrfToken)
+	if err != nil {
+		return "", err
+	}
+	return csrfCookie.Value, nil
+}
+
+// cookieSameSite возвращает режим SameSite из конфигурации (cookie.same_site). Неизвестное значение
+// считается strict.
+func cookieSameSite() http.SameSite {
+	switch strings.ToLower(viper.GetString("cookie.same_site")) {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteStrictMode
+	}
+}
+
+// GetRefreshToken HTTP Headers "Cookie"
+func GetRefreshToken(r *http.Request) (string, error) {
+	refreshCookie, err := r.Cookie(mc.RefreshToken)
+	if err != nil {
+		return "", err
+	}
+	return refreshCookie.Value, nil
+}
+
+// SetOIDCFlowCookie сохраняет зашифрованное состояние входа через внешнего провайдера.
+func SetOIDCFlowCookie(w http.ResponseWriter, value string, dateExp time.Time) {
+	cookie := http.Cookie{
+		Name:     mc.OIDCFlow,
+		Value:    value,
+		Expires:  dateExp,
+		HttpOnly: true,
+		Secure:   viper.GetBool("cookie.secure"),
+		// cookie должна приходить при возврате от провайдера, т.е. при переходе с другого сайта
+		SameSite: http.SameSiteLaxMode,
+		Path:     mc.OIDCFlowPath,
+	}
+	http.SetCookie(w, &cookie)
+}
+
+// GetOIDCFlowCookie HTTP Headers "Cookie"
+func GetOIDCFlowCookie(r *http.Request) (string, error) {
+	cookie, err := r.Cookie(mc.OIDCFlow)
+	if err != nil {
+		return "", err
+	}
+	return cookie.Value, nil
+}
+
+func FlashOIDCFlowCookie(w http.ResponseWriter) {
+	cookie := &http.Cookie{
+		Name:     mc.OIDCFlow,
+		Value:    "",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   viper.GetBool("cookie.secure"),
+		SameSite: http.SameSiteLaxMode,
+		Path:     mc.OIDCFlowPath,
+	}
+	http.SetCookie(w, cookie)
 }
@@ -0,0 +1,65 @@
+package functions
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+
+	"github.com/spf13/viper"
+)
+
+var errCiphertextTooShort = errors.New("ciphertext is too short")
+
+// Encrypt шифрует данные с помощью AES-256-GCM. Используется для секретов, которые нужно хранить
+// в базе в восстановимом виде (например, секрет TOTP). Результат: base64(nonce + ciphertext).
+func Encrypt(plaintext string) (string, error) {
+	gcm, err := newGCM()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt расшифровывает данные, зашифрованные функцией Encrypt.
+func Decrypt(ciphertext string) (string, error) {
+	gcm, err := newGCM()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errCiphertextTooShort
+	}
+	nonce, data := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, data, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// newGCM создает шифр из ключа auth.encryption_key. Если ключ не задан, используется secret_key.
+func newGCM() (cipher.AEAD, error) {
+	key := viper.GetString("auth.encryption_key")
+	if key == "" {
+		key = viper.GetString("secret_key")
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
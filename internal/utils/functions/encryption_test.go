@@ -0,0 +1,85 @@
+package functions
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func setEncryptionKey(t *testing.T, key string) {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("auth.encryption_key", key)
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	setEncryptionKey(t, "encryption-key")
+
+	first, err := Encrypt("JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	second, err := Encrypt("JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if first == second {
+		t.Error("Encrypt() returns the same ciphertext twice, nonce is not random")
+	}
+	got, err := Decrypt(first)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if got != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("Decrypt() = %q, want the original secret", got)
+	}
+}
+
+func TestEncryptFallsBackToSecretKey(t *testing.T) {
+	setEncryptionKey(t, "")
+	viper.Set("secret_key", "secret")
+	ciphertext, err := Encrypt("secret data")
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	viper.Set("auth.encryption_key", "secret")
+	if got, err := Decrypt(ciphertext); err != nil || got != "secret data" {
+		t.Errorf("Decrypt() = %q, %v, want data encrypted with secret_key", got, err)
+	}
+}
+
+func TestDecryptRejects(t *testing.T) {
+	setEncryptionKey(t, "encryption-key")
+	ciphertext, err := Encrypt("secret data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	tampered := base64.StdEncoding.EncodeToString(sealed)
+
+	tests := []struct {
+		name       string
+		key        string
+		ciphertext string
+	}{
+		{name: "wrong key", key: "another-key", ciphertext: ciphertext},
+		{name: "tampered ciphertext", key: "encryption-key", ciphertext: tampered},
+		{name: "too short", key: "encryption-key", ciphertext: base64.StdEncoding.EncodeToString([]byte("short"))},
+		{name: "not base64", key: "encryption-key", ciphertext: "%%%"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("auth.encryption_key", tt.key)
+			if got, err := Decrypt(tt.ciphertext); err == nil {
+				t.Errorf("Decrypt() = %q, want error", got)
+			}
+		})
+	}
+}
@@ -2,44 +2,130 @@ package functions
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"strings"
 
 	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/spf13/viper"
 	"golang.org/x/crypto/argon2"
 )
 
-// HashData хэширует данные используя алгоритм Argon2. Принимает на вход данные для хэширования, соль
-// и возвращает хэш в виде строки шестнадцетеричных цифр.
+// Пароли хранятся в формате PHC: $argon2id$v=19$m=65536,t=3,p=2$<соль>$<хэш>, соль и хэш в base64 без
+// выравнивания. Параметры стоимости хранятся вместе с хэшем, поэтому их можно повышать в конфигурации:
+// старые хэши продолжают проверяться, а при следующем входе пароль перехэшируется с новыми параметрами.
+
+// Argon2Params параметры стоимости Argon2id.
+type Argon2Params struct {
+	Memory  uint32 // в KiB
+	Time    uint32
+	Threads uint8
+	KeyLen  uint32
+	SaltLen uint32
+}
+
+// GetArgon2Params возвращает текущие параметры хэширования из конфигурации.
+func GetArgon2Params() Argon2Params {
+	return Argon2Params{
+		Memory:  viper.GetUint32("auth.argon2.memory"),
+		Time:    viper.GetUint32("auth.argon2.time"),
+		Threads: uint8(viper.GetUint("auth.argon2.threads")),
+		KeyLen:  viper.GetUint32("auth.argon2.key_length"),
+		SaltLen: viper.GetUint32("auth.argon2.salt_length"),
+	}
+}
+
+// HashData хэширует данные используя алгоритм Argon2 с устаревшими параметрами. Принимает на вход данные
+// для хэширования, соль и возвращает хэш в виде строки шестнадцетеричных цифр. Нужна только для проверки
+// паролей, сохраненных в старом формате `hash.salt`.
 func HashData(payload, salt []byte) string {
-	saltCopy := make([]byte, len(salt))
-	copy(saltCopy, salt)
 	hashedPassword := argon2.IDKey(payload, salt, mc.HashTime, mc.HashMemory, mc.HashThreads, mc.HashKeylen)
 	return hex.EncodeToString(hashedPassword)
 }
 
+// GetHashedPassword хэширует пароль с текущими параметрами и возвращает строку в формате PHC.
 func GetHashedPassword(pwdPass string) (string, error) {
-	salt, err := generateNewSalt()
+	p := GetArgon2Params()
+	salt := make([]byte, p.SaltLen)
+	_, err := rand.Read(salt)
 	if err != nil {
 		return "", err
 	}
-	hashedPwd := HashData([]byte(pwdPass), []byte(salt))
-	return fmt.Sprintf("%s.%s", hashedPwd, salt), nil
+	hash := argon2.IDKey([]byte(pwdPass), salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash),
+	), nil
 }
 
-func generateNewSalt() (string, error) {
-	salt := make([]byte, 16)
-	_, err := rand.Read(salt)
+// VerifyPassword сравнивает пароль с сохраненным значением за постоянное время. needsRehash равен true,
+// если пароль верный, но сохранен в старом формате или с параметрами, отличными от текущих.
+func VerifyPassword(pwdPass, pwdDB string) (ok bool, needsRehash bool, err error) {
+	if !strings.HasPrefix(pwdDB, "$") {
+		ok, err = verifyLegacyPassword(pwdPass, pwdDB)
+		return ok, ok, err
+	}
+	p, salt, hash, err := decodePHC(pwdDB)
 	if err != nil {
-		return "", err
+		return false, false, err
+	}
+	computed := argon2.IDKey([]byte(pwdPass), salt, p.Time, p.Memory, p.Threads, uint32(len(hash)))
+	if subtle.ConstantTimeCompare(computed, hash) != 1 {
+		return false, false, nil
 	}
-	return hex.EncodeToString(salt), nil
+	current := GetArgon2Params()
+	needsRehash = p.Memory != current.Memory || p.Time != current.Time || p.Threads != current.Threads ||
+		uint32(len(hash)) != current.KeyLen || uint32(len(salt)) != current.SaltLen
+	return true, needsRehash, nil
 }
 
+// IsPasswordsEqual сообщает, совпадает ли пароль с сохраненным значением. Поврежденное значение
+// считается несовпадением.
 func IsPasswordsEqual(pwdPass, pwdDB string) bool {
-	saltDB := strings.Split(pwdDB, ".")[1]
+	ok, _, err := VerifyPassword(pwdPass, pwdDB)
+	return err == nil && ok
+}
+
+// verifyLegacyPassword проверяет пароль в старом формате `hex(hash).hex(salt)`. Исторически солью
+// служили байты hex-строки, а не декодированные байты, это сохраняется для совместимости.
+func verifyLegacyPassword(pwdPass, pwdDB string) (bool, error) {
+	hashDB, saltDB, found := strings.Cut(pwdDB, ".")
+	if !found || hashDB == "" || saltDB == "" {
+		return false, me.ErrInvalidPasswordHash
+	}
 	hash := HashData([]byte(pwdPass), []byte(saltDB))
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(hashDB)) == 1, nil
+}
 
-	return fmt.Sprintf("%s.%s", hash, saltDB) == pwdDB
+// decodePHC разбирает строку вида $argon2id$v=19$m=...,t=...,p=...$salt$hash.
+func decodePHC(encoded string) (Argon2Params, []byte, []byte, error) {
+	var p Argon2Params
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, me.ErrInvalidPasswordHash
+	}
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return p, nil, nil, me.ErrInvalidPasswordHash
+	}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads)
+	if err != nil || p.Memory == 0 || p.Time == 0 || p.Threads == 0 {
+		return p, nil, nil, me.ErrInvalidPasswordHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, me.ErrInvalidPasswordHash
+	}
+	hash, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(hash) == 0 {
+		return p, nil, nil, me.ErrInvalidPasswordHash
+	}
+	p.SaltLen = uint32(len(salt))
+	p.KeyLen = uint32(len(hash))
+	return p, salt, hash, nil
 }
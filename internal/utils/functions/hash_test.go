@@ -0,0 +1,138 @@
+package functions
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+	"github.com/spf13/viper"
+)
+
+func setArgon2Params(t *testing.T, memory, time uint32) {
+	t.Helper()
+	viper.Set("auth.argon2.memory", memory)
+	viper.Set("auth.argon2.time", time)
+	viper.Set("auth.argon2.threads", 1)
+	viper.Set("auth.argon2.key_length", 32)
+	viper.Set("auth.argon2.salt_length", 16)
+}
+
+func TestVerifyPassword(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	setArgon2Params(t, 1024, 1)
+	phc, err := GetHashedPassword("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(phc, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("GetHashedPassword() = %q, want PHC with current parameters", phc)
+	}
+	// старый формат: hex(hash).hex(salt), солью служат байты hex-строки
+	legacySalt := "0123456789abcdef"
+	legacy := HashData([]byte("password"), []byte(legacySalt)) + "." + legacySalt
+
+	tests := []struct {
+		name            string
+		password        string
+		stored          string
+		wantOk          bool
+		wantNeedsRehash bool
+	}{
+		{name: "phc round trip", password: "password", stored: phc, wantOk: true},
+		{name: "phc wrong password", password: "wrong", stored: phc},
+		{name: "legacy format", password: "password", stored: legacy, wantOk: true, wantNeedsRehash: true},
+		{name: "legacy wrong password", password: "wrong", stored: legacy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash, err := VerifyPassword(tt.password, tt.stored)
+			if err != nil {
+				t.Fatalf("VerifyPassword() error = %v", err)
+			}
+			if ok != tt.wantOk || needsRehash != tt.wantNeedsRehash {
+				t.Errorf("VerifyPassword() = %v, %v, want %v, %v", ok, needsRehash, tt.wantOk, tt.wantNeedsRehash)
+			}
+		})
+	}
+}
+
+// Хэш со старыми параметрами стоимости проверяется, но требует перехэширования.
+func TestVerifyPasswordNeedsRehash(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	setArgon2Params(t, 1024, 1)
+	phc, err := GetHashedPassword("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		set  func()
+	}{
+		{name: "memory", set: func() { viper.Set("auth.argon2.memory", 2048) }},
+		{name: "time", set: func() { viper.Set("auth.argon2.time", 2) }},
+		{name: "threads", set: func() { viper.Set("auth.argon2.threads", 2) }},
+		{name: "key length", set: func() { viper.Set("auth.argon2.key_length", 64) }},
+		{name: "salt length", set: func() { viper.Set("auth.argon2.salt_length", 32) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setArgon2Params(t, 1024, 1)
+			tt.set()
+			ok, needsRehash, err := VerifyPassword("password", phc)
+			if err != nil || !ok || !needsRehash {
+				t.Errorf("VerifyPassword() = %v, %v, %v, want valid password that needs rehash", ok, needsRehash, err)
+			}
+		})
+	}
+}
+
+// Поврежденное сохраненное значение — ошибка, а не паника.
+func TestVerifyPasswordMalformed(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	setArgon2Params(t, 1024, 1)
+	phc, err := GetHashedPassword("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(phc, "$")
+
+	tests := map[string]string{
+		"empty":                  "",
+		"only dollar":            "$",
+		"truncated":              phc[:len(phc)/2],
+		"missing hash":           strings.Join(parts[:5], "$"),
+		"empty hash":             strings.Join(parts[:5], "$") + "$",
+		"extra part":             phc + "$extra",
+		"other algorithm":        strings.Replace(phc, "argon2id", "argon2i", 1),
+		"other version":          strings.Replace(phc, "v=19", "v=16", 1),
+		"garbage version":        strings.Replace(phc, "v=19", "v=x", 1),
+		"garbage parameters":     strings.Replace(phc, "m=1024,t=1,p=1", "m=,t=,p=", 1),
+		"zero memory":            strings.Replace(phc, "m=1024", "m=0", 1),
+		"zero time":              strings.Replace(phc, "t=1", "t=0", 1),
+		"zero threads":           strings.Replace(phc, "p=1", "p=0", 1),
+		"salt is not base64":     strings.Join([]string{"", parts[1], parts[2], parts[3], "!!!", parts[5]}, "$"),
+		"hash is not base64":     strings.Join([]string{"", parts[1], parts[2], parts[3], parts[4], "!!!"}, "$"),
+		"legacy without salt":    "abcdef",
+		"legacy with empty salt": "abcdef.",
+		"legacy with empty hash": ".abcdef",
+	}
+	for name, stored := range tests {
+		t.Run(name, func(t *testing.T) {
+			ok, needsRehash, err := VerifyPassword("password", stored)
+			if !errors.Is(err, me.ErrInvalidPasswordHash) {
+				t.Errorf("VerifyPassword() error = %v, want %v", err, me.ErrInvalidPasswordHash)
+			}
+			if ok || needsRehash {
+				t.Errorf("VerifyPassword() = %v, %v for a malformed hash", ok, needsRehash)
+			}
+			if IsPasswordsEqual("password", stored) {
+				t.Error("IsPasswordsEqual() = true for a malformed hash")
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package functions
+
+import (
+	"net"
+	"net/http"
+
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	"github.com/spf13/viper"
+)
+
+// GetClientIP возвращает IP-адрес клиента. Заголовок X-Real-IP учитывается, только если сервис стоит
+// за доверенным прокси (server.trust_x_real_ip).
+func GetClientIP(r *http.Request) string {
+	if viper.GetBool("server.trust_x_real_ip") {
+		if ip := r.Header.Get(mc.XRealIP); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
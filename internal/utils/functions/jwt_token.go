@@ -6,19 +6,34 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
 	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
 	"github.com/spf13/viper"
 )
 
 type NewJwtTokenProps struct {
-	Username string
+	Username      string
+	Role          string
+	Typ           string
+	PasswordReset string
 }
 
-// HTTP Headers "Cookie"
+// GetJWtToken HTTP Headers "Authorization" and "Cookie".
+// Если в запросе есть и заголовок Authorization, и cookie, то используется заголовок: клиент, который явно
+// передает токен, важнее cookie, оставшейся в браузере от прошлой сессии. Если нет ни того, ни другого,
+// возвращается http.ErrNoCookie.
 func GetJWtToken(r *http.Request) (string, error) {
+	if authHeader := r.Header.Get(mc.Authorization); authHeader != "" {
+		scheme, token, found := strings.Cut(authHeader, " ")
+		if !found || !strings.EqualFold(scheme, mc.BearerScheme) || strings.TrimSpace(token) == "" {
+			return "", me.ErrInvalidAuthHeader
+		}
+		return strings.TrimSpace(token), nil
+	}
 	jwtCookie, err := r.Cookie(mc.JwtToken)
 	if err != nil {
 		return "", err
@@ -34,12 +49,37 @@ func GetUsernameCtx(r *http.Request) string {
 	return ""
 }
 
+// GetSessionUsernameCtx возвращает имя пользователя, только если запрос авторизован access-токеном сессии.
+// Для запросов по API-ключу возвращает пустую строку: ключ не дает права менять способы входа владельца.
+func GetSessionUsernameCtx(r *http.Request) string {
+	if _, apiKey := r.Context().Value("api_key_scopes").([]string); apiKey {
+		return ""
+	}
+	return GetUsernameCtx(r)
+}
+
+// GetRoleCtx возвращает роль авторизованного пользователя.
+func GetRoleCtx(r *http.Request) string {
+	role, ok := r.Context().Value("role").(string)
+	if ok && role != "" {
+		return role
+	}
+	return mc.RoleUser
+}
+
+// GetApiKeyScopesCtx возвращает права API-ключа, если запрос выполнен по API-ключу, иначе nil.
+func GetApiKeyScopesCtx(r *http.Request) []string {
+	scopes, _ := r.Context().Value("api_key_scopes").([]string)
+	return scopes
+}
+
 // NewCsrfToken
 // Generates jwt-token.
 func NewJwtToken(props NewJwtTokenProps, dateExp time.Time) (string, error) {
 	// Encode header.
 	h := dto.JwtTokenHeader{
 		Exp: dateExp.Format("02.01.2006 15:04:05 UTC-07"),
+		Typ: props.Typ,
 	}
 	rawDataHeader, err := json.Marshal(h)
 	if err != nil {
@@ -48,7 +88,9 @@ func NewJwtToken(props NewJwtTokenProps, dateExp time.Time) (string, error) {
 	hEncoded := hex.EncodeToString(rawDataHeader)
 	// Encode payload.
 	p := dto.JwtTokenPayload{
-		Username: props.Username,
+		Username:      props.Username,
+		Role:          props.Role,
+		PasswordReset: props.PasswordReset,
 	}
 	rawDataPayload, err := json.Marshal(p)
 	if err != nil {
@@ -65,6 +107,54 @@ func NewJwtToken(props NewJwtTokenProps, dateExp time.Time) (string, error) {
 	return hpEncoded + "." + signature, nil
 }
 
+// ParseJwtToken
+// Checks signature and expiration time of jwt-token, returns decoded header and payload.
+func ParseJwtToken(token string) (*dto.JwtTokenHeader, *dto.JwtTokenPayload, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, nil, me.ErrInvalidJwt
+	}
+	signatureHash, err := hashWithStatement(parts[0] + "." + parts[1]) // header + "." + payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	signature := hex.EncodeToString([]byte(signatureHash))
+	if !hmac.Equal([]byte(signature), []byte(parts[2])) {
+		return nil, nil, me.ErrInvalidJwt
+	}
+
+	dataHeader, err := hex.DecodeString(parts[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	var h dto.JwtTokenHeader
+	err = json.Unmarshal(dataHeader, &h)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dataPayload, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, err
+	}
+	var p dto.JwtTokenPayload
+	err = json.Unmarshal(dataPayload, &p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// "02.01.2006 15:04:05 UTC-07" template
+	jwtDate, err := time.Parse("02.01.2006 15:04:05 UTC-07", h.Exp)
+	if err != nil {
+		return nil, nil, err
+	}
+	dateNow := time.Now()
+	if jwtDate.Equal(dateNow) || dateNow.After(jwtDate) {
+		return nil, nil, me.ErrAccessTokenExpired
+	}
+	return &h, &p, nil
+}
+
 // HashWithStatement
 // Returns hash that is transmitted in the client-server model by custom header.
 func hashWithStatement(statement string) (string, error) {
@@ -0,0 +1,48 @@
+package functions
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+	me "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myerrors"
+)
+
+func TestGetJWtToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		cookie  string
+		want    string
+		wantErr error
+	}{
+		{name: "header only", header: "Bearer from-header", want: "from-header"},
+		{name: "cookie only", cookie: "from-cookie", want: "from-cookie"},
+		{name: "header wins over cookie", header: "Bearer from-header", cookie: "from-cookie", want: "from-header"},
+		{name: "scheme is case insensitive", header: "bearer from-header", want: "from-header"},
+		{name: "invalid header is not replaced by cookie", header: "Basic dXNlcjpwYXNz", cookie: "from-cookie", wantErr: me.ErrInvalidAuthHeader},
+		{name: "empty bearer token", header: "Bearer  ", wantErr: me.ErrInvalidAuthHeader},
+		{name: "no credentials", wantErr: http.ErrNoCookie},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+			if tt.header != "" {
+				r.Header.Set(mc.Authorization, tt.header)
+			}
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: mc.JwtToken, Value: tt.cookie})
+			}
+
+			got, err := GetJWtToken(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("token = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
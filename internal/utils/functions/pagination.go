@@ -0,0 +1,34 @@
+package functions
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity/dto"
+	"github.com/spf13/viper"
+)
+
+// GetPagination возвращает номер и размер страницы из параметров page и per_page. Размер страницы по
+// умолчанию и максимальный берутся из конфигурации admin.page_size и admin.max_page_size.
+func GetPagination(query url.Values) (int, int, error) {
+	page, err := parsePositive(query.Get("page"), 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	perPage, err := parsePositive(query.Get("per_page"), viper.GetInt("admin.page_size"))
+	if err != nil {
+		return 0, 0, err
+	}
+	return page, min(perPage, viper.GetInt("admin.max_page_size")), nil
+}
+
+func parsePositive(value string, defaultValue int) (int, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, dto.ErrInvalidPage
+	}
+	return n, nil
+}
@@ -0,0 +1,36 @@
+package functions
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+)
+
+// RoleHasPermission проверяет, что у роли есть право. У неизвестной роли прав нет.
+func RoleHasPermission(role, permission string) bool {
+	return slices.Contains(mc.RolePermissions[role], permission)
+}
+
+// ScopesHavePermission проверяет, что права API-ключа покрывают право. read:all заменяет любое право на чтение.
+func ScopesHavePermission(scopes []string, permission string) bool {
+	for _, scope := range scopes {
+		if scope == permission || (scope == mc.ScopeReadAll && strings.HasSuffix(permission, ":read")) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission проверяет право авторизованного пользователя на запрос: право должно быть у его роли, а если
+// запрос выполнен по API-ключу, то и у ключа.
+func HasPermission(r *http.Request, permission string) bool {
+	if !RoleHasPermission(GetRoleCtx(r), permission) {
+		return false
+	}
+	if scopes := GetApiKeyScopesCtx(r); scopes != nil {
+		return ScopesHavePermission(scopes, permission)
+	}
+	return true
+}
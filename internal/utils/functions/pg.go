@@ -0,0 +1,13 @@
+package functions
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// IsPgError проверяет, что err (или обернутая в нее ошибка) является ошибкой PostgreSQL с кодом code.
+func IsPgError(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
@@ -0,0 +1,27 @@
+package functions
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// NewRandomString возвращает случайную строку в base64url из n случайных байт (state, nonce и т.п.).
+func NewRandomString(n int) (string, error) {
+	raw := make([]byte, n)
+	_, err := rand.Read(raw)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// NewPKCE возвращает пару code_verifier и code_challenge (метод S256, RFC 7636).
+func NewPKCE() (string, string, error) {
+	verifier, err := NewRandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,30 @@
+package functions
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// NewRefreshToken генерирует непрозрачный refresh-токен из 32 случайных байт.
+func NewRefreshToken() (string, error) {
+	raw := make([]byte, 32)
+	_, err := rand.Read(raw)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// NewCsrfToken генерирует CSRF-токен из 32 случайных байт.
+func NewCsrfToken() (string, error) {
+	return NewRefreshToken()
+}
+
+// HashToken возвращает sha256-хэш токена в виде строки шестнадцатеричных цифр. Токены содержат достаточно
+// энтропии, поэтому медленный хэш (как для паролей) здесь не нужен.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
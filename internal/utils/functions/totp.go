@@ -0,0 +1,121 @@
+package functions
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+)
+
+// RFC 6238 (TOTP) поверх RFC 4226 (HOTP): HMAC-SHA1, 6 цифр, шаг 30 секунд. Эти параметры понимают все
+// популярные приложения-аутентификаторы.
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret возвращает новый секрет в кодировке base32 без выравнивания.
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, mc.TOTPSecretSize)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// GetTOTPStep возвращает номер временного шага для момента t.
+func GetTOTPStep(t time.Time) int64 {
+	return t.Unix() / mc.TOTPPeriod
+}
+
+// GetTOTPCode вычисляет одноразовый код для заданного шага.
+func GetTOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	// dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < mc.TOTPDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", mc.TOTPDigits, value%mod), nil
+}
+
+// ValidateTOTPCode проверяет код с допуском в один шаг в обе стороны (расхождение часов клиента и сервера).
+// Возвращает шаг, которому соответствует код, чтобы вызывающая сторона могла запретить его повторное использование.
+func ValidateTOTPCode(secret, code string, t time.Time) (int64, bool) {
+	if len(code) != mc.TOTPDigits {
+		return 0, false
+	}
+	current := GetTOTPStep(t)
+	for step := current - mc.TOTPSkew; step <= current+mc.TOTPSkew; step++ {
+		expected, err := GetTOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// GetOtpauthURI формирует ссылку otpauth://, из которой приложение-аутентификатор создает учетную запись
+// (обычно ее показывают пользователю в виде QR-кода).
+func GetOtpauthURI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(mc.TOTPDigits))
+	params.Set("period", fmt.Sprint(mc.TOTPPeriod))
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, params.Encode())
+}
+
+// GenerateRecoveryCodes возвращает набор одноразовых кодов восстановления вида XXXXX-XXXXX.
+func GenerateRecoveryCodes() ([]string, error) {
+	const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	codes := make([]string, 0, mc.RecoveryCodesCount)
+	raw := make([]byte, 10)
+	for i := 0; i < mc.RecoveryCodesCount; i++ {
+		_, err := rand.Read(raw)
+		if err != nil {
+			return nil, err
+		}
+		var b strings.Builder
+		for j, c := range raw {
+			if j == 5 {
+				b.WriteByte('-')
+			}
+			b.WriteByte(alphabet[int(c)%len(alphabet)])
+		}
+		codes = append(codes, b.String())
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode приводит введенный пользователем код восстановления к виду, в котором он хэшировался.
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) == 10 && !strings.Contains(code, "-") {
+		code = code[:5] + "-" + code[5:]
+	}
+	return code
+}
@@ -0,0 +1,93 @@
+package functions
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	mc "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/utils/myconstants"
+)
+
+// rfc6238Secret ключ тестовых векторов RFC 6238 для HMAC-SHA1.
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestGetTOTPCodeRFC6238(t *testing.T) {
+	// в RFC коды из 8 цифр, сервис использует 6 последних
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+	for _, tt := range tests {
+		got, err := GetTOTPCode(rfc6238Secret, GetTOTPStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("GetTOTPCode(%d) error = %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("GetTOTPCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTPCodeWindow(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := GetTOTPStep(now)
+	tests := []struct {
+		name   string
+		offset int64
+		wantOk bool
+	}{
+		{name: "current step", offset: 0, wantOk: true},
+		{name: "previous step", offset: -1, wantOk: true},
+		{name: "next step", offset: 1, wantOk: true},
+		{name: "two steps ago", offset: -2},
+		{name: "two steps ahead", offset: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := GetTOTPCode(rfc6238Secret, current+tt.offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			step, ok := ValidateTOTPCode(rfc6238Secret, code, now)
+			if ok != tt.wantOk {
+				t.Fatalf("ValidateTOTPCode() ok = %v, want %v", ok, tt.wantOk)
+			}
+			// шаг нужен вызывающей стороне, чтобы запретить повторное использование кода
+			if ok && step != current+tt.offset {
+				t.Errorf("ValidateTOTPCode() step = %d, want %d", step, current+tt.offset)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPCodeMalformed(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, err := GetTOTPCode(rfc6238Secret, GetTOTPStep(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, tt := range map[string]struct{ secret, code string }{
+		"short code":     {secret: rfc6238Secret, code: code[:mc.TOTPDigits-1]},
+		"long code":      {secret: rfc6238Secret, code: code + "0"},
+		"invalid secret": {secret: "not base32!", code: code},
+	} {
+		if _, ok := ValidateTOTPCode(tt.secret, tt.code, now); ok {
+			t.Errorf("%s: ValidateTOTPCode() ok = true", name)
+		}
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	for _, input := range []string{"ABCDE-FGHJK", "abcde-fghjk", " abcdefghjk ", "ABCDE FGHJK"} {
+		if got := NormalizeRecoveryCode(input); got != "ABCDE-FGHJK" {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, want ABCDE-FGHJK", input, got)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+// Package metrics метрики сервиса в формате Prometheus.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Default набор метрик сервиса: метрики из service.go, среды выполнения Go и процесса.
+var Default = prometheus.NewRegistry()
+
+func init() {
+	Default.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler отдает метрики набора Default. Ошибка сбора одной метрики не мешает отдать остальные.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Default, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRegisterCacheStats(t *testing.T) {
+	r := prometheus.NewRegistry()
+	RegisterCacheStats(r, func() ent.CacheStats { return ent.CacheStats{Hits: 3, Misses: 1, Errors: 2} })
+
+	expected := `
+# HELP healthcheck_cache_errors_total Number of failed user profile cache requests.
+# TYPE healthcheck_cache_errors_total counter
+healthcheck_cache_errors_total 2
+# HELP healthcheck_cache_hit_ratio Share of user profile cache hits among hits and misses since start.
+# TYPE healthcheck_cache_hit_ratio gauge
+healthcheck_cache_hit_ratio 0.75
+# HELP healthcheck_cache_hits_total Number of user profile cache hits.
+# TYPE healthcheck_cache_hits_total counter
+healthcheck_cache_hits_total 3
+# HELP healthcheck_cache_misses_total Number of user profile cache misses.
+# TYPE healthcheck_cache_misses_total counter
+healthcheck_cache_misses_total 1
+`
+	if err := testutil.GatherAndCompare(r, strings.NewReader(expected)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegisterDBStats(t *testing.T) {
+	// пул не подключается к базе, пока соединение не понадобится
+	config, err := pgxpool.ParseConfig("postgres://user@127.0.0.1:1/db?pool_max_conns=7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	r := prometheus.NewRegistry()
+	RegisterDBStats(r, pool.Stat)
+
+	if err = testutil.GatherAndCompare(r, strings.NewReader(`
+# HELP healthcheck_db_pool_max_conns Maximum size of the connection pool.
+# TYPE healthcheck_db_pool_max_conns gauge
+healthcheck_db_pool_max_conns 7
+`), "healthcheck_db_pool_max_conns"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := testutil.GatherAndCount(r); err != nil || n != 8 {
+		t.Fatalf("GatherAndCount() = %d, %v, want 8 pool metrics", n, err)
+	}
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolMetric метрика пула соединений, значение которой берется из pgxpool.Stat.
+type poolMetric struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	value     func(s *pgxpool.Stat) float64
+}
+
+// dbStatsCollector читает состояние пула соединений с PostgreSQL в момент сбора метрик.
+type dbStatsCollector struct {
+	stat    func() *pgxpool.Stat
+	metrics []poolMetric
+}
+
+var _ prometheus.Collector = (*dbStatsCollector)(nil)
+
+func newDBStatsCollector(stat func() *pgxpool.Stat) *dbStatsCollector {
+	gauge := func(name, help string, value func(s *pgxpool.Stat) float64) poolMetric {
+		return poolMetric{prometheus.NewDesc(name, help, nil, nil), prometheus.GaugeValue, value}
+	}
+	counter := func(name, help string, value func(s *pgxpool.Stat) float64) poolMetric {
+		return poolMetric{prometheus.NewDesc(name, help, nil, nil), prometheus.CounterValue, value}
+	}
+	return &dbStatsCollector{
+		stat: stat,
+		metrics: []poolMetric{
+			gauge("healthcheck_db_pool_max_conns", "Maximum size of the connection pool.",
+				func(s *pgxpool.Stat) float64 { return float64(s.MaxConns()) }),
+			gauge("healthcheck_db_pool_total_conns", "Number of connections in the pool.",
+				func(s *pgxpool.Stat) float64 { return float64(s.TotalConns()) }),
+			gauge("healthcheck_db_pool_acquired_conns", "Number of connections currently in use.",
+				func(s *pgxpool.Stat) float64 { return float64(s.AcquiredConns()) }),
+			gauge("healthcheck_db_pool_idle_conns", "Number of idle connections in the pool.",
+				func(s *pgxpool.Stat) float64 { return float64(s.IdleConns()) }),
+			counter("healthcheck_db_pool_acquires_total", "Number of successful connection acquires.",
+				func(s *pgxpool.Stat) float64 { return float64(s.AcquireCount()) }),
+			counter("healthcheck_db_pool_acquire_duration_seconds_total", "Total time spent acquiring connections.",
+				func(s *pgxpool.Stat) float64 { return s.AcquireDuration().Seconds() }),
+			counter("healthcheck_db_pool_empty_acquires_total", "Number of acquires that waited for a free connection.",
+				func(s *pgxpool.Stat) float64 { return float64(s.EmptyAcquireCount()) }),
+			counter("healthcheck_db_pool_canceled_acquires_total", "Number of acquires canceled by context.",
+				func(s *pgxpool.Stat) float64 { return float64(s.CanceledAcquireCount()) }),
+		},
+	}
+}
+
+func (c *dbStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range c.metrics {
+		ch <- m.desc
+	}
+}
+
+func (c *dbStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stat()
+	for _, m := range c.metrics {
+		ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, m.value(s))
+	}
+}
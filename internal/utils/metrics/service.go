@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Способы входа и регистрации (метка method), источники взвешиваний (метка source).
+const (
+	MethodPassword = "password"
+	MethodOIDC     = "oidc"
+	MethodMfa      = "mfa"
+
+	SourceProfile     = "profile"
+	SourceMeasurement = "measurement"
+)
+
+// Метрики HTTP-запросов к /api/v1. route — шаблон маршрута (/api/v1/groups/{name}), а не путь запроса,
+// чтобы число серий не зависело от данных пользователей.
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "healthcheck_http_requests_total",
+		Help: "Number of processed HTTP requests.",
+	}, []string{"method", "route", "status"})
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "healthcheck_http_request_duration_seconds",
+		Help:    "HTTP request latency in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+	HTTPInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "healthcheck_http_requests_in_flight",
+		Help: "Number of HTTP requests being processed.",
+	})
+)
+
+// Бизнес-метрики.
+var (
+	SignUps = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "healthcheck_signups_total",
+		Help: "Number of registered users by registration method.",
+	}, []string{"method"})
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "healthcheck_logins_total",
+		Help: "Number of successful sign ins by the last authentication step.",
+	}, []string{"method"})
+	LoginFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "healthcheck_login_failures_total",
+		Help: "Number of failed sign ins by reason.",
+	}, []string{"reason"})
+	WeighIns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "healthcheck_weigh_ins_total",
+		Help: "Number of recorded body weights by source.",
+	}, []string{"source"})
+	Measurements = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "healthcheck_measurements_total",
+		Help: "Number of saved measurements by kind.",
+	}, []string{"kind"})
+)
+
+func init() {
+	Default.MustRegister(HTTPRequests, HTTPRequestDuration, HTTPInFlight,
+		SignUps, Logins, LoginFailures, WeighIns, Measurements)
+}
+
+// RegisterDBStats добавляет в набор r метрики пула соединений с PostgreSQL.
+func RegisterDBStats(r prometheus.Registerer, stat func() *pgxpool.Stat) {
+	r.MustRegister(newDBStatsCollector(stat))
+}
+
+// RegisterCacheStats добавляет в набор r метрики кэша профилей пользователей.
+func RegisterCacheStats(r prometheus.Registerer, stat func() ent.CacheStats) {
+	counter := func(name, help string, value func(s ent.CacheStats) uint64) prometheus.CounterFunc {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{Name: name, Help: help},
+			func() float64 { return float64(value(stat())) })
+	}
+	r.MustRegister(
+		counter("healthcheck_cache_hits_total", "Number of user profile cache hits.",
+			func(s ent.CacheStats) uint64 { return s.Hits }),
+		counter("healthcheck_cache_misses_total", "Number of user profile cache misses.",
+			func(s ent.CacheStats) uint64 { return s.Misses }),
+		counter("healthcheck_cache_errors_total", "Number of failed user profile cache requests.",
+			func(s ent.CacheStats) uint64 { return s.Errors }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "healthcheck_cache_hit_ratio",
+			Help: "Share of user profile cache hits among hits and misses since start.",
+		}, func() float64 {
+			s := stat()
+			if s.Hits+s.Misses == 0 {
+				return 0
+			}
+			return float64(s.Hits) / float64(s.Hits+s.Misses)
+		}),
+	)
+}
@@ -4,12 +4,37 @@ type AccessKey string
 
 // Частые переменные
 const (
-	RequestID = "request_id"
-	XRealIP   = "X-Real-IP"
-	JwtToken  = "jwt-token"
+	RequestID  = "request_id"
+	TraceID    = "trace_id"
+	SpanID     = "span_id"
+	XRealIP    = "X-Real-IP"
+	RetryAfter = "Retry-After"
+	JwtToken   = "jwt-token"
+
+	Authorization = "Authorization"
+	BearerScheme  = "Bearer"
+
+	RefreshToken     = "refresh-token"
+	RefreshTokenPath = "/api/v1"
+
+	OIDCFlow     = "oidc-flow"
+	OIDCFlowPath = "/api/v1/oidc"
+
+	CsrfToken  = "csrf-token"
+	XCsrfToken = "X-CSRF-Token"
 )
 
-// Настройка хэширования с помощью Argon2
+// CsrfExemptPaths ручки входа, которые не проверяют CSRF-токен: они не полагаются на cookie сессии,
+// а оставшаяся от прошлой сессии cookie не должна мешать войти заново.
+var CsrfExemptPaths = map[string]struct{}{
+	"/api/v1/signup":        {},
+	"/api/v1/signin":        {},
+	"/api/v1/signin/2fa":    {},
+	"/api/v1/oidc/complete": {},
+}
+
+// Устаревшие параметры Argon2, с которыми хэшировались пароли в формате `hash.salt`. Нужны только для
+// проверки таких паролей, новые параметры задаются в конфигурации (auth.argon2).
 const (
 	HashTime    = 1
 	HashMemory  = 2 * 1024
@@ -18,6 +43,225 @@ const (
 	HashLetters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-"
 )
 
+// Настройка двухфакторной аутентификации (RFC 6238)
+const (
+	TOTPSecretSize     = 20
+	TOTPDigits         = 6
+	TOTPPeriod         = 30
+	TOTPSkew           = 1
+	RecoveryCodesCount = 10
+	MfaChallengeType   = "mfa"
+)
+
+// Роли пользователей
+const (
+	RoleUser  = "user"
+	RoleCoach = "coach"
+	RoleAdmin = "admin"
+)
+
+// Права, которые маршруты требуют при регистрации (middlewares.Authorize)
+const (
+	PermProfileRead    = "profile:read"
+	PermProfileWrite   = "profile:write"
+	PermWeightWrite    = "weight:write"
+	PermSecurityManage = "security:manage"
+	PermUsersManage    = "users:manage"
+	PermGroupsUse      = "groups:use"
+	PermGroupsManage   = "groups:manage"
+	PermAgentsManage   = "agents:manage"
+	PermAccessRead     = "access:read"
+	PermAccessCheck    = "access:check"
+	PermCoachingUse    = "coaching:use"
+	PermClientsRead    = "clients:read"
+	PermClientsWrite   = "clients:write"
+	PermMeasureRead    = "measurements:read"
+	PermMeasureWrite   = "measurements:write"
+)
+
+var userPermissions = []string{PermProfileRead, PermProfileWrite, PermWeightWrite, PermSecurityManage,
+	PermGroupsUse, PermAccessRead, PermCoachingUse, PermMeasureRead, PermMeasureWrite}
+
+// RolePermissions права каждой роли. Тренер дополнительно работает с данными своих клиентов (в пределах
+// согласия клиента), администратор (root) управляет пользователями, агентами, группами и доступом к агентам.
+var RolePermissions = map[string][]string{
+	RoleUser:  userPermissions,
+	RoleCoach: append([]string{PermClientsRead, PermClientsWrite}, userPermissions...),
+	RoleAdmin: append([]string{PermUsersManage, PermGroupsManage, PermAgentsManage, PermAccessCheck},
+		userPermissions...),
+}
+
+// Персональные API-ключи. Ключ имеет вид hck_<base64url>, первые ApiKeyShownLen символов хранятся открыто,
+// чтобы пользователь мог отличить ключи друг от друга в списке.
+const (
+	ApiKeyPrefix   = "hck_"
+	ApiKeyShownLen = 12
+
+	ScopeReadAll      = "read:all"
+	ScopeProfileRead  = PermProfileRead
+	ScopeWeightWrite  = PermWeightWrite
+	ScopeAccessRead   = PermAccessRead
+	ScopeAccessCheck  = PermAccessCheck
+	ScopeMeasureRead  = PermMeasureRead
+	ScopeMeasureWrite = PermMeasureWrite
+)
+
+// AllowedApiKeyScopes права, которые можно выдать API-ключу. read:all включает в себя все права на чтение.
+// Ключ не может получить больше прав, чем есть у роли его владельца.
+var AllowedApiKeyScopes = map[string]struct{}{
+	ScopeReadAll:      {},
+	ScopeProfileRead:  {},
+	ScopeWeightWrite:  {},
+	ScopeAccessRead:   {},
+	ScopeAccessCheck:  {},
+	ScopeMeasureRead:  {},
+	ScopeMeasureWrite: {},
+}
+
+// Виды измерений и допустимые значения
+const (
+	MeasureWeight    = "weight"
+	MeasureSteps     = "steps"
+	MeasureHeartRate = "heart_rate"
+	// MeasureCalories запись дневника питания: энергетическая ценность съеденного, ккал
+	MeasureCalories = "calories"
+)
+
+// MeasureRange отрезок [Min, Max] допустимых значений измерения.
+type MeasureRange struct {
+	Min float64
+	Max float64
+}
+
+var AllowedMeasures = map[string]MeasureRange{
+	MeasureWeight:    {Min: 1, Max: 700},
+	MeasureSteps:     {Min: 0, Max: 200000},
+	MeasureHeartRate: {Min: 20, Max: 300},
+	MeasureCalories:  {Min: 0, Max: 20000},
+}
+
+// Хранилища данных сервиса (storage)
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
+// Экспортеры трассировки (tracing.exporter)
+const (
+	TracingExporterNone   = "none"
+	TracingExporterOTLP   = "otlp"
+	TracingExporterStdout = "stdout"
+	TracingExporterFile   = "file"
+)
+
+// Хранилища измерений (measurements.storage)
+const (
+	MeasureStoragePostgres = "postgres"
+	MeasureStorageMongo    = "mongo"
+)
+
+// Статусы проверки готовности (/readyz)
+const (
+	HealthOK       = "ok"
+	HealthFail     = "fail"
+	HealthDraining = "draining"
+)
+
+// Статусы заявок на создание группы
+const (
+	BidStatusInProgress = "in_progress"
+	BidStatusRejected   = "rejected"
+	BidStatusApproved   = "approved"
+)
+
+// Источники доступа к агенту в ответе GET /check
+const (
+	AccessSourceRoot   = "root"
+	AccessSourceDirect = "direct"
+	AccessSourceGroup  = "group:"
+)
+
+// Статусы связи тренера и клиента
+const (
+	CoachLinkPending = "pending"
+	CoachLinkActive  = "active"
+	CoachLinkRevoked = "revoked"
+)
+
+// Данные клиента, доступ к которым клиент может открыть тренеру
+const (
+	CoachScopeProfile = "profile"
+	CoachScopeWeight  = "weight"
+	CoachScopeDiary   = "diary"
+	CoachScopeVitals  = "vitals"
+)
+
+var AllowedCoachScopes = map[string]struct{}{
+	CoachScopeProfile: {},
+	CoachScopeWeight:  {},
+	CoachScopeDiary:   {},
+	CoachScopeVitals:  {},
+}
+
+// MeasureCoachScopes право тренера, которое открывает ему измерения клиента каждого вида.
+var MeasureCoachScopes = map[string]string{
+	MeasureWeight:    CoachScopeWeight,
+	MeasureSteps:     CoachScopeVitals,
+	MeasureHeartRate: CoachScopeVitals,
+	MeasureCalories:  CoachScopeDiary,
+}
+
+// Действия, которые записываются в журнал (таблица audit_event). Группа действия — часть названия до первой
+// точки, по ней можно искать в журнале.
+const (
+	AuditSignUp         = "auth.signup"
+	AuditSignInSuccess  = "auth.signin.success"
+	AuditSignInFailure  = "auth.signin.failure"
+	AuditSignOut        = "auth.signout"
+	AuditPasswordChange = "auth.password.change"
+	AuditTokenReuse     = "auth.token.reuse"
+	AuditUserDelete     = "user.delete"
+	AuditMfaEnable      = "mfa.enable"
+	AuditMfaDisable     = "mfa.disable"
+	AuditApiKeyCreate   = "apikey.create"
+	AuditApiKeyRevoke   = "apikey.revoke"
+
+	AuditAdminUsersList     = "admin.users.list"
+	AuditAdminUserView      = "admin.user.view"
+	AuditAdminUserLock      = "admin.user.lock"
+	AuditAdminUserUnlock    = "admin.user.unlock"
+	AuditAdminPasswordReset = "admin.user.password_reset"
+	AuditAdminUserDelete    = "admin.user.delete"
+	AuditAdminUserCreate    = "admin.user.create"
+	AuditAdminUserRole      = "admin.user.role"
+)
+
+// BMIRange полуинтервал [Min, Max) значений индекса массы тела.
+type BMIRange struct {
+	Min float32
+	Max float32
+}
+
+// BMICategories категории индекса массы тела для поиска пользователей, границы совпадают с entity.BMIType.
+var BMICategories = map[string]BMIRange{
+	"severe_underweight": {0, 16},
+	"underweight":        {16, 18.5},
+	"normal":             {18.5, 25},
+	"overweight":         {25, 30},
+	"obesity_1":          {30, 35},
+	"obesity_2":          {35, 40},
+	"obesity_3":          {40, 1000},
+}
+
+// Коды ошибок PostgreSQL
+const (
+	PgUniqueViolation     = "23505"
+	PgForeignKeyViolation = "23503"
+	// транзакцию откатила параллельная транзакция, ее можно повторить
+	PgSerializationFailure = "40001"
+	PgDeadlockDetected     = "40P01"
+)
+
 var AllowedActivities = map[string]float32{
 	"NFA": 1.2,
 	"LA":  1.375,
@@ -6,17 +6,68 @@ var (
 	ErrInternal             = errors.New("Внутренняя ошибка сервера, пожалуйста, попробуйте немного позже")
 	ErrNoRequestIdInContext = errors.New("Отсутствует request_id в контексте объекта запроса")
 
-	ErrInvalidJwt = errors.New("Невалидный jwt_token")
-
-	ErrIncorrectPwdOrLogin  = errors.New("Неверный пароль или логин")
-	ErrUserAlreadyExist     = errors.New("Пользователь с таким никнеймом уже существует")
-	ErrUserNotExist         = errors.New("Пользователь с таким никнеймом не существует")
-	ErrAlreadyRegistered    = errors.New("Вы уже зарегистрированы")
-	ErrAlreadyAuthenticated = errors.New("Вы уже авторизованы")
-	ErrNotAuthenticated     = errors.New("Вы не авторизованы")
-	ErrInvalidData          = errors.New("Вы ввели неправильные данные")
+	ErrInvalidJwt          = errors.New("Невалидный jwt_token")
+	ErrAccessTokenExpired  = errors.New("Срок действия jwt_token истек")
+	ErrInvalidAuthHeader   = errors.New("Заголовок Authorization должен иметь вид 'Bearer <token>'")
+	ErrInvalidCsrfToken    = errors.New("Неверный CSRF-токен, передайте значение cookie csrf-token в заголовке X-CSRF-Token")
+	ErrInvalidRefreshToken = errors.New("Невалидный или просроченный refresh_token")
+	ErrRefreshTokenReused  = errors.New("Refresh_token уже был использован, все сессии этого входа завершены")
+
+	ErrIncorrectPwdOrLogin    = errors.New("Неверный пароль или логин")
+	ErrUserAlreadyExist       = errors.New("Пользователь с таким никнеймом уже существует")
+	ErrUserNotExist           = errors.New("Пользователь с таким никнеймом не существует")
+	ErrAlreadyRegistered      = errors.New("Вы уже зарегистрированы")
+	ErrAlreadyAuthenticated   = errors.New("Вы уже авторизованы")
+	ErrNotAuthenticated       = errors.New("Вы не авторизованы")
+	ErrForbidden              = errors.New("Недостаточно прав для выполнения запроса")
+	ErrInvalidData            = errors.New("Вы ввели неправильные данные")
+	ErrTooManyAttempts        = errors.New("Слишком много неудачных попыток входа, попробуйте позже")
+	ErrAccountLocked          = errors.New("Учетная запись заблокирована администратором")
+	ErrPasswordResetNeeded    = errors.New("Администратор потребовал сменить пароль, повторите вход с новым паролем в поле new_password")
+	ErrPasswordNotChanged     = errors.New("Новый пароль должен отличаться от старого")
+	ErrPasswordResetNotNeeded = errors.New("Смена пароля при входе не требуется, поле new_password нужно убрать")
+
+	ErrMfaAlreadyEnabled   = errors.New("Двухфакторная аутентификация уже включена")
+	ErrMfaNotEnabled       = errors.New("Двухфакторная аутентификация не включена")
+	ErrInvalidMfaCode      = errors.New("Неверный или уже использованный код подтверждения")
+	ErrInvalidMfaChallenge = errors.New("Невалидный или просроченный challenge_token, пройдите авторизацию заново")
+
+	ErrUnknownProvider          = errors.New("Вход через этого провайдера не настроен")
+	ErrInvalidOIDCState         = errors.New("Невалидное или просроченное состояние входа, начните вход через провайдера заново")
+	ErrOIDCLoginFailed          = errors.New("Не удалось выполнить вход через провайдера")
+	ErrInvalidRegistrationToken = errors.New("Невалидный или просроченный registration_token, начните вход через провайдера заново")
+	ErrIdentityAlreadyLinked    = errors.New("Эта учетная запись провайдера уже привязана к другому пользователю")
+	ErrOIDCEmailTaken           = errors.New("Пользователь с такой почтой уже зарегистрирован: войдите в свою учетную запись и привяжите провайдера")
+
+	ErrInvalidApiKey  = errors.New("Невалидный или отозванный API-ключ")
+	ErrApiKeyNotFound = errors.New("API-ключ не найден")
+	ErrApiKeyScope    = errors.New("У API-ключа нет прав на этот запрос")
+	ErrApiKeyLimit    = errors.New("Достигнуто максимальное количество API-ключей, отзовите неиспользуемые")
+
+	ErrGroupAlreadyExist = errors.New("Группа с таким названием уже существует")
+	ErrGroupNotExist     = errors.New("Группа с таким названием не существует")
+	ErrBidAlreadyExist   = errors.New("Заявка на создание группы с таким названием уже рассматривается")
+	ErrBidNotExist       = errors.New("Заявка не существует или уже рассмотрена")
+	ErrNotGroupOwner     = errors.New("Управлять участниками может только ответственный за группу")
+	ErrAlreadyMember     = errors.New("Пользователь уже состоит в группе")
+	ErrNotMember         = errors.New("Пользователь не состоит в группе")
+	ErrCannotRemoveOwner = errors.New("Ответственного нельзя исключить из группы")
+	ErrUserOwnsGroups    = errors.New("Пользователь является ответственным за группу, сначала удалите группу")
+	ErrAgentAlreadyExist = errors.New("Агент с таким названием уже существует")
+	ErrAgentNotExist     = errors.New("Агент с таким названием не существует")
+	ErrPrivilegeNotExist = errors.New("Такого доступа к агенту нет")
+
+	ErrNotCoach          = errors.New("Пользователь не является тренером")
+	ErrCoachSelf         = errors.New("Нельзя пригласить тренером самого себя")
+	ErrCoachLinkExist    = errors.New("Приглашение этому тренеру уже отправлено или он уже ваш тренер")
+	ErrCoachLinkNotExist = errors.New("Связь с тренером не найдена")
+	ErrCoachNoConsent    = errors.New("Клиент не открыл тренеру доступ к этим данным")
+
+	ErrAdminSelf   = errors.New("Нельзя применить это действие к своей учетной записи")
+	ErrInvalidRole = errors.New("Такой роли не существует, доступны роли user, coach и admin")
 )
 
 var (
-	ErrNoRowsAffected = errors.New("no rows were affected")
+	ErrNoRowsAffected      = errors.New("no rows were affected")
+	ErrInvalidPasswordHash = errors.New("stored password hash has invalid format")
 )
@@ -0,0 +1,77 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Локальная база утекших паролей в формате k-anonymity (как у Have I Been Pwned): каталог с файлами,
+// названными по первым 5 hex-символам SHA-1 пароля (например, `5BAA6` или `5BAA6.txt`). Каждая строка файла
+// имеет вид `ОСТАТОК_ХЭША:КОЛИЧЕСТВО`. Скачать такую базу можно утилитой PwnedPasswordsDownloader, для
+// небольших инсталляций достаточно файлов только с популярными паролями.
+
+const sha1PrefixLen = 5
+
+// IsBreached сообщает, встречается ли пароль в локальной базе утекших паролей. Если база не настроена
+// (auth.password_policy.breached_dir пустой), всегда возвращает false.
+func IsBreached(pwd string) (bool, error) {
+	dir := viper.GetString("auth.password_policy.breached_dir")
+	if dir == "" {
+		return false, nil
+	}
+	sum := sha1.Sum([]byte(pwd))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:sha1PrefixLen], hash[sha1PrefixLen:]
+
+	for _, name := range []string{prefix, prefix + ".txt"} {
+		found, err := searchRangeFile(filepath.Join(dir, name), suffix)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		return found, err
+	}
+	// файла с таким префиксом нет, значит, ни один пароль с этим префиксом не утекал
+	return false, nil
+}
+
+// CheckBreachedDir проверяет, что каталог с базой утекших паролей существует. Вызывается при старте сервиса,
+// чтобы ошибка в конфигурации не отключала проверку незаметно.
+func CheckBreachedDir() error {
+	dir := viper.GetString("auth.password_policy.breached_dir")
+	if dir == "" {
+		return nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.New("auth.password_policy.breached_dir is not a directory")
+	}
+	return nil
+}
+
+func searchRangeFile(path, suffix string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		hashSuffix, _, _ := strings.Cut(line, ":")
+		if strings.EqualFold(hashSuffix, suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
@@ -0,0 +1,119 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// SHA-1 от "password": 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8.
+const (
+	passwordPrefix = "5BAA6"
+	passwordSuffix = "1E4C9B93F3F0682250B6CF8331B7EE68FD8"
+)
+
+func setBreachedDir(t *testing.T, dir string) {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("auth.password_policy.breached_dir", dir)
+}
+
+func writeRangeFile(t *testing.T, dir, name string, lines ...string) {
+	t.Helper()
+	content := ""
+	for _, line := range lines {
+		content += line + "\r\n"
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIsBreached(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		lines    []string
+		pwd      string
+		want     bool
+	}{
+		{
+			name:     "hit",
+			fileName: passwordPrefix,
+			lines:    []string{"0018A45C4D1DEF81644B54AB7F969B88D65:1", passwordSuffix + ":9545824"},
+			pwd:      "password",
+			want:     true,
+		},
+		{
+			name:     "hit in txt file, lower case",
+			fileName: passwordPrefix + ".txt",
+			lines:    []string{"1e4c9b93f3f0682250b6cf8331b7ee68fd8:3"},
+			pwd:      "password",
+			want:     true,
+		},
+		{
+			name:     "miss in prefix file",
+			fileName: passwordPrefix,
+			lines:    []string{"0018A45C4D1DEF81644B54AB7F969B88D65:1"},
+			pwd:      "password",
+		},
+		{
+			name:     "no prefix file",
+			fileName: passwordPrefix,
+			lines:    []string{passwordSuffix + ":9545824"},
+			pwd:      "kX9#vQ2!mZ7$",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeRangeFile(t, dir, tt.fileName, tt.lines...)
+			setBreachedDir(t, dir)
+
+			got, err := IsBreached(tt.pwd)
+			if err != nil {
+				t.Fatalf("IsBreached() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsBreached() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsBreachedDisabled(t *testing.T) {
+	setBreachedDir(t, "")
+
+	got, err := IsBreached("password")
+	if err != nil || got {
+		t.Errorf("IsBreached() = %v, %v, want false, nil", got, err)
+	}
+	if err := CheckBreachedDir(); err != nil {
+		t.Errorf("CheckBreachedDir() error = %v, want nil", err)
+	}
+}
+
+func TestCheckBreachedDir(t *testing.T) {
+	dir := t.TempDir()
+	writeRangeFile(t, dir, passwordPrefix, passwordSuffix+":1")
+	tests := []struct {
+		name    string
+		dir     string
+		wantErr bool
+	}{
+		{name: "directory", dir: dir},
+		{name: "missing", dir: filepath.Join(dir, "missing"), wantErr: true},
+		{name: "file", dir: filepath.Join(dir, passwordPrefix), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setBreachedDir(t, tt.dir)
+			if err := CheckBreachedDir(); (err != nil) != tt.wantErr {
+				t.Errorf("CheckBreachedDir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package password
+
+import (
+	_ "embed"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Оценка стойкости пароля по мотивам zxcvbn: пароль разбивается на фрагменты (словарные слова,
+// последовательности, повторы, клавиатурные ряды, годы, остальное перебором), для каждого фрагмента
+// оценивается число попыток, которое понадобится атакующему, и выбирается самое "дешевое" разбиение.
+// Итоговая оценка от 0 (угадывается мгновенно) до 4 (очень стойкий пароль).
+
+//go:embed common.txt
+var commonPasswordsRaw string
+
+// commonRanks ранг слова в словаре: чем популярнее слово, тем меньше попыток нужно на его угадывание.
+var commonRanks = loadRanks(commonPasswordsRaw)
+
+const (
+	bruteforceCardinality = 10
+	minDictionaryLen      = 3
+	minSequenceLen        = 3
+	minKeyboardLen        = 4
+	minDateLen            = 6
+	maxDateLen            = 10
+	// dateGuesses около сотни лет по 365 дней, как MIN_YEAR_SPACE и DATE_RANK в zxcvbn
+	dateGuesses = 365 * 100
+)
+
+var keyboardRows = []string{
+	"1234567890-=",
+	"qwertyuiop[]",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+	"йцукенгшщзхъ",
+	"фывапролджэ",
+	"ячсмитьбю",
+	// столбцы, которые часто используют как "сложный" пароль
+	"1qaz2wsx3edc4rfv5tgb",
+	"zaq1xsw2cde3vfr4",
+}
+
+var l33tTable = map[rune]rune{
+	'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '1': 'i', '!': 'i',
+	'|': 'l', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '2': 'z',
+}
+
+// EstimateStrength возвращает оценку стойкости от 0 до 4. userInputs это данные пользователя (никнейм,
+// имя), они считаются словарными словами с наивысшим рангом.
+func EstimateStrength(pwd string, userInputs ...string) int {
+	return scoreFromGuesses(EstimateGuesses(pwd, userInputs...))
+}
+
+// EstimateGuesses возвращает оценку числа попыток, необходимых для подбора пароля.
+func EstimateGuesses(pwd string, userInputs ...string) float64 {
+	runes := []rune(pwd)
+	n := len(runes)
+	if n == 0 {
+		return 1
+	}
+	lower := []rune(strings.ToLower(pwd))
+	unleet := make([]rune, n)
+	for i, r := range lower {
+		if sub, ok := l33tTable[r]; ok {
+			unleet[i] = sub
+		} else {
+			unleet[i] = r
+		}
+	}
+	userRanks := make(map[string]int, len(userInputs))
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		if len([]rune(input)) >= minDictionaryLen {
+			userRanks[input] = 1
+		}
+	}
+
+	// best[i] минимальное (в логарифмах) число попыток для префикса длины i, segments[i] число фрагментов
+	best := make([]float64, n+1)
+	segments := make([]int, n+1)
+	for i := 1; i <= n; i++ {
+		best[i] = math.Inf(1)
+	}
+	for end := 1; end <= n; end++ {
+		for start := 0; start < end; start++ {
+			if math.IsInf(best[start], 1) {
+				continue
+			}
+			guesses := fragmentGuesses(runes[start:end], lower[start:end], unleet[start:end], userRanks)
+			candidate := best[start] + math.Log10(guesses)
+			if candidate < best[end] {
+				best[end] = candidate
+				segments[end] = segments[start] + 1
+			}
+		}
+	}
+	// как и в zxcvbn, учитываем, что атакующему нужно перебрать и порядок фрагментов
+	return math.Pow(10, best[n]) * factorial(segments[n])
+}
+
+// fragmentGuesses оценивает число попыток для одного фрагмента как минимум среди подходящих шаблонов.
+func fragmentGuesses(orig, lower, unleet []rune, userRanks map[string]int) float64 {
+	n := len(orig)
+	guesses := math.Pow(bruteforceCardinality, float64(n))
+	if n == 1 {
+		return guesses
+	}
+	if n >= minDictionaryLen {
+		word := string(lower)
+		rank, ok := userRanks[word]
+		if !ok {
+			rank, ok = commonRanks[word]
+		}
+		variations := uppercaseVariations(orig)
+		if !ok {
+			if rank, ok = userRanks[string(unleet)]; !ok {
+				rank, ok = commonRanks[string(unleet)]
+			}
+			variations *= 2
+		}
+		if ok {
+			guesses = math.Min(guesses, float64(rank)*variations)
+		}
+	}
+	if n >= minSequenceLen && isSequence(lower) {
+		guesses = math.Min(guesses, 4*float64(n))
+	}
+	if isRepeat(lower) {
+		guesses = math.Min(guesses, bruteforceCardinality*float64(n))
+	}
+	if n >= minKeyboardLen && isKeyboardRun(lower) {
+		guesses = math.Min(guesses, 40*float64(n))
+	}
+	if n == 4 && isYear(lower) {
+		guesses = math.Min(guesses, 120)
+	}
+	if n >= minDateLen && n <= maxDateLen {
+		if date, separated := isDate(lower); date {
+			if separated {
+				guesses = math.Min(guesses, 4*dateGuesses)
+			} else {
+				guesses = math.Min(guesses, dateGuesses)
+			}
+		}
+	}
+	return guesses
+}
+
+// scoreFromGuesses пороги те же, что в zxcvbn.
+func scoreFromGuesses(guesses float64) int {
+	const delta = 5
+	switch {
+	case guesses < 1e3+delta:
+		return 0
+	case guesses < 1e6+delta:
+		return 1
+	case guesses < 1e8+delta:
+		return 2
+	case guesses < 1e10+delta:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func uppercaseVariations(word []rune) float64 {
+	upper := 0
+	for _, r := range word {
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 1
+	case upper == len(word) || (upper == 1 && unicode.IsUpper(word[0])):
+		// ВСЕ ЗАГЛАВНЫЕ или Первая заглавная
+		return 2
+	default:
+		return math.Pow(2, float64(upper))
+	}
+}
+
+// isSequence abc, 1234, zyx и т.п. с постоянным шагом 1.
+func isSequence(word []rune) bool {
+	delta := word[1] - word[0]
+	if delta != 1 && delta != -1 {
+		return false
+	}
+	for i := 2; i < len(word); i++ {
+		if word[i]-word[i-1] != delta {
+			return false
+		}
+	}
+	return true
+}
+
+// isRepeat aaa, abab, 123123.
+func isRepeat(word []rune) bool {
+	n := len(word)
+	for size := 1; size <= n/2; size++ {
+		if n%size != 0 {
+			continue
+		}
+		repeated := true
+		for i := size; i < n; i++ {
+			if word[i] != word[i-size] {
+				repeated = false
+				break
+			}
+		}
+		if repeated {
+			return true
+		}
+	}
+	return false
+}
+
+func isKeyboardRun(word []rune) bool {
+	s := string(word)
+	for _, row := range keyboardRows {
+		if strings.Contains(row, s) || strings.Contains(reverse(row), s) {
+			return true
+		}
+	}
+	return false
+}
+
+func isYear(word []rune) bool {
+	year, err := strconv.Atoi(string(word))
+	return err == nil && year >= 1900 && year <= 2099
+}
+
+// isDate 19051990, 1990-05-19, 19.05.90 и т.п.: день, месяц и год (2 или 4 цифры) в любом из
+// распространенных порядков, без разделителя или с одним и тем же разделителем между частями.
+func isDate(word []rune) (date, separated bool) {
+	s := string(word)
+	var parts []string
+	if sep := strings.IndexAny(s, "-./_ \\"); sep >= 0 {
+		if parts = strings.Split(s, s[sep:sep+1]); len(parts) != 3 {
+			return false, false
+		}
+		separated = true
+	} else {
+		if len(s) != 6 && len(s) != 8 {
+			return false, false
+		}
+		// ггггммдд и ггммдд
+		yearLen := len(s) - 4
+		if isDateParts(s[:yearLen], s[yearLen:yearLen+2], s[yearLen+2:]) {
+			return true, false
+		}
+		// ддммгггг и ммддгггг
+		parts = []string{s[:2], s[2:4], s[4:]}
+	}
+	for _, part := range parts {
+		if part == "" || strings.Trim(part, "0123456789") != "" {
+			return false, false
+		}
+	}
+	date = isDateParts(parts[0], parts[1], parts[2]) ||
+		isDateParts(parts[2], parts[1], parts[0]) ||
+		isDateParts(parts[2], parts[0], parts[1])
+	return date, separated
+}
+
+// isDateParts проверяет, что year, month и day могут быть годом, месяцем и днем.
+func isDateParts(year, month, day string) bool {
+	if len(year) != 2 && len(year) != 4 || len(month) > 2 || len(day) > 2 {
+		return false
+	}
+	y, errY := strconv.Atoi(year)
+	m, errM := strconv.Atoi(month)
+	d, errD := strconv.Atoi(day)
+	if errY != nil || errM != nil || errD != nil {
+		return false
+	}
+	if len(year) == 4 && (y < 1900 || y > 2099) {
+		return false
+	}
+	return m >= 1 && m <= 12 && d >= 1 && d <= 31
+}
+
+func reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
+
+func factorial(n int) float64 {
+	result := 1.0
+	for i := 2; i <= n; i++ {
+		result *= float64(i)
+	}
+	return result
+}
+
+func loadRanks(raw string) map[string]int {
+	ranks := make(map[string]int)
+	rank := 1
+	for _, line := range strings.Split(raw, "\n") {
+		word := strings.ToLower(strings.TrimSpace(line))
+		if word == "" {
+			continue
+		}
+		if _, ok := ranks[word]; !ok {
+			ranks[word] = rank
+			rank++
+		}
+	}
+	return ranks
+}
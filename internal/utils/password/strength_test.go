@@ -0,0 +1,68 @@
+package password
+
+import "testing"
+
+// minStrength значение auth.password_policy.min_strength по умолчанию.
+const minStrength = 2
+
+func TestEstimateStrengthWeak(t *testing.T) {
+	tests := []struct {
+		name       string
+		pwd        string
+		userInputs []string
+	}{
+		{name: "common", pwd: "password"},
+		{name: "common with capital and digit", pwd: "Password1"},
+		{name: "l33t", pwd: "p@ssw0rd"},
+		{name: "keyboard", pwd: "qwerty123"},
+		{name: "keyboard row", pwd: "zxcvbnm123"},
+		{name: "sequence", pwd: "abcdefgh"},
+		{name: "repeat", pwd: "aaaaaaaaaaaa"},
+		{name: "repeated year", pwd: "19901990"},
+		{name: "date", pwd: "19051990"},
+		{name: "date with separators", pwd: "1990-05-19"},
+		{name: "short date", pwd: "19.05.90"},
+		{name: "user input", pwd: "ivanivan1990", userInputs: []string{"ivan"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateStrength(tt.pwd, tt.userInputs...); got >= minStrength {
+				t.Errorf("EstimateStrength(%q) = %d, want < %d", tt.pwd, got, minStrength)
+			}
+		})
+	}
+}
+
+func TestEstimateStrengthStrong(t *testing.T) {
+	for _, pwd := range []string{"Tr0ub4dour&3", "correct horse battery staple", "kX9#vQ2!mZ7$"} {
+		if got := EstimateStrength(pwd); got < minStrength {
+			t.Errorf("EstimateStrength(%q) = %d, want >= %d", pwd, got, minStrength)
+		}
+	}
+}
+
+func TestIsDate(t *testing.T) {
+	tests := []struct {
+		word          string
+		wantDate      bool
+		wantSeparated bool
+	}{
+		{word: "19900519", wantDate: true},
+		{word: "19051990", wantDate: true},
+		{word: "05191990", wantDate: true},
+		{word: "190590", wantDate: true},
+		{word: "1990-05-19", wantDate: true, wantSeparated: true},
+		{word: "19/5/1990", wantDate: true, wantSeparated: true},
+		{word: "19.05.90", wantDate: true, wantSeparated: true},
+		{word: "19903519"},
+		{word: "1990-05.19"},
+		{word: "123456789"},
+		{word: "ab.cd.ef"},
+	}
+	for _, tt := range tests {
+		date, separated := isDate([]rune(tt.word))
+		if date != tt.wantDate || separated != tt.wantSeparated {
+			t.Errorf("isDate(%q) = %v, %v, want %v, %v", tt.word, date, separated, tt.wantDate, tt.wantSeparated)
+		}
+	}
+}
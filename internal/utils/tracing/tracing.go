@@ -0,0 +1,32 @@
+// Package tracing спаны сервиса в OpenTelemetry. Спаны пишутся в глобальный TracerProvider, который
+// настраивает app.InitTracing; HTTP-запросы и запросы pgx записываются otelhttp и otelpgx.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName имя, под которым сервис записывает собственные спаны.
+const instrumentationName = "github.com/cantylv/hackathon-bmstu-2024-healthcheck"
+
+// Start начинает дочерний спан name, например для вызова usecase. Спан завершается End:
+//
+//	ctx, span := tracing.Start(ctx, "user.Read")
+//	defer tracing.End(span, &err)
+func Start(ctx context.Context, name string) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name)
+}
+
+// End завершает спан. Ошибка *err становится статусом спана; указатель нужен, чтобы в defer прочитать
+// значение, которое вернула функция.
+func End(span trace.Span, err *error) {
+	if err != nil && *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
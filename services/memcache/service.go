@@ -2,6 +2,7 @@
 package memcache
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -10,15 +11,40 @@ import (
 	"go.uber.org/zap"
 )
 
-// I want to use memcache, but it doesn't fit out data model.
-func Init() *memcache.Client {
-	logger := zap.Must(zap.NewProduction()).Sugar()
+// Enabled сообщает, задан ли Memcached в конфигурации. Пустой memcached.host отключает его.
+func Enabled() bool {
+	return viper.GetString("memcached.host") != ""
+}
 
+// Init инициализирует клиента Memcached. Соединения открываются при запросах, поэтому клиент возвращается,
+// даже если Memcached сейчас не отвечает: запросы к нему будут завершаться ошибкой, а /readyz — отвечать 503,
+// пока Memcached не станет доступен.
+func Init(logger *zap.Logger) *memcache.Client {
 	connLine := fmt.Sprintf("%s:%d", viper.GetString("memcached.host"), viper.GetUint16("memcached.port"))
 	client := memcache.New(connLine)
 	client.MaxIdleConns = 20
 	client.Timeout = 5 * time.Second
 
+	err := client.Ping()
+	if err != nil {
+		logger.Error(fmt.Sprintf("memcached is not available, sign-in attempt counters and cache will fail until it responds: %v", err))
+		return client
+	}
 	logger.Info("succesful connection to Memcached")
 	return client
-}
\ No newline at end of file
+}
+
+// Ping проверяет, что Memcached отвечает. Клиент не принимает контекст, поэтому ожидание ответа прерывается
+// по отмене ctx, а сам запрос завершится по client.Timeout.
+func Ping(ctx context.Context, client *memcache.Client) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Ping()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -1,2 +0,0 @@
-db = db.getSiblingDB("admin");
-db.createCollection('weight');
\ No newline at end of file
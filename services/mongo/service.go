@@ -3,19 +3,27 @@ package mongodb
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"go.uber.org/zap"
 )
 
+// Init подключается к MongoDB. MongoDB нужна только хранилищу измерений (measurements.storage: mongo), раз она
+// явно выбрана в конфигурации, то без нее сервис не запускается.
 func Init(logger *zap.Logger) *mongo.Client {
-	connLine := fmt.Sprintf(`mongodb://%s:%d/main?connectTimeoutMS=5000&socketTimeoutMS=10000&maxPoolSize=30&minPoolSize=0&maxConnecting=3`,
+	connLine := fmt.Sprintf(`mongodb://%s:%d/?connectTimeoutMS=5000&socketTimeoutMS=10000&maxPoolSize=30&minPoolSize=0&maxConnecting=3`,
 		viper.GetString("mongo.host"), viper.GetInt("mongo.port"))
-	client, err := mongo.Connect(options.Client().ApplyURI(connLine))
+	opts := options.Client().ApplyURI(connLine)
+	if user := viper.GetString("mongo.conn_name"); user != "" {
+		opts.SetAuth(options.Credential{Username: user, Password: viper.GetString("mongo.conn_pwd")})
+	}
+	client, err := mongo.Connect(opts)
 	if err != nil {
 		logger.Fatal(fmt.Sprintf("error MongoDB connect: %v", err))
 	}
@@ -29,10 +37,41 @@ func Init(logger *zap.Logger) *mongo.Client {
 		if i == 2 {
 			logger.Fatal("MongoDB is not respond")
 		}
-		logger.Warn(fmt.Sprintf("error while ping to postgresql: %v", err))
+		logger.Warn(fmt.Sprintf("error while ping to mongodb: %v", err))
 		time.Sleep(3 * time.Second)
 	}
 
 	logger.Info("succesful connection to MongoDB")
 	return client
 }
+
+// EnsureTimeSeries возвращает коллекцию временных рядов measurements.mongo.collection и создает ее, если ее
+// еще нет. Параметры существующей коллекции не меняются: гранулярность можно только увеличить вручную
+// (collMod), а срок хранения — изменить через collMod expireAfterSeconds.
+func EnsureTimeSeries(ctx context.Context, client *mongo.Client) (*mongo.Collection, error) {
+	db := client.Database(viper.GetString("mongo.database"))
+	name := viper.GetString("measurements.mongo.collection")
+	names, err := db.ListCollectionNames(ctx, bson.D{{Key: "name", Value: name}})
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		opts := options.CreateCollection().SetTimeSeriesOptions(options.TimeSeries().
+			SetTimeField("recorded_at").
+			SetMetaField("meta").
+			SetGranularity(viper.GetString("measurements.mongo.granularity")))
+		if retention := viper.GetDuration("measurements.mongo.retention"); retention > 0 {
+			opts.SetExpireAfterSeconds(int64(retention.Seconds()))
+		}
+		err = db.CreateCollection(ctx, name, opts)
+		// коллекцию мог успеть создать другой экземпляр сервиса
+		var cmdErr mongo.CommandError
+		if err != nil && !(errors.As(err, &cmdErr) && cmdErr.HasErrorCode(namespaceExists)) {
+			return nil, err
+		}
+	}
+	return db.Collection(name), nil
+}
+
+// namespaceExists код ошибки MongoDB: коллекция уже существует.
+const namespaceExists = 48
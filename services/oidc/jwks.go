@@ -0,0 +1,118 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+)
+
+var (
+	errUnsupportedAlg = errors.New("unsupported id_token signing algorithm")
+	errUnsupportedKey = errors.New("unsupported jwk")
+	errBadSignature   = errors.New("id_token signature is invalid")
+)
+
+// jwk открытый ключ провайдера (RFC 7517). Поддерживаются RSA и EC (P-256, P-384).
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, errUnsupportedKey
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, errUnsupportedKey
+}
+
+// verifySignature проверяет подпись JWS. Алгоритм none и симметричные алгоритмы не принимаются:
+// id_token должен быть подписан закрытым ключом провайдера.
+func verifySignature(alg string, key crypto.PublicKey, signingInput, signature []byte) error {
+	var h hash.Hash
+	var hashFunc crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		h, hashFunc = sha256.New(), crypto.SHA256
+	case "RS384", "ES384":
+		h, hashFunc = sha512.New384(), crypto.SHA384
+	case "RS512":
+		h, hashFunc = sha512.New(), crypto.SHA512
+	default:
+		return errUnsupportedAlg
+	}
+	h.Write(signingInput)
+	digest := h.Sum(nil)
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if alg[:2] != "RS" {
+			return errUnsupportedAlg
+		}
+		if err := rsa.VerifyPKCS1v15(pub, hashFunc, digest, signature); err != nil {
+			return errBadSignature
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		if alg[:2] != "ES" {
+			return errUnsupportedAlg
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errBadSignature
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errBadSignature
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %T", errUnsupportedKey, key)
+}
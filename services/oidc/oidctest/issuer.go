@@ -0,0 +1,208 @@
+// Package oidctest локальный провайдер OpenID Connect для тестов: документ discovery, JWKS и token endpoint
+// с проверкой PKCE. id_token подписываются ключом RS256, который можно сменить, как делают настоящие провайдеры.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Issuer провайдер, запущенный на httptest.Server. Адрес сервера служит издателем (iss).
+type Issuer struct {
+	*httptest.Server
+	ClientID string
+
+	mu sync.Mutex
+	// metadataIssuer издатель в документе discovery, по умолчанию адрес сервера
+	metadataIssuer string
+	key            *signingKey
+	keySeq         int
+	grants         map[string]grant
+	requests       map[string]int
+}
+
+type signingKey struct {
+	kid     string
+	private *rsa.PrivateKey
+}
+
+// grant код авторизации, выданный провайдером.
+type grant struct {
+	codeChallenge string
+	idToken       string
+}
+
+// Пути провайдера.
+const (
+	DiscoveryPath = "/.well-known/openid-configuration"
+	AuthorizePath = "/authorize"
+	TokenPath     = "/token"
+	JwksPath      = "/jwks"
+)
+
+// NewIssuer запускает провайдера для клиента clientID. Сервер останавливается по завершении теста.
+func NewIssuer(t testing.TB, clientID string) *Issuer {
+	t.Helper()
+	i := &Issuer{
+		ClientID: clientID,
+		grants:   make(map[string]grant),
+		requests: make(map[string]int),
+	}
+	i.RotateKey()
+	mux := http.NewServeMux()
+	mux.HandleFunc(DiscoveryPath, i.discovery)
+	mux.HandleFunc(TokenPath, i.token)
+	mux.HandleFunc(JwksPath, i.jwks)
+	i.Server = httptest.NewServer(mux)
+	t.Cleanup(i.Close)
+	return i
+}
+
+// SetMetadataIssuer подменяет издателя в документе discovery.
+func (i *Issuer) SetMetadataIssuer(issuer string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.metadataIssuer = issuer
+}
+
+// RotateKey заменяет ключ подписи новым с новым kid. Прежний ключ из JWKS удаляется.
+func (i *Issuer) RotateKey() {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.keySeq++
+	i.key = &signingKey{kid: fmt.Sprintf("key-%d", i.keySeq), private: private}
+}
+
+// Requests количество запросов к пути path.
+func (i *Issuer) Requests(path string) int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.requests[path]
+}
+
+// Claims возвращает набор claims действительного id_token для пользователя subject.
+func (i *Issuer) Claims(subject, nonce string) map[string]any {
+	timeNow := time.Now()
+	return map[string]any{
+		"iss":   i.URL,
+		"sub":   subject,
+		"aud":   i.ClientID,
+		"iat":   timeNow.Unix(),
+		"exp":   timeNow.Add(time.Hour).Unix(),
+		"nonce": nonce,
+	}
+}
+
+// Sign подписывает claims текущим ключом и возвращает id_token.
+func (i *Issuer) Sign(claims map[string]any) string {
+	i.mu.Lock()
+	key := i.key
+	i.mu.Unlock()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": key.kid})
+	if err != nil {
+		panic(err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		panic(err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key.private, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// IssueCode выдает код авторизации, который token endpoint обменяет на idToken, если клиент предъявит
+// code_verifier к codeChallenge.
+func (i *Issuer) IssueCode(codeChallenge, idToken string) string {
+	code := fmt.Sprintf("code-%d", time.Now().UnixNano())
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.grants[code] = grant{codeChallenge: codeChallenge, idToken: idToken}
+	return code
+}
+
+func (i *Issuer) count(r *http.Request) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.requests[r.URL.Path]++
+}
+
+func (i *Issuer) discovery(w http.ResponseWriter, r *http.Request) {
+	i.count(r)
+	i.mu.Lock()
+	issuer := i.metadataIssuer
+	i.mu.Unlock()
+	if issuer == "" {
+		issuer = i.URL
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 issuer,
+		"authorization_endpoint": i.URL + AuthorizePath,
+		"token_endpoint":         i.URL + TokenPath,
+		"jwks_uri":               i.URL + JwksPath,
+	})
+}
+
+func (i *Issuer) jwks(w http.ResponseWriter, r *http.Request) {
+	i.count(r)
+	i.mu.Lock()
+	key := i.key
+	i.mu.Unlock()
+	public := key.private.PublicKey
+	writeJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": key.kid,
+		"use": "sig",
+		"alg": "RS256",
+		"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+	}}})
+}
+
+func (i *Issuer) token(w http.ResponseWriter, r *http.Request) {
+	i.count(r)
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	if r.PostForm.Get("client_id") != i.ClientID {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	code := r.PostForm.Get("code")
+	i.mu.Lock()
+	g, ok := i.grants[code]
+	delete(i.grants, code)
+	i.mu.Unlock()
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != g.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"token_type": "Bearer", "id_token": g.idToken})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
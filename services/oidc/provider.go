@@ -0,0 +1,347 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	ent "github.com/cantylv/hackathon-bmstu-2024-healthcheck/internal/entity"
+)
+
+var (
+	ErrInvalidIDToken = errors.New("id_token is invalid")
+	errNoIDToken      = errors.New("token response doesn't contain id_token")
+)
+
+// clockSkew допустимое расхождение часов с провайдером.
+const clockSkew = time.Minute
+
+// jwksMinRefresh как часто можно перечитывать JWKS при встрече неизвестного kid, чтобы поддельные
+// токены не превращали нас в источник нагрузки на провайдера.
+const jwksMinRefresh = time.Minute
+
+// Config настройки одного провайдера из секции oidc.providers.
+type Config struct {
+	Issuer       string   `mapstructure:"issuer"`
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	RedirectURL  string   `mapstructure:"redirect_url"`
+	Scopes       []string `mapstructure:"scopes"`
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+// Provider клиент OpenID Connect (authorization code flow + PKCE). Метаданные провайдера загружаются
+// при первом обращении и кэшируются.
+type Provider struct {
+	name   string
+	config Config
+	client *http.Client
+
+	mu          sync.Mutex
+	meta        *discovery
+	keys        map[string]crypto.PublicKey
+	keysFetched time.Time
+}
+
+// NewProvider возвращает клиента провайдера.
+func NewProvider(name string, config Config, client *http.Client) *Provider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &Provider{
+		name:   name,
+		config: config,
+		client: client,
+	}
+}
+
+// Name возвращает имя провайдера из конфигурации.
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// AuthCodeURL возвращает адрес страницы входа провайдера.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.config.ClientID)
+	params.Set("redirect_uri", p.config.RedirectURL)
+	params.Set("scope", strings.Join(p.config.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", codeChallenge)
+	params.Set("code_challenge_method", "S256")
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+// Exchange обменивает код авторизации на id_token.
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("client_id", p.config.ClientID)
+	form.Set("code_verifier", codeVerifier)
+	if p.config.ClientSecret != "" {
+		form.Set("client_secret", p.config.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	var tokenResponse struct {
+		IDToken string `json:"id_token"`
+	}
+	err = p.doJSON(req, &tokenResponse)
+	if err != nil {
+		return "", err
+	}
+	if tokenResponse.IDToken == "" {
+		return "", errNoIDToken
+	}
+	return tokenResponse.IDToken, nil
+}
+
+// VerifyIDToken проверяет подпись id_token по JWKS провайдера, издателя, получателя, срок действия и nonce.
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*ent.ExternalIdentity, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(rawIDToken, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidIDToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrInvalidIDToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidIDToken
+	}
+	key, err := p.key(ctx, meta, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	err = verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	var claims struct {
+		Issuer        string          `json:"iss"`
+		Subject       string          `json:"sub"`
+		Audience      json.RawMessage `json:"aud"`
+		AuthorizedBy  string          `json:"azp"`
+		Expiry        int64           `json:"exp"`
+		IssuedAt      int64           `json:"iat"`
+		Nonce         string          `json:"nonce"`
+		Email         string          `json:"email"`
+		EmailVerified json.RawMessage `json:"email_verified"`
+		Name          string          `json:"name"`
+		GivenName     string          `json:"given_name"`
+	}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidIDToken
+	}
+	timeNow := time.Now()
+	audience := parseAudience(claims.Audience)
+	switch {
+	case claims.Issuer != meta.Issuer:
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIDToken, claims.Issuer)
+	case !contains(audience, p.config.ClientID):
+		return nil, fmt.Errorf("%w: token is issued for another client", ErrInvalidIDToken)
+	case len(audience) > 1 && claims.AuthorizedBy != p.config.ClientID:
+		return nil, fmt.Errorf("%w: unexpected azp", ErrInvalidIDToken)
+	case claims.Subject == "":
+		return nil, fmt.Errorf("%w: empty subject", ErrInvalidIDToken)
+	case timeNow.After(time.Unix(claims.Expiry, 0).Add(clockSkew)):
+		return nil, fmt.Errorf("%w: token is expired", ErrInvalidIDToken)
+	case time.Unix(claims.IssuedAt, 0).After(timeNow.Add(clockSkew)):
+		return nil, fmt.Errorf("%w: token is issued in the future", ErrInvalidIDToken)
+	case claims.Nonce == "" || claims.Nonce != nonce:
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	firstName := claims.GivenName
+	if firstName == "" {
+		firstName = claims.Name
+	}
+	return &ent.ExternalIdentity{
+		Provider:      p.name,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: parseBool(claims.EmailVerified),
+		FirstName:     firstName,
+	}, nil
+}
+
+// discover загружает метаданные провайдера (/.well-known/openid-configuration).
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+	wellKnown := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, err
+	}
+	var meta discovery
+	err = p.doJSON(req, &meta)
+	if err != nil {
+		return nil, err
+	}
+	// OpenID Connect Discovery 1.0, 4.3: issuer из документа должен совпадать с настроенным
+	if strings.TrimSuffix(meta.Issuer, "/") != strings.TrimSuffix(p.config.Issuer, "/") {
+		return nil, fmt.Errorf("discovery issuer %q doesn't match configured issuer %q", meta.Issuer, p.config.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JwksURI == "" {
+		return nil, errors.New("discovery document is incomplete")
+	}
+	p.meta = &meta
+	return p.meta, nil
+}
+
+// key возвращает ключ по kid. Если ключ неизвестен, JWKS перечитывается: провайдеры периодически меняют ключи.
+func (p *Provider) key(ctx context.Context, meta *discovery, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetched) < jwksMinRefresh && p.keys != nil {
+		return nil, fmt.Errorf("%w: unknown key id %q", ErrInvalidIDToken, kid)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, meta.JwksURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set jwkSet
+	err = p.doJSON(req, &set)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	p.keys = keys
+	p.keysFetched = time.Now()
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown key id %q", ErrInvalidIDToken, kid)
+}
+
+// lookupKey если kid не указан, а ключ у провайдера один, используем его.
+func (p *Provider) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if key, ok := p.keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+func (p *Provider) doJSON(req *http.Request, dst any) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc provider %s responded %d to %s: %s", p.name, resp.StatusCode, req.URL.Path, body)
+	}
+	return json.Unmarshal(body, dst)
+}
+
+func decodeSegment(segment string, dst any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// parseAudience claim aud может быть строкой или массивом строк.
+func parseAudience(raw json.RawMessage) []string {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return []string{single}
+	}
+	var many []string
+	if err := json.Unmarshal(raw, &many); err == nil {
+		return many
+	}
+	return nil
+}
+
+// parseBool некоторые провайдеры передают email_verified строкой "true".
+func parseBool(raw json.RawMessage) bool {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s == "true"
+	}
+	return false
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
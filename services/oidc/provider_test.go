@@ -0,0 +1,234 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cantylv/hackathon-bmstu-2024-healthcheck/services/oidc/oidctest"
+)
+
+const testClientID = "healthcheck"
+
+func newTestProvider(t *testing.T) (*Provider, *oidctest.Issuer) {
+	t.Helper()
+	issuer := oidctest.NewIssuer(t, testClientID)
+	provider := NewProvider("mock", Config{
+		Issuer:      issuer.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://localhost:8010/api/v1/oidc/mock/callback",
+	}, &http.Client{Timeout: 5 * time.Second})
+	return provider, issuer
+}
+
+func TestDiscovery(t *testing.T) {
+	provider, issuer := newTestProvider(t)
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", "challenge-1")
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := u.Scheme+"://"+u.Host+u.Path, issuer.URL+oidctest.AuthorizePath; got != want {
+		t.Errorf("authorization endpoint = %s, want %s", got, want)
+	}
+	query := u.Query()
+	for param, want := range map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"state":                 "state-1",
+		"nonce":                 "nonce-1",
+		"code_challenge":        "challenge-1",
+		"code_challenge_method": "S256",
+		"scope":                 "openid email profile",
+	} {
+		if got := query.Get(param); got != want {
+			t.Errorf("%s = %q, want %q", param, got, want)
+		}
+	}
+
+	// метаданные загружаются один раз
+	if _, err := provider.AuthCodeURL(ctx, "state-2", "nonce-2", "challenge-2"); err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	if got := issuer.Requests(oidctest.DiscoveryPath); got != 1 {
+		t.Errorf("discovery requests = %d, want 1", got)
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	provider, issuer := newTestProvider(t)
+	issuer.SetMetadataIssuer("https://evil.example.com")
+
+	_, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "challenge")
+	if err == nil || !strings.Contains(err.Error(), "doesn't match configured issuer") {
+		t.Fatalf("AuthCodeURL() error = %v, want issuer mismatch", err)
+	}
+}
+
+func TestExchangePKCE(t *testing.T) {
+	provider, issuer := newTestProvider(t)
+	ctx := context.Background()
+	verifier := "verifier-0123456789-0123456789-0123456789"
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	idToken := issuer.Sign(issuer.Claims("subject", "nonce"))
+
+	got, err := provider.Exchange(ctx, issuer.IssueCode(challenge, idToken), verifier)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if got != idToken {
+		t.Errorf("Exchange() returned another id_token")
+	}
+
+	_, err = provider.Exchange(ctx, issuer.IssueCode(challenge, idToken), "another-verifier")
+	if err == nil {
+		t.Fatal("Exchange() with wrong code_verifier succeeded")
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	provider, issuer := newTestProvider(t)
+	const nonce = "nonce-1"
+	timeNow := time.Now()
+
+	tests := []struct {
+		name    string
+		claims  func(claims map[string]any)
+		token   func(token string) string
+		wantErr bool
+	}{
+		{name: "valid", claims: func(claims map[string]any) {
+			claims["email"] = "ivan@bmstu.ru"
+			claims["email_verified"] = true
+			claims["given_name"] = "Ivan"
+		}},
+		{name: "audience as array", claims: func(claims map[string]any) {
+			claims["aud"] = []string{testClientID}
+		}},
+		{name: "nonce mismatch", claims: func(claims map[string]any) { claims["nonce"] = "another-nonce" }, wantErr: true},
+		{name: "empty nonce", claims: func(claims map[string]any) { delete(claims, "nonce") }, wantErr: true},
+		{name: "expired", claims: func(claims map[string]any) {
+			claims["iat"] = timeNow.Add(-2 * time.Hour).Unix()
+			claims["exp"] = timeNow.Add(-time.Hour).Unix()
+		}, wantErr: true},
+		{name: "expired within clock skew", claims: func(claims map[string]any) {
+			claims["exp"] = timeNow.Add(-clockSkew / 2).Unix()
+		}},
+		{name: "issued in the future", claims: func(claims map[string]any) {
+			claims["iat"] = timeNow.Add(time.Hour).Unix()
+		}, wantErr: true},
+		{name: "another issuer", claims: func(claims map[string]any) { claims["iss"] = "https://evil.example.com" }, wantErr: true},
+		{name: "another audience", claims: func(claims map[string]any) { claims["aud"] = "another-client" }, wantErr: true},
+		{name: "several audiences without azp", claims: func(claims map[string]any) {
+			claims["aud"] = []string{testClientID, "another-client"}
+		}, wantErr: true},
+		{name: "tampered payload", token: func(token string) string {
+			parts := strings.Split(token, ".")
+			parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"admin"}`))
+			return strings.Join(parts, ".")
+		}, wantErr: true},
+		{name: "alg none", token: func(token string) string {
+			parts := strings.Split(token, ".")
+			parts[0] = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"key-1"}`))
+			return parts[0] + "." + parts[1] + "."
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := issuer.Claims("subject-1", nonce)
+			if tt.claims != nil {
+				tt.claims(claims)
+			}
+			token := issuer.Sign(claims)
+			if tt.token != nil {
+				token = tt.token(token)
+			}
+
+			identity, err := provider.VerifyIDToken(context.Background(), token, nonce)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidIDToken) {
+					t.Fatalf("VerifyIDToken() error = %v, want %v", err, ErrInvalidIDToken)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyIDToken() error = %v", err)
+			}
+			if identity.Provider != "mock" || identity.Subject != "subject-1" {
+				t.Errorf("identity = %+v, want provider mock and subject subject-1", identity)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenClaims(t *testing.T) {
+	provider, issuer := newTestProvider(t)
+	claims := issuer.Claims("subject-1", "nonce")
+	claims["email"] = "ivan@bmstu.ru"
+	claims["email_verified"] = "true"
+	claims["name"] = "Ivan Petrov"
+
+	identity, err := provider.VerifyIDToken(context.Background(), issuer.Sign(claims), "nonce")
+	if err != nil {
+		t.Fatalf("VerifyIDToken() error = %v", err)
+	}
+	if identity.Email != "ivan@bmstu.ru" || !identity.EmailVerified || identity.FirstName != "Ivan Petrov" {
+		t.Errorf("identity = %+v", identity)
+	}
+}
+
+func TestJWKSRotation(t *testing.T) {
+	provider, issuer := newTestProvider(t)
+	ctx := context.Background()
+	verify := func(token string) error {
+		_, err := provider.VerifyIDToken(ctx, token, "nonce")
+		return err
+	}
+
+	oldToken := issuer.Sign(issuer.Claims("subject", "nonce"))
+	if err := verify(oldToken); err != nil {
+		t.Fatalf("token signed with the first key: %v", err)
+	}
+	if got := issuer.Requests(oidctest.JwksPath); got != 1 {
+		t.Fatalf("jwks requests = %d, want 1", got)
+	}
+
+	issuer.RotateKey()
+	newToken := issuer.Sign(issuer.Claims("subject", "nonce"))
+
+	// неизвестный kid сразу после загрузки JWKS не приводит к повторной загрузке
+	if err := verify(newToken); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("token with unknown kid right after jwks refresh: error = %v, want %v", err, ErrInvalidIDToken)
+	}
+	if got := issuer.Requests(oidctest.JwksPath); got != 1 {
+		t.Fatalf("jwks requests = %d, want 1", got)
+	}
+
+	// когда интервал прошел, JWKS перечитывается и новый ключ принимается
+	provider.mu.Lock()
+	provider.keysFetched = provider.keysFetched.Add(-jwksMinRefresh)
+	provider.mu.Unlock()
+	if err := verify(newToken); err != nil {
+		t.Fatalf("token signed with the rotated key: %v", err)
+	}
+	if got := issuer.Requests(oidctest.JwksPath); got != 2 {
+		t.Fatalf("jwks requests = %d, want 2", got)
+	}
+
+	// ключ, удаленный из JWKS, больше не принимается
+	if err := verify(oldToken); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("token signed with the removed key: error = %v, want %v", err, ErrInvalidIDToken)
+	}
+}
@@ -0,0 +1,33 @@
+package oidc
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.uber.org/zap"
+)
+
+// Init создает клиентов провайдеров OpenID Connect из секции oidc.providers. Метаданные провайдеров
+// загружаются лениво, поэтому недоступность провайдера не мешает старту сервиса.
+func Init(logger *zap.Logger) map[string]*Provider {
+	var configs map[string]Config
+	err := viper.UnmarshalKey("oidc.providers", &configs)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error while reading oidc providers configuration: %v", err))
+		return nil
+	}
+	client := &http.Client{Timeout: 10 * time.Second, Transport: otelhttp.NewTransport(http.DefaultTransport)}
+	providers := make(map[string]*Provider, len(configs))
+	for name, config := range configs {
+		if config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+			logger.Warn(fmt.Sprintf("oidc provider '%s' is skipped: issuer, client_id and redirect_url are required", name))
+			continue
+		}
+		providers[name] = NewProvider(name, config, client)
+		logger.Info(fmt.Sprintf("oidc provider '%s' is configured", name))
+	}
+	return providers
+}
@@ -0,0 +1,7 @@
+-------- откат начальной схемы --------
+DROP TABLE IF EXISTS "user";
+
+DROP FUNCTION IF EXISTS update_updated_at_column();
+
+DROP TYPE IF EXISTS user_activity;
+DROP TYPE IF EXISTS user_sex;
@@ -0,0 +1,2 @@
+-------- откат таблицы refresh-токенов --------
+DROP TABLE IF EXISTS refresh_token;
@@ -0,0 +1,19 @@
+-------- DDL table 'refresh_token' --------
+-- Хранит хэши выданных refresh-токенов. Токены одной авторизации образуют семейство (family_id),
+-- при повторном использовании токена отзывается все семейство.
+CREATE TABLE refresh_token (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    family_id UUID NOT NULL,
+    username TEXT NOT NULL REFERENCES "user"(username) ON DELETE CASCADE ON UPDATE CASCADE,
+    token_hash TEXT NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    replaced_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+ALTER TABLE refresh_token
+    ADD CONSTRAINT refresh_token_unique_hash UNIQUE (token_hash);
+
+CREATE INDEX refresh_token_family_id_idx ON refresh_token (family_id);
+CREATE INDEX refresh_token_username_idx ON refresh_token (username);
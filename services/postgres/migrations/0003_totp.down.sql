@@ -0,0 +1,3 @@
+-------- откат второго фактора --------
+DROP TABLE IF EXISTS recovery_code;
+DROP TABLE IF EXISTS user_totp;
@@ -0,0 +1,20 @@
+-------- DDL table 'user_totp' --------
+-- Второй фактор (RFC 6238). Секрет зашифрован на стороне приложения (AES-GCM).
+CREATE TABLE user_totp (
+    username TEXT PRIMARY KEY REFERENCES "user"(username) ON DELETE CASCADE ON UPDATE CASCADE,
+    secret_encrypted TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT false,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-------- DDL table 'recovery_code' --------
+CREATE TABLE recovery_code (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    username TEXT NOT NULL REFERENCES "user"(username) ON DELETE CASCADE ON UPDATE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX recovery_code_username_idx ON recovery_code (username);
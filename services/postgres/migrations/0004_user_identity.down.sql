@@ -0,0 +1,2 @@
+-------- откат внешних учетных записей --------
+DROP TABLE IF EXISTS user_identity;
@@ -0,0 +1,13 @@
+-------- DDL table 'user_identity' --------
+-- Эта таблица содержит внешние учетные записи (OpenID Connect), привязанные к пользователям
+CREATE TABLE user_identity (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    provider TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    username TEXT NOT NULL REFERENCES "user"(username) ON DELETE CASCADE ON UPDATE CASCADE,
+    email TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX user_identity_username_idx ON user_identity (username);
@@ -0,0 +1,2 @@
+-------- откат API-ключей --------
+DROP TABLE IF EXISTS api_key;
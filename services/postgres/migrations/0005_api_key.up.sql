@@ -0,0 +1,15 @@
+-------- DDL table 'api_key' --------
+-- Эта таблица содержит персональные API-ключи пользователей, сами ключи не хранятся, только их хэши
+CREATE TABLE api_key (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    username TEXT NOT NULL REFERENCES "user"(username) ON DELETE CASCADE ON UPDATE CASCADE,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX api_key_username_idx ON api_key (username);
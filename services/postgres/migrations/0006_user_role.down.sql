@@ -0,0 +1,4 @@
+-------- откат ролей пользователей --------
+ALTER TABLE "user" DROP COLUMN IF EXISTS role;
+
+DROP TYPE IF EXISTS user_role;
@@ -0,0 +1,6 @@
+-------- роли пользователей --------
+-- Роли пользователей, права ролей описаны в приложении (myconstants.RolePermissions)
+CREATE TYPE user_role AS ENUM ('user', 'coach', 'admin');
+
+ALTER TABLE "user"
+    ADD COLUMN role user_role NOT NULL DEFAULT 'user';
@@ -0,0 +1,9 @@
+-------- откат групп, агентов и заявок --------
+DROP TABLE IF EXISTS participation;
+DROP TABLE IF EXISTS privelege_user;
+DROP TABLE IF EXISTS privelege_group;
+DROP TABLE IF EXISTS bid;
+DROP TABLE IF EXISTS agent;
+DROP TABLE IF EXISTS "group";
+
+DROP TYPE IF EXISTS status_type;
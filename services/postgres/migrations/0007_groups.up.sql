@@ -0,0 +1,102 @@
+-------- DDL table 'group' --------
+-- Эта таблица содержит данные о группах. Пользователь, ответственный за группу, не может быть удален,
+-- пока группа существует.
+CREATE TABLE "group" (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name TEXT,
+    owner_id UUID REFERENCES "user"(id) ON DELETE RESTRICT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+ALTER TABLE "group"
+    ADD CONSTRAINT group_unique_name UNIQUE (name),
+    ADD CONSTRAINT group_name_length CHECK (LENGTH(name) >= 2 AND LENGTH(name) <= 30);
+
+ALTER TABLE "group"
+    ALTER COLUMN name SET NOT NULL,
+    ALTER COLUMN owner_id SET NOT NULL,
+    ALTER COLUMN created_at SET NOT NULL,
+    ALTER COLUMN updated_at SET NOT NULL;
+
+-------- DDL table 'agent' --------
+-- Эта таблица содержит агентов, доступ к которым выдается пользователям и группам
+CREATE TABLE agent (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+ALTER TABLE agent
+    ADD CONSTRAINT agent_unique_name UNIQUE (name),
+    ADD CONSTRAINT agent_name_length CHECK (LENGTH(name) >= 2 AND LENGTH(name) <= 50);
+
+ALTER TABLE agent
+    ALTER COLUMN name SET NOT NULL,
+    ALTER COLUMN created_at SET NOT NULL;
+
+-------- DDL table 'bid' --------
+CREATE TYPE status_type AS ENUM ('in_progress', 'rejected', 'approved');
+
+-- Эта таблица содержит данные о заявках на создание группы
+CREATE TABLE bid (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    group_name TEXT,
+    user_id UUID REFERENCES "user"(id) ON DELETE CASCADE,
+    status status_type,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+ALTER TABLE bid
+    ALTER COLUMN group_name SET NOT NULL,
+    ALTER COLUMN user_id SET NOT NULL,
+    ALTER COLUMN status SET NOT NULL,
+    ALTER COLUMN created_at SET NOT NULL,
+    ALTER COLUMN updated_at SET NOT NULL;
+
+-- на одно название группы может быть только одна нерассмотренная заявка
+CREATE UNIQUE INDEX bid_unique_group_name_in_progress ON bid (group_name) WHERE status = 'in_progress';
+CREATE INDEX bid_user_id_idx ON bid (user_id);
+
+-------- DDL table 'privelege_group' --------
+-- Эта таблица содержит доступ групп к агентам
+CREATE TABLE privelege_group (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    agent_id INT NOT NULL REFERENCES agent(id) ON DELETE CASCADE,
+    group_id INT NOT NULL REFERENCES "group"(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    UNIQUE (agent_id, group_id)
+);
+
+-------- DDL table 'privelege_user' --------
+-- Эта таблица содержит доступ пользователей к агентам
+CREATE TABLE privelege_user (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    agent_id INT NOT NULL REFERENCES agent(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    UNIQUE (agent_id, user_id)
+);
+
+-------- DDL table 'participation' --------
+-- Эта таблица содержит принадлежность пользователей к группам
+CREATE TABLE participation (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES "user"(id) ON DELETE CASCADE,
+    group_id INT NOT NULL REFERENCES "group"(id) ON DELETE CASCADE,
+    UNIQUE (user_id, group_id)
+);
+
+CREATE INDEX participation_group_id_idx ON participation (group_id);
+
+-------- TRIGGERS --------
+CREATE TRIGGER update_group_updated_at
+BEFORE UPDATE ON "group"
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_bid_updated_at
+BEFORE UPDATE ON bid
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
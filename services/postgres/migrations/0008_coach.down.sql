@@ -0,0 +1,5 @@
+-------- откат связей тренеров и клиентов --------
+DROP TABLE IF EXISTS coach_comment;
+DROP TABLE IF EXISTS coach_link;
+
+DROP TYPE IF EXISTS coach_link_status;
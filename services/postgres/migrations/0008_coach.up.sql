@@ -0,0 +1,38 @@
+-------- DDL table 'coach_link' --------
+CREATE TYPE coach_link_status AS ENUM ('pending', 'active', 'revoked');
+
+-- Связь тренера и клиента. Клиент приглашает тренера и выбирает, какие данные ему открыть (scopes),
+-- тренер может задать клиенту норму калорий, которая заменяет рассчитанную (day_calories)
+CREATE TABLE coach_link (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    client_username TEXT NOT NULL REFERENCES "user"(username) ON DELETE CASCADE ON UPDATE CASCADE,
+    coach_username TEXT NOT NULL REFERENCES "user"(username) ON DELETE CASCADE ON UPDATE CASCADE,
+    status coach_link_status NOT NULL DEFAULT 'pending',
+    scopes TEXT[] NOT NULL,
+    calorie_target FLOAT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    CHECK (client_username <> coach_username)
+);
+
+-- у пары клиент-тренер может быть только одна действующая связь или приглашение
+CREATE UNIQUE INDEX coach_link_unique_pair ON coach_link (client_username, coach_username)
+    WHERE status IN ('pending', 'active');
+CREATE INDEX coach_link_coach_username_idx ON coach_link (coach_username);
+
+-------- DDL table 'coach_comment' --------
+-- Комментарии тренера для клиента
+CREATE TABLE coach_comment (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    link_id UUID NOT NULL REFERENCES coach_link(id) ON DELETE CASCADE,
+    text TEXT NOT NULL CHECK (LENGTH(text) >= 1 AND LENGTH(text) <= 1000),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX coach_comment_link_id_idx ON coach_comment (link_id);
+
+-------- TRIGGERS --------
+CREATE TRIGGER update_coach_link_updated_at
+BEFORE UPDATE ON coach_link
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,8 @@
+-------- откат журнала действий и блокировок --------
+DROP TABLE IF EXISTS audit_event;
+
+DROP FUNCTION IF EXISTS forbid_audit_event_change();
+
+ALTER TABLE "user"
+    DROP COLUMN IF EXISTS password_reset_required,
+    DROP COLUMN IF EXISTS locked_at;
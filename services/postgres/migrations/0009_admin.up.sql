@@ -0,0 +1,43 @@
+-------- блокировка учетных записей --------
+ALTER TABLE "user"
+    -- учетная запись заблокирована администратором
+    ADD COLUMN locked_at TIMESTAMP WITH TIME ZONE,
+    -- администратор потребовал сменить пароль при следующем входе
+    ADD COLUMN password_reset_required BOOLEAN NOT NULL DEFAULT false;
+
+-------- DDL table 'audit_event' --------
+-- Журнал действий. Записи только добавляются: изменение и удаление запрещены триггером, поэтому subject
+-- не ссылается на "user" — записи об удаленных пользователях сохраняются.
+CREATE TABLE audit_event (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    actor TEXT,
+    action TEXT NOT NULL,
+    subject TEXT,
+    ip TEXT,
+    user_agent TEXT,
+    request_id TEXT,
+    details JSONB,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX audit_event_created_at_idx ON audit_event (created_at);
+CREATE INDEX audit_event_subject_idx ON audit_event (subject, created_at);
+
+-------- FUNCTIONS AND TRIGGERS --------
+-- table 'audit_event'
+CREATE OR REPLACE FUNCTION forbid_audit_event_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_event is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_event_append_only
+BEFORE UPDATE OR DELETE ON audit_event
+FOR EACH ROW
+EXECUTE FUNCTION forbid_audit_event_change();
+
+CREATE TRIGGER audit_event_no_truncate
+BEFORE TRUNCATE ON audit_event
+FOR EACH STATEMENT
+EXECUTE FUNCTION forbid_audit_event_change();
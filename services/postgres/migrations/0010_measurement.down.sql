@@ -0,0 +1,2 @@
+-------- откат таблицы измерений --------
+DROP TABLE IF EXISTS measurement;
@@ -0,0 +1,12 @@
+-------- DDL table 'measurement' --------
+-- Измерения пользователя (масса тела с умных весов, шаги, пульс). Используется, если measurements.storage: postgres
+CREATE TABLE measurement (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    username TEXT NOT NULL REFERENCES "user"(username) ON DELETE CASCADE ON UPDATE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('weight', 'steps', 'heart_rate')),
+    value DOUBLE PRECISION NOT NULL,
+    recorded_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX measurement_username_kind_idx ON measurement (username, kind, recorded_at);
+CREATE INDEX measurement_username_idx ON measurement (username, recorded_at);
@@ -0,0 +1,6 @@
+-------- откат дневника питания --------
+DELETE FROM measurement WHERE kind = 'calories';
+
+ALTER TABLE measurement DROP CONSTRAINT measurement_kind_check;
+ALTER TABLE measurement ADD CONSTRAINT measurement_kind_check
+    CHECK (kind IN ('weight', 'steps', 'heart_rate'));
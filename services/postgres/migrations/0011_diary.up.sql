@@ -0,0 +1,5 @@
+-------- дневник питания --------
+-- Записи дневника питания хранятся измерениями calories (ккал съеденного), их видит тренер с правом diary
+ALTER TABLE measurement DROP CONSTRAINT measurement_kind_check;
+ALTER TABLE measurement ADD CONSTRAINT measurement_kind_check
+    CHECK (kind IN ('weight', 'steps', 'heart_rate', 'calories'));
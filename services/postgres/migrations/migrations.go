@@ -0,0 +1,297 @@
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// Файлы миграций называются <версия>_<название>.up.sql и <версия>_<название>.down.sql и встраиваются
+// в исполняемый файл. Примененные версии хранятся в таблице schema_migrations.
+//
+//go:embed *.sql
+var files embed.FS
+
+// lockKey ключ advisory-блокировки, которую держит мигрирующий экземпляр сервиса.
+const lockKey = 7_242_024
+
+// baselineVersion начальная миграция. Она совпадает со скриптом ddl.sql, которым база создавалась до появления
+// миграций, поэтому такая база считается мигрированной до этой версии.
+const baselineVersion = 1
+
+var fileNameRegexp = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+var (
+	ErrNoDownScript = errors.New("migration has no down script")
+	ErrDirtyHistory = errors.New("schema_migrations contains versions unknown to this binary")
+)
+
+// Migration версия схемы базы данных.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status версия схемы и признак того, что она применена.
+type Status struct {
+	Migration
+	Applied bool
+}
+
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+	logger     *zap.Logger
+}
+
+// New возвращает мигратор со встроенными миграциями.
+func New(pool *pgxpool.Pool, logger *zap.Logger) (*Migrator, error) {
+	migrations, err := load(files)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{pool: pool, migrations: migrations, logger: logger}, nil
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileNameRegexp.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has different names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+	result := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		result = append(result, *m)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
+
+// Up применяет все непримененные миграции, каждую в своей транзакции. В режиме dryRun только пишет в лог,
+// какие миграции будут применены. Возвращает применяемые миграции.
+func (m *Migrator) Up(ctx context.Context, dryRun bool) ([]Migration, error) {
+	var pending []Migration
+	err := m.withLock(ctx, dryRun, func(conn *pgxpool.Conn, applied map[int64]bool) error {
+		for _, migration := range m.migrations {
+			if applied[migration.Version] {
+				continue
+			}
+			pending = append(pending, migration)
+			if dryRun {
+				m.logger.Info(fmt.Sprintf("dry run: migration %d_%s will be applied:\n%s", migration.Version, migration.Name, migration.Up))
+				continue
+			}
+			err := m.apply(ctx, conn, migration.Up,
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, migration.Version, migration.Name)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			m.logger.Info(fmt.Sprintf("migration %d_%s applied", migration.Version, migration.Name))
+		}
+		return nil
+	})
+	return pending, err
+}
+
+// Down откатывает steps последних примененных миграций.
+func (m *Migrator) Down(ctx context.Context, steps int, dryRun bool) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(ctx, dryRun, func(conn *pgxpool.Conn, applied map[int64]bool) error {
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if !applied[migration.Version] {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, ErrNoDownScript)
+			}
+			reverted = append(reverted, migration)
+			if dryRun {
+				m.logger.Info(fmt.Sprintf("dry run: migration %d_%s will be reverted:\n%s", migration.Version, migration.Name, migration.Down))
+				continue
+			}
+			err := m.apply(ctx, conn, migration.Down, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			m.logger.Info(fmt.Sprintf("migration %d_%s reverted", migration.Version, migration.Name))
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status возвращает все известные миграции и признак их применения.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var result []Status
+	err := m.withLock(ctx, true, func(_ *pgxpool.Conn, applied map[int64]bool) error {
+		for _, migration := range m.migrations {
+			result = append(result, Status{Migration: migration, Applied: applied[migration.Version]})
+		}
+		return nil
+	})
+	return result, err
+}
+
+// withLock берет advisory-блокировку на отдельном соединении, чтобы несколько экземпляров сервиса
+// не мигрировали одновременно, и передает в fn примененные версии. В режиме readOnly таблица
+// schema_migrations не создается. База, созданная до появления миграций, отмечается мигрированной
+// до baselineVersion.
+func (m *Migrator) withLock(ctx context.Context, readOnly bool, fn func(conn *pgxpool.Conn, applied map[int64]bool) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockKey)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// соединение возвращается в пул, поэтому блокировку нужно снять явно
+		_, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+		if err != nil {
+			m.logger.Error(fmt.Sprintf("error while releasing migration lock: %v", err))
+		}
+	}()
+
+	// таблица "user" без schema_migrations: схему создал скрипт ddl.sql
+	var legacy bool
+	err = conn.QueryRow(ctx,
+		`SELECT to_regclass('schema_migrations') IS NULL AND to_regclass('"user"') IS NOT NULL`).Scan(&legacy)
+	if err != nil {
+		return err
+	}
+	if !readOnly {
+		_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`)
+		if err != nil {
+			return err
+		}
+		if legacy {
+			err = m.stampBaseline(ctx, conn)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if legacy {
+		applied[baselineVersion] = true
+	}
+	return fn(conn, applied)
+}
+
+// stampBaseline отмечает начальную миграцию примененной, не выполняя ее.
+func (m *Migrator) stampBaseline(ctx context.Context, conn *pgxpool.Conn) error {
+	for _, migration := range m.migrations {
+		if migration.Version != baselineVersion {
+			continue
+		}
+		_, err := conn.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`,
+			migration.Version, migration.Name)
+		if err != nil {
+			return err
+		}
+		m.logger.Info(fmt.Sprintf("existing schema marked as migration %d_%s", migration.Version, migration.Name))
+		return nil
+	}
+	return nil
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *pgxpool.Conn) (map[int64]bool, error) {
+	var exists bool
+	err := conn.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists)
+	if err != nil || !exists {
+		return map[int64]bool{}, err
+	}
+	rows, err := conn.Query(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[int64]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+	}
+	applied := make(map[int64]bool, len(versions))
+	for _, version := range versions {
+		// база новее исполняемого файла: применять или откатывать что-либо опасно
+		if !known[version] {
+			return nil, fmt.Errorf("%w: version %d", ErrDirtyHistory, version)
+		}
+		applied[version] = true
+	}
+	return applied, nil
+}
+
+// apply выполняет скрипт миграции и изменяет schema_migrations в одной транзакции.
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, script, historySQL string, historyArgs ...any) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// без аргументов запрос выполняется простым протоколом, который допускает несколько команд
+	_, err = tx.Exec(ctx, script)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, historySQL, historyArgs...)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := load(files)
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if len(migrations) == 0 || migrations[0].Version != baselineVersion || migrations[0].Name != "init" {
+		t.Fatalf("first migration = %+v, want %d_init", migrations[0], baselineVersion)
+	}
+	for i, m := range migrations {
+		// версии идут подряд, чтобы новая миграция не встала между уже примененными
+		if m.Version != int64(i+1) {
+			t.Errorf("migration %d_%s: version %d, want %d", m.Version, m.Name, m.Version, i+1)
+		}
+		if m.Down == "" {
+			t.Errorf("migration %d_%s has no down script", m.Version, m.Name)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{name: "no up script", files: fstest.MapFS{"0001_init.down.sql": {Data: []byte("DROP TABLE t;")}}},
+		{name: "different names", files: fstest.MapFS{
+			"0001_init.up.sql":    {Data: []byte("CREATE TABLE t ();")},
+			"0001_other.down.sql": {Data: []byte("DROP TABLE t;")},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := load(tt.files); err == nil {
+				t.Fatal("load() succeeded, want error")
+			}
+		})
+	}
+}
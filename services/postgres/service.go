@@ -5,13 +5,17 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/exaring/otelpgx"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
-// Init инициализирует клиента PostgreSQL.
-func Init(logger *zap.Logger) *pgx.Conn {
+// Init инициализирует пул соединений с PostgreSQL. Пул сам восстанавливает соединения: разорванные
+// соединения отбрасываются при проверке здоровья или при ошибке запроса, а новые открываются по требованию.
+// Поэтому недоступность базы при старте не останавливает сервис — запросы к базе завершаются ошибкой,
+// пока база не вернется.
+func Init(logger *zap.Logger) *pgxpool.Pool {
 	connString := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s",
 		viper.GetString("postgres.user"),
 		viper.GetString("postgres.password"),
@@ -20,29 +24,35 @@ func Init(logger *zap.Logger) *pgx.Conn {
 		viper.GetString("postgres.database_name"),
 		viper.GetString("postgres.sslmode"),
 	)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	conn, err := pgx.Connect(ctx, connString)
+	poolConfig, err := pgxpool.ParseConfig(connString)
 	if err != nil {
-		logger.Fatal(fmt.Sprintf("error while connecting to postgresql: %v", err))
+		logger.Fatal(fmt.Sprintf("invalid postgresql configuration: %v", err))
+	}
+	poolConfig.MaxConns = viper.GetInt32("postgres.pool.max_conns")
+	poolConfig.MinConns = viper.GetInt32("postgres.pool.min_conns")
+	poolConfig.MaxConnLifetime = viper.GetDuration("postgres.pool.max_conn_lifetime")
+	poolConfig.MaxConnIdleTime = viper.GetDuration("postgres.pool.max_conn_idle_time")
+	poolConfig.HealthCheckPeriod = viper.GetDuration("postgres.pool.health_check_period")
+	poolConfig.ConnConfig.ConnectTimeout = viper.GetDuration("postgres.pool.connect_timeout")
+	// запросы записываются спанами трассировки запроса, который их выполняет
+	poolConfig.ConnConfig.Tracer = otelpgx.NewTracer()
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("error while creating postgresql pool: %v", err))
 	}
 	const maxPingAttempts = 3
 
-	var successConn bool
 	for i := 0; i < maxPingAttempts; i++ {
 		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-		err := conn.Ping(ctx)
+		err = pool.Ping(ctx)
 		cancel()
 		if err == nil {
-			successConn = true
-			break
+			logger.Info("postgresql connected successfully")
+			return pool
 		}
 		logger.Warn(fmt.Sprintf("error while ping to postgresql: %v", err))
 	}
-	if !successConn {
-		logger.Fatal("can't establish connection to postgresql")
-	}
-
-	logger.Info("postgresql connected successfully")
-	return conn
+	logger.Error("postgresql is not available, connection will be established on demand")
+	return pool
 }